@@ -0,0 +1,249 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Agile window kinds
+const (
+	AgileWindowPlunge = "plunge"
+	AgileWindowCheap  = "cheap"
+	AgileWindowPeak   = "peak"
+)
+
+// AgileConfig sets the price thresholds, in pence per kWh including VAT, that
+// turn day-ahead Agile rates into plunge, cheap and peak windows
+type AgileConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	PlungeBelow float64  `yaml:"plunge_below"` // default 0, so plunge means you are paid to use power
+	CheapBelow  *float64 `yaml:"cheap_below"`  // default 10; a pointer so 0 can be set
+	PeakAbove   *float64 `yaml:"peak_above"`   // default 35; a pointer so 0 can be set
+	AlertOn     []string `yaml:"alert_on"`     // window kinds to alert on, default all
+}
+
+// applyDefaults fills thresholds that were left out
+func (c *AgileConfig) applyDefaults() {
+	if c.CheapBelow == nil {
+		cheapBelow := AgileDefaultCheapBelow
+		c.CheapBelow = &cheapBelow
+	}
+	if c.PeakAbove == nil {
+		peakAbove := AgileDefaultPeakAbove
+		c.PeakAbove = &peakAbove
+	}
+	if len(c.AlertOn) == 0 {
+		c.AlertOn = []string{AgileWindowPlunge, AgileWindowCheap, AgileWindowPeak}
+	}
+}
+
+func (c *AgileConfig) validate() []string {
+	var problems []string
+	if !c.Enabled {
+		return problems
+	}
+	cfg := *c
+	cfg.applyDefaults()
+	if cfg.PlungeBelow > *cfg.CheapBelow {
+		problems = append(problems, fmt.Sprintf("plunge_below (%.2f) must not be above cheap_below (%.2f)", cfg.PlungeBelow, *cfg.CheapBelow))
+	}
+	if *cfg.CheapBelow >= *cfg.PeakAbove {
+		problems = append(problems, fmt.Sprintf("cheap_below (%.2f) must be below peak_above (%.2f)", *cfg.CheapBelow, *cfg.PeakAbove))
+	}
+	for _, kind := range cfg.AlertOn {
+		if kind != AgileWindowPlunge && kind != AgileWindowCheap && kind != AgileWindowPeak {
+			problems = append(problems, fmt.Sprintf("unknown alert_on window %q (use plunge, cheap or peak)", kind))
+		}
+	}
+	return problems
+}
+
+// alertsOn reports whether windows of kind should raise alerts
+func (c *AgileConfig) alertsOn(kind string) bool {
+	for _, k := range c.AlertOn {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// classify returns the window kind for a price, or "" for an ordinary price
+func (c *AgileConfig) classify(pence float64) string {
+	switch {
+	case pence < c.PlungeBelow:
+		return AgileWindowPlunge
+	case pence < *c.CheapBelow:
+		return AgileWindowCheap
+	case pence > *c.PeakAbove:
+		return AgileWindowPeak
+	}
+	return ""
+}
+
+// AgileWindow is a run of consecutive half-hours in the same price band
+type AgileWindow struct {
+	Code     string    `json:"code"` // stable while the window is known, used for alert state
+	Kind     string    `json:"kind"`
+	StartAt  time.Time `json:"start"`
+	EndAt    time.Time `json:"end"`
+	MinPence float64   `json:"min_pence"`
+	MaxPence float64   `json:"max_pence"`
+	AvgPence float64   `json:"avg_pence"`
+}
+
+// isAgileTariff reports whether a tariff code is on an Agile product
+func isAgileTariff(tariffCode string) bool {
+	return strings.Contains(strings.ToUpper(tariffCode), "-AGILE-")
+}
+
+// findAgileWindows groups half-hourly rates into plunge, cheap and peak
+// windows in time order. Open-ended rates are not Agile prices and are ignored.
+func findAgileWindows(rates []TariffRate, cfg AgileConfig) []AgileWindow {
+	slots := make([]TariffRate, 0, len(rates))
+	for _, rate := range rates {
+		if rate.ValidTo != nil {
+			slots = append(slots, rate)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].ValidFrom.Before(slots[j].ValidFrom) })
+
+	windows := []AgileWindow{}
+	var current *AgileWindow
+	var count int
+	closeWindow := func() {
+		if current != nil {
+			current.AvgPence /= float64(count)
+			windows = append(windows, *current)
+			current = nil
+		}
+	}
+	for _, slot := range slots {
+		kind := cfg.classify(slot.ValueIncVAT)
+		if current != nil && (kind != current.Kind || !slot.ValidFrom.Equal(current.EndAt)) {
+			closeWindow()
+		}
+		if kind == "" {
+			continue
+		}
+		if current == nil {
+			current = &AgileWindow{
+				Code:     fmt.Sprintf("agile-%s-%d", kind, slot.ValidFrom.Unix()),
+				Kind:     kind,
+				StartAt:  slot.ValidFrom,
+				MinPence: slot.ValueIncVAT,
+				MaxPence: slot.ValueIncVAT,
+			}
+			count = 0
+		}
+		current.EndAt = *slot.ValidTo
+		current.MinPence = math.Min(current.MinPence, slot.ValueIncVAT)
+		current.MaxPence = math.Max(current.MaxPence, slot.ValueIncVAT)
+		current.AvgPence += slot.ValueIncVAT
+		count++
+	}
+	closeWindow()
+	return windows
+}
+
+// SetAgileAlerts enables Agile price windows with the given thresholds
+func (m *SavingSessionMonitor) SetAgileAlerts(cfg *AgileConfig) {
+	agile := *cfg
+	agile.applyDefaults()
+	m.agile = &agile
+}
+
+// agileWindows returns the current and upcoming price windows for the Agile
+// import tariff, or none when Agile alerts are off or the account isn't on Agile
+func (m *SavingSessionMonitor) agileWindows(now time.Time) ([]AgileWindow, error) {
+	if m.agile == nil {
+		return nil, nil
+	}
+	tariffs, err := m.client.getTariffsWithCache(m.state)
+	if err != nil {
+		return nil, err
+	}
+
+	var upcoming []AgileWindow
+	for _, tariff := range tariffs {
+		if tariff.Fuel != FuelElectricity || !isAgileTariff(tariff.TariffCode) {
+			continue
+		}
+		for _, window := range findAgileWindows(tariff.UnitRates, *m.agile) {
+			if window.EndAt.After(now) {
+				upcoming = append(upcoming, window)
+			}
+		}
+		break // every import meter on an account shares the region's prices
+	}
+	return upcoming, nil
+}
+
+// checkAgilePrices raises the multi-stage alerts for Agile windows, the same
+// way as free electricity sessions
+func (m *SavingSessionMonitor) checkAgilePrices() error {
+	now := time.Now()
+	windows, err := m.agileWindows(now)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Agile prices: %w", err)
+	}
+
+	for _, window := range windows {
+		if !m.agile.alertsOn(window.Kind) {
+			continue
+		}
+		timeUntil := time.Duration(0)
+		if window.StartAt.After(now) {
+			timeUntil = window.StartAt.Sub(now)
+		}
+		shouldAlert, alertType := m.shouldAlertWindow(window.Code, window.StartAt, window.EndAt, timeUntil)
+		if !shouldAlert {
+			continue
+		}
+
+		title := fmt.Sprintf("Agile %s prices - %s", window.Kind, alertType)
+		message := fmt.Sprintf("%s %s-%s, %.2fp to %.2fp/kWh",
+			window.StartAt.Format("Monday, Jan 2"), window.StartAt.Format("15:04"), window.EndAt.Format("15:04"),
+			window.MinPence, window.MaxPence)
+		if m.daemonMode {
+			m.logger.Info("AGILE PRICE WINDOW",
+				"kind", window.Kind,
+				"alert_type", alertType,
+				"start", window.StartAt.Format("15:04"),
+				"end", window.EndAt.Format("15:04"),
+				"min_pence", window.MinPence,
+				"max_pence", window.MaxPence,
+			)
+		} else {
+			m.logger.UserMessage("💷 AGILE %s PRICES - %s", strings.ToUpper(window.Kind), alertType)
+			m.logger.UserMessage("   %s", message)
+		}
+		m.notify(EventAgilePriceAlert, title, message, map[string]interface{}{
+			"code":       window.Code,
+			"kind":       window.Kind,
+			"start_at":   window.StartAt,
+			"end_at":     window.EndAt,
+			"min_pence":  window.MinPence,
+			"max_pence":  window.MaxPence,
+			"alert_type": alertType,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindAgileWindows(t *testing.T) {
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	var rates []TariffRate
+	for i, pence := range []float64{-2, -0.5, 4, 15, 20, 40, 38, 15, 8} {
+		from := start.Add(time.Duration(i) * 30 * time.Minute)
+		to := from.Add(30 * time.Minute)
+		rates = append(rates, TariffRate{ValueIncVAT: pence, ValidFrom: from, ValidTo: &to})
+	}
+	// Newest first like the products API, plus an open-ended rate that isn't an Agile price
+	for i, j := 0, len(rates)-1; i < j; i, j = i+1, j-1 {
+		rates[i], rates[j] = rates[j], rates[i]
+	}
+	rates = append(rates, TariffRate{ValueIncVAT: -10, ValidFrom: start})
+
+	cfg := AgileConfig{Enabled: true}
+	cfg.applyDefaults()
+	windows := findAgileWindows(rates, cfg)
+
+	expected := []struct {
+		kind     string
+		slots    int
+		min, max float64
+	}{
+		{AgileWindowPlunge, 2, -2, -0.5},
+		{AgileWindowCheap, 1, 4, 4},
+		{AgileWindowPeak, 2, 38, 40},
+		{AgileWindowCheap, 1, 8, 8},
+	}
+	if len(windows) != len(expected) {
+		t.Fatalf("Expected %d windows, got %+v", len(expected), windows)
+	}
+	for i, want := range expected {
+		window := windows[i]
+		if window.Kind != want.kind || window.EndAt.Sub(window.StartAt) != time.Duration(want.slots)*30*time.Minute {
+			t.Errorf("Window %d: expected %d %s slots, got %+v", i, want.slots, want.kind, window)
+		}
+		if window.MinPence != want.min || window.MaxPence != want.max {
+			t.Errorf("Window %d: expected %.2fp-%.2fp, got %.2fp-%.2fp", i, want.min, want.max, window.MinPence, window.MaxPence)
+		}
+	}
+	if windows[0].AvgPence != -1.25 {
+		t.Errorf("Expected plunge average of -1.25p, got %.2f", windows[0].AvgPence)
+	}
+	if windows[0].Code == windows[1].Code || !strings.HasPrefix(windows[2].Code, "agile-peak-") {
+		t.Errorf("Expected unique codes per window, got %q, %q and %q", windows[0].Code, windows[1].Code, windows[2].Code)
+	}
+
+	// A gap in the published prices splits a window
+	firstEnd, second, secondEnd := start.Add(30*time.Minute), start.Add(time.Hour), start.Add(90*time.Minute)
+	gapped := []TariffRate{
+		{ValueIncVAT: 40, ValidFrom: start, ValidTo: &firstEnd},
+		{ValueIncVAT: 40, ValidFrom: second, ValidTo: &secondEnd},
+	}
+	if got := findAgileWindows(gapped, cfg); len(got) != 2 {
+		t.Errorf("Expected non-contiguous peak slots to make 2 windows, got %+v", got)
+	}
+}
+
+// pence returns a price threshold for an AgileConfig
+func pence(p float64) *float64 {
+	return &p
+}
+
+func TestAgileConfigZeroThresholds(t *testing.T) {
+	var cfg AgileConfig
+	if err := yaml.Unmarshal([]byte("enabled: true\nplunge_below: -5\ncheap_below: -1\npeak_above: 0\n"), &cfg); err != nil {
+		t.Fatalf("Expected the config to parse, got %v", err)
+	}
+	cfg.applyDefaults()
+
+	// A threshold set to zero is kept rather than replaced by the default
+	if *cfg.CheapBelow != -1 || *cfg.PeakAbove != 0 {
+		t.Fatalf("Expected thresholds of -1 and 0, got %v and %v", *cfg.CheapBelow, *cfg.PeakAbove)
+	}
+	if kind := cfg.classify(0.5); kind != AgileWindowPeak {
+		t.Errorf("Expected any positive price to be peak, got %q", kind)
+	}
+	if kind := cfg.classify(-2); kind != AgileWindowCheap {
+		t.Errorf("Expected -2p to be cheap, got %q", kind)
+	}
+}
+
+func TestAgileConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AgileConfig
+		valid  bool
+	}{
+		{"disabled is never checked", AgileConfig{CheapBelow: pence(50), PeakAbove: pence(10)}, true},
+		{"defaults", AgileConfig{Enabled: true}, true},
+		{"custom thresholds", AgileConfig{Enabled: true, PlungeBelow: -1, CheapBelow: pence(12), PeakAbove: pence(30), AlertOn: []string{"plunge", "peak"}}, true},
+		{"cheap above peak", AgileConfig{Enabled: true, CheapBelow: pence(40)}, false},
+		{"plunge above cheap", AgileConfig{Enabled: true, PlungeBelow: 15}, false},
+		{"zero thresholds", AgileConfig{Enabled: true, PlungeBelow: -5, CheapBelow: pence(0), PeakAbove: pence(0)}, false},
+		{"negative cheap threshold", AgileConfig{Enabled: true, PlungeBelow: -10, CheapBelow: pence(-2), PeakAbove: pence(0)}, true},
+		{"unknown window", AgileConfig{Enabled: true, AlertOn: []string{"free"}}, false},
+	}
+
+	for _, test := range tests {
+		problems := test.config.validate()
+		if (len(problems) == 0) != test.valid {
+			t.Errorf("%s: expected valid=%v, got problems %v", test.name, test.valid, problems)
+		}
+	}
+}
+
+func TestIsAgileTariff(t *testing.T) {
+	if !isAgileTariff("E-1R-AGILE-24-10-01-C") || !isAgileTariff("e-1r-agile-flex-22-11-25-c") {
+		t.Error("Expected Agile tariff codes to be recognised")
+	}
+	if isAgileTariff("E-1R-VAR-22-11-01-C") {
+		t.Error("Expected non-Agile tariff codes to be rejected")
+	}
+}
+
+// cacheAgileRates stores an Agile import tariff whose half-hourly prices run
+// from start, as if it had just been fetched
+func cacheAgileRates(state *AppState, start time.Time, prices ...float64) {
+	var rates []TariffRate
+	for i, pence := range prices {
+		from := start.Add(time.Duration(i) * 30 * time.Minute)
+		to := from.Add(30 * time.Minute)
+		rates = append(rates, TariffRate{ValueIncVAT: pence, ValidFrom: from, ValidTo: &to})
+	}
+	state.Update(func(s *AppState) {
+		s.CachedTariffs = &CachedTariffs{Timestamp: time.Now(), Data: []Tariff{{
+			Agreement: Agreement{Fuel: FuelElectricity, TariffCode: "E-1R-AGILE-24-10-01-C", ValidFrom: start.AddDate(0, -1, 0)},
+			UnitRates: rates,
+		}}}
+	})
+}
+
+// agileTestMonitor has a finished peak, a peak running now and a cheap
+// window still to come
+func agileTestMonitor(t *testing.T) *SavingSessionMonitor {
+	monitor := newOfflineMonitor(t)
+	start := time.Now().Truncate(30 * time.Minute).Add(-90 * time.Minute)
+	cacheAgileRates(monitor.state, start, 40, 15, 15, 40, 40, 5)
+	return monitor
+}
+
+func TestAgileWindowsUpcoming(t *testing.T) {
+	monitor := agileTestMonitor(t)
+	if windows, err := monitor.agileWindows(time.Now()); err != nil || windows != nil {
+		t.Fatalf("Expected no windows with Agile alerts off, got %+v (%v)", windows, err)
+	}
+
+	monitor.SetAgileAlerts(&AgileConfig{Enabled: true})
+	windows, err := monitor.agileWindows(time.Now())
+	if err != nil {
+		t.Fatalf("Expected Agile windows, got %v", err)
+	}
+	if len(windows) != 2 || windows[0].Kind != AgileWindowPeak || windows[1].Kind != AgileWindowCheap {
+		t.Fatalf("Expected the running peak and the cheap window to come, got %+v", windows)
+	}
+	if windows[0].StartAt.After(time.Now()) || !windows[0].EndAt.After(time.Now()) {
+		t.Errorf("Expected the peak to be running now, got %+v", windows[0])
+	}
+}
+
+func TestCheckAgilePricesAlertsSelectedKinds(t *testing.T) {
+	monitor := agileTestMonitor(t)
+	monitor.SetAgileAlerts(&AgileConfig{Enabled: true, AlertOn: []string{AgileWindowPeak}})
+	windows, _ := monitor.agileWindows(time.Now())
+
+	if err := monitor.checkAgilePrices(); err != nil {
+		t.Fatalf("Expected Agile prices to be checked, got %v", err)
+	}
+	alerted := monitor.state.Snapshot().AlertStates
+	for _, window := range windows {
+		if _, ok := alerted[window.Code]; ok != (window.Kind == AgileWindowPeak) {
+			t.Errorf("Expected alert state only for peak windows, %s window alerted=%v", window.Kind, ok)
+		}
+	}
+}
+
+func TestAgileWindowsInSessionData(t *testing.T) {
+	monitor := agileTestMonitor(t)
+	monitor.SetAgileAlerts(&AgileConfig{Enabled: true})
+	windows, _ := monitor.agileWindows(time.Now())
+
+	data := monitor.buildSessionData()
+	if len(data.AgileWindows) != len(windows) || data.NextAgileWindow == nil || data.NextAgileWindow.Code != windows[0].Code {
+		t.Errorf("Expected session data to list the Agile windows, got %+v", data.NextAgileWindow)
+	}
+}
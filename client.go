@@ -16,14 +16,16 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -32,6 +34,14 @@ var octopusEndpoints = map[string]string{
 	"api":             "https://api.octopus.energy/v1",
 	"graphql":         "https://api.octopus.energy/v1/graphql/",
 	"backend-graphql": "https://api.backend.octopus.energy/v1/graphql/",
+
+	// Latest octojoin release, for the update check
+	"github-releases": "https://api.github.com/repos/matthewgall/octojoin/releases/latest",
+
+	// Free electricity session feeds, tried in order
+	"free-electricity":          "https://matthewgall.github.io/octoevents/free_electricity.json",
+	"free-electricity-raw":      "https://raw.githubusercontent.com/matthewgall/octoevents/refs/heads/main/free_electricity.json",
+	"free-electricity-fallback": "https://oe-api.davidskendall.co.uk/free_electricity.json",
 }
 
 // Helper function to get endpoint URLs
@@ -43,10 +53,45 @@ func getEndpoint(key string) string {
 	return octopusEndpoints["api"]
 }
 
-// APIMetrics tracks API call performance and rate limiting
+// apiDurationBuckets are the upper bounds, in seconds, of the API request
+// duration histogram buckets
+var apiDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// DurationHistogram is a fixed-size histogram of request durations
+type DurationHistogram struct {
+	BucketCounts []uint64 // Observations per bucket in apiDurationBuckets (not cumulative)
+	Count        uint64   // Total observations, including those above the last bucket
+	Sum          float64  // Sum of observed durations in seconds
+}
+
+func newDurationHistogram() *DurationHistogram {
+	return &DurationHistogram{BucketCounts: make([]uint64, len(apiDurationBuckets))}
+}
+
+func (h *DurationHistogram) observe(seconds float64) {
+	for i, bound := range apiDurationBuckets {
+		if seconds <= bound {
+			h.BucketCounts[i]++
+			break
+		}
+	}
+	h.Count++
+	h.Sum += seconds
+}
+
+// APIMetrics tracks API call performance and rate limiting. It is updated by
+// every request, so readers should take a Snapshot.
 type APIMetrics struct {
+	mu sync.Mutex
+
 	// API call durations by endpoint
-	RequestDurations map[string][]float64 // endpoint -> list of durations in seconds
+	Durations map[string]*DurationHistogram
+
+	// Completed API calls by endpoint and HTTP status code
+	Responses map[string]map[int]int64
+
+	// Failed calls by error class, see errorClass
+	Errors map[string]int64
 
 	// Rate limiting metrics
 	TotalRequests     int64   // Total number of API requests
@@ -57,24 +102,135 @@ type APIMetrics struct {
 // NewAPIMetrics creates a new metrics tracker
 func NewAPIMetrics() *APIMetrics {
 	return &APIMetrics{
-		RequestDurations: make(map[string][]float64),
+		Durations: make(map[string]*DurationHistogram),
+		Responses: make(map[string]map[int]int64),
+		Errors:    make(map[string]int64),
 	}
 }
 
+// recordRequest counts an API request, successful or not
+func (m *APIMetrics) recordRequest() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TotalRequests++
+}
+
+// recordResponse tracks the status and duration of a completed request
+func (m *APIMetrics) recordResponse(endpoint string, statusCode int, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	histogram, ok := m.Durations[endpoint]
+	if !ok {
+		histogram = newDurationHistogram()
+		m.Durations[endpoint] = histogram
+	}
+	histogram.observe(seconds)
+
+	if m.Responses[endpoint] == nil {
+		m.Responses[endpoint] = make(map[int]int64)
+	}
+	m.Responses[endpoint][statusCode]++
+}
+
+// recordError counts a failed call by error class
+func (m *APIMetrics) recordError(class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Errors[class]++
+}
+
+// recordRateLimitSleep tracks time spent waiting for the rate limiter
+func (m *APIMetrics) recordRateLimitSleep(sleep time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RateLimitSleeps++
+	m.TotalSleepSeconds += sleep.Seconds()
+}
+
+// Snapshot returns a copy of the metrics that is safe to read
+func (m *APIMetrics) Snapshot() *APIMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &APIMetrics{
+		Durations:         make(map[string]*DurationHistogram, len(m.Durations)),
+		Responses:         make(map[string]map[int]int64, len(m.Responses)),
+		Errors:            make(map[string]int64, len(m.Errors)),
+		TotalRequests:     m.TotalRequests,
+		RateLimitSleeps:   m.RateLimitSleeps,
+		TotalSleepSeconds: m.TotalSleepSeconds,
+	}
+	for endpoint, histogram := range m.Durations {
+		copied := *histogram
+		copied.BucketCounts = append([]uint64(nil), histogram.BucketCounts...)
+		snapshot.Durations[endpoint] = &copied
+	}
+	for class, count := range m.Errors {
+		snapshot.Errors[class] = count
+	}
+	for endpoint, codes := range m.Responses {
+		snapshot.Responses[endpoint] = make(map[int]int64, len(codes))
+		for code, count := range codes {
+			snapshot.Responses[endpoint][code] = count
+		}
+	}
+	return snapshot
+}
+
+// metricsEndpointLabel collapses account numbers, numeric IDs and product and
+// tariff codes in a REST path, and drops any query string, so the endpoint
+// label has bounded cardinality
+func metricsEndpointLabel(endpoint, accountID string) string {
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	segments := strings.Split(endpoint, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		previous := ""
+		if i > 0 {
+			previous = segments[i-1]
+		}
+		switch {
+		case segment == accountID:
+			segments[i] = ":account"
+		case previous == "products":
+			segments[i] = ":product"
+		case previous == "electricity-tariffs" || previous == "gas-tariffs":
+			segments[i] = ":tariff"
+		default:
+			if _, err := strconv.Atoi(segment); err == nil {
+				segments[i] = ":id"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
 type OctopusClient struct {
 	AccountID      string
 	APIKey         string
 	BaseURL        string
 	client         *http.Client
+	rateMu          sync.Mutex // guards lastRequestTime
 	lastRequestTime time.Time
 	minInterval     time.Duration
 	maxRetries      int
+	jwtMu          sync.Mutex // guards jwtToken/jwtExpiry and serialises refreshes
 	jwtToken       string
 	jwtExpiry      time.Time
+	refreshToken   string
+	refreshExpiry  time.Time
 	debug          bool
 	state          *AppState
 	logger         *Logger
 	metrics        *APIMetrics
+	breaker        *CircuitBreaker
+
+	gasCalorificValue float64 // MJ/m³, for converting gas readings to kWh
 }
 
 type SavingSession struct {
@@ -82,6 +238,33 @@ type SavingSession struct {
 	StartAt    time.Time `json:"startAt"`
 	EndAt      time.Time `json:"endAt"`
 	OctoPoints int       `json:"octopoints"`
+	// PointsPerKWh is the reward per kWh saved, known for announced events.
+	// What a session pays in total depends on how much is saved.
+	PointsPerKWh int `json:"pointsPerKwh,omitempty"`
+	// RewardGivenInOctoPoints is set once a joined session has been settled
+	RewardGivenInOctoPoints int `json:"rewardGivenInOctoPoints,omitempty"`
+}
+
+// SavingSessionEvent is a saving session announced by Octopus. Unlike
+// SavingSession it is not tied to the account, so it covers events that
+// have not been joined yet.
+type SavingSessionEvent struct {
+	ID                       int       `json:"id"`
+	Code                     string    `json:"code"`
+	StartAt                  time.Time `json:"startAt"`
+	EndAt                    time.Time `json:"endAt"`
+	RewardPerKwhInOctoPoints int       `json:"rewardPerKwhInOctoPoints"`
+}
+
+// ToSavingSession converts an announced event into the SavingSession shape used
+// by the join logic. The total points are unknown until the session is settled.
+func (e SavingSessionEvent) ToSavingSession() SavingSession {
+	return SavingSession{
+		EventID:      e.ID,
+		StartAt:      e.StartAt,
+		EndAt:        e.EndAt,
+		PointsPerKWh: e.RewardPerKwhInOctoPoints,
+	}
 }
 
 type FreeElectricitySession struct {
@@ -128,6 +311,24 @@ type SavingSessionsResponse struct {
 			} `json:"account"`
 		} `json:"octoPoints"`
 	} `json:"data"`
+	// AvailableEvents holds every announced saving session event, joined or not
+	AvailableEvents []SavingSessionEvent `json:"availableEvents,omitempty"`
+}
+
+// UnjoinedEvents returns the announced events the account has not joined yet
+func (r *SavingSessionsResponse) UnjoinedEvents() []SavingSessionEvent {
+	joined := make(map[int]bool)
+	for _, session := range r.Data.SavingSessions.Account.JoinedEvents {
+		joined[session.EventID] = true
+	}
+
+	var unjoined []SavingSessionEvent
+	for _, event := range r.AvailableEvents {
+		if !joined[event.ID] {
+			unjoined = append(unjoined, event)
+		}
+	}
+	return unjoined
 }
 
 type SmartDevice struct {
@@ -152,6 +353,8 @@ type MeterEligibilityResponse struct {
 }
 
 type UsageMeasurement struct {
+	DeviceID string    `json:"deviceId,omitempty"` // not in the API response, set from the request
+	VolumeM3 float64   `json:"volumeM3,omitempty"` // gas readings converted to kWh keep their volume here
 	Value    string    `json:"value"` // API returns this as string, we'll parse it
 	Unit     string    `json:"unit"`
 	StartAt  time.Time `json:"startAt"`
@@ -178,6 +381,22 @@ type UsageMeasurement struct {
 	} `json:"metaData"`
 }
 
+// TelemetryReading is one Home Mini reading. Demand is in watts, consumption
+// in Wh, and cost in pence.
+type TelemetryReading struct {
+	ReadAt           time.Time `json:"readAt"`
+	Consumption      string    `json:"consumption"`      // API returns as string
+	Demand           string    `json:"demand"`           // API returns as string
+	ConsumptionDelta string    `json:"consumptionDelta"` // API returns as string
+	CostDelta        string    `json:"costDelta"`        // API returns as string
+}
+
+type SmartMeterTelemetryResponse struct {
+	Data struct {
+		SmartMeterTelemetry []TelemetryReading `json:"smartMeterTelemetry"`
+	} `json:"data"`
+}
+
 type UsageMeasurementsResponse struct {
 	Data struct {
 		Account struct {
@@ -201,7 +420,7 @@ type UsageMeasurementsResponse struct {
 
 func NewOctopusClient(accountID, apiKey string, debug bool) *OctopusClient {
 	logger := NewLogger(debug).WithComponent("octopus_client")
-	return &OctopusClient{
+	client := &OctopusClient{
 		AccountID:   accountID,
 		APIKey:      apiKey,
 		BaseURL:     getEndpoint("api"),
@@ -210,10 +429,55 @@ func NewOctopusClient(accountID, apiKey string, debug bool) *OctopusClient {
 		debug:       debug,
 		logger:      logger,
 		metrics:     NewAPIMetrics(),
-		client: &http.Client{
-			Timeout: HTTPClientTimeout,
-		},
+		breaker:     NewCircuitBreaker(CircuitBreakerThreshold, CircuitBreakerCooldown),
+
+		gasCalorificValue: GasDefaultCalorificValue,
+	}
+	client.SetTransport(http.DefaultTransport)
+	return client
+}
+
+// SetGasCalorificValue sets the calorific value (MJ/m³) used to convert gas
+// readings to kWh. It is shown on gas bills and varies slightly by region.
+func (c *OctopusClient) SetGasCalorificValue(mjPerCubicMetre float64) {
+	c.gasCalorificValue = mjPerCubicMetre
+}
+
+// SetTransport rebuilds the HTTP client around base, which performs the actual
+// round trip. Every upstream call goes through the same middleware chain:
+// retries outermost, so each attempt is separately rate limited, counted and
+// logged, then the circuit breaker, rate limiter, metrics, logging and a
+// per-attempt timeout.
+func (c *OctopusClient) SetTransport(base http.RoundTripper) {
+	c.client = &http.Client{
+		Transport: ChainTransport(base,
+			RetryMiddleware(c.maxRetries, c.logger),
+			CircuitBreakerMiddleware(c.breaker),
+			RateLimitMiddleware(c.enforceRateLimit),
+			MetricsMiddleware(c.metrics, c.endpointLabel),
+			LoggingMiddleware(c.logger, c.debug),
+			TimeoutMiddleware(HTTPClientTimeout),
+		),
+	}
+}
+
+// endpointLabel names a request for the API metrics: GraphQL endpoints by
+// their octopusEndpoints key, REST paths with IDs collapsed, and anything
+// else by host and path
+func (c *OctopusClient) endpointLabel(req *http.Request) string {
+	// Labels come from the path alone; query strings carry timestamps
+	bare := *req.URL
+	bare.RawQuery, bare.Fragment = "", ""
+	address := bare.String()
+	for key, endpoint := range octopusEndpoints {
+		if address == endpoint {
+			return key
+		}
 	}
+	if strings.HasPrefix(address, c.BaseURL) {
+		return metricsEndpointLabel(strings.TrimPrefix(address, c.BaseURL), c.AccountID)
+	}
+	return req.URL.Host + metricsEndpointLabel(req.URL.Path, c.AccountID)
 }
 
 func (c *OctopusClient) SetState(state *AppState) {
@@ -222,29 +486,80 @@ func (c *OctopusClient) SetState(state *AppState) {
 }
 
 func (c *OctopusClient) loadJWTFromState() {
-	if c.state != nil && c.state.JWTToken != "" {
-		c.jwtToken = c.state.JWTToken
-		c.jwtExpiry = c.state.JWTTokenExpiry
-		c.debugLog("Loaded cached JWT token, expires: %v", c.jwtExpiry)
+	if c.state == nil {
+		return
+	}
+
+	var token, refreshToken string
+	var expiry, refreshExpiry time.Time
+	c.state.View(func(s *AppState) {
+		token, expiry = s.JWTToken, s.JWTTokenExpiry
+		refreshToken, refreshExpiry = s.JWTRefreshToken, s.JWTRefreshTokenExpiry
+	})
+	if token == "" && refreshToken == "" {
+		return
 	}
+
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+	c.jwtToken = token
+	c.jwtExpiry = expiry
+	c.refreshToken = refreshToken
+	c.refreshExpiry = refreshExpiry
+	c.debugLog("Loaded cached JWT token, expires: %v, refresh token expires: %v", c.jwtExpiry, c.refreshExpiry)
 }
 
+// saveJWTToState persists the current token. Callers must hold jwtMu.
 func (c *OctopusClient) saveJWTToState() {
 	if c.state != nil {
-		c.state.JWTToken = c.jwtToken
-		c.state.JWTTokenExpiry = c.jwtExpiry
+		token, expiry := c.jwtToken, c.jwtExpiry
+		refreshToken, refreshExpiry := c.refreshToken, c.refreshExpiry
+		c.state.Update(func(s *AppState) {
+			s.JWTToken = token
+			s.JWTTokenExpiry = expiry
+			s.JWTRefreshToken = refreshToken
+			s.JWTRefreshTokenExpiry = refreshExpiry
+		})
 		c.debugLog("Saved JWT token to state, expires: %v", c.jwtExpiry)
 	}
 }
 
+// currentJWTToken returns the token used to authorise GraphQL requests
+func (c *OctopusClient) currentJWTToken() string {
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+	return c.jwtToken
+}
+
+// invalidateJWTToken forgets both the access and refresh tokens, so the next
+// request exchanges the API key again
 func (c *OctopusClient) invalidateJWTToken() {
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+	c.refreshToken = ""
+	c.refreshExpiry = time.Time{}
+	c.clearJWTToken()
+}
+
+// expireJWTToken invalidates a token that was rejected by the API, unless a
+// concurrent request has already replaced it with a fresh one
+func (c *OctopusClient) expireJWTToken(rejected string) {
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+	if c.jwtToken != rejected {
+		c.debugLog("JWT token already refreshed by another request")
+		return
+	}
+	c.clearJWTToken()
+}
+
+// clearJWTToken forgets the current access token, keeping any refresh token
+// for the next refresh. Callers must hold jwtMu.
+func (c *OctopusClient) clearJWTToken() {
 	c.debugLog("Invalidating expired JWT token")
 	c.jwtToken = ""
 	c.jwtExpiry = time.Time{}
-	if c.state != nil {
-		c.state.JWTToken = ""
-		c.state.JWTTokenExpiry = time.Time{}
-	}
+	c.saveJWTToState()
 }
 
 func (c *OctopusClient) makeGraphQLRequest(query string, variables map[string]interface{}, retryOnAuth bool) (*http.Response, error) {
@@ -252,7 +567,14 @@ func (c *OctopusClient) makeGraphQLRequest(query string, variables map[string]in
 }
 
 func (c *OctopusClient) makeGraphQLRequestWithEndpoint(endpoint, query string, variables map[string]interface{}, retryOnAuth bool, operationName string) (*http.Response, error) {
+	return c.makeGraphQLRequestWithContext(context.Background(), endpoint, query, variables, retryOnAuth, operationName)
+}
+
+// makeGraphQLRequestWithContext sends a GraphQL request with ctx, e.g. one
+// from withoutRetries for mutations that must not be sent twice
+func (c *OctopusClient) makeGraphQLRequestWithContext(ctx context.Context, endpoint, query string, variables map[string]interface{}, retryOnAuth bool, operationName string) (*http.Response, error) {
 	if err := c.refreshJWTToken(); err != nil {
+		c.metrics.recordError(errorClass(err))
 		return nil, fmt.Errorf("failed to get JWT token: %w", err)
 	}
 
@@ -267,46 +589,30 @@ func (c *OctopusClient) makeGraphQLRequestWithEndpoint(endpoint, query string, v
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	token := c.currentJWTToken()
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", c.jwtToken)
+	req.Header.Set("Authorization", token)
 	req.Header.Set("User-Agent", GetUserAgent())
 
-	// Log GraphQL request details in debug mode
-	c.debugLogRequest("POST", endpoint, req.Header, bodyBytes)
-
-	startTime := time.Now()
 	resp, err := c.client.Do(req)
-	duration := time.Since(startTime).Seconds()
 	if err != nil {
+		c.metrics.recordError(errorClass(err))
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 
-	// Log GraphQL response in debug mode (before any body reading)
-	if c.debug {
-		// We need to be careful here since we might read the body later for error checking
-		// So we'll only log a preview without consuming the body yet
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err == nil {
-			resp.Body.Close()
-			c.debugLogResponse(resp, bodyBytes, duration)
-			// Restore the body
-			resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		}
-	}
-
 	// Check for authentication errors that indicate token expiration
 	if (resp.StatusCode == 401 || resp.StatusCode == 403) && retryOnAuth {
 		resp.Body.Close()
 		c.debugLog("Got %d response, JWT token may be expired. Invalidating and retrying...", resp.StatusCode)
-		c.invalidateJWTToken()
+		c.expireJWTToken(token)
 		
 		// Retry once with fresh token
-		return c.makeGraphQLRequestWithEndpoint(endpoint, query, variables, false, operationName)
+		return c.makeGraphQLRequestWithContext(ctx, endpoint, query, variables, false, operationName)
 	}
 
 	// For GraphQL, we also need to check for JWT expiration in the response body
@@ -318,20 +624,15 @@ func (c *OctopusClient) makeGraphQLRequestWithEndpoint(endpoint, query string, v
 		}
 		resp.Body.Close()
 
-		// Check if the response contains JWT expiration error
-		bodyStr := string(bodyBytes)
-		if strings.Contains(bodyStr, "Signature of the JWT has expired") ||
-		   strings.Contains(bodyStr, "JWT has expired") ||
-		   strings.Contains(bodyStr, "Token has expired") ||
-		   strings.Contains(bodyStr, OctopusErrorCodeJWTExpired) || // Octopus specific auth error code
-		   strings.Contains(bodyStr, OctopusErrorCodeInvalidAuth) || // Invalid authorization header error
-		   strings.Contains(bodyStr, "Authentication failed") {
+		// Check if the response's errors say the token was rejected
+		var envelope graphQLResponse
+		if json.Unmarshal(bodyBytes, &envelope) == nil && envelope.tokenRejected() {
 			c.debugLog("GraphQL response contains JWT expiration/auth error. Invalidating token and retrying...")
-			c.debugLog("Error details: %s", bodyStr)
-			c.invalidateJWTToken()
+			c.debugLog("Error details: %s", string(bodyBytes))
+			c.expireJWTToken(token)
 			
 			// Retry once with fresh token
-			return c.makeGraphQLRequestWithEndpoint(endpoint, query, variables, false, operationName)
+			return c.makeGraphQLRequestWithContext(ctx, endpoint, query, variables, false, operationName)
 		}
 
 		// Create new response with the body we read
@@ -347,76 +648,13 @@ func (c *OctopusClient) debugLog(format string, args ...interface{}) {
 	}
 }
 
-// debugLogRequest logs detailed request information in debug mode
-func (c *OctopusClient) debugLogRequest(method, url string, headers http.Header, bodyBytes []byte) {
-	if !c.debug {
-		return
-	}
-
-	// Mask sensitive headers
-	maskedHeaders := make(map[string]string)
-	for key, values := range headers {
-		if len(values) > 0 {
-			if key == "Authorization" {
-				// Show only first and last 4 chars of auth tokens
-				val := values[0]
-				if len(val) > 12 {
-					maskedHeaders[key] = val[:6] + "..." + val[len(val)-4:]
-				} else {
-					maskedHeaders[key] = "***"
-				}
-			} else {
-				maskedHeaders[key] = values[0]
-			}
-		}
-	}
-
-	c.logger.Debug("→ HTTP Request",
-		"method", method,
-		"url", url,
-		"headers", maskedHeaders,
-	)
-
-	if len(bodyBytes) > 0 {
-		bodyStr := string(bodyBytes)
-		// Truncate long bodies
-		if len(bodyStr) > 500 {
-			bodyStr = bodyStr[:500] + "... (truncated)"
-		}
-		c.logger.Debug("  Request Body", "body", bodyStr)
-	}
-}
-
-// debugLogResponse logs detailed response information in debug mode
-func (c *OctopusClient) debugLogResponse(resp *http.Response, bodyPreview []byte, duration float64) {
-	if !c.debug {
-		return
-	}
-
-	c.logger.Debug("← HTTP Response",
-		"status", resp.StatusCode,
-		"status_text", resp.Status,
-		"duration_ms", duration*1000,
-		"content_type", resp.Header.Get("Content-Type"),
-	)
-
-	if len(bodyPreview) > 0 {
-		bodyStr := string(bodyPreview)
-		// Truncate long response bodies
-		if len(bodyStr) > 500 {
-			bodyStr = bodyStr[:500] + "... (truncated)"
-		}
-		c.logger.Debug("  Response Body", "body", bodyStr)
-	}
-}
-
 func (c *OctopusClient) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
-	return c.makeRequestWithRetry(method, endpoint, body, 0)
+	return c.makeRequestWithContext(context.Background(), method, endpoint, body)
 }
 
-func (c *OctopusClient) makeRequestWithRetry(method, endpoint string, body interface{}, attempt int) (*http.Response, error) {
-	c.enforceRateLimit()
-
+// makeRequestWithContext sends a REST request with ctx, e.g. one from
+// withoutRetries for requests that must not be sent twice
+func (c *OctopusClient) makeRequestWithContext(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
 	var reqBody []byte
 	var err error
 
@@ -428,7 +666,7 @@ func (c *OctopusClient) makeRequestWithRetry(method, endpoint string, body inter
 	}
 
 	url := c.BaseURL + endpoint
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -437,106 +675,42 @@ func (c *OctopusClient) makeRequestWithRetry(method, endpoint string, body inter
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", GetUserAgent())
 
-	// Log request details in debug mode
-	c.debugLogRequest(method, url, req.Header, reqBody)
-
+	// Retries, rate limiting, metrics and debug logging happen in the transport
 	startTime := time.Now()
-	c.lastRequestTime = startTime
 	resp, err := c.client.Do(req)
-	duration := time.Since(startTime).Seconds()
-
-	// Track total requests (including failed ones)
-	c.metrics.TotalRequests++
-
 	if err != nil {
-		if attempt < c.maxRetries {
-			backoff := c.calculateBackoff(attempt)
-			c.logger.Warn("Request failed, retrying",
-				"method", method,
-				"endpoint", endpoint,
-				"attempt", attempt+1,
-				"max_attempts", c.maxRetries+1,
-				"backoff_ms", backoff.Milliseconds(),
-				"error", err.Error(),
-			)
-			time.Sleep(backoff)
-			return c.makeRequestWithRetry(method, endpoint, body, attempt+1)
-		}
 		return nil, NewAPIError(0, endpoint, "request failed", err)
 	}
 
-	c.logger.LogAPIRequest(method, endpoint, resp.StatusCode, duration)
-
-	// Track API call duration by endpoint
-	c.metrics.RequestDurations[endpoint] = append(c.metrics.RequestDurations[endpoint], duration)
-
-	// Log response details in debug mode (read preview without consuming body)
-	if c.debug {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err == nil {
-			resp.Body.Close()
-			c.debugLogResponse(resp, bodyBytes, duration)
-			// Restore the response body for the caller
-			resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		}
-	}
-
-	if c.shouldRetry(resp.StatusCode) && attempt < c.maxRetries {
-		backoff := c.calculateBackoffFromResponse(resp, attempt)
-		c.logger.Warn("Retrying due to status code",
-			"status_code", resp.StatusCode,
-			"attempt", attempt+1,
-			"max_attempts", c.maxRetries+1,
-			"backoff_ms", backoff.Milliseconds(),
-		)
-		resp.Body.Close()
-		time.Sleep(backoff)
-		return c.makeRequestWithRetry(method, endpoint, body, attempt+1)
-	}
+	c.logger.LogAPIRequest(method, endpoint, resp.StatusCode, time.Since(startTime).Seconds())
 
 	return resp, nil
 }
 
+// enforceRateLimit reserves the next request slot and waits for it. Slots are
+// handed out under a lock so concurrent callers queue up minInterval apart.
 func (c *OctopusClient) enforceRateLimit() {
+	c.rateMu.Lock()
+	now := time.Now()
+	slot := now
 	if !c.lastRequestTime.IsZero() {
-		elapsed := time.Since(c.lastRequestTime)
-		if elapsed < c.minInterval {
-			sleep := c.minInterval - elapsed
-			c.logger.Debug("Rate limiting",
-				"sleep_ms", sleep.Milliseconds(),
-			)
-
-			// Track rate limiting metrics
-			c.metrics.RateLimitSleeps++
-			c.metrics.TotalSleepSeconds += sleep.Seconds()
-
-			time.Sleep(sleep)
+		if next := c.lastRequestTime.Add(c.minInterval); next.After(now) {
+			slot = next
 		}
 	}
-}
+	c.lastRequestTime = slot
+	c.rateMu.Unlock()
 
-func (c *OctopusClient) shouldRetry(statusCode int) bool {
-	return statusCode == http.StatusTooManyRequests ||
-		statusCode == http.StatusInternalServerError ||
-		statusCode == http.StatusBadGateway ||
-		statusCode == http.StatusServiceUnavailable ||
-		statusCode == http.StatusGatewayTimeout
-}
+	if sleep := slot.Sub(now); sleep > 0 {
+		c.logger.Debug("Rate limiting",
+			"sleep_ms", sleep.Milliseconds(),
+		)
 
-func (c *OctopusClient) calculateBackoff(attempt int) time.Duration {
-	base := float64(time.Second)
-	backoff := base * math.Pow(2, float64(attempt))
-	jitter := rand.Float64() * 0.1 * backoff
-	return time.Duration(backoff + jitter)
-}
+		// Track rate limiting metrics
+		c.metrics.recordRateLimitSleep(sleep)
 
-func (c *OctopusClient) calculateBackoffFromResponse(resp *http.Response, attempt int) time.Duration {
-	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-		if seconds, err := strconv.Atoi(retryAfter); err == nil {
-			return time.Duration(seconds) * time.Second
-		}
+		time.Sleep(sleep)
 	}
-	return c.calculateBackoff(attempt)
 }
 
 func (c *OctopusClient) GetSavingSessions() (*SavingSessionsResponse, error) {
@@ -545,9 +719,9 @@ func (c *OctopusClient) GetSavingSessions() (*SavingSessionsResponse, error) {
 
 func (c *OctopusClient) getCampaignStatusWithCache(state *AppState) (map[string]bool, error) {
 	// Check cache if state is provided - campaign status rarely changes
-	if state != nil && state.CachedCampaignStatus != nil {
-		if state.IsCacheValid(state.CachedCampaignStatus.Timestamp, CacheDurationCampaignStatus) {
-			return state.CachedCampaignStatus.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedCampaignStatus { return s.CachedCampaignStatus }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationCampaignStatus) {
+			return cached.Data, nil
 		}
 	}
 
@@ -559,10 +733,12 @@ func (c *OctopusClient) getCampaignStatusWithCache(state *AppState) (map[string]
 
 	// Update cache if state is provided
 	if state != nil {
-		state.CachedCampaignStatus = &CachedCampaignStatus{
-			Data:      campaigns,
-			Timestamp: time.Now(),
-		}
+		state.Update(func(s *AppState) {
+			s.CachedCampaignStatus = &CachedCampaignStatus{
+				Data:      campaigns,
+				Timestamp: time.Now(),
+			}
+		})
 	}
 
 	return campaigns, nil
@@ -587,10 +763,6 @@ func (c *OctopusClient) getCampaignStatus() (map[string]bool, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("campaign request failed with status %d", resp.StatusCode)
-	}
-
 	var result struct {
 		Data struct {
 			Account struct {
@@ -601,8 +773,8 @@ func (c *OctopusClient) getCampaignStatus() (map[string]bool, error) {
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode campaign response: %w", err)
+	if err := c.decodeGraphQL(resp, "checkCampaigns", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get campaign status: %w", err)
 	}
 
 	// Build campaign status map
@@ -649,9 +821,9 @@ func (c *OctopusClient) GetSavingSessionsWithCache(state *AppState) (*SavingSess
 	}
 
 	// Check cache if state is provided
-	if state != nil && state.CachedSavingSessions != nil {
-		if state.IsCacheValid(state.CachedSavingSessions.Timestamp, cacheDuration) {
-			return state.CachedSavingSessions.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedSavingSessions { return s.CachedSavingSessions }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, cacheDuration) {
+			return cached.Data, nil
 		}
 	}
 
@@ -681,6 +853,13 @@ func (c *OctopusClient) GetSavingSessionsWithCache(state *AppState) (*SavingSess
 	}
 	c.debugLog("Campaign enrollment status: %v", hasJoinedCampaign)
 
+	// Get announced events so sessions that have not been joined yet can be discovered
+	events, err := c.getSavingSessionEvents()
+	if err != nil {
+		c.logger.Warn("Failed to get saving session events", "error", err)
+		events = nil // Fall back to joined events only
+	}
+
 	// Combine the data
 	result := &SavingSessionsResponse{
 		Data: struct {
@@ -722,14 +901,17 @@ func (c *OctopusClient) GetSavingSessionsWithCache(state *AppState) (*SavingSess
 				},
 			},
 		},
+		AvailableEvents: events,
 	}
 
 	// Update cache if state is provided
 	if state != nil {
-		state.CachedSavingSessions = &CachedSavingSessions{
-			Data:      result,
-			Timestamp: time.Now(),
-		}
+		state.Update(func(s *AppState) {
+			s.CachedSavingSessions = &CachedSavingSessions{
+				Data:      result,
+				Timestamp: time.Now(),
+			}
+		})
 	}
 
 	return result, nil
@@ -756,19 +938,107 @@ func (c *OctopusClient) getSavingSessionsREST() (*SavingSessionsResponse, error)
 	return &result, nil
 }
 
+// getSavingSessionEvents retrieves all announced saving session events via GraphQL
+func (c *OctopusClient) getSavingSessionEvents() ([]SavingSessionEvent, error) {
+	query := `query getSavingSessionEvents {
+		savingSessions {
+			events {
+				id
+				code
+				startAt
+				endAt
+				rewardPerKwhInOctoPoints
+			}
+		}
+	}`
+
+	resp, err := c.makeGraphQLRequest(query, map[string]interface{}{}, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute saving session events request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			SavingSessions struct {
+				Events []SavingSessionEvent `json:"events"`
+			} `json:"savingSessions"`
+		} `json:"data"`
+	}
+
+	if err := c.decodeGraphQL(resp, "getSavingSessionEvents", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get saving session events: %w", err)
+	}
+
+	c.debugLog("Found %d announced saving session events", len(result.Data.SavingSessions.Events))
+	return result.Data.SavingSessions.Events, nil
+}
+
+// krakenToken is the result of an obtainKrakenToken mutation
+type krakenToken struct {
+	Token            string `json:"token"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresIn int64  `json:"refreshExpiresIn"`
+}
+
+// refreshJWTToken obtains a new token if the current one is close to expiry,
+// using the refresh token while it lasts and falling back to the API key.
+// The lock is held for the whole exchange so concurrent callers share a single
+// refresh and then find the fresh token already in place.
 func (c *OctopusClient) refreshJWTToken() error {
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+
 	// Check if token is still valid (with buffer before expiry)
-	if !c.jwtExpiry.IsZero() && time.Until(c.jwtExpiry) > JWTRefreshBuffer {
+	if c.jwtToken != "" && time.Until(c.jwtExpiry) > JWTRefreshBuffer {
 		c.debugLog("JWT token still valid until %v", c.jwtExpiry)
 		return nil // Token still valid
 	}
 
-	c.debugLog("Requesting new JWT token...")
+	var token *krakenToken
+	if c.refreshToken != "" && time.Until(c.refreshExpiry) > JWTRefreshBuffer {
+		c.debugLog("Refreshing JWT token with refresh token...")
+		refreshed, err := c.obtainKrakenToken(map[string]interface{}{"refreshToken": c.refreshToken})
+		var authErr *AuthError
+		switch {
+		case errors.As(err, &authErr):
+			c.debugLog("Refresh token rejected, falling back to API key: %v", err)
+			c.refreshToken = ""
+			c.refreshExpiry = time.Time{}
+		case err != nil:
+			// Kraken couldn't be reached, so the refresh token may still be good
+			return err
+		default:
+			token = refreshed
+		}
+	}
+	if token == nil {
+		c.debugLog("Requesting new JWT token with API key...")
+		obtained, err := c.obtainKrakenToken(map[string]interface{}{"APIKey": c.APIKey})
+		if err != nil {
+			return err
+		}
+		token = obtained
+	}
 
-	// JWT token request endpoint
-	tokenURL := "https://api.octopus.energy/v1/graphql/"
-	
-	// Query to get JWT token using API key
+	now := time.Now()
+	c.jwtToken = token.Token
+	c.jwtExpiry = jwtExpiry(token.Token, now)
+	if token.RefreshToken != "" {
+		c.refreshToken = token.RefreshToken
+		c.refreshExpiry = refreshTokenExpiry(token.RefreshExpiresIn, now)
+	}
+
+	c.debugLog("JWT token obtained successfully, expires: %v, refresh token expires: %v", c.jwtExpiry, c.refreshExpiry)
+
+	// Save token to persistent state
+	c.saveJWTToState()
+
+	return nil
+}
+
+// obtainKrakenToken exchanges either an API key or a refresh token for a JWT
+func (c *OctopusClient) obtainKrakenToken(input map[string]interface{}) (*krakenToken, error) {
 	query := `mutation obtainKrakenToken($input: ObtainJSONWebTokenInput!) {
 		obtainKrakenToken(input: $input) {
 			token
@@ -780,22 +1050,20 @@ func (c *OctopusClient) refreshJWTToken() error {
 	requestBody := GraphQLRequest{
 		Query: query,
 		Variables: map[string]interface{}{
-			"input": map[string]interface{}{
-				"APIKey": c.APIKey,
-			},
+			"input": input,
 		},
 	}
 
 	reqBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal token request: %w", err)
+		return nil, fmt.Errorf("failed to marshal token request: %w", err)
 	}
 
-	c.debugLog("Token request body: %s", string(reqBody))
-
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBuffer(reqBody))
+	// Like every mutation it is sent at most once; a failed token request is
+	// retried with the task that needed it
+	req, err := http.NewRequestWithContext(withoutRetries(context.Background()), "POST", getEndpoint("graphql"), bytes.NewBuffer(reqBody))
 	if err != nil {
-		return fmt.Errorf("failed to create token request: %w", err)
+		return nil, fmt.Errorf("failed to create token request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -803,87 +1071,103 @@ func (c *OctopusClient) refreshJWTToken() error {
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute token request: %w", err)
+		return nil, fmt.Errorf("failed to execute token request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	c.debugLog("Token request status: %d", resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
-		// Read body for error details
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		c.debugLog("Token request failed body: %s", string(bodyBytes))
-		return fmt.Errorf("token request failed with status %d", resp.StatusCode)
-	}
-
+	// A rejected API key or refresh token comes back as an AuthError
 	var tokenResult struct {
 		Data struct {
-			ObtainKrakenToken struct {
-				Token            string `json:"token"`
-				RefreshToken     string `json:"refreshToken"`
-				RefreshExpiresIn int    `json:"refreshExpiresIn"`
-			} `json:"obtainKrakenToken"`
+			ObtainKrakenToken krakenToken `json:"obtainKrakenToken"`
 		} `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResult); err != nil {
-		return fmt.Errorf("failed to decode token response: %w", err)
 	}
 
-	if len(tokenResult.Errors) > 0 {
-		c.debugLog("GraphQL errors: %v", tokenResult.Errors)
-		return fmt.Errorf("GraphQL errors: %s", tokenResult.Errors[0].Message)
+	if err := c.decodeGraphQL(resp, "obtainKrakenToken", &tokenResult.Data); err != nil {
+		return nil, err
 	}
 
 	if tokenResult.Data.ObtainKrakenToken.Token == "" {
-		return fmt.Errorf("empty token received")
+		return nil, &AuthError{Message: "empty token received"}
 	}
 
-	c.jwtToken = tokenResult.Data.ObtainKrakenToken.Token
-	c.jwtExpiry = time.Now().Add(time.Duration(tokenResult.Data.ObtainKrakenToken.RefreshExpiresIn) * time.Second)
+	return &tokenResult.Data.ObtainKrakenToken, nil
+}
 
-	c.debugLog("JWT token obtained successfully, expires: %v", c.jwtExpiry)
-	
-	// Save token to persistent state
-	c.saveJWTToState()
+// jwtExpiry reads the exp claim from a JWT. Tokens that cannot be decoded are
+// assumed to last JWTDefaultLifetime from now.
+func jwtExpiry(token string, now time.Time) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return now.Add(JWTDefaultLifetime)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return now.Add(JWTDefaultLifetime)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return now.Add(JWTDefaultLifetime)
+	}
+	return time.Unix(claims.Exp, 0)
+}
 
-	return nil
+// refreshTokenExpiry interprets refreshExpiresIn, which Kraken documents as a
+// Unix timestamp. Small values are treated as a lifetime in seconds.
+func refreshTokenExpiry(refreshExpiresIn int64, now time.Time) time.Time {
+	if refreshExpiresIn > now.Unix()/2 {
+		return time.Unix(refreshExpiresIn, 0)
+	}
+	return now.Add(time.Duration(refreshExpiresIn) * time.Second)
 }
 
 func (c *OctopusClient) getOctoPointsGraphQLWithCache(state *AppState) (int, error) {
 	// Check cache if state is provided - OctoPoints change at most hourly
-	if state != nil && state.CachedOctoPoints != nil {
-		if state.IsCacheValid(state.CachedOctoPoints.Timestamp, CacheDurationOctoPoints) {
-			return state.CachedOctoPoints.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedOctoPoints { return s.CachedOctoPoints }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationOctoPoints) {
+			return cached.Data, nil
 		}
 	}
 
 	// Get fresh OctoPoints data
-	points, err := c.getOctoPointsGraphQL()
+	points, ledger, err := c.getOctoPointsGraphQL()
 	if err != nil {
 		return 0, err
 	}
 
-	// Update cache if state is provided
+	// Update cache if state is provided, and keep any new ledger entries
 	if state != nil {
-		state.CachedOctoPoints = &CachedOctoPoints{
-			Data:      points,
-			Timestamp: time.Now(),
+		state.Update(func(s *AppState) {
+			s.CachedOctoPoints = &CachedOctoPoints{
+				Data:      points,
+				Timestamp: time.Now(),
+			}
+		})
+		if added := state.MergePointsLedger(ledger); added > 0 {
+			c.debugLog("Stored %d new OctoPoints ledger entries", added)
 		}
 	}
 
 	return points, nil
 }
 
-func (c *OctopusClient) getOctoPointsGraphQL() (int, error) {
+// getOctoPointsGraphQL returns the wallet balance and the ledger entries
+// Kraken currently reports, newest first
+func (c *OctopusClient) getOctoPointsGraphQL() (int, []PointsLedgerEntry, error) {
 	c.debugLog("Requesting OctoPoints with JWT token...")
 
 	query := `query octoplusData($accountNumber: String!) {
 		loyaltyPointLedgers {
+			id
+			ledgerType
+			value
+			balanceBroughtForward
 			balanceCarriedForward
+			reasonCode
+			postedAt
 		}
 		account(accountNumber: $accountNumber) {
 			campaigns {
@@ -901,23 +1185,15 @@ func (c *OctopusClient) getOctoPointsGraphQL() (int, error) {
 
 	resp, err := c.makeGraphQLRequest(query, variables, true)
 	if err != nil {
-		return 0, fmt.Errorf("failed to execute request: %w", err)
+		return 0, nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	c.debugLog("OctoPoints request status: %d", resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		c.debugLog("OctoPoints request failed body: %s", string(bodyBytes))
-		return 0, fmt.Errorf("GraphQL request failed with status %d", resp.StatusCode)
-	}
-
 	var result struct {
 		Data struct {
-			LoyaltyPointLedgers []struct {
-				BalanceCarriedForward string `json:"balanceCarriedForward"`
-			} `json:"loyaltyPointLedgers"`
+			LoyaltyPointLedgers []krakenLedgerEntry `json:"loyaltyPointLedgers"`
 			Account struct {
 				Campaigns []struct {
 					Slug string `json:"slug"`
@@ -927,21 +1203,11 @@ func (c *OctopusClient) getOctoPointsGraphQL() (int, error) {
 				EnrollmentStatus string `json:"enrollmentStatus"`
 			} `json:"octoplusAccountInfo"`
 		} `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors"`
 	}
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	c.debugLog("OctoPoints response: %s", string(bodyBytes))
-
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(result.Errors) > 0 {
-		c.debugLog("OctoPoints GraphQL errors: %v", result.Errors)
-		return 0, fmt.Errorf("GraphQL errors: %s", result.Errors[0].Message)
+	// Campaigns and enrolment are only logged, so the ledger is enough
+	if err := c.decodeGraphQL(resp, "octoplusData", &result.Data, "account", "octoplusAccountInfo"); err != nil {
+		return 0, nil, fmt.Errorf("failed to get OctoPoints: %w", err)
 	}
 
 	// Debug campaign information
@@ -957,14 +1223,25 @@ func (c *OctopusClient) getOctoPointsGraphQL() (int, error) {
 		points, err := strconv.Atoi(pointsStr)
 		if err != nil {
 			c.debugLog("Failed to convert points string '%s' to int: %v", pointsStr, err)
-			return 0, fmt.Errorf("failed to convert points to integer: %w", err)
+			return 0, nil, fmt.Errorf("failed to convert points to integer: %w", err)
 		}
-		c.debugLog("Found %d OctoPoints", points)
-		return points, nil
+
+		var ledger []PointsLedgerEntry
+		for _, entry := range result.Data.LoyaltyPointLedgers {
+			converted, err := entry.toLedgerEntry()
+			if err != nil {
+				// The balance is still good, the history can wait for the next fetch
+				c.debugLog("Skipping OctoPoints ledger entry %s: %v", entry.ID, err)
+				continue
+			}
+			ledger = append(ledger, converted)
+		}
+		c.debugLog("Found %d OctoPoints across %d ledger entries", points, len(ledger))
+		return points, ledger, nil
 	}
 
 	c.debugLog("No OctoPoints data found")
-	return 0, nil // No points data available
+	return 0, nil, nil // No points data available
 }
 
 func (c *OctopusClient) GetFreeElectricitySessions() (*FreeElectricitySessionsResponse, error) {
@@ -973,16 +1250,16 @@ func (c *OctopusClient) GetFreeElectricitySessions() (*FreeElectricitySessionsRe
 
 func (c *OctopusClient) GetFreeElectricitySessionsWithCache(state *AppState) (*FreeElectricitySessionsResponse, error) {
 	// Check cache if state is provided - static file with no rate limits, check frequently
-	if state != nil && state.CachedFreeElectricity != nil {
-		if state.IsCacheValid(state.CachedFreeElectricity.Timestamp, CacheDurationFreeElectricity) {
-			return state.CachedFreeElectricity.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedFreeElectricitySessions { return s.CachedFreeElectricity }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationFreeElectricity) {
+			return cached.Data, nil
 		}
 	}
 	// Free electricity sessions with fallback endpoints for reliability
 	urls := []string{
-		"https://matthewgall.github.io/octoevents/free_electricity.json",           // Primary: GitHub Pages (fastest)
-		"https://raw.githubusercontent.com/matthewgall/octoevents/refs/heads/main/free_electricity.json", // Fallback 1: GitHub Raw
-		"https://oe-api.davidskendall.co.uk/free_electricity.json",                // Fallback 2: David's API
+		getEndpoint("free-electricity"),          // Primary: GitHub Pages (fastest)
+		getEndpoint("free-electricity-raw"),      // Fallback 1: GitHub Raw
+		getEndpoint("free-electricity-fallback"), // Fallback 2: David's API
 	}
 	
 	var lastErr error
@@ -1019,10 +1296,12 @@ func (c *OctopusClient) GetFreeElectricitySessionsWithCache(state *AppState) (*F
 		
 		// Update cache if state is provided
 		if state != nil {
-			state.CachedFreeElectricity = &CachedFreeElectricitySessions{
-				Data:      &result,
-				Timestamp: time.Now(),
-			}
+			state.Update(func(s *AppState) {
+				s.CachedFreeElectricity = &CachedFreeElectricitySessions{
+					Data:      &result,
+					Timestamp: time.Now(),
+				}
+			})
 		}
 
 		return &result, nil
@@ -1032,10 +1311,12 @@ func (c *OctopusClient) GetFreeElectricitySessionsWithCache(state *AppState) (*F
 	return nil, fmt.Errorf("all free electricity endpoints failed, last error: %w", lastErr)
 }
 
+// JoinSavingSession joins a saving session. The request is sent at most once;
+// the monitor retries failed joins itself.
 func (c *OctopusClient) JoinSavingSession(eventID int) error {
 	endpoint := fmt.Sprintf("/accounts/%s/saving-sessions/%d/join", c.AccountID, eventID)
 	
-	resp, err := c.makeRequest("POST", endpoint, nil)
+	resp, err := c.makeRequestWithContext(withoutRetries(context.Background()), "POST", endpoint, nil)
 	if err != nil {
 		return fmt.Errorf("failed to join saving session: %w", err)
 	}
@@ -1050,9 +1331,9 @@ func (c *OctopusClient) JoinSavingSession(eventID int) error {
 
 func (c *OctopusClient) getWheelOfFortuneSpinsWithCache(state *AppState) (*WheelOfFortuneSpins, error) {
 	// Check cache if state is provided - Wheel of Fortune spins update once daily
-	if state != nil && state.CachedWheelOfFortuneSpins != nil {
-		if state.IsCacheValid(state.CachedWheelOfFortuneSpins.Timestamp, CacheDurationWheelSpins) {
-			return state.CachedWheelOfFortuneSpins.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedWheelOfFortuneSpins { return s.CachedWheelOfFortuneSpins }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationWheelSpins) {
+			return cached.Data, nil
 		}
 	}
 
@@ -1064,10 +1345,12 @@ func (c *OctopusClient) getWheelOfFortuneSpinsWithCache(state *AppState) (*Wheel
 
 	// Update cache if state is provided
 	if state != nil {
-		state.CachedWheelOfFortuneSpins = &CachedWheelOfFortuneSpins{
-			Data:      spins,
-			Timestamp: time.Now(),
-		}
+		state.Update(func(s *AppState) {
+			s.CachedWheelOfFortuneSpins = &CachedWheelOfFortuneSpins{
+				Data:      spins,
+				Timestamp: time.Now(),
+			}
+		})
 	}
 
 	return spins, nil
@@ -1106,15 +1389,6 @@ func (c *OctopusClient) getWheelOfFortuneSpins() (*WheelOfFortuneSpins, error) {
 
 	c.debugLog("Wheel of Fortune request status: %d", resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		c.debugLog("Wheel of Fortune request failed body: %s", string(bodyBytes))
-		c.logger.Error("Wheel of Fortune GraphQL error",
-			"status_code", resp.StatusCode,
-			"body", string(bodyBytes))
-		return nil, fmt.Errorf("GraphQL request failed with status %d", resp.StatusCode)
-	}
-
 	var result struct {
 		Data struct {
 			ElectricitySpins struct {
@@ -1126,21 +1400,11 @@ func (c *OctopusClient) getWheelOfFortuneSpins() (*WheelOfFortuneSpins, error) {
 				Typename     string `json:"__typename"`
 			} `json:"gasSpins"`
 		} `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(result.Errors) > 0 {
-		errorMessages := make([]string, len(result.Errors))
-		for i, err := range result.Errors {
-			errorMessages[i] = err.Message
-		}
-		return nil, fmt.Errorf("GraphQL errors: %s", strings.Join(errorMessages, ", "))
+	// An account without one of the fuels can still spin the other's wheel
+	if err := c.decodeGraphQL(resp, "getWheelOfFortuneSpinsAllowed", &result.Data, "electricitySpins", "gasSpins"); err != nil {
+		return nil, fmt.Errorf("failed to get Wheel of Fortune spins: %w", err)
 	}
 
 	spins := &WheelOfFortuneSpins{
@@ -1153,7 +1417,9 @@ func (c *OctopusClient) getWheelOfFortuneSpins() (*WheelOfFortuneSpins, error) {
 	return spins, nil
 }
 
-// spinWheelOfFortune performs a single spin of the Wheel of Fortune for the specified fuel type
+// spinWheelOfFortune performs a single spin of the Wheel of Fortune for the
+// specified fuel type. The mutation is sent at most once, as a retried spin
+// could use up a second spin.
 func (c *OctopusClient) spinWheelOfFortune(fuelType string) (*WheelSpinResult, error) {
 	c.debugLog("Spinning Wheel of Fortune for %s...", fuelType)
 
@@ -1175,24 +1441,19 @@ func (c *OctopusClient) spinWheelOfFortune(fuelType string) (*WheelSpinResult, e
 	c.debugLog("Spin query: %s", query)
 	c.debugLog("Spin variables: %+v", variables)
 
-	resp, err := c.makeGraphQLRequestWithEndpoint(getEndpoint("backend-graphql"), query, variables, true, "spinWheelOfFortune")
+	ctx := withoutRetries(context.Background())
+	resp, err := c.makeGraphQLRequestWithContext(ctx, getEndpoint("backend-graphql"), query, variables, true, "spinWheelOfFortune")
 	if err != nil {
 		c.debugLog("Spin request failed: %v", err)
 		return nil, fmt.Errorf("failed to execute spin request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read the response body for debugging
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-	c.debugLog("Spin response body: %s", string(bodyBytes))
-
+	// A refused spin comes back as a SessionError rather than a 0 point prize
 	var result WheelSpinResponse
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		c.debugLog("Failed to decode spin response: %v", err)
-		return nil, fmt.Errorf("failed to decode spin response: %w", err)
+	if err := c.decodeGraphQL(resp, "spinWheelOfFortune", &result.Data); err != nil {
+		c.debugLog("Spin failed: %v", err)
+		return nil, fmt.Errorf("failed to spin %s wheel: %w", fuelType, err)
 	}
 
 	prize := result.Data.SpinWheelOfFortune.Prize.Value
@@ -1217,6 +1478,14 @@ func (c *OctopusClient) spinAllAvailableWheels(spins *WheelOfFortuneSpins) ([]Wh
 			c.logger.Error("Failed to spin electricity wheel",
 				"wheel_number", i+1,
 				"error", err)
+			var authErr *AuthError
+			if errors.As(err, &authErr) {
+				return results, err // every later spin would be rejected too
+			}
+			var sessionErr *SessionError
+			if errors.As(err, &sessionErr) {
+				break // refused, e.g. no spins left for this fuel
+			}
 			continue
 		}
 		results = append(results, *result)
@@ -1235,6 +1504,14 @@ func (c *OctopusClient) spinAllAvailableWheels(spins *WheelOfFortuneSpins) ([]Wh
 			c.logger.Error("Failed to spin gas wheel",
 				"wheel_number", i+1,
 				"error", err)
+			var authErr *AuthError
+			if errors.As(err, &authErr) {
+				return results, err // every later spin would be rejected too
+			}
+			var sessionErr *SessionError
+			if errors.As(err, &sessionErr) {
+				break // refused, e.g. no spins left for this fuel
+			}
 			continue
 		}
 		results = append(results, *result)
@@ -1256,9 +1533,9 @@ type AccountInfo struct {
 
 func (c *OctopusClient) getAccountInfoWithCache(state *AppState) (*AccountInfo, error) {
 	// Check cache if state is provided - account balance changes at most hourly, often less
-	if state != nil && state.CachedAccountInfo != nil {
-		if state.IsCacheValid(state.CachedAccountInfo.Timestamp, CacheDurationAccountInfo) {
-			return state.CachedAccountInfo.Data, nil
+	if cached := loadCached(state, func(s *AppState) *CachedAccountInfo { return s.CachedAccountInfo }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationAccountInfo) {
+			return cached.Data, nil
 		}
 	}
 
@@ -1270,10 +1547,12 @@ func (c *OctopusClient) getAccountInfoWithCache(state *AppState) (*AccountInfo,
 
 	// Update cache if state is provided
 	if state != nil {
-		state.CachedAccountInfo = &CachedAccountInfo{
-			Data:      accountInfo,
-			Timestamp: time.Now(),
-		}
+		state.Update(func(s *AppState) {
+			s.CachedAccountInfo = &CachedAccountInfo{
+				Data:      accountInfo,
+				Timestamp: time.Now(),
+			}
+		})
 	}
 
 	return accountInfo, nil
@@ -1310,12 +1589,6 @@ func (c *OctopusClient) getAccountInfo() (*AccountInfo, error) {
 
 	c.debugLog("Account info request status: %d", resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		c.debugLog("Account info request failed body: %s", string(bodyBytes))
-		return nil, fmt.Errorf("GraphQL request failed with status %d", resp.StatusCode)
-	}
-
 	var result struct {
 		Data struct {
 			Account struct {
@@ -1323,21 +1596,10 @@ func (c *OctopusClient) getAccountInfo() (*AccountInfo, error) {
 				AccountType string  `json:"accountType"`
 			} `json:"account"`
 		} `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(result.Errors) > 0 {
-		errorMessages := make([]string, len(result.Errors))
-		for i, err := range result.Errors {
-			errorMessages[i] = err.Message
-		}
-		return nil, fmt.Errorf("GraphQL errors: %s", strings.Join(errorMessages, ", "))
+	if err := c.decodeGraphQL(resp, "getAccountInfo", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get account info: %w", err)
 	}
 
 	accountInfo := &AccountInfo{
@@ -1352,78 +1614,137 @@ func (c *OctopusClient) getAccountInfo() (*AccountInfo, error) {
 
 // getSmartMeterDevices retrieves ESME (Electricity Smart Meter) device IDs
 func (c *OctopusClient) getSmartMeterDevices() ([]string, error) {
-	query := `query getEligibility($accountNumber: String!) {
-		account(accountNumber: $accountNumber) {
-			properties {
-				id
-				address
-				smartDeviceNetworks {
-					smartDevices {
-						deviceId
-						type
-						__typename
-					}
-					__typename
-				}
-				__typename
-			}
-			__typename
-		}
-	}`
+	electricity, _, err := c.getMeterDevices()
+	return electricity, err
+}
 
-	variables := map[string]interface{}{
-		"accountNumber": c.AccountID,
+// getMeterDevices retrieves ESME (electricity) and GSME (gas) smart meter
+// device IDs across every property. Gas is never nil, so callers can tell a
+// lookup from no lookup.
+func (c *OctopusClient) getMeterDevices() ([]string, []string, error) {
+	properties, err := c.getProperties()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	resp, err := c.makeGraphQLRequest(query, variables, true)
+	electricity, gas := meterDevices(properties)
+	c.debugLog("Found %d ESME and %d GSME devices", len(electricity), len(gas))
+	return electricity, gas, nil
+}
+
+// getUsageMeasurements retrieves electricity usage measurements for the last N
+// days from every device, each measurement tagged with the device it came from
+func (c *OctopusClient) getUsageMeasurements(deviceIDs []string, days int) ([]UsageMeasurement, error) {
+	return c.getFuelUsageMeasurements(FuelElectricity, deviceIDs, days)
+}
+
+// getExportMeasurements retrieves electricity exported to the grid for the
+// last N days. The export MPAN's readings are the GENERATION direction of the
+// ESME it shares with the import MPAN; see exportDevices.
+func (c *OctopusClient) getExportMeasurements(deviceIDs []string, days int) ([]UsageMeasurement, error) {
+	return c.getFuelUsageMeasurements(FuelExport, deviceIDs, days)
+}
+
+// getGasUsageMeasurements retrieves gas usage for the last N days, converted
+// from cubic metres to kWh with the configured calorific value
+func (c *OctopusClient) getGasUsageMeasurements(deviceIDs []string, days int) ([]UsageMeasurement, error) {
+	measurements, err := c.getFuelUsageMeasurements(FuelGas, deviceIDs, days)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute meter eligibility request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result MeterEligibilityResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode meter eligibility response: %w", err)
+	for i := range measurements {
+		convertGasToKWh(&measurements[i], c.gasCalorificValue)
 	}
+	return measurements, nil
+}
 
-	var deviceIDs []string
-	for _, property := range result.Data.Account.Properties {
-		for _, network := range property.SmartDeviceNetworks {
-			for _, device := range network.SmartDevices {
-				// Only include ESME (Electricity Smart Meter) devices
-				if device.Type == "ESME" {
-					deviceIDs = append(deviceIDs, device.DeviceID)
-					c.debugLog("Found ESME device: %s", device.DeviceID)
-				}
-			}
-		}
+// convertGasToKWh replaces a reading in cubic metres with its energy in kWh,
+// keeping the volume. Readings already in kWh are left alone.
+func convertGasToKWh(m *UsageMeasurement, calorificValue float64) {
+	unit := strings.ToLower(m.Unit)
+	if unit != "m3" && unit != "m³" {
+		return
 	}
-
-	c.debugLog("Found %d ESME devices", len(deviceIDs))
-	return deviceIDs, nil
+	volume := m.GetValueAsFloat64()
+	kwh := volume * GasVolumeCorrectionFactor * calorificValue / MegajoulesPerKWh
+	m.VolumeM3 = volume
+	m.Value = strconv.FormatFloat(kwh, 'f', 3, 64)
+	m.Unit = "kwh"
 }
 
-// getUsageMeasurements retrieves electricity usage measurements for the last N days
-func (c *OctopusClient) getUsageMeasurements(deviceIDs []string, days int) ([]UsageMeasurement, error) {
+// getFuelUsageMeasurements retrieves measurements for the last N days from
+// every device of one fuel type
+func (c *OctopusClient) getFuelUsageMeasurements(fuel string, deviceIDs []string, days int) ([]UsageMeasurement, error) {
 	if len(deviceIDs) == 0 {
 		return nil, fmt.Errorf("no device IDs provided")
 	}
 
-	// Use first device ID for now (most users have one electricity meter)
-	deviceID := deviceIDs[0]
-	
 	// Calculate time range
 	endTime := time.Now()
 	startTime := endTime.AddDate(0, 0, -days)
 	
-	c.debugLog("Fetching usage measurements: %d days from %s to %s", days, startTime.Format("2006-01-02 15:04"), endTime.Format("2006-01-02 15:04"))
+	c.debugLog("Fetching %s usage measurements: %d days from %s to %s for %d devices", strings.ToLower(fuel), days, startTime.Format("2006-01-02 15:04"), endTime.Format("2006-01-02 15:04"), len(deviceIDs))
+
+	var measurements []UsageMeasurement
+	for _, deviceID := range deviceIDs {
+		deviceMeasurements, err := c.getDeviceUsageMeasurements(fuel, deviceID, startTime, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", deviceID, err)
+		}
+		measurements = append(measurements, deviceMeasurements...)
+	}
+
+	// Debug: Show first few measurements to understand data structure
+	if len(measurements) > 0 && c.debug {
+		c.debugLog("Sample measurements:")
+		sampleCount := len(measurements)
+		if sampleCount > 3 {
+			sampleCount = 3
+		}
+		for i, m := range measurements[:sampleCount] {
+			costStr := "no cost data"
+			if len(m.MetaData.Statistics) > 0 {
+				costStr = m.MetaData.Statistics[0].CostInclTax.EstimatedAmount
+			}
+			c.debugLog("  %d. %s: %s %s (Cost: %s)", i+1, m.StartAt.Format("2006-01-02 15:04"), m.Value, m.Unit, costStr)
+		}
+	}
+	
+	return measurements, nil
+}
+
+// getDeviceUsageMeasurements follows the measurements cursor until every page
+// for one device has been read
+func (c *OctopusClient) getDeviceUsageMeasurements(fuel, deviceID string, startTime, endTime time.Time) ([]UsageMeasurement, error) {
+	var utilityFilter map[string]interface{}
+	if fuel == FuelGas {
+		utilityFilter = map[string]interface{}{
+			"gasFilters": map[string]interface{}{
+				"readingFrequencyType": "RAW_INTERVAL",
+				"deviceId":             deviceID,
+			},
+		}
+	} else {
+		direction := "CONSUMPTION"
+		if fuel == FuelExport {
+			direction = "GENERATION"
+		}
+		utilityFilter = map[string]interface{}{
+			"electricityFilters": map[string]interface{}{
+				"readingFrequencyType": "RAW_INTERVAL",
+				"readingDirection":     direction,
+				"deviceId":             deviceID,
+			},
+		}
+	}
 
-	query := `query getMeasurements($accountNumber: String!, $first: Int!, $utilityFilters: [UtilityFiltersInput!], $startAt: DateTime, $endAt: DateTime, $timezone: String) {
+	query := `query getMeasurements($accountNumber: String!, $first: Int!, $after: String, $utilityFilters: [UtilityFiltersInput!], $startAt: DateTime, $endAt: DateTime, $timezone: String) {
 		account(accountNumber: $accountNumber) {
 			properties {
 				id
 				measurements(
 					first: $first
+					after: $after
 					utilityFilters: $utilityFilters
 					startAt: $startAt
 					endAt: $endAt
@@ -1482,60 +1803,121 @@ func (c *OctopusClient) getUsageMeasurements(deviceIDs []string, days int) ([]Us
 		}
 	}`
 
+	var measurements []UsageMeasurement
+	cursor := ""
+	for page := 1; ; page++ {
+		variables := map[string]interface{}{
+			"accountNumber": c.AccountID,
+			"first":         UsageMeasurementsPageSize,
+			"startAt":       startTime.Format(time.RFC3339),
+			"endAt":         endTime.Format(time.RFC3339),
+			"timezone":       "Europe/London",
+			"utilityFilters": []map[string]interface{}{utilityFilter},
+		}
+		if cursor != "" {
+			variables["after"] = cursor
+		}
+
+		resp, err := c.makeGraphQLRequest(query, variables, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute measurements request: %w", err)
+		}
+
+		var result UsageMeasurementsResponse
+		err = c.decodeGraphQL(resp, "getMeasurements", &result.Data)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get usage measurements: %w", err)
+		}
+
+		hasNextPage := false
+		for _, property := range result.Data.Account.Properties {
+			for _, edge := range property.Measurements.Edges {
+				edge.Node.DeviceID = deviceID
+				measurements = append(measurements, edge.Node)
+			}
+			if property.Measurements.PageInfo.HasNextPage {
+				hasNextPage = true
+				cursor = property.Measurements.PageInfo.EndCursor
+			}
+		}
+
+		if !hasNextPage || cursor == "" {
+			break
+		}
+		if page >= UsageMeasurementsMaxPages {
+			c.logger.Warn("Stopped paging usage measurements", "device_id", deviceID, "pages", page, "measurements", len(measurements))
+			break
+		}
+	}
+
+	c.debugLog("Retrieved %d usage measurements for device %s", len(measurements), deviceID)
+	return measurements, nil
+}
+
+// getSmartMeterTelemetry retrieves Home Mini readings for one electricity
+// meter between start and end at 10-second granularity. Meters without a
+// Home Mini return no readings.
+func (c *OctopusClient) getSmartMeterTelemetry(deviceID string, start, end time.Time) ([]TelemetryReading, error) {
+	query := `query getSmartMeterTelemetry($deviceId: String!, $start: DateTime, $end: DateTime, $grouping: TelemetryGrouping) {
+		smartMeterTelemetry(deviceId: $deviceId, start: $start, end: $end, grouping: $grouping) {
+			readAt
+			consumption
+			demand
+			consumptionDelta
+			costDelta
+			__typename
+		}
+	}`
+
 	variables := map[string]interface{}{
-		"accountNumber": c.AccountID,
-		"first":         1000, // Adjust based on expected data volume
-		"startAt":       startTime.Format(time.RFC3339),
-		"endAt":         endTime.Format(time.RFC3339),
-		"timezone":      "Europe/London",
-		"utilityFilters": []map[string]interface{}{
-			{
-				"electricityFilters": map[string]interface{}{
-					"readingFrequencyType": "RAW_INTERVAL",
-					"readingDirection":     "CONSUMPTION",
-					"deviceId":             deviceID,
-				},
-			},
-		},
+		"deviceId": deviceID,
+		"start":    start.Format(time.RFC3339),
+		"end":      end.Format(time.RFC3339),
+		"grouping": TelemetryGrouping,
 	}
 
 	resp, err := c.makeGraphQLRequest(query, variables, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute measurements request: %w", err)
+		return nil, fmt.Errorf("failed to execute telemetry request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var result UsageMeasurementsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode measurements response: %w", err)
+	var result SmartMeterTelemetryResponse
+	if err := c.decodeGraphQL(resp, "getSmartMeterTelemetry", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get telemetry: %w", err)
 	}
 
-	var measurements []UsageMeasurement
-	for _, property := range result.Data.Account.Properties {
-		for _, edge := range property.Measurements.Edges {
-			measurements = append(measurements, edge.Node)
-		}
+	c.debugLog("Retrieved %d telemetry readings for device %s", len(result.Data.SmartMeterTelemetry), deviceID)
+	return result.Data.SmartMeterTelemetry, nil
+}
+
+// GetDemandAsFloat64 parses the demand in watts
+func (t *TelemetryReading) GetDemandAsFloat64() float64 {
+	if val, err := strconv.ParseFloat(t.Demand, 64); err == nil {
+		return val
 	}
+	return 0.0
+}
 
-	c.debugLog("Retrieved %d usage measurements for device %s", len(measurements), deviceID)
-	
-	// Debug: Show first few measurements to understand data structure
-	if len(measurements) > 0 && c.debug {
-		c.debugLog("Sample measurements:")
-		sampleCount := len(measurements)
-		if sampleCount > 3 {
-			sampleCount = 3
-		}
-		for i, m := range measurements[:sampleCount] {
-			costStr := "no cost data"
-			if len(m.MetaData.Statistics) > 0 {
-				costStr = m.MetaData.Statistics[0].CostInclTax.EstimatedAmount
-			}
-			c.debugLog("  %d. %s: %s %s (Cost: %s)", i+1, m.StartAt.Format("2006-01-02 15:04"), m.Value, m.Unit, costStr)
-		}
+// GetConsumptionDeltaAsFloat64 parses the energy used since the previous reading in Wh
+func (t *TelemetryReading) GetConsumptionDeltaAsFloat64() float64 {
+	if val, err := strconv.ParseFloat(t.ConsumptionDelta, 64); err == nil {
+		return val
 	}
-	
-	return measurements, nil
+	return 0.0
+}
+
+// GetCostAsFloat64 parses the estimated cost (including tax) in pence. For
+// export readings this is what the export tariff pays.
+func (m *UsageMeasurement) GetCostAsFloat64() float64 {
+	if len(m.MetaData.Statistics) == 0 {
+		return 0.0
+	}
+	if val, err := strconv.ParseFloat(m.MetaData.Statistics[0].CostInclTax.EstimatedAmount, 64); err == nil {
+		return val
+	}
+	return 0.0
 }
 
 // GetValueAsFloat64 parses the string value as float64
@@ -1548,43 +1930,95 @@ func (m *UsageMeasurement) GetValueAsFloat64() float64 {
 
 // getSmartMeterDevicesWithCache retrieves ESME device IDs with caching
 func (c *OctopusClient) getSmartMeterDevicesWithCache(state *AppState) ([]string, error) {
-	if state != nil && state.CachedMeterDevices != nil {
-		if state.IsCacheValid(state.CachedMeterDevices.Timestamp, CacheDurationMeterDevices) {
-			return state.CachedMeterDevices.Data, nil
+	devices, err := c.getMeterDevicesWithCache(state)
+	if err != nil {
+		return nil, err
+	}
+	return devices.Data, nil
+}
+
+// getGasMeterDevicesWithCache retrieves GSME device IDs with caching
+func (c *OctopusClient) getGasMeterDevicesWithCache(state *AppState) ([]string, error) {
+	devices, err := c.getMeterDevicesWithCache(state)
+	if err != nil {
+		return nil, err
+	}
+	return devices.Gas, nil
+}
+
+// getMeterDevicesWithCache retrieves the properties and both fuels' device
+// IDs with caching. Entries cached before gas meters or properties were
+// discovered are refreshed.
+func (c *OctopusClient) getMeterDevicesWithCache(state *AppState) (*CachedMeterDevices, error) {
+	if cached := loadCached(state, func(s *AppState) *CachedMeterDevices { return s.CachedMeterDevices }); cached != nil && cached.Gas != nil && cached.Properties != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationMeterDevices) {
+			return cached, nil
 		}
 	}
 
 	// Get fresh data
-	devices, err := c.getSmartMeterDevices()
+	properties, err := c.getProperties()
 	if err != nil {
 		return nil, err
 	}
+	electricity, gas := meterDevices(properties)
+
+	devices := &CachedMeterDevices{
+		Data:       electricity,
+		Gas:        gas,
+		Properties: properties,
+		Timestamp:  time.Now(),
+	}
 
 	// Cache the result
 	if state != nil {
-		state.CachedMeterDevices = &CachedMeterDevices{
-			Data:      devices,
-			Timestamp: time.Now(),
-		}
+		state.Update(func(s *AppState) {
+			s.CachedMeterDevices = devices
+		})
 	}
 
 	return devices, nil
 }
 
-// getUsageMeasurementsWithCache retrieves usage measurements with caching
+// getUsageMeasurementsWithCache retrieves electricity usage measurements with caching
 func (c *OctopusClient) getUsageMeasurementsWithCache(state *AppState, days int) ([]UsageMeasurement, error) {
-	if state != nil && state.CachedUsageMeasurements != nil {
+	return c.getFuelUsageMeasurementsWithCache(state, FuelElectricity, days)
+}
+
+// getGasUsageMeasurementsWithCache retrieves gas usage measurements with caching
+func (c *OctopusClient) getGasUsageMeasurementsWithCache(state *AppState, days int) ([]UsageMeasurement, error) {
+	return c.getFuelUsageMeasurementsWithCache(state, FuelGas, days)
+}
+
+// getExportMeasurementsWithCache retrieves electricity export measurements with caching
+func (c *OctopusClient) getExportMeasurementsWithCache(state *AppState, days int) ([]UsageMeasurement, error) {
+	return c.getFuelUsageMeasurementsWithCache(state, FuelExport, days)
+}
+
+// usageCache returns the state field caching a fuel's measurements
+func usageCache(s *AppState, fuel string) **CachedUsageMeasurements {
+	switch fuel {
+	case FuelGas:
+		return &s.CachedGasUsage
+	case FuelExport:
+		return &s.CachedExportUsage
+	}
+	return &s.CachedUsageMeasurements
+}
+
+func (c *OctopusClient) getFuelUsageMeasurementsWithCache(state *AppState, fuel string, days int) ([]UsageMeasurement, error) {
+	if cached := loadCached(state, func(s *AppState) *CachedUsageMeasurements { return *usageCache(s, fuel) }); cached != nil {
 		// Cache is valid if it's less than duration old and covers the same or more days
-		if state.IsCacheValid(state.CachedUsageMeasurements.Timestamp, CacheDurationUsageMeasurements) && 
-		   state.CachedUsageMeasurements.Days >= days {
-			c.debugLog("Using cached usage measurements (%d measurements, %d days, age: %v)", 
-				len(state.CachedUsageMeasurements.Data), state.CachedUsageMeasurements.Days, 
-				time.Since(state.CachedUsageMeasurements.Timestamp))
+		if state.IsCacheValid(cached.Timestamp, CacheDurationUsageMeasurements) && 
+		   cached.Days >= days {
+			c.debugLog("Using cached %s usage measurements (%d measurements, %d days, age: %v)", 
+				strings.ToLower(fuel), len(cached.Data), cached.Days, 
+				time.Since(cached.Timestamp))
 			
 			// Filter cached data to only include the requested number of days
 			cutoffTime := time.Now().AddDate(0, 0, -days)
 			var filteredData []UsageMeasurement
-			for _, measurement := range state.CachedUsageMeasurements.Data {
+			for _, measurement := range cached.Data {
 				if measurement.StartAt.After(cutoffTime) {
 					filteredData = append(filteredData, measurement)
 				}
@@ -1594,29 +2028,51 @@ func (c *OctopusClient) getUsageMeasurementsWithCache(state *AppState, days int)
 	}
 
 	// Get device IDs first
-	devices, err := c.getSmartMeterDevicesWithCache(state)
+	var devices []string
+	var err error
+	switch fuel {
+	case FuelGas:
+		devices, err = c.getGasMeterDevicesWithCache(state)
+	case FuelExport:
+		devices, err = c.getExportDevicesWithCache(state)
+	default:
+		devices, err = c.getSmartMeterDevicesWithCache(state)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meter devices: %w", err)
 	}
 
 	if len(devices) == 0 {
+		switch fuel {
+		case FuelGas:
+			return nil, fmt.Errorf("no GSME devices found")
+		case FuelExport:
+			return nil, fmt.Errorf("no ESME devices with an export MPAN found")
+		}
 		return nil, fmt.Errorf("no ESME devices found")
 	}
 
 	// Get fresh usage data
-	measurements, err := c.getUsageMeasurements(devices, days)
+	var measurements []UsageMeasurement
+	if fuel == FuelGas {
+		measurements, err = c.getGasUsageMeasurements(devices, days)
+	} else {
+		measurements, err = c.getFuelUsageMeasurements(fuel, devices, days)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result
 	if state != nil {
-		state.CachedUsageMeasurements = &CachedUsageMeasurements{
-			Data:      measurements,
-			Timestamp: time.Now(),
-			Days:      days,
-		}
+		state.Update(func(s *AppState) {
+			*usageCache(s, fuel) = &CachedUsageMeasurements{
+				Data:      measurements,
+				Timestamp: time.Now(),
+				Days:      days,
+			}
+		})
 	}
 
 	return measurements, nil
-}
\ No newline at end of file
+}
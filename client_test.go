@@ -15,6 +15,11 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -54,8 +59,10 @@ func TestNewOctopusClient(t *testing.T) {
 		t.Error("Expected HTTP client to be initialized")
 	}
 
-	if client.client.Timeout != 30*time.Second {
-		t.Errorf("Expected HTTP timeout %v, got %v", 30*time.Second, client.client.Timeout)
+	// The timeout is applied per attempt in the transport, so an overall
+	// client timeout would cut retries and Retry-After waits short
+	if client.client.Timeout != 0 {
+		t.Errorf("Expected no overall HTTP timeout, got %v", client.client.Timeout)
 	}
 }
 
@@ -130,9 +137,14 @@ func TestInvalidateJWTToken(t *testing.T) {
 	// Set some JWT data
 	client.jwtToken = "some-token"
 	client.jwtExpiry = time.Now().Add(1 * time.Hour)
+	client.refreshToken = "some-refresh-token"
 
 	client.invalidateJWTToken()
 
+	if client.refreshToken != "" || state.JWTRefreshToken != "" {
+		t.Errorf("Expected refresh token to be cleared, got %q", client.refreshToken)
+	}
+
 	// Check client state is cleared
 	if client.jwtToken != "" {
 		t.Errorf("Expected empty JWT token, got %s", client.jwtToken)
@@ -155,7 +167,7 @@ func TestInvalidateJWTToken(t *testing.T) {
 func TestWheelOfFortuneSpins(t *testing.T) {
 	spins := WheelOfFortuneSpins{
 		ElectricitySpins: 3,
-		GasSpins:        2,
+		GasSpins:         2,
 	}
 
 	if spins.ElectricitySpins != 3 {
@@ -165,4 +177,255 @@ func TestWheelOfFortuneSpins(t *testing.T) {
 	if spins.GasSpins != 2 {
 		t.Errorf("Expected 2 gas spins, got %d", spins.GasSpins)
 	}
-}
\ No newline at end of file
+}
+
+func TestSavingSessionsResponseUnjoinedEvents(t *testing.T) {
+	now := time.Now()
+	response := &SavingSessionsResponse{
+		AvailableEvents: []SavingSessionEvent{
+			{ID: 1, StartAt: now.Add(1 * time.Hour), EndAt: now.Add(2 * time.Hour), RewardPerKwhInOctoPoints: 800},
+			{ID: 2, StartAt: now.Add(25 * time.Hour), EndAt: now.Add(26 * time.Hour), RewardPerKwhInOctoPoints: 1200},
+		},
+	}
+	response.Data.SavingSessions.Account.JoinedEvents = []SavingSession{
+		{EventID: 1, StartAt: now.Add(1 * time.Hour), EndAt: now.Add(2 * time.Hour), PointsPerKWh: 800},
+	}
+
+	unjoined := response.UnjoinedEvents()
+	if len(unjoined) != 1 {
+		t.Fatalf("Expected 1 unjoined event, got %d", len(unjoined))
+	}
+
+	if unjoined[0].ID != 2 {
+		t.Errorf("Expected unjoined event ID 2, got %d", unjoined[0].ID)
+	}
+
+	session := unjoined[0].ToSavingSession()
+	if session.EventID != 2 || session.PointsPerKWh != 1200 {
+		t.Errorf("Expected converted session with ID 2 and 1200 points per kWh, got %+v", session)
+	}
+}
+
+func TestGetSavingSessionEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"savingSessions":{"events":[{"id":42,"code":"EVENT_42","startAt":"2025-01-15T17:30:00Z","endAt":"2025-01-15T18:30:00Z","rewardPerKwhInOctoPoints":1600}]}}}`))
+	}))
+	defer server.Close()
+
+	original := octopusEndpoints["graphql"]
+	octopusEndpoints["graphql"] = server.URL
+	defer func() { octopusEndpoints["graphql"] = original }()
+
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	client.jwtToken = "test-token"
+	client.jwtExpiry = time.Now().Add(1 * time.Hour)
+
+	events, err := client.getSavingSessionEvents()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	if events[0].ID != 42 || events[0].Code != "EVENT_42" || events[0].RewardPerKwhInOctoPoints != 1600 {
+		t.Errorf("Unexpected event decoded: %+v", events[0])
+	}
+}
+
+func TestRefreshJWTTokenSingleFlight(t *testing.T) {
+	var tokenRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		time.Sleep(50 * time.Millisecond) // Keep the refresh in flight while others queue
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"obtainKrakenToken":{"token":"fresh-token","refreshExpiresIn":3600}}}`))
+	}))
+	defer server.Close()
+
+	original := octopusEndpoints["graphql"]
+	octopusEndpoints["graphql"] = server.URL
+	defer func() { octopusEndpoints["graphql"] = original }()
+
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	client.SetState(&AppState{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.refreshJWTToken(); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&tokenRequests); got != 1 {
+		t.Errorf("Expected 1 token request for concurrent refreshes, got %d", got)
+	}
+	if client.currentJWTToken() != "fresh-token" || client.state.JWTToken != "fresh-token" {
+		t.Errorf("Expected fresh token on client and state, got %q and %q", client.currentJWTToken(), client.state.JWTToken)
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(30 * time.Minute)
+
+	testCases := []struct {
+		name     string
+		token    string
+		expected time.Time
+	}{
+		{"exp claim", fakeJWT(1, now, expiry), expiry},
+		{"padded payload", "e30." + "eyJleHAiOjE3NjIwMDEwMDB9" + "==.sig", time.Unix(1762001000, 0)},
+		{"opaque token", "fresh-token", now.Add(JWTDefaultLifetime)},
+		{"no exp claim", "e30.e30.sig", now.Add(JWTDefaultLifetime)},
+		{"bad payload", "e30.!!!.sig", now.Add(JWTDefaultLifetime)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := jwtExpiry(tc.token, now); !got.Equal(tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	now := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	if got := refreshTokenExpiry(now.Add(7*24*time.Hour).Unix(), now); !got.Equal(now.Add(7 * 24 * time.Hour)) {
+		t.Errorf("Expected a timestamp to be used as is, got %v", got)
+	}
+	if got := refreshTokenExpiry(3600, now); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected a lifetime in seconds to be added to now, got %v", got)
+	}
+}
+
+func TestRefreshJWTTokenPrefersRefreshToken(t *testing.T) {
+	var inputs []map[string]interface{}
+	rejectRefresh := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				Input map[string]interface{} `json:"input"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs = append(inputs, req.Variables.Input)
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, ok := req.Variables.Input["refreshToken"]; ok && rejectRefresh {
+			w.Write([]byte(`{"errors":[{"message":"Invalid data in the refresh token."}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"obtainKrakenToken":{"token":"access-token","refreshToken":"refresh-token","refreshExpiresIn":4102444800}}}`))
+	}))
+	defer server.Close()
+
+	original := octopusEndpoints["graphql"]
+	octopusEndpoints["graphql"] = server.URL
+	defer func() { octopusEndpoints["graphql"] = original }()
+
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	client.SetState(&AppState{})
+
+	// The first token comes from the API key and its refresh token is kept
+	if err := client.refreshJWTToken(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := inputs[0]["APIKey"]; !ok {
+		t.Errorf("Expected the API key to be exchanged first, got %v", inputs[0])
+	}
+	if client.state.JWTRefreshToken != "refresh-token" || !client.state.JWTRefreshTokenExpiry.Equal(time.Unix(4102444800, 0)) {
+		t.Errorf("Expected the refresh token to be persisted, got %q until %v", client.state.JWTRefreshToken, client.state.JWTRefreshTokenExpiry)
+	}
+
+	// Once the access token is rejected, the refresh token replaces it
+	client.expireJWTToken("access-token")
+	if err := client.refreshJWTToken(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(inputs) != 2 || inputs[1]["refreshToken"] != "refresh-token" || inputs[1]["APIKey"] != nil {
+		t.Errorf("Expected a refresh token exchange, got %v", inputs)
+	}
+
+	// A rejected refresh token falls back to the API key
+	rejectRefresh = true
+	client.expireJWTToken("access-token")
+	if err := client.refreshJWTToken(); err != nil {
+		t.Fatalf("Expected the API key fallback to succeed, got %v", err)
+	}
+	if len(inputs) != 4 || inputs[3]["APIKey"] != "sk_live_test" {
+		t.Errorf("Expected a refresh attempt then an API key exchange, got %v", inputs)
+	}
+}
+
+func TestConvertGasToKWh(t *testing.T) {
+	testCases := []struct {
+		name           string
+		measurement    UsageMeasurement
+		expectedValue  string
+		expectedVolume float64
+	}{
+		{"cubic metres", UsageMeasurement{Value: "1.000", Unit: "m3"}, "11.221", 1},
+		{"unicode unit", UsageMeasurement{Value: "0.500", Unit: "m³"}, "5.610", 0.5},
+		{"already kwh", UsageMeasurement{Value: "2.500", Unit: "kWh"}, "2.500", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.measurement
+			convertGasToKWh(&m, GasDefaultCalorificValue)
+			if m.Value != tc.expectedValue || m.VolumeM3 != tc.expectedVolume {
+				t.Errorf("Expected %s kWh from %.1f m³, got %s %s from %.1f m³", tc.expectedValue, tc.expectedVolume, m.Value, m.Unit, m.VolumeM3)
+			}
+		})
+	}
+}
+
+func TestExpireJWTTokenKeepsNewerToken(t *testing.T) {
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	client.jwtToken = "newer-token"
+	client.jwtExpiry = time.Now().Add(1 * time.Hour)
+
+	// A request that failed with an older token must not discard the newer one
+	client.expireJWTToken("older-token")
+	if client.jwtToken != "newer-token" {
+		t.Errorf("Expected newer token to survive, got %q", client.jwtToken)
+	}
+
+	client.expireJWTToken("newer-token")
+	if client.jwtToken != "" || !client.jwtExpiry.IsZero() {
+		t.Errorf("Expected rejected token to be cleared, got %q", client.jwtToken)
+	}
+}
+
+func TestEnforceRateLimitSpacesConcurrentRequests(t *testing.T) {
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	client.minInterval = 20 * time.Millisecond
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.enforceRateLimit()
+		}()
+	}
+	wg.Wait()
+
+	// The first request goes immediately, the other four each wait a slot
+	if elapsed := time.Since(start); elapsed < 4*client.minInterval {
+		t.Errorf("Expected concurrent requests to be spaced at least %v apart, finished in %v", client.minInterval, elapsed)
+	}
+	if metrics := client.metrics.Snapshot(); metrics.RateLimitSleeps != 4 {
+		t.Errorf("Expected 4 rate limit sleeps, got %d", metrics.RateLimitSleeps)
+	}
+}
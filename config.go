@@ -32,6 +32,23 @@ type Config struct {
 	WebPort          int    `yaml:"web_port"`
 	Debug            bool   `yaml:"debug"`
 	NoSmartIntervals bool   `yaml:"no_smart_intervals"`
+	JoinPolicy       *JoinPolicyConfig `yaml:"join_policy"`
+	Notifications    *NotificationsConfig `yaml:"notifications"`
+	MQTT             *MQTTConfig `yaml:"mqtt"`
+	GasCalorificValue float64 `yaml:"gas_calorific_value"` // MJ/m³ from your gas bill
+	Agile            *AgileConfig `yaml:"agile"`
+	Redemption       *RedemptionConfig `yaml:"redemption"`
+	Accounts         []AccountConfig `yaml:"accounts"` // replaces account_id and api_key when set
+}
+
+// AccountConfig is one Octopus account monitored by the daemon. Accounts
+// without their own join policy or notifications use the top-level ones.
+type AccountConfig struct {
+	AccountID     string               `yaml:"account_id"`
+	APIKey        string               `yaml:"api_key"`
+	Name          string               `yaml:"name"` // shown in the dashboard, defaults to the account ID
+	JoinPolicy    *JoinPolicyConfig    `yaml:"join_policy"`
+	Notifications *NotificationsConfig `yaml:"notifications"`
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -73,26 +90,86 @@ func (c *Config) ApplyDefaults() {
 	}
 }
 
-// Validate checks if the configuration is valid
-func (c *Config) Validate() error {
-	var errors []string
+// AccountConfigs returns the accounts to monitor: the accounts list when
+// set, otherwise a single account from account_id and api_key
+func (c *Config) AccountConfigs() []AccountConfig {
+	if len(c.Accounts) == 0 {
+		return []AccountConfig{{
+			AccountID:     c.AccountID,
+			APIKey:        c.APIKey,
+			JoinPolicy:    c.JoinPolicy,
+			Notifications: c.Notifications,
+		}}
+	}
+
+	accounts := make([]AccountConfig, len(c.Accounts))
+	for i, account := range c.Accounts {
+		if account.JoinPolicy == nil {
+			account.JoinPolicy = c.JoinPolicy
+		}
+		if account.Notifications == nil {
+			account.Notifications = c.Notifications
+		}
+		accounts[i] = account
+	}
+	return accounts
+}
+
+// validateCredentials checks an account ID and API key look like Octopus ones
+func validateCredentials(accountID, apiKey string) []string {
+	var problems []string
 
 	// Validate account ID
-	if c.AccountID == "" {
-		errors = append(errors, "account ID is required")
-	} else if !strings.HasPrefix(c.AccountID, "A-") {
-		errors = append(errors, fmt.Sprintf("account ID should start with 'A-', got: %s", c.AccountID))
-	} else if len(c.AccountID) < 3 {
-		errors = append(errors, fmt.Sprintf("account ID appears too short: %s", c.AccountID))
+	if accountID == "" {
+		problems = append(problems, "account ID is required")
+	} else if !strings.HasPrefix(accountID, "A-") {
+		problems = append(problems, fmt.Sprintf("account ID should start with 'A-', got: %s", accountID))
+	} else if len(accountID) < 3 {
+		problems = append(problems, fmt.Sprintf("account ID appears too short: %s", accountID))
 	}
 
 	// Validate API key
-	if c.APIKey == "" {
-		errors = append(errors, "API key is required")
-	} else if !strings.HasPrefix(c.APIKey, "sk_live_") {
-		errors = append(errors, "API key should start with 'sk_live_' (use your live API key, not test key)")
-	} else if len(c.APIKey) < 20 {
-		errors = append(errors, "API key appears too short (should be ~40+ characters)")
+	if apiKey == "" {
+		problems = append(problems, "API key is required")
+	} else if !strings.HasPrefix(apiKey, "sk_live_") {
+		problems = append(problems, "API key should start with 'sk_live_' (use your live API key, not test key)")
+	} else if len(apiKey) < 20 {
+		problems = append(problems, "API key appears too short (should be ~40+ characters)")
+	}
+
+	return problems
+}
+
+// Validate checks if the configuration is valid
+func (c *Config) Validate() error {
+	var errors []string
+
+	// Validate credentials, per account when several are configured
+	if len(c.Accounts) == 0 {
+		errors = append(errors, validateCredentials(c.AccountID, c.APIKey)...)
+	}
+	seen := make(map[string]bool)
+	for i, account := range c.Accounts {
+		prefix := fmt.Sprintf("accounts[%d]: ", i)
+		for _, problem := range validateCredentials(account.AccountID, account.APIKey) {
+			errors = append(errors, prefix+problem)
+		}
+		if seen[strings.ToUpper(account.AccountID)] {
+			errors = append(errors, fmt.Sprintf("%saccount %s is listed more than once", prefix, account.AccountID))
+		}
+		seen[strings.ToUpper(account.AccountID)] = true
+		if account.JoinPolicy != nil {
+			for _, problem := range account.JoinPolicy.validate() {
+				errors = append(errors, prefix+"join_policy: "+problem)
+			}
+		}
+		if account.Notifications != nil {
+			for j := range account.Notifications.Backends {
+				for _, problem := range account.Notifications.Backends[j].validate() {
+					errors = append(errors, prefix+"notifications: "+problem)
+				}
+			}
+		}
 	}
 
 	// Validate web port
@@ -119,6 +196,48 @@ func (c *Config) Validate() error {
 		errors = append(errors, fmt.Sprintf("warning: min points threshold very high (%d), you may miss most sessions", c.MinPoints))
 	}
 
+	// Validate gas calorific value (GB gas is normally 37.5-43 MJ/m³)
+	if c.GasCalorificValue != 0 && (c.GasCalorificValue < 30 || c.GasCalorificValue > 50) {
+		errors = append(errors, fmt.Sprintf("gas calorific value should be in MJ/m³ (around 39), got: %.2f", c.GasCalorificValue))
+	}
+
+	// Validate join policy rules
+	if c.JoinPolicy != nil {
+		for _, problem := range c.JoinPolicy.validate() {
+			errors = append(errors, "join_policy: "+problem)
+		}
+	}
+
+	// Validate notification backends
+	if c.Notifications != nil {
+		for i := range c.Notifications.Backends {
+			for _, problem := range c.Notifications.Backends[i].validate() {
+				errors = append(errors, "notifications: "+problem)
+			}
+		}
+	}
+
+	// Validate MQTT settings
+	if c.MQTT != nil {
+		for _, problem := range c.MQTT.validate() {
+			errors = append(errors, "mqtt: "+problem)
+		}
+	}
+
+	// Validate Agile price thresholds
+	if c.Agile != nil {
+		for _, problem := range c.Agile.validate() {
+			errors = append(errors, "agile: "+problem)
+		}
+	}
+
+	// Validate the OctoPoints redemption policy
+	if c.Redemption != nil {
+		for _, problem := range c.Redemption.validate() {
+			errors = append(errors, "redemption: "+problem)
+		}
+	}
+
 	// Logical validations
 	if c.WebUI && !c.Daemon {
 		errors = append(errors, "web UI requires daemon mode (use both -daemon and -web flags)")
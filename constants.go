@@ -30,6 +30,12 @@ const (
 	// CacheDurationAccountInfo - Account balance updates hourly
 	CacheDurationAccountInfo = 1 * time.Hour
 
+	// CacheDurationDispatches - Intelligent Octopus can grant extra charging slots at short notice
+	CacheDurationDispatches = 5 * time.Minute
+
+	// CacheDurationTariffs - Agreements rarely change, but Agile publishes tomorrow's rates around 4 PM
+	CacheDurationTariffs = 1 * time.Hour
+
 	// CacheDurationCampaignStatus - Campaign enrollment status changes rarely
 	CacheDurationCampaignStatus = 24 * time.Hour
 
@@ -70,10 +76,37 @@ const (
 	IntervalAfterNewSession = 30 * time.Minute
 )
 
+// Saving session join settings
+const (
+	// JoinRetryDelay - Wait before retrying a failed join, doubled after each further failure
+	JoinRetryDelay = 5 * time.Minute
+
+	// JoinMaxAttempts - Joins attempted for one session before giving up
+	JoinMaxAttempts = 5
+)
+
+// Task scheduler settings - each monitor task runs on its own interval
+const (
+	// TaskRetryInterval - First retry after a task fails, doubled per consecutive failure up to the task's interval
+	TaskRetryInterval = 1 * time.Minute
+
+	// TaskDefaultTimeout - Longest a task may run before it is reported as failed
+	TaskDefaultTimeout = 2 * time.Minute
+
+	// TaskUsageSyncTimeout - Usage sync can page through a month of readings per meter
+	TaskUsageSyncTimeout = 5 * time.Minute
+
+	// TaskIntervalAgilePrices - How often Agile price windows are checked for reminders
+	TaskIntervalAgilePrices = 10 * time.Minute
+)
+
 // JWT token settings
 const (
 	// JWTRefreshBuffer - Refresh JWT tokens this many minutes before expiry
 	JWTRefreshBuffer = 5 * time.Minute
+
+	// JWTDefaultLifetime - Assumed lifetime of a JWT whose exp claim cannot be read
+	JWTDefaultLifetime = 1 * time.Hour
 )
 
 // HTTP client settings
@@ -86,6 +119,90 @@ const (
 
 	// HTTPMaxRetries - Maximum number of retries for failed requests
 	HTTPMaxRetries = 3
+
+	// HTTPMaxRetryAfter - Longest Retry-After delay honoured before retrying
+	HTTPMaxRetryAfter = 2 * time.Minute
+
+	// CircuitBreakerThreshold - Consecutive failures before requests to a host are stopped
+	CircuitBreakerThreshold = 5
+
+	// CircuitBreakerCooldown - How long an open circuit rejects requests before a trial request
+	CircuitBreakerCooldown = 1 * time.Minute
+)
+
+// Smart meter usage settings
+const (
+	// UsageMeasurementsPageSize - Measurements requested per page (30 days of half-hours spans two pages)
+	UsageMeasurementsPageSize = 1000
+
+	// UsageMeasurementsMaxPages - Safety limit on pages fetched per device
+	UsageMeasurementsMaxPages = 20
+
+	// FuelElectricity - Fuel type for electricity meters (ESME) and their measurements
+	FuelElectricity = "ELECTRICITY"
+
+	// FuelGas - Fuel type for gas meters (GSME) and their measurements
+	FuelGas = "GAS"
+
+	// FuelExport - Electricity exported to the grid, read from ESME GENERATION registers
+	FuelExport = "EXPORT"
+
+	// GasDefaultCalorificValue - Typical GB calorific value in MJ/m³, used unless configured
+	GasDefaultCalorificValue = 39.5
+
+	// GasVolumeCorrectionFactor - Standard correction for gas temperature and pressure
+	GasVolumeCorrectionFactor = 1.02264
+
+	// MegajoulesPerKWh - Energy conversion from MJ to kWh
+	MegajoulesPerKWh = 3.6
+
+	// TariffRatesPageSize - Rates requested per products API call (two days of Agile is 96)
+	TariffRatesPageSize = 1500
+)
+
+// Agile price window settings
+const (
+	// AgileDefaultCheapBelow - Half-hourly price (p/kWh inc VAT) below which Agile prices count as cheap
+	AgileDefaultCheapBelow = 10.0
+
+	// AgileDefaultPeakAbove - Half-hourly price (p/kWh inc VAT) above which Agile prices count as peak
+	AgileDefaultPeakAbove = 35.0
+)
+
+// Intelligent Octopus settings
+const (
+	// IntelligentOffPeakStart - Start of the standard overnight off-peak window (23:30 UK)
+	IntelligentOffPeakStart = 23*time.Hour + 30*time.Minute
+
+	// IntelligentOffPeakEnd - End of the standard overnight off-peak window (05:30 UK)
+	IntelligentOffPeakEnd = 5*time.Hour + 30*time.Minute
+)
+
+// Saving session performance settings
+const (
+	// BaselineWeekdays - Comparable weekdays averaged for a weekday session's baseline
+	BaselineWeekdays = 10
+
+	// BaselineWeekendDays - Comparable weekend days averaged for a weekend session's baseline
+	BaselineWeekendDays = 4
+
+	// BaselineLookbackDays - How far before a session comparable days are looked for
+	BaselineLookbackDays = 21
+
+	// PerformanceReportMaxAge - Stop waiting for a session's smart meter readings after this long
+	PerformanceReportMaxAge = 7 * 24 * time.Hour
+)
+
+// Home Mini telemetry settings
+const (
+	// TelemetryGrouping - Granularity requested from smartMeterTelemetry
+	TelemetryGrouping = "TEN_SECONDS"
+
+	// TelemetryPollInterval - How often live demand is read while a joined saving session is running
+	TelemetryPollInterval = 10 * time.Second
+
+	// TelemetryWindow - How far back each poll asks for readings (the Home Mini can lag a reading or two)
+	TelemetryWindow = 2 * time.Minute
 )
 
 // Wheel of Fortune settings
@@ -104,6 +221,9 @@ const (
 
 	// WebDefaultUsageDays - Default number of days shown in usage graph
 	WebDefaultUsageDays = 7
+
+	// WebRecentPerformanceSessions - Joined sessions whose results are shown on the dashboard
+	WebRecentPerformanceSessions = 3
 )
 
 // UK business hours for smart interval calculation
@@ -128,6 +248,18 @@ const (
 
 	// OctopusErrorCodeInvalidAuth - Invalid authorization header
 	OctopusErrorCodeInvalidAuth = "KT-CT-1143"
+
+	// OctopusErrorCodeInvalidRefreshToken - Refresh token is invalid or expired
+	OctopusErrorCodeInvalidRefreshToken = "KT-CT-1135"
+
+	// OctopusErrorCodeInvalidAPIKey - API key was not recognised
+	OctopusErrorCodeInvalidAPIKey = "KT-CT-1138"
+
+	// OctopusErrorCodeTooManyRequests - Rate limited by Kraken
+	OctopusErrorCodeTooManyRequests = "KT-CT-1199"
+
+	// OctopusErrorCodeUnauthorized - Token is not allowed to access the account
+	OctopusErrorCodeUnauthorized = "KT-CT-4123"
 )
 
 // State management settings
@@ -137,6 +269,18 @@ const (
 
 	// StateCleanupAge - Clean up alert states older than this duration
 	StateCleanupAge = 7 * 24 * time.Hour
+
+	// SessionHistoryRetention - Keep saving session history for roughly three seasons
+	SessionHistoryRetention = 3 * 365 * 24 * time.Hour
+)
+
+// OctoPoints redemption settings
+const (
+	// OctoPointsPerPound - Octoplus redeems 8 points per penny of account credit
+	OctoPointsPerPound = 800
+
+	// RedemptionClockSkew - Allowance between our clock and a redemption's ledger timestamp
+	RedemptionClockSkew = 5 * time.Minute
 )
 
 // Free electricity alert intervals - multi-stage alerting to prevent spam
@@ -165,3 +309,57 @@ const (
 	// MonitorDefaultCheckInterval - Default check interval when smart intervals disabled
 	MonitorDefaultCheckInterval = 15 * time.Minute
 )
+
+// Notification settings
+const (
+	// NotificationTimeout - Maximum time for a single notification delivery attempt
+	NotificationTimeout = 10 * time.Second
+
+	// NotificationDispatchTimeout - Maximum time to deliver one event including retries
+	NotificationDispatchTimeout = 2 * time.Minute
+
+	// NotificationDefaultRetryDelay - Initial delay between delivery retries (doubles each attempt)
+	NotificationDefaultRetryDelay = 2 * time.Second
+
+	// NotificationDefaultNtfyServer - ntfy server used when none is configured
+	NotificationDefaultNtfyServer = "https://ntfy.sh"
+
+	// WebhookSignatureHeader - Header carrying the HMAC-SHA256 signature of webhook payloads
+	WebhookSignatureHeader = "X-Octojoin-Signature-256"
+)
+
+// MQTT settings
+const (
+	// MQTTKeepAlive - Keep-alive interval negotiated with the broker
+	MQTTKeepAlive = 60 * time.Second
+
+	// MQTTConnectTimeout - Maximum time to establish a broker connection
+	MQTTConnectTimeout = 10 * time.Second
+
+	// MQTTReconnectMinDelay - Initial delay before reconnecting to the broker
+	MQTTReconnectMinDelay = 5 * time.Second
+
+	// MQTTReconnectMaxDelay - Maximum delay between reconnection attempts
+	MQTTReconnectMaxDelay = 5 * time.Minute
+
+	// MonitorCommandQueueSize - Number of pending commands (e.g. from MQTT) the monitor will buffer
+	MonitorCommandQueueSize = 8
+)
+
+// Demo mode settings
+const (
+	// DemoAccountID - Account served by the fake Kraken server in demo mode
+	DemoAccountID = "A-DEMO0001"
+
+	// DemoAPIKey - Placeholder API key accepted by the fake Kraken server
+	DemoAPIKey = "sk_live_demo0000000000000000"
+
+	// DemoCheckInterval - Fixed check interval in demo mode so scripted announcements show up quickly
+	DemoCheckInterval = 1 * time.Minute
+
+	// FakeKrakenTokenLifetime - Lifetime of JWTs issued by the fake Kraken server
+	FakeKrakenTokenLifetime = 1 * time.Hour
+
+	// FakeKrakenRefreshTokenLifetime - Lifetime of refresh tokens issued by the fake Kraken server
+	FakeKrakenRefreshTokenLifetime = 7 * 24 * time.Hour
+)
@@ -0,0 +1,287 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dispatch statuses
+const (
+	DispatchPlanned   = "planned"
+	DispatchCompleted = "completed"
+)
+
+// Dispatch is an Intelligent Octopus smart-charge slot. Slots outside the
+// normal off-peak window are marked Extra: the whole home is billed at the
+// off-peak rate during them.
+type Dispatch struct {
+	Code     string    `json:"code"`
+	Status   string    `json:"status"`
+	StartAt  time.Time `json:"start"`
+	EndAt    time.Time `json:"end"`
+	DeltaKWh float64   `json:"delta_kwh"` // energy scheduled (or drawn) by the charger
+	Source   string    `json:"source"`    // smart-charge, bump-charge, ...
+	Extra    bool      `json:"extra"`
+}
+
+// krakenDispatch is a dispatch as returned by plannedDispatches and
+// completedDispatches
+type krakenDispatch struct {
+	StartDt string `json:"startDt"`
+	EndDt   string `json:"endDt"`
+	Delta   string `json:"delta"`
+	Meta    struct {
+		Source   string `json:"source"`
+		Location string `json:"location"`
+	} `json:"meta"`
+}
+
+// DispatchesResponse is the GraphQL response for an account's dispatches
+type DispatchesResponse struct {
+	Data struct {
+		PlannedDispatches   []krakenDispatch `json:"plannedDispatches"`
+		CompletedDispatches []krakenDispatch `json:"completedDispatches"`
+	} `json:"data"`
+}
+
+// isIntelligentTariff reports whether a tariff code is on an Intelligent Octopus product
+func isIntelligentTariff(tariffCode string) bool {
+	return strings.Contains(strings.ToUpper(tariffCode), "-INTELLI-")
+}
+
+// parseDispatchTime reads Kraken dispatch times, which use a space rather
+// than a T between date and time
+func parseDispatchTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05Z07:00", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised dispatch time %q", value)
+}
+
+// inIntelligentOffPeak reports whether t falls in the standard overnight window
+func inIntelligentOffPeak(t time.Time, location *time.Location) bool {
+	local := t.In(location)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	return offset >= IntelligentOffPeakStart || offset < IntelligentOffPeakEnd
+}
+
+// isExtraDispatch reports whether any part of a slot falls outside the
+// standard overnight off-peak window
+func isExtraDispatch(startAt, endAt time.Time) bool {
+	ukLocation, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		ukLocation = time.UTC
+	}
+	if !inIntelligentOffPeak(startAt, ukLocation) {
+		return true
+	}
+
+	// The window the slot started in closes at the next 05:30
+	local := startAt.In(ukLocation)
+	windowEnd := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, ukLocation).Add(IntelligentOffPeakEnd)
+	if !windowEnd.After(startAt) {
+		windowEnd = time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, ukLocation).Add(IntelligentOffPeakEnd)
+	}
+	return endAt.After(windowEnd)
+}
+
+// toDispatch converts a Kraken dispatch, marking slots outside off-peak as extra
+func (d krakenDispatch) toDispatch(status string) (Dispatch, error) {
+	startAt, err := parseDispatchTime(d.StartDt)
+	if err != nil {
+		return Dispatch{}, err
+	}
+	endAt, err := parseDispatchTime(d.EndDt)
+	if err != nil {
+		return Dispatch{}, err
+	}
+	// Kraken reports charging as a negative delta
+	delta, _ := strconv.ParseFloat(d.Delta, 64)
+	if delta < 0 {
+		delta = -delta
+	}
+	return Dispatch{
+		Code:     fmt.Sprintf("dispatch-%d-%d", startAt.Unix(), endAt.Unix()),
+		Status:   status,
+		StartAt:  startAt,
+		EndAt:    endAt,
+		DeltaKWh: delta,
+		Source:   d.Meta.Source,
+		Extra:    isExtraDispatch(startAt, endAt),
+	}, nil
+}
+
+// getDispatches retrieves the planned and recently completed smart-charge
+// dispatches for the account, in time order
+func (c *OctopusClient) getDispatches() ([]Dispatch, error) {
+	query := `query getDispatches($accountNumber: String!) {
+		plannedDispatches(accountNumber: $accountNumber) {
+			startDt
+			endDt
+			delta
+			meta {
+				source
+				location
+			}
+		}
+		completedDispatches(accountNumber: $accountNumber) {
+			startDt
+			endDt
+			delta
+			meta {
+				source
+				location
+			}
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"accountNumber": c.AccountID,
+	}
+
+	resp, err := c.makeGraphQLRequest(query, variables, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute dispatches request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result DispatchesResponse
+	if err := c.decodeGraphQL(resp, "getDispatches", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get dispatches: %w", err)
+	}
+
+	dispatches := []Dispatch{}
+	add := func(status string, list []krakenDispatch) error {
+		for _, d := range list {
+			dispatch, err := d.toDispatch(status)
+			if err != nil {
+				return err
+			}
+			dispatches = append(dispatches, dispatch)
+		}
+		return nil
+	}
+	if err := add(DispatchCompleted, result.Data.CompletedDispatches); err != nil {
+		return nil, err
+	}
+	if err := add(DispatchPlanned, result.Data.PlannedDispatches); err != nil {
+		return nil, err
+	}
+	sort.Slice(dispatches, func(i, j int) bool { return dispatches[i].StartAt.Before(dispatches[j].StartAt) })
+
+	c.debugLog("Retrieved %d planned and %d completed dispatches", len(result.Data.PlannedDispatches), len(result.Data.CompletedDispatches))
+	return dispatches, nil
+}
+
+// getDispatchesWithCache returns dispatches, refreshed often because extra
+// slots are granted at short notice
+func (c *OctopusClient) getDispatchesWithCache(state *AppState) ([]Dispatch, error) {
+	if cached := loadCached(state, func(s *AppState) *CachedDispatches { return s.CachedDispatches }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationDispatches) {
+			return cached.Data, nil
+		}
+	}
+
+	dispatches, err := c.getDispatches()
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil {
+		state.Update(func(s *AppState) {
+			s.CachedDispatches = &CachedDispatches{
+				Data:      dispatches,
+				Timestamp: time.Now(),
+			}
+		})
+	}
+
+	return dispatches, nil
+}
+
+// intelligentDispatches returns the account's dispatches, or none when no
+// electricity import meter is on an Intelligent Octopus tariff
+func (m *SavingSessionMonitor) intelligentDispatches() ([]Dispatch, error) {
+	tariffs, err := m.client.getTariffsWithCache(m.state)
+	if err != nil {
+		return nil, err
+	}
+	for _, tariff := range tariffs {
+		if tariff.Fuel == FuelElectricity && isIntelligentTariff(tariff.TariffCode) {
+			return m.client.getDispatchesWithCache(m.state)
+		}
+	}
+	return nil, nil
+}
+
+// checkDispatches alerts once for each extra off-peak slot that has not ended
+func (m *SavingSessionMonitor) checkDispatches() error {
+	dispatches, err := m.intelligentDispatches()
+	if err != nil {
+		return fmt.Errorf("failed to fetch Intelligent Octopus dispatches: %w", err)
+	}
+
+	now := time.Now()
+	for _, dispatch := range dispatches {
+		if dispatch.Status != DispatchPlanned || !dispatch.Extra || !dispatch.EndAt.After(now) {
+			continue
+		}
+		// Check and record under one lock, so overlapping checks alert once
+		var known bool
+		m.state.Update(func(s *AppState) {
+			if s.KnownDispatches == nil {
+				s.KnownDispatches = make(map[string]time.Time)
+			}
+			if _, known = s.KnownDispatches[dispatch.Code]; !known {
+				s.KnownDispatches[dispatch.Code] = dispatch.EndAt
+			}
+		})
+		if known {
+			continue
+		}
+
+		duration := dispatch.EndAt.Sub(dispatch.StartAt)
+		message := fmt.Sprintf("%s %s-%s (%s), everything in the home is charged at the off-peak rate",
+			dispatch.StartAt.Format("Monday, Jan 2"), dispatch.StartAt.Format("15:04"), dispatch.EndAt.Format("15:04"),
+			m.formatDuration(duration))
+		if m.daemonMode {
+			m.logger.Info("EXTRA OFF-PEAK DISPATCH",
+				"start", dispatch.StartAt.Format("15:04"),
+				"end", dispatch.EndAt.Format("15:04"),
+				"duration", m.formatDuration(duration),
+				"delta_kwh", dispatch.DeltaKWh,
+				"source", dispatch.Source,
+			)
+		} else {
+			m.logger.UserMessage("🚗 EXTRA OFF-PEAK DISPATCH")
+			m.logger.UserMessage("   %s", message)
+		}
+		m.notify(EventIntelligentDispatch, "Extra off-peak dispatch granted", message, map[string]interface{}{
+			"code":      dispatch.Code,
+			"start_at":  dispatch.StartAt,
+			"end_at":    dispatch.EndAt,
+			"delta_kwh": dispatch.DeltaKWh,
+			"source":    dispatch.Source,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsExtraDispatch(t *testing.T) {
+	uk, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("Europe/London timezone not available")
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, uk)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		extra      bool
+	}{
+		{"inside overnight window", at(15, 23, 30), at(16, 2, 0), false},
+		{"early morning inside window", at(16, 1, 0), at(16, 5, 30), false},
+		{"afternoon slot", at(15, 14, 0), at(15, 15, 0), true},
+		{"runs past 05:30", at(16, 4, 30), at(16, 6, 0), true},
+		{"starts before 23:30", at(15, 23, 0), at(15, 23, 59), true},
+	}
+
+	for _, test := range tests {
+		if got := isExtraDispatch(test.start, test.end); got != test.extra {
+			t.Errorf("%s: expected extra=%v, got %v", test.name, test.extra, got)
+		}
+	}
+}
+
+func TestKrakenDispatchConversion(t *testing.T) {
+	d := krakenDispatch{StartDt: "2025-01-15 14:00:00+00:00", EndDt: "2025-01-15T15:00:00Z", Delta: "-3.50"}
+	d.Meta.Source = "smart-charge"
+
+	dispatch, err := d.toDispatch(DispatchPlanned)
+	if err != nil {
+		t.Fatalf("Expected dispatch to convert, got %v", err)
+	}
+	if dispatch.DeltaKWh != 3.5 || !dispatch.Extra || dispatch.EndAt.Sub(dispatch.StartAt) != time.Hour {
+		t.Errorf("Expected a 1 hour extra dispatch of 3.5 kWh, got %+v", dispatch)
+	}
+	if dispatch.Code != "dispatch-1736949600-1736953200" {
+		t.Errorf("Expected code from start and end, got %q", dispatch.Code)
+	}
+
+	if _, err := (krakenDispatch{StartDt: "yesterday", EndDt: "today"}).toDispatch(DispatchPlanned); err == nil {
+		t.Error("Expected an error for unparseable times")
+	}
+}
+
+func TestGetDispatches(t *testing.T) {
+	uk, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("Europe/London timezone not available")
+	}
+	now := time.Now().In(uk)
+	nextAt := func(hour int) time.Duration {
+		at := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, uk)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return time.Until(at)
+	}
+
+	fixtures := DefaultKrakenFixtures()
+	fixtures.TariffCode = "E-1R-INTELLI-VAR-24-10-29-C"
+	fixtures.Dispatches = []FixtureDispatch{
+		{StartsIn: -26 * time.Hour, Duration: time.Hour, KWh: 7},
+		{StartsIn: nextAt(14), Duration: time.Hour, KWh: 3.5},
+		{StartsIn: nextAt(1), Duration: 2 * time.Hour, KWh: 14, Source: "bump-charge"},
+	}
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+
+	dispatches, err := monitor.intelligentDispatches()
+	if err != nil || len(dispatches) != 3 {
+		t.Fatalf("Expected 3 dispatches, got %+v (%v)", dispatches, err)
+	}
+	if dispatches[0].Status != DispatchCompleted || dispatches[0].DeltaKWh != 7 {
+		t.Errorf("Expected the first dispatch to be completed, got %+v", dispatches[0])
+	}
+	// Of the planned slots only the afternoon one falls outside off-peak hours
+	for _, dispatch := range dispatches[1:] {
+		afternoon := dispatch.DeltaKWh == 3.5
+		if dispatch.Status != DispatchPlanned || dispatch.Extra != afternoon {
+			t.Errorf("Expected only the afternoon slot to be extra, got %+v", dispatch)
+		}
+		if !afternoon && dispatch.Source != "bump-charge" {
+			t.Errorf("Expected the dispatch source to be kept, got %+v", dispatch)
+		}
+	}
+	if monitor.state.Snapshot().CachedDispatches == nil {
+		t.Error("Expected dispatches to be cached")
+	}
+}
+
+// cacheDispatches stores an electricity tariff and dispatches as if they had
+// just been fetched
+func cacheDispatches(state *AppState, tariffCode string, dispatches ...Dispatch) {
+	now := time.Now()
+	state.Update(func(s *AppState) {
+		s.CachedTariffs = &CachedTariffs{Timestamp: now, Data: []Tariff{{
+			Agreement: Agreement{Fuel: FuelElectricity, TariffCode: tariffCode, ValidFrom: now.AddDate(0, -1, 0)},
+		}}}
+		s.CachedDispatches = &CachedDispatches{Timestamp: now, Data: dispatches}
+	})
+}
+
+// testDispatches are a completed slot from yesterday, an extra afternoon slot
+// and an overnight bump charge
+func testDispatches() []Dispatch {
+	now := time.Now()
+	return []Dispatch{
+		{Code: "completed", Status: DispatchCompleted, StartAt: now.Add(-26 * time.Hour), EndAt: now.Add(-25 * time.Hour), DeltaKWh: 7},
+		{Code: "afternoon", Status: DispatchPlanned, StartAt: now.Add(2 * time.Hour), EndAt: now.Add(3 * time.Hour), DeltaKWh: 3.5, Extra: true},
+		{Code: "overnight", Status: DispatchPlanned, StartAt: now.Add(10 * time.Hour), EndAt: now.Add(12 * time.Hour), DeltaKWh: 14, Source: "bump-charge"},
+	}
+}
+
+func TestIntelligentDispatchesOnlyOnIntelligentTariffs(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+
+	cacheDispatches(monitor.state, "E-1R-AGILE-24-10-01-C", testDispatches()...)
+	if dispatches, err := monitor.intelligentDispatches(); err != nil || dispatches != nil {
+		t.Errorf("Expected no dispatches on Agile, got %+v (%v)", dispatches, err)
+	}
+
+	cacheDispatches(monitor.state, "E-1R-INTELLI-VAR-24-10-29-C", testDispatches()...)
+	if dispatches, err := monitor.intelligentDispatches(); err != nil || len(dispatches) != 3 {
+		t.Errorf("Expected 3 dispatches on Intelligent Octopus, got %+v (%v)", dispatches, err)
+	}
+}
+
+func TestCheckDispatchesAlertsOnce(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheDispatches(monitor.state, "E-1R-INTELLI-VAR-24-10-29-C", testDispatches()...)
+	events := make(channelNotifier, 10)
+	notifier, _ := NewNotificationManager(nil, NewLogger(false))
+	notifier.AddNotifier(events, nil, 0, 0)
+	monitor.SetNotifier(notifier)
+
+	// Overlapping checks must not both alert
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			monitor.checkDispatches()
+		}()
+	}
+	wg.Wait()
+	monitor.checkDispatches()
+
+	known := monitor.state.Snapshot().KnownDispatches
+	if _, ok := known["afternoon"]; len(known) != 1 || !ok {
+		t.Fatalf("Expected only the extra afternoon slot to alert, got %v", known)
+	}
+	select {
+	case event := <-events:
+		if event.Type != EventIntelligentDispatch || event.Data["code"] != "afternoon" {
+			t.Errorf("Expected an alert for the afternoon slot, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a dispatch notification")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected a single notification, got another %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDispatchesInSessionData(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheDispatches(monitor.state, "E-1R-INTELLI-VAR-24-10-29-C", testDispatches()...)
+
+	// Yesterday's completed slot has dropped off the dashboard
+	data := monitor.buildSessionData()
+	if len(data.Dispatches) != 2 || data.Dispatches[0].Code != "afternoon" {
+		t.Errorf("Expected the two planned dispatches on the dashboard, got %+v", data.Dispatches)
+	}
+}
+
+func TestDispatchMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheDispatches(monitor.state, "E-1R-INTELLI-VAR-24-10-29-C", testDispatches()...)
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_dispatches{account="A-DEMO0001",status="planned"} 2`,
+		`octojoin_dispatches{account="A-DEMO0001",status="completed"} 1`,
+		`octojoin_dispatches_extra{account="A-DEMO0001",status="planned"} 1`,
+		`octojoin_dispatch_duration_seconds{account="A-DEMO0001",status="planned"} 10800`,
+		`cache_type="dispatches"`,
+	)
+}
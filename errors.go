@@ -15,20 +15,26 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // APIError represents an error from the Octopus Energy API
 type APIError struct {
 	StatusCode int
 	Endpoint   string
+	Code       string // Kraken error code from a GraphQL response, if any
 	Message    string
 	Retryable  bool
 	Err        error // Underlying error if any
 }
 
 func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("API error (%d) at %s: [%s] %s", e.StatusCode, e.Endpoint, e.Code, e.Message)
+	}
 	if e.Err != nil {
 		return fmt.Sprintf("API error (%d) at %s: %s (caused by: %v)", e.StatusCode, e.Endpoint, e.Message, e.Err)
 	}
@@ -64,6 +70,16 @@ func isRetryableStatus(statusCode int) bool {
 	}
 }
 
+// CircuitOpenError is returned without calling a host whose circuit breaker is open
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s after repeated failures", e.Host, e.Until.Format(time.RFC3339))
+}
+
 // AuthError represents authentication/authorization errors
 type AuthError struct {
 	Code    string // Error code from API (e.g., "KT-CT-1139")
@@ -111,10 +127,11 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("validation error for %s: %s", e.Field, e.Message)
 }
 
-// SessionError represents errors specific to saving session operations
+// SessionError represents errors specific to saving session and OctoPlus
+// operations, such as a join, wheel spin or redemption being refused
 type SessionError struct {
 	SessionID string
-	Operation string // e.g., "join", "leave", "fetch"
+	Operation string // e.g., "join", "leave", "fetch", "spin", "redeem"
 	Err       error
 }
 
@@ -128,3 +145,24 @@ func (e *SessionError) Error() string {
 func (e *SessionError) Unwrap() error {
 	return e.Err
 }
+
+// errorClass names the class of an error for metrics. A SessionError is
+// checked first since it wraps the APIError that caused it.
+func errorClass(err error) string {
+	var sessionErr *SessionError
+	var authErr *AuthError
+	var apiErr *APIError
+	var circuitErr *CircuitOpenError
+	switch {
+	case errors.As(err, &sessionErr):
+		return "session"
+	case errors.As(err, &authErr):
+		return "auth"
+	case errors.As(err, &circuitErr):
+		return "circuit_open"
+	case errors.As(err, &apiErr):
+		return "api"
+	default:
+		return "other"
+	}
+}
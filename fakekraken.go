@@ -0,0 +1,1055 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KrakenFixtures scripts the account served by the fake Kraken server. Times
+// are offsets from when the server starts, so a fixture file stays valid.
+type KrakenFixtures struct {
+	AccountID        string            `yaml:"account_id"`
+	Points           int               `yaml:"points"`
+	BalancePence     float64           `yaml:"balance_pence"`
+	AccountType      string            `yaml:"account_type"`
+	Campaigns        []string          `yaml:"campaigns"`
+	ElectricitySpins int               `yaml:"electricity_spins"`
+	GasSpins         int               `yaml:"gas_spins"`
+	SpinPrizes       []int             `yaml:"spin_prizes"` // awarded in turn, wrapping around
+	DeviceIDs        []string          `yaml:"device_ids"`  // electricity meters
+	GasDeviceIDs     []string          `yaml:"gas_device_ids"`
+	Properties       []FixtureProperty `yaml:"properties"` // replaces device_ids and gas_device_ids to spread meters over properties
+	UnitRatePence    float64           `yaml:"unit_rate_pence"`
+	GasUnitRatePence float64           `yaml:"gas_unit_rate_pence"`
+	SolarPeakKWh     float64           `yaml:"solar_peak_kwh"` // generation per half hour at midday, 0 for no solar
+	ExportRatePence  float64           `yaml:"export_rate_pence"`
+	HomeMini         bool              `yaml:"home_mini"`       // live telemetry for the first electricity meter
+	TariffCode       string            `yaml:"tariff_code"`     // Agile tariffs get half-hourly rates around unit_rate_pence
+	GasTariffCode    string            `yaml:"gas_tariff_code"` // empty for no gas supply
+	StandingPence    float64           `yaml:"standing_charge_pence"`
+	GasStandingPence float64           `yaml:"gas_standing_charge_pence"`
+
+	SavingSessions  []FixtureSavingSession `yaml:"saving_sessions"`
+	JoinFailures    int                    `yaml:"join_failures"` // joins rejected before one succeeds
+	FreeElectricity []FixtureSession       `yaml:"free_electricity"`
+	Dispatches      []FixtureDispatch      `yaml:"dispatches"`    // smart-charge slots, used with an INTELLI tariff_code
+	PointsLedger    []FixtureLedgerEntry   `yaml:"points_ledger"` // oldest first, ending at points
+}
+
+// FixtureProperty is a scripted property and the smart meters installed there
+type FixtureProperty struct {
+	ID           string   `yaml:"id"`
+	Address      string   `yaml:"address"`
+	DeviceIDs    []string `yaml:"device_ids"`
+	GasDeviceIDs []string `yaml:"gas_device_ids"`
+}
+
+// FixtureSavingSession is a scripted saving session event
+type FixtureSavingSession struct {
+	ID            int           `yaml:"id"`
+	StartsIn      time.Duration `yaml:"starts_in"`
+	Duration      time.Duration `yaml:"duration"`
+	Reward        int           `yaml:"reward"` // OctoPoints per kWh
+	Joined        bool          `yaml:"joined"`
+	Awarded       int           `yaml:"awarded"`        // points settled once a joined session has ended
+	AnnounceAfter time.Duration `yaml:"announce_after"` // hidden until this long after start
+}
+
+// FixtureSession is a scripted free electricity session
+type FixtureSession struct {
+	StartsIn time.Duration `yaml:"starts_in"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// FixtureDispatch is a scripted Intelligent Octopus smart-charge slot. It is
+// reported as completed once it has ended.
+type FixtureDispatch struct {
+	StartsIn time.Duration `yaml:"starts_in"`
+	Duration time.Duration `yaml:"duration"`
+	KWh      float64       `yaml:"kwh"`
+	Source   string        `yaml:"source"` // defaults to smart-charge
+}
+
+// FixtureLedgerEntry is a scripted OctoPoints ledger entry. Wheel spins add
+// their own entries as they happen.
+type FixtureLedgerEntry struct {
+	PostedIn   time.Duration `yaml:"posted_in"` // negative, entries are in the past
+	ReasonCode string        `yaml:"reason_code"`
+	Points     int           `yaml:"points"` // negative for redemptions
+}
+
+// DefaultKrakenFixtures returns the demo account: enrolled in Octoplus, one
+// joined session, one open session, one announced shortly after start, an
+// upcoming free electricity hour and a couple of wheel spins
+func DefaultKrakenFixtures() KrakenFixtures {
+	return KrakenFixtures{
+		AccountID:        DemoAccountID,
+		Points:           1850,
+		BalancePence:     4273,
+		AccountType:      "DOMESTIC",
+		Campaigns:        []string{"octoplus", "octoplus-saving-sessions", "free_electricity"},
+		ElectricitySpins: 2,
+		GasSpins:         1,
+		SpinPrizes:       []int{20, 5, 100, 10},
+		DeviceIDs:        []string{"00-11-22-33-44-55-66-77"},
+		GasDeviceIDs:     []string{"00-AA-BB-CC-DD-EE-FF-00"},
+		UnitRatePence:    24.5,
+		GasUnitRatePence: 6.3,
+		SolarPeakKWh:     1.2,
+		ExportRatePence:  15,
+		HomeMini:         true,
+		TariffCode:       "E-1R-AGILE-24-10-01-C",
+		GasTariffCode:    "G-1R-VAR-22-11-01-C",
+		StandingPence:    53.35,
+		GasStandingPence: 31.43,
+		SavingSessions: []FixtureSavingSession{
+			{ID: 9001, StartsIn: -3 * 24 * time.Hour, Duration: time.Hour, Reward: 1800, Joined: true, Awarded: 1620},
+			{ID: 9002, StartsIn: 26 * time.Hour, Duration: time.Hour, Reward: 2400},
+			{ID: 9003, StartsIn: 50 * time.Hour, Duration: 90 * time.Minute, Reward: 3200, AnnounceAfter: 3 * time.Minute},
+		},
+		FreeElectricity: []FixtureSession{
+			{StartsIn: 30 * time.Hour, Duration: time.Hour},
+		},
+		PointsLedger: []FixtureLedgerEntry{
+			{PostedIn: -60 * 24 * time.Hour, ReasonCode: "REFERRAL_REWARD", Points: 400},
+			{PostedIn: -45 * 24 * time.Hour, ReasonCode: "WHEEL_OF_FORTUNE_PRIZE", Points: 50},
+			{PostedIn: -30 * 24 * time.Hour, ReasonCode: "SAVING_SESSION_REWARD", Points: 1900},
+			{PostedIn: -10 * 24 * time.Hour, ReasonCode: "POINTS_REDEMPTION", Points: -500},
+		},
+	}
+}
+
+// LoadKrakenFixtures reads fixtures from a YAML file, filling anything left
+// out from the defaults
+func LoadKrakenFixtures(path string) (KrakenFixtures, error) {
+	fixtures := DefaultKrakenFixtures()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixtures, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	if !strings.HasPrefix(fixtures.AccountID, "A-") {
+		return fixtures, fmt.Errorf("fixture account ID should start with 'A-', got: %s", fixtures.AccountID)
+	}
+	return fixtures, nil
+}
+
+// FakeKraken is an in-process stand-in for the Octopus REST API, both GraphQL
+// endpoints and the free electricity feed. Joins, spins and points are kept
+// in memory, so the daemon sees the effects of its own actions.
+type FakeKraken struct {
+	fixtures KrakenFixtures
+	started  time.Time
+	logger   *Logger
+
+	mu        sync.Mutex
+	points    int
+	elecSpins int
+	gasSpins  int
+	spins     int
+	joined    map[int]bool
+	joinFails int               // joins still to be rejected
+	ledger    []fakeLedgerEntry // oldest first
+	redeemed  int               // points turned into account credit
+
+	// Issued tokens and when they expire, plus how each was obtained
+	tokens           map[string]time.Time
+	refreshTokens    map[string]time.Time
+	apiKeyExchanges  int
+	refreshExchanges int
+
+	server *http.Server
+	URL    string
+}
+
+func NewFakeKraken(fixtures KrakenFixtures, debug bool) *FakeKraken {
+	if len(fixtures.Properties) == 0 {
+		fixtures.Properties = []FixtureProperty{{
+			ID:           "1000001",
+			Address:      "1 Demo Street, London, SW1A 1AA",
+			DeviceIDs:    fixtures.DeviceIDs,
+			GasDeviceIDs: fixtures.GasDeviceIDs,
+		}}
+	} else {
+		// Meters are numbered across the account, in property order
+		fixtures.DeviceIDs, fixtures.GasDeviceIDs = nil, nil
+		for _, property := range fixtures.Properties {
+			fixtures.DeviceIDs = append(fixtures.DeviceIDs, property.DeviceIDs...)
+			fixtures.GasDeviceIDs = append(fixtures.GasDeviceIDs, property.GasDeviceIDs...)
+		}
+	}
+
+	f := &FakeKraken{
+		fixtures:      fixtures,
+		started:       time.Now(),
+		logger:        NewLogger(debug).WithComponent("fake_kraken"),
+		points:        fixtures.Points,
+		elecSpins:     fixtures.ElectricitySpins,
+		gasSpins:      fixtures.GasSpins,
+		joined:        make(map[int]bool),
+		joinFails:     fixtures.JoinFailures,
+		tokens:        make(map[string]time.Time),
+		refreshTokens: make(map[string]time.Time),
+	}
+	for _, session := range fixtures.SavingSessions {
+		if session.Joined {
+			f.joined[session.ID] = true
+		}
+	}
+
+	// Scripted entries end at the fixture's points, whatever balance they started from
+	balance := fixtures.Points
+	for _, entry := range fixtures.PointsLedger {
+		balance -= entry.Points
+	}
+	for _, entry := range fixtures.PointsLedger {
+		balance += entry.Points
+		f.addLedgerEntry(f.started.Add(entry.PostedIn), entry.ReasonCode, entry.Points, balance)
+	}
+	return f
+}
+
+// fakeLedgerEntry is an OctoPoints ledger entry served by loyaltyPointLedgers
+type fakeLedgerEntry struct {
+	ID         string
+	PostedAt   time.Time
+	ReasonCode string
+	Points     int
+	Balance    int
+}
+
+// addLedgerEntry records a points movement; callers hold f.mu or own f
+func (f *FakeKraken) addLedgerEntry(postedAt time.Time, reasonCode string, points, balance int) {
+	f.ledger = append(f.ledger, fakeLedgerEntry{
+		ID:         fmt.Sprintf("ledger-%d", len(f.ledger)+1),
+		PostedAt:   postedAt,
+		ReasonCode: reasonCode,
+		Points:     points,
+		Balance:    balance,
+	})
+}
+
+// Start serves the fake API on addr (e.g. "127.0.0.1:0") in the background
+func (f *FakeKraken) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start fake Kraken server: %w", err)
+	}
+	f.URL = "http://" + listener.Addr().String()
+	f.server = &http.Server{Handler: f.Handler()}
+	go f.server.Serve(listener)
+	return nil
+}
+
+func (f *FakeKraken) Close() error {
+	if f.server == nil {
+		return nil
+	}
+	return f.server.Close()
+}
+
+// krakenEndpoints returns octopusEndpoints for a fake server at baseURL
+func krakenEndpoints(baseURL string) map[string]string {
+	feed := baseURL + "/octoevents/free_electricity.json"
+	return map[string]string{
+		"api":                       baseURL + "/v1",
+		"graphql":                   baseURL + "/v1/graphql/",
+		"backend-graphql":           baseURL + "/backend/v1/graphql/",
+		"free-electricity":          feed,
+		"free-electricity-raw":      feed,
+		"free-electricity-fallback": feed,
+	}
+}
+
+// UseEndpoints points every upstream endpoint at the fake server. Clients
+// read BaseURL when they are created, so call this first.
+func (f *FakeKraken) UseEndpoints() {
+	for key, url := range krakenEndpoints(f.URL) {
+		octopusEndpoints[key] = url
+	}
+}
+
+func (f *FakeKraken) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/graphql/", f.handleGraphQL)
+	mux.HandleFunc("/backend/v1/graphql/", f.handleGraphQL)
+	mux.HandleFunc("/v1/accounts/", f.handleAccounts)
+	mux.HandleFunc("/v1/products/", f.handleProducts)
+	mux.HandleFunc("/octoevents/free_electricity.json", f.handleFreeElectricity)
+	return mux
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// graphQLError writes a Kraken-style error envelope
+func graphQLError(w http.ResponseWriter, code, message string) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": nil,
+		"errors": []map[string]interface{}{{
+			"message":    message,
+			"extensions": map[string]string{"errorCode": code},
+		}},
+	})
+}
+
+// handleAccounts serves GET /v1/accounts/<id>/ and
+// POST /v1/accounts/<id>/saving-sessions/<event>/join
+func (f *FakeKraken) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/accounts/"), "/"), "/")
+	if apiKey, _, ok := r.BasicAuth(); !ok || apiKey == "" {
+		writeFakeJSON(w, http.StatusUnauthorized, map[string]string{"detail": "Authentication credentials were not provided."})
+		return
+	}
+	if parts[0] != f.fixtures.AccountID {
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		awarded := make(map[int]int)
+		for _, event := range f.fixtures.SavingSessions {
+			awarded[event.ID] = event.Awarded
+		}
+
+		f.mu.Lock()
+		var joined []SavingSession
+		for _, event := range f.visibleEvents() {
+			if f.joined[event.ID] {
+				session := event.ToSavingSession()
+				if session.EndAt.Before(time.Now()) {
+					session.RewardGivenInOctoPoints = awarded[event.ID]
+				}
+				joined = append(joined, session)
+			}
+		}
+		f.mu.Unlock()
+
+		var resp SavingSessionsResponse
+		resp.Data.SavingSessions.Account.HasJoinedCampaign = f.hasCampaign("octoplus-saving-sessions")
+		resp.Data.SavingSessions.Account.JoinedEvents = joined
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"data":       resp.Data,
+			"number":     f.fixtures.AccountID,
+			"properties": f.properties(),
+		})
+
+	case len(parts) == 4 && parts[1] == "saving-sessions" && parts[3] == "join" && r.Method == http.MethodPost:
+		eventID, err := strconv.Atoi(parts[2])
+		if err != nil {
+			writeFakeJSON(w, http.StatusBadRequest, map[string]string{"detail": "Invalid event."})
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.joinFails > 0 {
+			f.joinFails--
+			writeFakeJSON(w, http.StatusBadRequest, map[string]string{"detail": "Unable to join the event."})
+			return
+		}
+		for _, event := range f.visibleEvents() {
+			if event.ID == eventID {
+				f.joined[eventID] = true
+				f.logger.Info("Joined saving session", "event_id", eventID)
+				writeFakeJSON(w, http.StatusCreated, map[string]int{"eventId": eventID})
+				return
+			}
+		}
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Event not found."})
+
+	default:
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+	}
+}
+
+// fakeExportTariffCode is the export tariff on the demo solar MPAN
+const fakeExportTariffCode = "E-1R-OUTGOING-FIX-12M-19-05-13-C"
+
+// properties describes the meter points and agreements in the REST account
+// payload. Each electricity meter gets its own MPAN, solar adds an export MPAN
+// on the property with the first electricity meter.
+func (f *FakeKraken) properties() []map[string]interface{} {
+	validFrom := f.started.AddDate(0, -6, 0).Truncate(24 * time.Hour)
+	agreements := func(tariffCode string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"tariff_code": "E-1R-VAR-22-11-01-C", "valid_from": validFrom.AddDate(-1, 0, 0), "valid_to": validFrom},
+			{"tariff_code": tariffCode, "valid_from": validFrom, "valid_to": nil},
+		}
+	}
+
+	properties := []map[string]interface{}{}
+	electricityMeters, gasMeters := 0, 0
+	for _, property := range f.fixtures.Properties {
+		electricity := []map[string]interface{}{}
+		for range property.DeviceIDs {
+			electricityMeters++
+			electricity = append(electricity, map[string]interface{}{
+				"mpan":       fmt.Sprintf("190000000%04d", electricityMeters),
+				"is_export":  false,
+				"meters":     []map[string]string{{"serial_number": fmt.Sprintf("22L%07d", electricityMeters)}},
+				"agreements": agreements(f.fixtures.TariffCode),
+			})
+			if f.fixtures.SolarPeakKWh > 0 && electricityMeters == 1 {
+				electricity = append(electricity, map[string]interface{}{
+					"mpan":       "1170000000001",
+					"is_export":  true,
+					"meters":     []map[string]string{{"serial_number": "22L0000001"}},
+					"agreements": agreements(fakeExportTariffCode)[1:],
+				})
+			}
+		}
+
+		gas := []map[string]interface{}{}
+		if f.fixtures.GasTariffCode != "" {
+			for range property.GasDeviceIDs {
+				gasMeters++
+				gas = append(gas, map[string]interface{}{
+					"mprn":       fmt.Sprintf("300000%04d", gasMeters),
+					"meters":     []map[string]string{{"serial_number": fmt.Sprintf("G4P%07d", gasMeters)}},
+					"agreements": []map[string]interface{}{{"tariff_code": f.fixtures.GasTariffCode, "valid_from": validFrom, "valid_to": nil}},
+				})
+			}
+		}
+
+		id, _ := strconv.Atoi(property.ID)
+		properties = append(properties, map[string]interface{}{
+			"id":                       id,
+			"electricity_meter_points": electricity,
+			"gas_meter_points":         gas,
+		})
+	}
+	return properties
+}
+
+// propertyOf returns the ID of the property a meter is installed at
+func (f *FakeKraken) propertyOf(deviceID string) string {
+	for _, property := range f.fixtures.Properties {
+		for _, devices := range [][]string{property.DeviceIDs, property.GasDeviceIDs} {
+			for _, id := range devices {
+				if id == deviceID {
+					return property.ID
+				}
+			}
+		}
+	}
+	return f.fixtures.Properties[0].ID
+}
+
+// handleProducts serves the public products API for the fixture tariffs:
+// GET /v1/products/<product>/<fuel>-tariffs/<tariff>/<charge>/
+func (f *FakeKraken) handleProducts(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/products/"), "/"), "/")
+	if len(parts) != 4 || tariffProductCode(parts[2]) != parts[0] {
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+		return
+	}
+	tariffCode, charge := parts[2], parts[3]
+
+	var rate, standing float64
+	switch tariffCode {
+	case f.fixtures.TariffCode:
+		rate, standing = f.fixtures.UnitRatePence, f.fixtures.StandingPence
+	case f.fixtures.GasTariffCode:
+		rate, standing = f.fixtures.GasUnitRatePence, f.fixtures.GasStandingPence
+	case fakeExportTariffCode:
+		rate = f.fixtures.ExportRatePence
+	default:
+		writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+		return
+	}
+
+	from, _ := time.Parse(time.RFC3339, r.URL.Query().Get("period_from"))
+	to, _ := time.Parse(time.RFC3339, r.URL.Query().Get("period_to"))
+	validFrom := f.started.AddDate(0, -6, 0).Truncate(24 * time.Hour)
+	value := func(pence float64) map[string]interface{} {
+		return map[string]interface{}{
+			"value_exc_vat": math.Round(pence/1.05*10000) / 10000,
+			"value_inc_vat": pence,
+		}
+	}
+
+	results := []map[string]interface{}{}
+	switch {
+	case charge == "standing-charges":
+		result := value(standing)
+		result["valid_from"], result["valid_to"] = validFrom, nil
+		results = append(results, result)
+	case charge != "standard-unit-rates":
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"detail": "Not available for this tariff."})
+		return
+	case strings.Contains(tariffCode, "AGILE") && !from.IsZero() && to.After(from):
+		// Half-hourly prices, cheap overnight and dear in the evening peak, newest first like the real API
+		for slot := to.Truncate(30 * time.Minute).Add(-30 * time.Minute); !slot.Before(from); slot = slot.Add(-30 * time.Minute) {
+			hour := float64(slot.Hour()) + float64(slot.Minute())/60
+			pence := rate * (0.55 + 0.2*math.Sin(math.Pi*hour/12) + 0.9*math.Exp(-math.Pow(hour-17.5, 2)/2))
+			result := value(math.Round(pence*100) / 100)
+			result["valid_from"], result["valid_to"] = slot, slot.Add(30*time.Minute)
+			results = append(results, result)
+		}
+	default:
+		result := value(rate)
+		result["valid_from"], result["valid_to"] = validFrom, nil
+		results = append(results, result)
+	}
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    len(results),
+		"next":     nil,
+		"previous": nil,
+		"results":  results,
+	})
+}
+
+func (f *FakeKraken) handleFreeElectricity(w http.ResponseWriter, r *http.Request) {
+	var resp FreeElectricitySessionsResponse
+	resp.Data = []FreeElectricitySession{}
+	for i, session := range f.fixtures.FreeElectricity {
+		start := f.started.Add(session.StartsIn).Truncate(time.Hour)
+		resp.Data = append(resp.Data, FreeElectricitySession{
+			Code:    fmt.Sprintf("FREE_%d", i+1),
+			StartAt: start,
+			EndAt:   start.Add(session.Duration),
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, resp)
+}
+
+// inJoinedSession reports whether a half-hour falls in a joined saving session
+func (f *FakeKraken) inJoinedSession(slot time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, session := range f.fixtures.SavingSessions {
+		start := f.started.Add(session.StartsIn).Truncate(30 * time.Minute)
+		if f.joined[session.ID] && !slot.Before(start) && slot.Before(start.Add(session.Duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleEvents returns the saving sessions announced so far. Callers hold f.mu.
+func (f *FakeKraken) visibleEvents() []SavingSessionEvent {
+	var events []SavingSessionEvent
+	for _, session := range f.fixtures.SavingSessions {
+		if time.Since(f.started) < session.AnnounceAfter {
+			continue
+		}
+		start := f.started.Add(session.StartsIn).Truncate(30 * time.Minute)
+		events = append(events, SavingSessionEvent{
+			ID:                       session.ID,
+			Code:                     fmt.Sprintf("EVENT_%d", session.ID),
+			StartAt:                  start,
+			EndAt:                    start.Add(session.Duration),
+			RewardPerKwhInOctoPoints: session.Reward,
+		})
+	}
+	return events
+}
+
+func (f *FakeKraken) hasCampaign(slug string) bool {
+	for _, campaign := range f.fixtures.Campaigns {
+		if campaign == slug {
+			return true
+		}
+	}
+	return false
+}
+
+var graphQLOperationName = regexp.MustCompile(`^\s*(?:query|mutation)\s+(\w+)`)
+
+func (f *FakeKraken) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GraphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	operation := req.OperationName
+	if operation == "" {
+		if match := graphQLOperationName.FindStringSubmatch(req.Query); match != nil {
+			operation = match[1]
+		}
+	}
+	f.logger.Debug("GraphQL operation", "operation", operation, "path", r.URL.Path)
+
+	if operation == "obtainKrakenToken" {
+		f.obtainKrakenToken(w, req.Variables)
+		return
+	}
+
+	f.mu.Lock()
+	expiry, authorized := f.tokens[r.Header.Get("Authorization")]
+	f.mu.Unlock()
+	if !authorized {
+		graphQLError(w, OctopusErrorCodeInvalidAuth, "Invalid data in the authorization header.")
+		return
+	}
+	if time.Now().After(expiry) {
+		graphQLError(w, OctopusErrorCodeJWTExpired, "Signature of the JWT has expired.")
+		return
+	}
+	if account, ok := req.Variables["accountNumber"]; ok && account != f.fixtures.AccountID {
+		graphQLError(w, "KT-CT-4123", "Unauthorized.")
+		return
+	}
+
+	switch operation {
+	case "octoplusData":
+		f.octoplusData(w)
+	case "checkCampaigns":
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"account": map[string]interface{}{"campaigns": f.campaignSlugs()}},
+		})
+	case "getSavingSessionEvents":
+		f.mu.Lock()
+		events := f.visibleEvents()
+		f.mu.Unlock()
+		if events == nil {
+			events = []SavingSessionEvent{}
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"savingSessions": map[string]interface{}{"events": events}},
+		})
+	case "getWheelOfFortuneSpinsAllowed":
+		f.mu.Lock()
+		elec, gas := f.elecSpins, f.gasSpins
+		f.mu.Unlock()
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"electricitySpins": map[string]interface{}{"spinsAllowed": elec, "__typename": "WheelOfFortuneSpinsAllowedType"},
+				"gasSpins":         map[string]interface{}{"spinsAllowed": gas, "__typename": "WheelOfFortuneSpinsAllowedType"},
+			},
+		})
+	case "spinWheelOfFortune":
+		f.spinWheelOfFortune(w, req.Variables)
+	case "redeemLoyaltyPointsForAccountCredit":
+		f.redeemPoints(w, req.Variables)
+	case "getAccountInfo":
+		f.mu.Lock()
+		credit := float64(f.redeemed) * 100 / OctoPointsPerPound
+		f.mu.Unlock()
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"account": map[string]interface{}{
+				"balance":     f.fixtures.BalancePence + credit,
+				"accountType": f.fixtures.AccountType,
+				"__typename":  "AccountType",
+			}},
+		})
+	case "getEligibility":
+		f.getEligibility(w)
+	case "getMeasurements":
+		f.getMeasurements(w, req.Variables)
+	case "getSmartMeterTelemetry":
+		f.getSmartMeterTelemetry(w, req.Variables)
+	case "getDispatches":
+		f.getDispatches(w)
+	default:
+		graphQLError(w, "KT-CT-0000", fmt.Sprintf("Unknown operation %q.", operation))
+	}
+}
+
+// getDispatches serves the fixture dispatches, planned until they end, with
+// Kraken's space-separated timestamps
+func (f *FakeKraken) getDispatches(w http.ResponseWriter) {
+	planned, completed := []map[string]interface{}{}, []map[string]interface{}{}
+	for _, dispatch := range f.fixtures.Dispatches {
+		start := f.started.Add(dispatch.StartsIn).Truncate(30 * time.Minute)
+		end := start.Add(dispatch.Duration)
+		source := dispatch.Source
+		if source == "" {
+			source = "smart-charge"
+		}
+		entry := map[string]interface{}{
+			"startDt": start.UTC().Format("2006-01-02 15:04:05-07:00"),
+			"endDt":   end.UTC().Format("2006-01-02 15:04:05-07:00"),
+			"delta":   strconv.FormatFloat(-dispatch.KWh, 'f', 2, 64),
+			"meta":    map[string]interface{}{"source": source, "location": "AT_HOME"},
+		}
+		if end.Before(time.Now()) {
+			completed = append(completed, entry)
+		} else {
+			planned = append(planned, entry)
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"plannedDispatches":   planned,
+			"completedDispatches": completed,
+		},
+	})
+}
+
+func (f *FakeKraken) obtainKrakenToken(w http.ResponseWriter, variables map[string]interface{}) {
+	input, _ := variables["input"].(map[string]interface{})
+	apiKey, _ := input["APIKey"].(string)
+	refreshToken, _ := input["refreshToken"].(string)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case refreshToken != "":
+		// Kraken keeps the same refresh token until it expires
+		if expiry, ok := f.refreshTokens[refreshToken]; !ok || now.After(expiry) {
+			graphQLError(w, "KT-CT-1135", "Invalid data in the refresh token.")
+			return
+		}
+		f.refreshExchanges++
+	case apiKey != "":
+		refreshToken = fmt.Sprintf("fake-refresh-%d-%d", f.started.Unix(), len(f.refreshTokens)+1)
+		f.refreshTokens[refreshToken] = now.Add(FakeKrakenRefreshTokenLifetime)
+		f.apiKeyExchanges++
+	default:
+		graphQLError(w, "KT-CT-1138", "Please make sure the API key is correct.")
+		return
+	}
+
+	expiry := now.Add(FakeKrakenTokenLifetime)
+	token := fakeJWT(len(f.tokens)+1, now, expiry)
+	f.tokens[token] = expiry
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"obtainKrakenToken": map[string]interface{}{
+			"token":            token,
+			"refreshToken":     refreshToken,
+			"refreshExpiresIn": f.refreshTokens[refreshToken].Unix(), // a timestamp, despite the name
+		}},
+	})
+}
+
+// fakeJWT builds an unsigned JWT carrying iat and exp claims, enough for the
+// client to read its expiry
+func fakeJWT(serial int, issued, expiry time.Time) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	header := encode(map[string]string{"alg": "none", "typ": "JWT"})
+	claims := encode(map[string]interface{}{"sub": fmt.Sprintf("fake-%d", serial), "iat": issued.Unix(), "exp": expiry.Unix()})
+	return header + "." + claims + ".fake"
+}
+
+func (f *FakeKraken) campaignSlugs() []map[string]string {
+	slugs := []map[string]string{}
+	for _, campaign := range f.fixtures.Campaigns {
+		slugs = append(slugs, map[string]string{"slug": campaign})
+	}
+	return slugs
+}
+
+func (f *FakeKraken) octoplusData(w http.ResponseWriter) {
+	f.mu.Lock()
+	points := f.points
+	// Newest first, like Kraken
+	ledger := []map[string]string{}
+	for i := len(f.ledger) - 1; i >= 0; i-- {
+		entry := f.ledger[i]
+		ledgerType, value := "POINTS_EARNED", entry.Points
+		if entry.Points < 0 {
+			ledgerType, value = "POINTS_SPENT", -entry.Points
+		}
+		ledger = append(ledger, map[string]string{
+			"id":                    entry.ID,
+			"ledgerType":            ledgerType,
+			"value":                 strconv.Itoa(value),
+			"balanceBroughtForward": strconv.Itoa(entry.Balance - entry.Points),
+			"balanceCarriedForward": strconv.Itoa(entry.Balance),
+			"reasonCode":            entry.ReasonCode,
+			"postedAt":              entry.PostedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	f.mu.Unlock()
+	if len(ledger) == 0 {
+		ledger = append(ledger, map[string]string{"balanceCarriedForward": strconv.Itoa(points)})
+	}
+
+	status := "NOT_ENROLLED"
+	if f.hasCampaign("octoplus") {
+		status = "ENROLLED"
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"loyaltyPointLedgers": ledger,
+			"account":             map[string]interface{}{"campaigns": f.campaignSlugs()},
+			"octoplusAccountInfo": map[string]string{"enrollmentStatus": status},
+		},
+	})
+}
+
+func (f *FakeKraken) spinWheelOfFortune(w http.ResponseWriter, variables map[string]interface{}) {
+	input, _ := variables["input"].(map[string]interface{})
+	fuelType, _ := input["fuelType"].(string)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	remaining := &f.elecSpins
+	if fuelType == "GAS" {
+		remaining = &f.gasSpins
+	} else if fuelType != "ELECTRICITY" {
+		graphQLError(w, "KT-CT-4321", "Invalid fuel type.")
+		return
+	}
+	if *remaining <= 0 {
+		graphQLError(w, "KT-CT-9801", "No spins remaining.")
+		return
+	}
+
+	prize := 0
+	if len(f.fixtures.SpinPrizes) > 0 {
+		prize = f.fixtures.SpinPrizes[f.spins%len(f.fixtures.SpinPrizes)]
+	}
+	*remaining--
+	f.spins++
+	f.points += prize
+	if prize > 0 {
+		f.addLedgerEntry(time.Now(), "WHEEL_OF_FORTUNE_PRIZE", prize, f.points)
+	}
+	f.logger.Info("Wheel of Fortune spun", "fuel_type", fuelType, "prize", prize)
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"spinWheelOfFortune": map[string]interface{}{
+			"prize": map[string]int{"value": prize},
+		}},
+	})
+}
+
+// redeemPoints takes points from the wallet, as credit on the account
+func (f *FakeKraken) redeemPoints(w http.ResponseWriter, variables map[string]interface{}) {
+	input, _ := variables["input"].(map[string]interface{})
+	if account, _ := input["accountNumber"].(string); account != f.fixtures.AccountID {
+		graphQLError(w, "KT-CT-4123", "Unauthorized.")
+		return
+	}
+	points, _ := input["points"].(float64) // JSON numbers decode as float64
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if points <= 0 || int(points) > f.points {
+		graphQLError(w, "KT-CT-9205", "Not enough points to redeem.")
+		return
+	}
+	f.points -= int(points)
+	f.redeemed += int(points)
+	f.addLedgerEntry(time.Now(), "POINTS_REDEMPTION", -int(points), f.points)
+	f.logger.Info("OctoPoints redeemed", "points", int(points), "balance", f.points)
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"redeemLoyaltyPointsForAccountCredit": map[string]int{"pointsRedeemed": int(points)}},
+	})
+}
+
+func (f *FakeKraken) getEligibility(w http.ResponseWriter) {
+	properties := []map[string]interface{}{}
+	for _, property := range f.fixtures.Properties {
+		devices := []map[string]string{}
+		for _, deviceID := range property.DeviceIDs {
+			devices = append(devices, map[string]string{"deviceId": deviceID, "type": "ESME", "__typename": "SmartMeterDeviceType"})
+		}
+		for _, deviceID := range property.GasDeviceIDs {
+			devices = append(devices, map[string]string{"deviceId": deviceID, "type": "GSME", "__typename": "SmartMeterDeviceType"})
+		}
+		properties = append(properties, map[string]interface{}{
+			"id":                  property.ID,
+			"address":             property.Address,
+			"smartDeviceNetworks": []map[string]interface{}{{"smartDevices": devices}},
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"account": map[string]interface{}{"properties": properties}},
+	})
+}
+
+// getMeasurements generates half-hourly consumption for the requested window:
+// a small base load with morning and evening peaks, scaled down for each
+// additional meter. Gas is metered in m³ with a heating peak in the morning,
+// and the first electricity meter exports whatever solar the load doesn't use.
+// Results are paged with opaque cursors like Kraken's.
+func (f *FakeKraken) getMeasurements(w http.ResponseWriter, variables map[string]interface{}) {
+	first := 1000
+	if n, ok := variables["first"].(float64); ok && n > 0 {
+		first = int(n)
+	}
+	offset := 0
+	if after, ok := variables["after"].(string); ok && after != "" {
+		decoded, err := base64.StdEncoding.DecodeString(after)
+		if _, scanErr := fmt.Sscanf(string(decoded), "arrayconnection:%d", &offset); err != nil || scanErr != nil {
+			graphQLError(w, "KT-CT-1141", "Invalid cursor.")
+			return
+		}
+		offset++
+	}
+	startAt, _ := time.Parse(time.RFC3339, fmt.Sprint(variables["startAt"]))
+	endAt, _ := time.Parse(time.RFC3339, fmt.Sprint(variables["endAt"]))
+
+	deviceID, gas, export := "", false, false
+	if filters, ok := variables["utilityFilters"].([]interface{}); ok && len(filters) > 0 {
+		if filter, ok := filters[0].(map[string]interface{}); ok {
+			if electricity, ok := filter["electricityFilters"].(map[string]interface{}); ok {
+				deviceID, _ = electricity["deviceId"].(string)
+				export = electricity["readingDirection"] == "GENERATION"
+			} else if gasFilter, ok := filter["gasFilters"].(map[string]interface{}); ok {
+				deviceID, _ = gasFilter["deviceId"].(string)
+				gas = true
+			}
+		}
+	}
+
+	meters, unit, label, rate := f.fixtures.DeviceIDs, "kwh", "Electricity", f.fixtures.UnitRatePence
+	if gas {
+		meters, unit, label, rate = f.fixtures.GasDeviceIDs, "m3", "Gas", f.fixtures.GasUnitRatePence
+	} else if export {
+		label, rate = "Export", f.fixtures.ExportRatePence
+		if f.fixtures.SolarPeakKWh <= 0 {
+			meters = nil
+		} else {
+			meters = meters[:min(1, len(meters))]
+		}
+	}
+	statistic := "Consumption"
+	if export {
+		statistic = "Generation"
+	}
+	meter := -1
+	for i, id := range meters {
+		if id == deviceID {
+			meter = i
+		}
+	}
+
+	edges := []map[string]interface{}{}
+	hasNextPage := false
+	if meter >= 0 && !startAt.IsZero() && endAt.After(startAt) {
+		index := 0
+		for slot := startAt.Truncate(30 * time.Minute); slot.Add(30 * time.Minute).Before(endAt); slot, index = slot.Add(30*time.Minute), index+1 {
+			if index < offset {
+				continue
+			}
+			if len(edges) == first {
+				hasNextPage = true
+				break
+			}
+			hour := float64(slot.Hour()) + float64(slot.Minute())/60
+			value := (0.12 + 0.25*math.Exp(-math.Pow(hour-7.5, 2)/2) + 0.55*math.Exp(-math.Pow(hour-18.5, 2)/3)) / float64(meter+1)
+			if !export && !gas && f.inJoinedSession(slot) {
+				value *= 0.4 // the household turns things off for joined sessions
+			}
+			kwh := value
+			if export {
+				solar := f.fixtures.SolarPeakKWh * math.Max(0, math.Sin(math.Pi*(hour-6)/14))
+				value = math.Max(0, solar-value)
+				kwh = value
+			} else if gas {
+				value = (0.02 + 0.35*math.Exp(-math.Pow(hour-7, 2)/1.5) + 0.2*math.Exp(-math.Pow(hour-19, 2)/4)) / float64(meter+1)
+				kwh = value * GasVolumeCorrectionFactor * GasDefaultCalorificValue / MegajoulesPerKWh
+			}
+			cost := kwh * rate
+			edges = append(edges, map[string]interface{}{"cursor": fakeCursor(index), "node": map[string]interface{}{
+				"value":             strconv.FormatFloat(value, 'f', 3, 64),
+				"unit":              unit,
+				"startAt":           slot.Format(time.RFC3339),
+				"endAt":             slot.Add(30 * time.Minute).Format(time.RFC3339),
+				"durationInSeconds": 1800,
+				"metaData": map[string]interface{}{"statistics": []map[string]interface{}{{
+					"costInclTax": map[string]string{"costCurrency": "GBP", "estimatedAmount": strconv.FormatFloat(cost, 'f', 2, 64)},
+					"costExclTax": map[string]interface{}{
+						"pricePerUnit":    map[string]string{"amount": strconv.FormatFloat(rate/1.05, 'f', 2, 64)},
+						"costCurrency":    "GBP",
+						"estimatedAmount": strconv.FormatFloat(cost/1.05, 'f', 2, 64),
+					},
+					"value":       strconv.FormatFloat(kwh, 'f', 3, 64),
+					"description": statistic,
+					"label":       label,
+					"type":        strings.ToUpper(statistic),
+				}}},
+			}})
+		}
+	}
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"account": map[string]interface{}{
+			"properties": []map[string]interface{}{{
+				"id": f.propertyOf(deviceID),
+				"measurements": map[string]interface{}{
+					"edges":    edges,
+					"pageInfo": map[string]interface{}{"hasNextPage": hasNextPage, "hasPreviousPage": offset > 0, "endCursor": fakeCursor(offset + len(edges) - 1)},
+				},
+			}},
+		}},
+	})
+}
+
+// getSmartMeterTelemetry serves 10-second Home Mini readings for the first
+// electricity meter, following the same daily shape as the measurements
+func (f *FakeKraken) getSmartMeterTelemetry(w http.ResponseWriter, variables map[string]interface{}) {
+	deviceID, _ := variables["deviceId"].(string)
+	start, _ := time.Parse(time.RFC3339, fmt.Sprint(variables["start"]))
+	end, _ := time.Parse(time.RFC3339, fmt.Sprint(variables["end"]))
+
+	readings := []map[string]interface{}{}
+	if f.fixtures.HomeMini && len(f.fixtures.DeviceIDs) > 0 && deviceID == f.fixtures.DeviceIDs[0] && !start.IsZero() {
+		consumption := 1250000.0 // meter register in Wh
+		for at := start.Truncate(10 * time.Second); !at.After(end); at = at.Add(10 * time.Second) {
+			hour := float64(at.Hour()) + float64(at.Minute())/60
+			kwh := 0.12 + 0.25*math.Exp(-math.Pow(hour-7.5, 2)/2) + 0.55*math.Exp(-math.Pow(hour-18.5, 2)/3)
+			demand := kwh*2000 + 150*math.Sin(float64(at.Unix())/60) // half-hourly kWh as watts, with a kettle or two
+			delta := demand * 10 / 3600
+			consumption += delta
+			readings = append(readings, map[string]interface{}{
+				"readAt":           at.Format(time.RFC3339),
+				"consumption":      strconv.FormatFloat(consumption, 'f', 1, 64),
+				"demand":           strconv.FormatFloat(demand, 'f', 1, 64),
+				"consumptionDelta": strconv.FormatFloat(delta, 'f', 1, 64),
+				"costDelta":        strconv.FormatFloat(delta/1000*f.fixtures.UnitRatePence, 'f', 2, 64),
+				"__typename":       "SmartMeterTelemetryType",
+			})
+		}
+	}
+
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"smartMeterTelemetry": readings},
+	})
+}
+
+// fakeCursor encodes a position the way Relay connections do
+func fakeCursor(index int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("arrayconnection:%d", index)))
+}
@@ -0,0 +1,519 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startFakeKraken runs a fake Kraken server and points the endpoints at it
+// for the duration of the test
+func startFakeKraken(t *testing.T, fixtures KrakenFixtures) (*FakeKraken, *OctopusClient) {
+	t.Setenv("HOME", t.TempDir())
+
+	saved := make(map[string]string, len(octopusEndpoints))
+	for key, url := range octopusEndpoints {
+		saved[key] = url
+	}
+	t.Cleanup(func() {
+		for key, url := range saved {
+			octopusEndpoints[key] = url
+		}
+	})
+
+	fake := NewFakeKraken(fixtures, false)
+	if err := fake.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Expected fake server to start, got %v", err)
+	}
+	t.Cleanup(func() { fake.Close() })
+	fake.UseEndpoints()
+
+	client := NewOctopusClient(fixtures.AccountID, DemoAPIKey, false)
+	client.minInterval = 0
+	return fake, client
+}
+
+func TestFakeKrakenServesClientOperations(t *testing.T) {
+	_, client := startFakeKraken(t, DefaultKrakenFixtures())
+
+	info, err := client.getAccountInfo()
+	if err != nil {
+		t.Fatalf("Expected account info, got %v", err)
+	}
+	if info.Balance != 42.73 || info.AccountType != "DOMESTIC" {
+		t.Errorf("Expected £42.73 DOMESTIC, got £%.2f %s", info.Balance, info.AccountType)
+	}
+
+	campaigns, err := client.getCampaignStatus()
+	if err != nil {
+		t.Fatalf("Expected campaign status, got %v", err)
+	}
+	for _, slug := range []string{"octoplus", "octoplus-saving-sessions", "free_electricity"} {
+		if !campaigns[slug] {
+			t.Errorf("Expected enrolment in %s", slug)
+		}
+	}
+
+	sessions, err := client.GetSavingSessions()
+	if err != nil {
+		t.Fatalf("Expected saving sessions, got %v", err)
+	}
+	if points := sessions.Data.OctoPoints.Account.CurrentPointsInWallet; points != 1850 {
+		t.Errorf("Expected 1850 points, got %d", points)
+	}
+	if joined := sessions.Data.SavingSessions.Account.JoinedEvents; len(joined) != 1 || joined[0].EventID != 9001 {
+		t.Errorf("Expected event 9001 to be joined, got %v", joined)
+	}
+	// Event 9003 is only announced a few minutes after start
+	if unjoined := sessions.UnjoinedEvents(); len(unjoined) != 1 || unjoined[0].ID != 9002 {
+		t.Errorf("Expected only event 9002 to be open, got %v", unjoined)
+	}
+
+	free, err := client.GetFreeElectricitySessions()
+	if err != nil || len(free.Data) != 1 {
+		t.Errorf("Expected one free electricity session, got %v (%v)", free, err)
+	}
+
+	devices, err := client.getSmartMeterDevices()
+	if err != nil || len(devices) != 1 || devices[0] != "00-11-22-33-44-55-66-77" {
+		t.Fatalf("Expected one ESME device, got %v (%v)", devices, err)
+	}
+	measurements, err := client.getUsageMeasurements(devices, 1)
+	if err != nil {
+		t.Fatalf("Expected measurements, got %v", err)
+	}
+	if len(measurements) < 46 || len(measurements) > 48 {
+		t.Errorf("Expected a day of half-hourly readings, got %d", len(measurements))
+	}
+	if len(measurements) > 0 && (measurements[0].GetValueAsFloat64() <= 0 || measurements[0].Duration != 1800) {
+		t.Errorf("Expected positive half-hour readings, got %+v", measurements[0])
+	}
+}
+
+func TestFakeKrakenDaemonCycle(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.ElectricitySpins = 0 // spins sleep between calls, covered below
+	fixtures.GasSpins = 0
+	fake, client := startFakeKraken(t, fixtures)
+
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	monitor.checkForNewSessions()
+
+	fake.mu.Lock()
+	joined := fake.joined[9002]
+	fake.mu.Unlock()
+	if !joined {
+		t.Error("Expected the monitor to join event 9002 on the fake server")
+	}
+	if monitor.MetricsSnapshot() == nil {
+		t.Error("Expected the cycle to record a metrics snapshot")
+	}
+
+	// Both sessions land in the history ledger, the settled one with its award
+	recorder := httptest.NewRecorder()
+	NewWebServer(monitor, 0).server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/history/sessions", nil))
+	var history SessionHistory
+	if err := json.NewDecoder(recorder.Body).Decode(&history); err != nil {
+		t.Fatalf("Expected JSON session history, got %v", err)
+	}
+	records := make(map[int]*SessionRecord)
+	for _, record := range history.Sessions {
+		records[record.EventID] = record
+	}
+	if record := records[9001]; record == nil || record.Status(time.Now()) != "awarded" || *record.AwardedPoints != 1620 {
+		t.Errorf("Expected event 9001 to be awarded 1620 points, got %+v", record)
+	}
+	if record := records[9002]; record == nil || record.Status(time.Now()) != "joined" || record.Decision == nil || record.JoinedAt == nil {
+		t.Errorf("Expected event 9002 to be joined by octojoin, got %+v", record)
+	}
+	if history.Totals.Joined != 2 || history.Totals.AwardedPoints != 1620 {
+		t.Errorf("Expected 2 joined sessions and 1620 points in total, got %+v", history.Totals)
+	}
+}
+
+func TestFakeKrakenJoinRetries(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.JoinFailures = 2
+	fake, client := startFakeKraken(t, fixtures)
+
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	events := make(channelNotifier, 10)
+	notifier, _ := NewNotificationManager(nil, NewLogger(false))
+	notifier.AddNotifier(events, nil, 0, 0)
+	monitor.SetNotifier(notifier)
+
+	// delivered waits briefly for notifications and returns their types
+	delivered := func() string {
+		var types []string
+		for {
+			select {
+			case event := <-events:
+				types = append(types, event.Type)
+			case <-time.After(200 * time.Millisecond):
+				return strings.Join(types, ",")
+			}
+		}
+	}
+	retryNow := func() {
+		monitor.state.Update(func(s *AppState) {
+			if retry := s.JoinRetries[9002]; retry != nil {
+				s.JoinRetries[9002] = &JoinRetry{Attempts: retry.Attempts, LastError: retry.LastError}
+			}
+		})
+	}
+	attempts := func() int {
+		if retry := monitor.state.Snapshot().JoinRetries[9002]; retry != nil {
+			return retry.Attempts
+		}
+		return 0
+	}
+
+	// The first failure announces the session and notifies once
+	if found, err := monitor.checkSavingSessions(); err != nil || !found {
+		t.Fatalf("Expected the new session to be found, got %v (%v)", found, err)
+	}
+	if types := delivered(); types != EventSavingSessionFound+","+EventSavingSessionJoinFailed {
+		t.Errorf("Expected the session found and one join failure notified, got %s", types)
+	}
+	if attempts() != 1 || !monitor.state.IsSessionKnown(9002) {
+		t.Errorf("Expected the session known with one failed attempt, got %d", attempts())
+	}
+
+	// Within the backoff nothing is retried or announced again
+	if found, _ := monitor.checkSavingSessions(); found || attempts() != 1 {
+		t.Errorf("Expected no new session and no retry, got found=%v attempts=%d", found, attempts())
+	}
+
+	// A failed retry backs off again without notifying
+	retryNow()
+	monitor.checkSavingSessions()
+	if attempts() != 2 {
+		t.Errorf("Expected a second failed attempt, got %d", attempts())
+	}
+	if types := delivered(); types != "" {
+		t.Errorf("Expected no notifications for the retries, got %s", types)
+	}
+
+	retryNow()
+	monitor.checkSavingSessions()
+	fake.mu.Lock()
+	joined := fake.joined[9002]
+	fake.mu.Unlock()
+	if !joined || attempts() != 0 {
+		t.Errorf("Expected the retry to join and clear, got joined=%v attempts=%d", joined, attempts())
+	}
+	if types := delivered(); types != EventSavingSessionJoined {
+		t.Errorf("Expected a joined notification, got %s", types)
+	}
+	if record := monitor.state.Snapshot().SessionHistory[9002]; record == nil || record.JoinedAt == nil {
+		t.Errorf("Expected the history to record the join, got %+v", record)
+	}
+}
+
+func TestFakeKrakenWheelOfFortune(t *testing.T) {
+	fake, client := startFakeKraken(t, DefaultKrakenFixtures())
+
+	spins, err := client.getWheelOfFortuneSpins()
+	if err != nil || spins.ElectricitySpins != 2 || spins.GasSpins != 1 {
+		t.Fatalf("Expected 2 electricity and 1 gas spin, got %+v (%v)", spins, err)
+	}
+
+	result, err := client.spinWheelOfFortune("GAS")
+	if err != nil || result.Prize != 20 {
+		t.Fatalf("Expected a 20 point prize, got %+v (%v)", result, err)
+	}
+	// With no spins left the refusal is an error, not a 0 point prize
+	var sessionErr *SessionError
+	if result, err := client.spinWheelOfFortune("GAS"); !errors.As(err, &sessionErr) || sessionErr.Operation != "spin" {
+		t.Fatalf("Expected a refused spin, got %+v (%v)", result, err)
+	}
+
+	fake.mu.Lock()
+	points, gasSpins := fake.points, fake.gasSpins
+	fake.mu.Unlock()
+	if points != 1870 || gasSpins != 0 {
+		t.Errorf("Expected 1870 points and no gas spins left, got %d and %d", points, gasSpins)
+	}
+}
+
+func TestFakeKrakenRejectsUnknownTokens(t *testing.T) {
+	_, client := startFakeKraken(t, DefaultKrakenFixtures())
+
+	// A token from an earlier run is rejected and transparently replaced
+	client.jwtToken = "stale-token"
+	client.jwtExpiry = time.Now().Add(time.Hour)
+
+	if _, err := client.getAccountInfo(); err != nil {
+		t.Fatalf("Expected the client to refresh its token, got %v", err)
+	}
+	if client.currentJWTToken() == "stale-token" {
+		t.Error("Expected the stale token to be replaced")
+	}
+}
+
+func TestFakeKrakenRefreshesExpiredTokens(t *testing.T) {
+	fake, client := startFakeKraken(t, DefaultKrakenFixtures())
+	client.SetState(&AppState{})
+
+	if _, err := client.getAccountInfo(); err != nil {
+		t.Fatalf("Expected account info, got %v", err)
+	}
+	expiry := client.jwtExpiry
+	if time.Until(expiry) < FakeKrakenTokenLifetime-time.Minute || time.Until(expiry) > FakeKrakenTokenLifetime {
+		t.Errorf("Expected expiry from the token's exp claim, got %v", expiry)
+	}
+
+	// Expire the access token on the server; the client should spend its
+	// refresh token rather than the API key
+	fake.mu.Lock()
+	fake.tokens[client.currentJWTToken()] = time.Now().Add(-time.Second)
+	fake.mu.Unlock()
+
+	if _, err := client.getAccountInfo(); err != nil {
+		t.Fatalf("Expected the client to refresh its token, got %v", err)
+	}
+	fake.mu.Lock()
+	apiKeyExchanges, refreshExchanges := fake.apiKeyExchanges, fake.refreshExchanges
+	fake.mu.Unlock()
+	if apiKeyExchanges != 1 || refreshExchanges != 1 {
+		t.Errorf("Expected 1 API key and 1 refresh token exchange, got %d and %d", apiKeyExchanges, refreshExchanges)
+	}
+}
+
+func TestFakeKrakenPaginatesUsageAcrossMeters(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.DeviceIDs = append(fixtures.DeviceIDs, "00-11-22-33-44-55-66-88")
+	_, client := startFakeKraken(t, fixtures)
+
+	// 30 days of half-hours is more than one page per meter
+	measurements, err := client.getUsageMeasurements(fixtures.DeviceIDs, 30)
+	if err != nil {
+		t.Fatalf("Expected measurements, got %v", err)
+	}
+	perDevice := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, m := range measurements {
+		perDevice[m.DeviceID]++
+		key := m.DeviceID + m.StartAt.String()
+		if seen[key] {
+			t.Fatalf("Expected no duplicate readings across pages, got %s twice", key)
+		}
+		seen[key] = true
+	}
+	for _, deviceID := range fixtures.DeviceIDs {
+		if count := perDevice[deviceID]; count < 1430 || count > 1440 {
+			t.Errorf("Expected about 1440 readings for %s, got %d", deviceID, count)
+		}
+	}
+
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	recorder := httptest.NewRecorder()
+	NewWebServer(monitor, 0).server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/usage?days=1", nil))
+	var response struct {
+		Measurements int `json:"measurements"`
+		Series       []struct {
+			DeviceID string        `json:"device_id"`
+			Data     []interface{} `json:"data"`
+		} `json:"series"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected JSON usage data, got %v", err)
+	}
+	if len(response.Series) != 2 || response.Series[0].DeviceID != fixtures.DeviceIDs[0] || response.Series[1].DeviceID != fixtures.DeviceIDs[1] {
+		t.Fatalf("Expected one series per meter, got %+v", response.Series)
+	}
+	if len(response.Series[0].Data)+len(response.Series[1].Data) != response.Measurements {
+		t.Errorf("Expected the series to cover all %d measurements", response.Measurements)
+	}
+}
+
+func TestFakeKrakenGasUsage(t *testing.T) {
+	_, client := startFakeKraken(t, DefaultKrakenFixtures())
+	state := &AppState{
+		// Cached before gas meters were discovered
+		CachedMeterDevices: &CachedMeterDevices{Data: []string{"00-11-22-33-44-55-66-77"}, Timestamp: time.Now()},
+	}
+	client.SetState(state)
+
+	gasDevices, err := client.getGasMeterDevicesWithCache(state)
+	if err != nil || len(gasDevices) != 1 || gasDevices[0] != "00-AA-BB-CC-DD-EE-FF-00" {
+		t.Fatalf("Expected the stale device cache to be refreshed with one GSME device, got %v (%v)", gasDevices, err)
+	}
+
+	measurements, err := client.getGasUsageMeasurementsWithCache(state, 1)
+	if err != nil || len(measurements) == 0 {
+		t.Fatalf("Expected gas measurements, got %d (%v)", len(measurements), err)
+	}
+	m := measurements[0]
+	if m.Unit != "kwh" || m.VolumeM3 <= 0 || m.DeviceID != gasDevices[0] {
+		t.Errorf("Expected a kWh reading converted from m³, got %+v", m)
+	}
+	if kwh := m.GetValueAsFloat64(); kwh < m.VolumeM3*11 || kwh > m.VolumeM3*11.5 {
+		t.Errorf("Expected about 11.2 kWh per m³, got %.3f kWh from %.3f m³", kwh, m.VolumeM3)
+	}
+
+	snapshot := state.Snapshot()
+	if snapshot.CachedGasUsage == nil || len(snapshot.CachedGasUsage.Data) != len(measurements) {
+		t.Error("Expected gas usage to be cached")
+	}
+	if snapshot.CachedUsageMeasurements != nil {
+		t.Error("Expected the electricity cache to be left alone")
+	}
+
+	monitor := NewSavingSessionMonitor(client, DemoAccountID)
+	monitor.state = state
+	recorder := httptest.NewRecorder()
+	NewWebServer(monitor, 0).server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/usage?days=1&fuel=gas", nil))
+	var response struct {
+		Fuel     string `json:"fuel"`
+		CacheAge int    `json:"cache_age"`
+		Series   []struct {
+			DeviceID string `json:"device_id"`
+		} `json:"series"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected JSON usage data, got %v", err)
+	}
+	if response.Fuel != "gas" || len(response.Series) != 1 || response.Series[0].DeviceID != gasDevices[0] {
+		t.Errorf("Expected one gas series, got %+v", response)
+	}
+}
+
+func TestFakeKrakenSolarExport(t *testing.T) {
+	_, client := startFakeKraken(t, DefaultKrakenFixtures())
+	state := &AppState{}
+	client.SetState(state)
+
+	exports, err := client.getExportMeasurementsWithCache(state, 1)
+	if err != nil || len(exports) == 0 {
+		t.Fatalf("Expected export measurements, got %d (%v)", len(exports), err)
+	}
+	var exported float64
+	for _, m := range exports {
+		if m.DeviceID != "00-11-22-33-44-55-66-77" {
+			t.Errorf("Expected export only from the first meter, got %q", m.DeviceID)
+		}
+		exported += m.GetValueAsFloat64()
+	}
+	if exported <= 0 {
+		t.Error("Expected some solar to be exported over a day")
+	}
+	if snapshot := state.Snapshot(); snapshot.CachedExportUsage == nil || snapshot.CachedUsageMeasurements != nil {
+		t.Error("Expected export usage to be cached separately from consumption")
+	}
+
+	monitor := NewSavingSessionMonitor(client, DemoAccountID)
+	monitor.state = state
+	recorder := httptest.NewRecorder()
+	NewWebServer(monitor, 0).server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/usage?days=1", nil))
+	var response struct {
+		Export    []interface{}            `json:"export"`
+		Net       []map[string]interface{} `json:"net"`
+		ExportKWh float64                  `json:"export_kwh"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected JSON usage data, got %v", err)
+	}
+	if len(response.Export) != 1 || len(response.Net) == 0 || response.ExportKWh <= 0 {
+		t.Errorf("Expected export series, net usage and export total, got %d series, %d net, %.3f kWh", len(response.Export), len(response.Net), response.ExportKWh)
+	}
+
+	// Gas has no export
+	recorder = httptest.NewRecorder()
+	NewWebServer(monitor, 0).server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/usage?days=1&fuel=gas", nil))
+	if strings.Contains(recorder.Body.String(), `"export"`) {
+		t.Error("Expected no export data for gas")
+	}
+}
+
+func TestNetUsage(t *testing.T) {
+	slot := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	reading := func(start time.Time, value string) UsageMeasurement {
+		return UsageMeasurement{Value: value, StartAt: start}
+	}
+	imports := []UsageMeasurement{
+		reading(slot.Add(30*time.Minute), "0.400"),
+		reading(slot, "0.100"),
+		reading(slot, "0.050"), // second meter
+	}
+	exports := []UsageMeasurement{reading(slot, "0.500")}
+
+	net := netUsage(imports, exports)
+	if len(net) != 2 {
+		t.Fatalf("Expected 2 intervals, got %d", len(net))
+	}
+	tests := []struct {
+		imported, exported, net float64
+	}{
+		{0.15, 0.5, -0.35},
+		{0.4, 0, 0.4},
+	}
+	for i, test := range tests {
+		if got := net[i]["import"].(float64); math.Abs(got-test.imported) > 1e-9 {
+			t.Errorf("Expected import %.2f in interval %d, got %.3f", test.imported, i, got)
+		}
+		if got := net[i]["export"].(float64); math.Abs(got-test.exported) > 1e-9 {
+			t.Errorf("Expected export %.2f in interval %d, got %.3f", test.exported, i, got)
+		}
+		if got := net[i]["net"].(float64); math.Abs(got-test.net) > 1e-9 {
+			t.Errorf("Expected net %.2f in interval %d, got %.3f", test.net, i, got)
+		}
+	}
+}
+
+func TestLoadKrakenFixtures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	data := `account_id: A-FIXTURE1
+points: 500
+saving_sessions:
+  - id: 1
+    starts_in: 2h
+    duration: 30m
+    reward: 1000
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadKrakenFixtures(path)
+	if err != nil {
+		t.Fatalf("Expected fixtures to load, got %v", err)
+	}
+	if fixtures.AccountID != "A-FIXTURE1" || fixtures.Points != 500 {
+		t.Errorf("Expected A-FIXTURE1 with 500 points, got %s with %d", fixtures.AccountID, fixtures.Points)
+	}
+	if len(fixtures.SavingSessions) != 1 || fixtures.SavingSessions[0].StartsIn != 2*time.Hour || fixtures.SavingSessions[0].Duration != 30*time.Minute {
+		t.Errorf("Expected one scripted session in 2h lasting 30m, got %+v", fixtures.SavingSessions)
+	}
+	// Unset fields keep their defaults
+	if len(fixtures.DeviceIDs) != 1 {
+		t.Error("Expected default device ID to be kept")
+	}
+
+	if err := os.WriteFile(path, []byte("account_id: 12345\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKrakenFixtures(path); err == nil {
+		t.Error("Expected an error for an invalid account ID")
+	}
+}
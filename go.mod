@@ -3,6 +3,6 @@ module octojoin
 go 1.24.0
 
 require (
-	golang.org/x/mod v0.29.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/mod v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
 )
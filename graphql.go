@@ -0,0 +1,203 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GraphQLError is one entry in a GraphQL response's errors list. Kraken puts
+// its error code and class in the extensions.
+type GraphQLError struct {
+	Message    string        `json:"message"`
+	Path       []interface{} `json:"path,omitempty"` // the field that failed, empty when the whole request did
+	Extensions struct {
+		ErrorCode  string `json:"errorCode"`
+		ErrorClass string `json:"errorClass"`
+	} `json:"extensions"`
+}
+
+// field names the top-level field an error applies to, if any
+func (e GraphQLError) field() string {
+	if len(e.Path) == 0 {
+		return ""
+	}
+	field, _ := e.Path[0].(string)
+	return field
+}
+
+// isAuth reports whether Kraken rejected the credentials rather than the request
+func (e GraphQLError) isAuth() bool {
+	return krakenAuthErrorCodes[e.Extensions.ErrorCode] || e.Extensions.ErrorClass == "AUTHORIZATION"
+}
+
+// tokenRejected reports whether a fresh JWT would fix the error
+func (e GraphQLError) tokenRejected() bool {
+	switch e.Extensions.ErrorCode {
+	case OctopusErrorCodeJWTExpired, OctopusErrorCodeInvalidAuth:
+		return true
+	}
+	for _, phrase := range []string{"JWT has expired", "Token has expired", "Authentication failed"} {
+		if strings.Contains(e.Message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// krakenAuthErrorCodes are the codes Kraken uses for bad or missing credentials
+var krakenAuthErrorCodes = map[string]bool{
+	OctopusErrorCodeUnauthorized:        true,
+	OctopusErrorCodeInvalidRefreshToken: true,
+	OctopusErrorCodeInvalidAPIKey:       true,
+	OctopusErrorCodeJWTExpired:          true,
+	OctopusErrorCodeInvalidAuth:         true,
+}
+
+// graphQLSessionOperations are the mutations whose refusals are reported as a
+// SessionError, by the operation named in it
+var graphQLSessionOperations = map[string]string{
+	"spinWheelOfFortune":                  "spin",
+	"redeemLoyaltyPointsForAccountCredit": "redeem",
+}
+
+// graphQLResponse is the envelope shared by every GraphQL response
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors"`
+}
+
+// tokenRejected reports whether any error in the response would be fixed by a fresh JWT
+func (r graphQLResponse) tokenRejected() bool {
+	for _, e := range r.Errors {
+		if e.tokenRejected() {
+			return true
+		}
+	}
+	return false
+}
+
+// partial reports whether the errors only affect fields listed in optional
+// and at least one field still came back, so the rest of the data can be used
+func (r graphQLResponse) partial(optional []string) bool {
+	var fields map[string]json.RawMessage
+	if len(optional) == 0 || json.Unmarshal(r.Data, &fields) != nil {
+		return false
+	}
+
+	tolerated := make(map[string]bool, len(optional))
+	for _, field := range optional {
+		tolerated[field] = true
+	}
+	for _, e := range r.Errors {
+		if e.isAuth() || !tolerated[e.field()] {
+			return false
+		}
+	}
+	for _, value := range fields {
+		if string(value) != "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLErrorFor maps a response's errors onto octojoin's error types: bad
+// credentials, or any failure to obtain a token, become an AuthError, a
+// refused spin or redemption a SessionError wrapping the APIError, and
+// anything else an APIError
+func graphQLErrorFor(endpoint, operation string, statusCode int, errs []GraphQLError) error {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	message := strings.Join(messages, ", ")
+
+	for _, e := range errs {
+		if e.isAuth() || operation == "obtainKrakenToken" {
+			return &AuthError{Code: e.Extensions.ErrorCode, Message: message}
+		}
+	}
+
+	apiErr := NewAPIError(statusCode, endpoint, message, nil)
+	apiErr.Code = errs[0].Extensions.ErrorCode
+	if apiErr.Code == OctopusErrorCodeTooManyRequests {
+		apiErr.Retryable = true
+	}
+	if sessionOperation, ok := graphQLSessionOperations[operation]; ok {
+		return &SessionError{Operation: sessionOperation, Err: apiErr}
+	}
+	return apiErr
+}
+
+// decodeGraphQL reads a GraphQL response into data, a pointer to the
+// response's data struct, and returns its errors typed by graphQLErrorFor.
+// Errors confined to the top-level fields named in optional are logged and
+// the rest of the response is used, as long as something came back. Every
+// error returned is counted in the API metrics by class.
+func (c *OctopusClient) decodeGraphQL(resp *http.Response, operation string, data interface{}, optional ...string) error {
+	err := c.decodeGraphQLResponse(resp, operation, data, optional)
+	if err != nil {
+		c.metrics.recordError(errorClass(err))
+	}
+	return err
+}
+
+func (c *OctopusClient) decodeGraphQLResponse(resp *http.Response, operation string, data interface{}, optional []string) error {
+	endpoint := "graphql"
+	if resp.Request != nil {
+		endpoint = c.endpointLabel(resp.Request)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return NewAPIError(resp.StatusCode, endpoint, "failed to read response", err)
+	}
+
+	var envelope graphQLResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			c.debugLog("%s request failed body: %s", operation, string(body))
+			return NewAPIError(resp.StatusCode, endpoint, fmt.Sprintf("%s request failed", operation), nil)
+		}
+		return NewAPIError(resp.StatusCode, endpoint, fmt.Sprintf("failed to decode %s response", operation), err)
+	}
+
+	if len(envelope.Errors) > 0 {
+		c.debugLog("%s GraphQL errors: %s", operation, string(body))
+		if !envelope.partial(optional) {
+			return graphQLErrorFor(endpoint, operation, resp.StatusCode, envelope.Errors)
+		}
+		for _, e := range envelope.Errors {
+			c.debugLog("Using partial %s response without %s: %s", operation, e.field(), e.Message)
+		}
+	} else if resp.StatusCode != http.StatusOK {
+		c.debugLog("%s request failed body: %s", operation, string(body))
+		return NewAPIError(resp.StatusCode, endpoint, fmt.Sprintf("%s request failed", operation), nil)
+	}
+
+	if len(envelope.Data) == 0 || bytes.Equal(envelope.Data, []byte("null")) {
+		return NewAPIError(resp.StatusCode, endpoint, fmt.Sprintf("%s response has no data", operation), nil)
+	}
+	if err := json.Unmarshal(envelope.Data, data); err != nil {
+		return NewAPIError(resp.StatusCode, endpoint, fmt.Sprintf("failed to decode %s response", operation), err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// graphQLTestResponse wraps a body as if it came back from Kraken
+func graphQLTestResponse(statusCode int, body string) *http.Response {
+	return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestGraphQLErrorFor(t *testing.T) {
+	expired := GraphQLError{Message: "Signature of the JWT has expired."}
+	expired.Extensions.ErrorCode = OctopusErrorCodeJWTExpired
+	noSpins := GraphQLError{Message: "No spins remaining."}
+	noSpins.Extensions.ErrorCode = "KT-CT-9801"
+	limited := GraphQLError{Message: "Too many requests."}
+	limited.Extensions.ErrorCode = OctopusErrorCodeTooManyRequests
+
+	var authErr *AuthError
+	if err := graphQLErrorFor("graphql", "getAccountInfo", 200, []GraphQLError{expired}); !errors.As(err, &authErr) || authErr.Code != OctopusErrorCodeJWTExpired {
+		t.Errorf("Expected an AuthError with the Kraken code, got %v", err)
+	}
+	if err := graphQLErrorFor("graphql", "obtainKrakenToken", 200, []GraphQLError{{Message: "Invalid data in the refresh token."}}); !errors.As(err, &authErr) {
+		t.Errorf("Expected any token exchange failure to be an AuthError, got %v", err)
+	}
+
+	var sessionErr *SessionError
+	var apiErr *APIError
+	err := graphQLErrorFor("backend-graphql", "spinWheelOfFortune", 200, []GraphQLError{noSpins})
+	if !errors.As(err, &sessionErr) || sessionErr.Operation != "spin" || !errors.As(err, &apiErr) || apiErr.Code != "KT-CT-9801" {
+		t.Errorf("Expected a SessionError wrapping the APIError, got %v", err)
+	}
+
+	err = graphQLErrorFor("graphql", "getMeasurements", 200, []GraphQLError{limited, noSpins})
+	if errors.As(err, &sessionErr) || !errors.As(err, &apiErr) || !apiErr.Retryable || !strings.Contains(apiErr.Message, "Too many requests., No spins remaining.") {
+		t.Errorf("Expected a retryable APIError with every message, got %v", err)
+	}
+}
+
+func TestDecodeGraphQL(t *testing.T) {
+	client := NewOctopusClient("A-TEST", "sk_live_test", false)
+	var data struct {
+		ElectricitySpins *struct {
+			SpinsAllowed int `json:"spinsAllowed"`
+		} `json:"electricitySpins"`
+		GasSpins *struct {
+			SpinsAllowed int `json:"spinsAllowed"`
+		} `json:"gasSpins"`
+	}
+
+	// An error on an optional field leaves the rest of the response usable
+	partial := `{"data":{"electricitySpins":{"spinsAllowed":2},"gasSpins":null},"errors":[{"message":"No gas meter.","path":["gasSpins"],"extensions":{"errorCode":"KT-CT-4178"}}]}`
+	if err := client.decodeGraphQL(graphQLTestResponse(200, partial), "getWheelOfFortuneSpinsAllowed", &data, "electricitySpins", "gasSpins"); err != nil || data.ElectricitySpins.SpinsAllowed != 2 || data.GasSpins != nil {
+		t.Errorf("Expected the electricity spins from a partial response, got %+v (%v)", data, err)
+	}
+	var apiErr *APIError
+	if err := client.decodeGraphQL(graphQLTestResponse(200, partial), "getWheelOfFortuneSpinsAllowed", &data, "electricitySpins"); !errors.As(err, &apiErr) {
+		t.Errorf("Expected an error on a required field to fail, got %v", err)
+	}
+
+	// Nothing came back, so the errors still fail the request
+	empty := `{"data":{"electricitySpins":null,"gasSpins":null},"errors":[{"message":"Broken.","path":["gasSpins"]},{"message":"Broken.","path":["electricitySpins"]}]}`
+	if err := client.decodeGraphQL(graphQLTestResponse(200, empty), "getWheelOfFortuneSpinsAllowed", &data, "electricitySpins", "gasSpins"); err == nil {
+		t.Error("Expected a response with no data to fail")
+	}
+
+	// Authentication errors are never treated as partial
+	auth := `{"data":{"electricitySpins":{"spinsAllowed":2}},"errors":[{"message":"Unauthorized.","path":["gasSpins"],"extensions":{"errorCode":"KT-CT-4123"}}]}`
+	var authErr *AuthError
+	if err := client.decodeGraphQL(graphQLTestResponse(200, auth), "getWheelOfFortuneSpinsAllowed", &data, "gasSpins"); !errors.As(err, &authErr) {
+		t.Errorf("Expected an AuthError, got %v", err)
+	}
+
+	if err := client.decodeGraphQL(graphQLTestResponse(502, "Bad Gateway"), "getAccountInfo", &data); !errors.As(err, &apiErr) || apiErr.StatusCode != 502 || !apiErr.Retryable {
+		t.Errorf("Expected a retryable APIError for a 502, got %v", err)
+	}
+
+	errorCounts := client.metrics.Snapshot().Errors
+	if errorCounts["api"] != 3 || errorCounts["auth"] != 1 {
+		t.Errorf("Expected 3 API errors and 1 auth error counted, got %v", errorCounts)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{&SessionError{Operation: "spin", Err: NewAPIError(200, "graphql", "refused", nil)}, "session"},
+		{&AuthError{Code: OctopusErrorCodeJWTExpired}, "auth"},
+		{NewAPIError(500, "graphql", "server error", nil), "api"},
+		{&CircuitOpenError{Host: "api.octopus.energy"}, "circuit_open"},
+		{errors.New("connection reset"), "other"},
+	}
+
+	for _, test := range tests {
+		if got := errorClass(test.err); got != test.class {
+			t.Errorf("%v: expected %s, got %s", test.err, test.class, got)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SessionRecord is the history of one saving session, from when octojoin
+// first saw it to the points it earned
+type SessionRecord struct {
+	EventID       int                 `json:"event_id"`
+	StartAt       time.Time           `json:"start_at"`
+	EndAt         time.Time           `json:"end_at"`
+	PointsPerKWh  int                 `json:"points_per_kwh"` // reward offered per kWh saved
+	AnnouncedAt   time.Time           `json:"announced_at"`   // when the monitor first saw the session
+	Decision      *JoinDecision       `json:"decision,omitempty"`
+	Joined        bool                `json:"joined"`
+	JoinedAt      *time.Time          `json:"joined_at,omitempty"` // only set when octojoin did the joining
+	JoinError     string              `json:"join_error,omitempty"`
+	AwardedPoints *int                `json:"awarded_points,omitempty"` // once Octopus has settled the session
+	Performance   *SessionPerformance `json:"performance,omitempty"`    // once smart meter readings for the session arrive
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// Status summarises the record for display
+func (r *SessionRecord) Status(now time.Time) string {
+	switch {
+	case r.AwardedPoints != nil:
+		return "awarded"
+	case r.Joined && r.EndAt.After(now):
+		return "joined"
+	case r.Joined:
+		return "awaiting_award"
+	case r.JoinError != "":
+		return "join_failed"
+	case r.Decision != nil && !r.Decision.Join:
+		return "skipped"
+	default:
+		return "missed"
+	}
+}
+
+// RecordSession applies fn to the history record for eventID, creating it if
+// needed. The record is copied before fn runs, so snapshots already handed out
+// never change underneath their readers.
+func (s *AppState) RecordSession(eventID int, fn func(r *SessionRecord)) {
+	s.Update(func(s *AppState) {
+		if s.SessionHistory == nil {
+			s.SessionHistory = make(map[int]*SessionRecord)
+		}
+		record := &SessionRecord{EventID: eventID, AnnouncedAt: time.Now()}
+		if existing, ok := s.SessionHistory[eventID]; ok {
+			copied := *existing
+			record = &copied
+		}
+		fn(record)
+		record.UpdatedAt = time.Now()
+		s.SessionHistory[eventID] = record
+	})
+}
+
+// sessionSeason names the saving sessions season a time falls in. Seasons run
+// over the winter, so anything from August onwards counts towards the next one.
+func sessionSeason(t time.Time) string {
+	if ukLocation, err := time.LoadLocation("Europe/London"); err == nil {
+		t = t.In(ukLocation)
+	}
+	year := t.Year()
+	if t.Month() < time.August {
+		year--
+	}
+	return fmt.Sprintf("%d/%02d", year, (year+1)%100)
+}
+
+// SeasonSummary totals the session history for one season
+type SeasonSummary struct {
+	Season          string `json:"season"`
+	Sessions        int    `json:"sessions"`
+	Joined          int    `json:"joined"`
+	Skipped         int    `json:"skipped"`
+	Failed          int    `json:"failed"`
+	Missed          int    `json:"missed"`
+	AwardedPoints   int    `json:"awarded_points"`
+	EstimatedPoints int    `json:"estimated_points"` // from the performance of joined sessions, a guide only
+	PendingAwards   int    `json:"pending_awards"`   // joined and finished, but not settled yet
+}
+
+func (s *SeasonSummary) add(record *SessionRecord, now time.Time) {
+	s.Sessions++
+	if record.Joined && record.Performance != nil {
+		s.EstimatedPoints += record.Performance.EstimatedPoints
+	}
+	switch record.Status(now) {
+	case "awarded":
+		s.Joined++
+		s.AwardedPoints += *record.AwardedPoints
+	case "joined":
+		s.Joined++
+	case "awaiting_award":
+		s.Joined++
+		s.PendingAwards++
+	case "join_failed":
+		s.Failed++
+	case "skipped":
+		s.Skipped++
+	default:
+		s.Missed++
+	}
+}
+
+// SessionHistory is the /api/history/sessions response
+type SessionHistory struct {
+	Sessions []*SessionRecord `json:"sessions"`
+	Seasons  []SeasonSummary  `json:"seasons"`
+	Totals   SeasonSummary    `json:"totals"`
+}
+
+// buildSessionHistory sorts records newest first and totals them per season.
+// If season is set only that season's sessions are listed; the per-season
+// totals always cover everything.
+func buildSessionHistory(records map[int]*SessionRecord, season string, now time.Time) SessionHistory {
+	history := SessionHistory{
+		Sessions: []*SessionRecord{},
+		Seasons:  []SeasonSummary{},
+		Totals:   SeasonSummary{Season: "all"},
+	}
+
+	seasons := make(map[string]*SeasonSummary)
+	for _, record := range records {
+		name := sessionSeason(record.StartAt)
+		summary, ok := seasons[name]
+		if !ok {
+			summary = &SeasonSummary{Season: name}
+			seasons[name] = summary
+		}
+		summary.add(record, now)
+		history.Totals.add(record, now)
+
+		if season == "" || season == name {
+			history.Sessions = append(history.Sessions, record)
+		}
+	}
+
+	sort.Slice(history.Sessions, func(i, j int) bool {
+		return history.Sessions[i].StartAt.After(history.Sessions[j].StartAt)
+	})
+	for _, summary := range seasons {
+		history.Seasons = append(history.Seasons, *summary)
+	}
+	sort.Slice(history.Seasons, func(i, j int) bool {
+		return history.Seasons[i].Season > history.Seasons[j].Season
+	})
+	return history
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionSeason(t *testing.T) {
+	testCases := []struct {
+		name     string
+		time     time.Time
+		expected string
+	}{
+		{"winter", time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC), "2024/25"},
+		{"end of july", time.Date(2025, 7, 31, 12, 0, 0, 0, time.UTC), "2024/25"},
+		{"august", time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC), "2025/26"},
+		{"uk midnight", time.Date(2025, 7, 31, 23, 30, 0, 0, time.UTC), "2025/26"}, // 00:30 BST on 1st August
+		{"century", time.Date(2099, 11, 1, 17, 0, 0, 0, time.UTC), "2099/00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sessionSeason(tc.time); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSessionRecordStatus(t *testing.T) {
+	now := time.Now()
+	awarded := 500
+	testCases := []struct {
+		name     string
+		record   SessionRecord
+		expected string
+	}{
+		{"awarded", SessionRecord{Joined: true, EndAt: now.Add(-time.Hour), AwardedPoints: &awarded}, "awarded"},
+		{"upcoming", SessionRecord{Joined: true, EndAt: now.Add(time.Hour)}, "joined"},
+		{"ended", SessionRecord{Joined: true, EndAt: now.Add(-time.Hour)}, "awaiting_award"},
+		{"failed", SessionRecord{JoinError: "boom", Decision: &JoinDecision{Join: true}}, "join_failed"},
+		{"skipped", SessionRecord{Decision: &JoinDecision{Join: false}}, "skipped"},
+		{"missed", SessionRecord{EndAt: now.Add(-time.Hour)}, "missed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.record.Status(now); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSessionHistory(t *testing.T) {
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+	awarded := 1500
+	session := func(id int, start time.Time, perKWh int, fn func(r *SessionRecord)) *SessionRecord {
+		record := &SessionRecord{EventID: id, StartAt: start, EndAt: start.Add(time.Hour), PointsPerKWh: perKWh}
+		if fn != nil {
+			fn(record)
+		}
+		return record
+	}
+	records := map[int]*SessionRecord{
+		1: session(1, time.Date(2024, 12, 3, 17, 0, 0, 0, time.UTC), 1000, func(r *SessionRecord) {
+			r.Joined = true
+			r.AwardedPoints = &awarded
+			r.Performance = &SessionPerformance{EstimatedPoints: 1400}
+		}),
+		2: session(2, time.Date(2025, 1, 8, 17, 0, 0, 0, time.UTC), 800, nil),
+		3: session(3, time.Date(2025, 11, 5, 17, 0, 0, 0, time.UTC), 1200, func(r *SessionRecord) {
+			r.Joined = true
+			r.Performance = &SessionPerformance{EstimatedPoints: 900}
+		}),
+		4: session(4, time.Date(2026, 1, 25, 17, 0, 0, 0, time.UTC), 900, func(r *SessionRecord) { r.Decision = &JoinDecision{Join: false} }),
+	}
+
+	history := buildSessionHistory(records, "", now)
+	if len(history.Sessions) != 4 || history.Sessions[0].EventID != 4 || history.Sessions[3].EventID != 1 {
+		t.Errorf("Expected sessions newest first, got %v", history.Sessions)
+	}
+	if len(history.Seasons) != 2 || history.Seasons[0].Season != "2025/26" || history.Seasons[1].Season != "2024/25" {
+		t.Fatalf("Expected seasons 2025/26 and 2024/25, got %+v", history.Seasons)
+	}
+
+	current := history.Seasons[0]
+	if current.Sessions != 2 || current.Joined != 1 || current.Skipped != 1 || current.PendingAwards != 1 || current.EstimatedPoints != 900 {
+		t.Errorf("Unexpected 2025/26 summary: %+v", current)
+	}
+	previous := history.Seasons[1]
+	if previous.Joined != 1 || previous.Missed != 1 || previous.AwardedPoints != 1500 || previous.EstimatedPoints != 1400 {
+		t.Errorf("Unexpected 2024/25 summary: %+v", previous)
+	}
+	if history.Totals.Sessions != 4 || history.Totals.Joined != 2 || history.Totals.AwardedPoints != 1500 {
+		t.Errorf("Unexpected totals: %+v", history.Totals)
+	}
+
+	// Filtering by season only narrows the session list
+	filtered := buildSessionHistory(records, "2024/25", now)
+	if len(filtered.Sessions) != 2 || len(filtered.Seasons) != 2 || filtered.Totals.Sessions != 4 {
+		t.Errorf("Expected 2 sessions with unfiltered totals, got %d sessions and %+v", len(filtered.Sessions), filtered.Totals)
+	}
+}
+
+func TestRecordSessionCopiesOnWrite(t *testing.T) {
+	state := &AppState{}
+	state.RecordSession(1, func(r *SessionRecord) { r.PointsPerKWh = 1000 })
+
+	before := state.Snapshot().SessionHistory[1]
+	if before.AnnouncedAt.IsZero() || before.PointsPerKWh != 1000 {
+		t.Fatalf("Expected a new record with its announce time, got %+v", before)
+	}
+
+	state.RecordSession(1, func(r *SessionRecord) { r.Joined = true })
+	after := state.Snapshot().SessionHistory[1]
+	if before.Joined {
+		t.Error("Expected earlier snapshots to be left unchanged")
+	}
+	if !after.Joined || after.PointsPerKWh != 1000 || !after.AnnouncedAt.Equal(before.AnnouncedAt) {
+		t.Errorf("Expected the update to keep earlier fields, got %+v", after)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTTConfig configures publishing to an MQTT broker with Home Assistant discovery
+type MQTTConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Broker           string `yaml:"broker"` // tcp://host:1883 or ssl://host:8883
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	ClientID         string `yaml:"client_id"`
+	TopicPrefix      string `yaml:"topic_prefix"`
+	DiscoveryPrefix  string `yaml:"discovery_prefix"`
+	DisableDiscovery bool   `yaml:"disable_discovery"`
+}
+
+func (c *MQTTConfig) validate() []string {
+	var problems []string
+	if !c.Enabled {
+		return problems
+	}
+	if c.Broker == "" {
+		problems = append(problems, "broker is required when MQTT is enabled")
+	} else if _, _, _, err := parseMQTTBroker(c.Broker); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if strings.ContainsAny(c.TopicPrefix, "#+") || strings.ContainsAny(c.DiscoveryPrefix, "#+") {
+		problems = append(problems, "topic prefixes cannot contain MQTT wildcards")
+	}
+	return problems
+}
+
+// MQTTPublisher mirrors the monitor's view of the account to MQTT as retained
+// state, announces Home Assistant entities and turns command topics into
+// monitor commands
+type MQTTPublisher struct {
+	config  MQTTConfig
+	monitor *SavingSessionMonitor
+	logger  *Logger
+
+	mu        sync.Mutex
+	client    *mqttClient
+	lastState []byte
+}
+
+func NewMQTTPublisher(cfg *MQTTConfig, monitor *SavingSessionMonitor) *MQTTPublisher {
+	config := *cfg
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = "octojoin"
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = "homeassistant"
+	}
+	if config.ClientID == "" {
+		config.ClientID = "octojoin-" + strings.ToLower(monitor.accountID)
+	}
+
+	return &MQTTPublisher{
+		config:  config,
+		monitor: monitor,
+		logger:  NewLogger(monitor.client.debug).WithComponent("mqtt").WithAccountID(monitor.accountID),
+	}
+}
+
+// topic returns an account-scoped topic under the configured prefix
+func (p *MQTTPublisher) topic(parts ...string) string {
+	return strings.Join(append([]string{p.config.TopicPrefix, p.monitor.accountID}, parts...), "/")
+}
+
+// Run keeps a broker connection open until the context is cancelled,
+// reconnecting with exponential backoff
+func (p *MQTTPublisher) Run(ctx context.Context) {
+	delay := MQTTReconnectMinDelay
+
+	for {
+		connectedAt := time.Now()
+		err := p.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that stayed up for a while resets the backoff
+		if time.Since(connectedAt) > MQTTReconnectMaxDelay {
+			delay = MQTTReconnectMinDelay
+		}
+		p.logger.Warn("MQTT connection ended, reconnecting", "error", err.Error(), "retry_in", delay.String())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > MQTTReconnectMaxDelay {
+			delay = MQTTReconnectMaxDelay
+		}
+	}
+}
+
+// session runs a single broker connection from connect to disconnect
+func (p *MQTTPublisher) session(ctx context.Context) error {
+	client, err := dialMQTT(ctx, mqttOptions{
+		Broker:      p.config.Broker,
+		ClientID:    p.config.ClientID,
+		Username:    p.config.Username,
+		Password:    p.config.Password,
+		KeepAlive:   MQTTKeepAlive,
+		WillTopic:   p.topic("status"),
+		WillPayload: []byte("offline"),
+		WillRetain:  true,
+	})
+	if err != nil {
+		return err
+	}
+	client.onMessage = p.handleMessage
+	p.logger.Info("Connected to MQTT broker", "broker", p.config.Broker)
+
+	if err := p.announce(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	p.mu.Lock()
+	p.client = client
+	state := p.lastState
+	p.mu.Unlock()
+
+	// Replay the latest state so a reconnect doesn't wait for the next check
+	if state != nil {
+		client.Publish(p.topic("state"), state, true)
+	}
+
+	err = client.Run(ctx)
+
+	p.mu.Lock()
+	p.client = nil
+	p.mu.Unlock()
+
+	return err
+}
+
+// announce publishes availability and discovery configs and subscribes to commands
+func (p *MQTTPublisher) announce(client *mqttClient) error {
+	if !p.config.DisableDiscovery {
+		for topic, payload := range p.discoveryConfigs() {
+			if err := client.Publish(topic, payload, true); err != nil {
+				return fmt.Errorf("failed to publish discovery config: %w", err)
+			}
+		}
+	}
+
+	if err := client.Subscribe(p.topic("command", "+")); err != nil {
+		return fmt.Errorf("failed to subscribe to command topics: %w", err)
+	}
+
+	if err := client.Publish(p.topic("status"), []byte("online"), true); err != nil {
+		return fmt.Errorf("failed to publish availability: %w", err)
+	}
+	return nil
+}
+
+// PublishState publishes the account view as retained JSON. It is safe to call
+// while disconnected; the latest state is replayed on reconnect.
+func (p *MQTTPublisher) PublishState(data SessionData) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		p.logger.Error("Failed to encode MQTT state", "error", err.Error())
+		return
+	}
+
+	p.mu.Lock()
+	p.lastState = payload
+	client := p.client
+	p.mu.Unlock()
+
+	if client == nil {
+		return
+	}
+	if err := client.Publish(p.topic("state"), payload, true); err != nil {
+		p.logger.Warn("Failed to publish MQTT state", "error", err.Error())
+	}
+}
+
+// handleMessage maps command topics to monitor commands
+func (p *MQTTPublisher) handleMessage(topic string, payload []byte) {
+	name := strings.TrimPrefix(topic, p.topic("command")+"/")
+	if name == topic {
+		return
+	}
+
+	cmd := MonitorCommand{}
+	switch name {
+	case "check":
+		cmd.Kind = CommandCheck
+	case "spin":
+		cmd.Kind = CommandSpinWheels
+	case "join":
+		cmd.Kind = CommandJoinSession
+		// Payload is an event ID, or empty/"next" for the next unjoined session
+		arg := strings.TrimSpace(string(payload))
+		if arg != "" && !strings.EqualFold(arg, "next") && !strings.EqualFold(arg, "press") {
+			id, err := strconv.Atoi(arg)
+			if err != nil || id <= 0 {
+				p.logger.Warn("Ignoring join command with invalid event ID", "payload", arg)
+				return
+			}
+			cmd.EventID = id
+		}
+	default:
+		p.logger.Warn("Ignoring unknown MQTT command", "topic", topic)
+		return
+	}
+
+	if err := p.monitor.SubmitCommand(cmd); err != nil {
+		p.logger.Warn("Failed to queue MQTT command", "error", err.Error())
+	}
+}
+
+// haEntity describes one Home Assistant entity announced via MQTT discovery
+type haEntity struct {
+	component string
+	key       string
+	config    map[string]interface{}
+}
+
+// discoveryConfigs returns the retained discovery payloads keyed by topic
+func (p *MQTTPublisher) discoveryConfigs() map[string][]byte {
+	nodeID := strings.ToLower(strings.ReplaceAll(p.monitor.accountID, "-", "_"))
+	device := map[string]interface{}{
+		"identifiers":  []string{"octojoin_" + nodeID},
+		"name":         "Octopus Energy " + p.monitor.accountID,
+		"manufacturer": "Octopus Energy",
+		"model":        "octojoin",
+	}
+
+	entities := []haEntity{
+		{"sensor", "octopoints", map[string]interface{}{
+			"name":                "OctoPoints",
+			"icon":                "mdi:star-circle",
+			"state_class":         "measurement",
+			"unit_of_measurement": "points",
+			"value_template":      "{{ value_json.current_points }}",
+		}},
+		{"sensor", "account_balance", map[string]interface{}{
+			"name":                "Account balance",
+			"device_class":        "monetary",
+			"unit_of_measurement": "GBP",
+			"value_template":      "{{ value_json.account_balance }}",
+		}},
+		{"sensor", "wheel_spins", map[string]interface{}{
+			"name":           "Wheel of Fortune spins",
+			"icon":           "mdi:ferris-wheel",
+			"value_template": "{{ (value_json.wheel_of_fortune_spins.electricity_spins | int(0)) + (value_json.wheel_of_fortune_spins.gas_spins | int(0)) }}",
+		}},
+		{"sensor", "next_saving_session", map[string]interface{}{
+			"name":                     "Next saving session",
+			"device_class":             "timestamp",
+			"value_template":           "{{ value_json.next_saving_session.startAt if value_json.next_saving_session else None }}",
+			"json_attributes_topic":    p.topic("state"),
+			"json_attributes_template": "{{ (value_json.next_saving_session or {}) | tojson }}",
+		}},
+		{"sensor", "next_free_electricity_session", map[string]interface{}{
+			"name":                     "Next free electricity session",
+			"device_class":             "timestamp",
+			"value_template":           "{{ value_json.next_free_electricity_session.start if value_json.next_free_electricity_session else None }}",
+			"json_attributes_topic":    p.topic("state"),
+			"json_attributes_template": "{{ (value_json.next_free_electricity_session or {}) | tojson }}",
+		}},
+		{"button", "check", map[string]interface{}{
+			"name":          "Check now",
+			"icon":          "mdi:refresh",
+			"command_topic": p.topic("command", "check"),
+		}},
+		{"button", "spin_wheels", map[string]interface{}{
+			"name":          "Spin Wheel of Fortune",
+			"icon":          "mdi:ferris-wheel",
+			"command_topic": p.topic("command", "spin"),
+		}},
+		{"button", "join_next_session", map[string]interface{}{
+			"name":          "Join next saving session",
+			"icon":          "mdi:lightning-bolt",
+			"command_topic": p.topic("command", "join"),
+			"payload_press": "next",
+		}},
+	}
+
+	configs := make(map[string][]byte, len(entities))
+	for _, entity := range entities {
+		uniqueID := "octojoin_" + nodeID + "_" + entity.key
+		entity.config["unique_id"] = uniqueID
+		entity.config["object_id"] = uniqueID
+		entity.config["device"] = device
+		entity.config["availability_topic"] = p.topic("status")
+		if entity.component == "sensor" {
+			entity.config["state_topic"] = p.topic("state")
+		}
+
+		payload, err := json.Marshal(entity.config)
+		if err != nil {
+			continue
+		}
+		topic := fmt.Sprintf("%s/%s/octojoin_%s/%s/config", p.config.DiscoveryPrefix, entity.component, nodeID, entity.key)
+		configs[topic] = payload
+	}
+	return configs
+}
@@ -21,6 +21,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -33,8 +34,8 @@ func min(a, b int) int {
 }
 
 func main() {
-	var accountID, apiKey, configPath string
-	var daemon, webUI, debug, showVersion, noSmartIntervals, runTest bool
+	var accountID, apiKey, configPath, demoFixtures string
+	var daemon, webUI, debug, showVersion, noSmartIntervals, runTest, demo bool
 	var minPoints, webPort int
 	
 	flag.StringVar(&configPath, "config", "", "Path to configuration file")
@@ -44,10 +45,12 @@ func main() {
 	flag.BoolVar(&daemon, "daemon", false, "Run in daemon mode (continuous monitoring)")
 	flag.BoolVar(&webUI, "web", false, "Enable web UI dashboard (daemon mode only)")
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
-	flag.IntVar(&minPoints, "min-points", 0, "Minimum points threshold to join a session (0 = join all sessions)")
+	flag.IntVar(&minPoints, "min-points", 0, "Minimum OctoPoints per kWh to join a session (0 = join all sessions)")
 	flag.IntVar(&webPort, "port", 8080, "Web UI port (default: 8080)")
 	flag.BoolVar(&noSmartIntervals, "no-smart-intervals", false, "Disable smart interval adjustment (use fixed intervals)")
 	flag.BoolVar(&runTest, "test", false, "Run compatibility test to verify OctoJoin requirements and exit")
+	flag.BoolVar(&demo, "demo", false, "Run the daemon and web UI against a built-in fake Octopus API (no credentials needed)")
+	flag.StringVar(&demoFixtures, "demo-fixtures", "", "YAML file scripting the demo account (with -demo)")
 	flag.Parse()
 
 	// Handle version flag
@@ -87,6 +90,29 @@ func main() {
 		webPort = config.WebPort
 	}
 
+	// Demo mode serves a scripted account from an in-process fake API
+	var fakeKraken *FakeKraken
+	if demo {
+		fixtures := DefaultKrakenFixtures()
+		if demoFixtures != "" {
+			if fixtures, err = LoadKrakenFixtures(demoFixtures); err != nil {
+				log.Fatal(err)
+			}
+		}
+		fakeKraken = NewFakeKraken(fixtures, debug)
+		if err := fakeKraken.Start("127.0.0.1:0"); err != nil {
+			log.Fatal(err)
+		}
+		defer fakeKraken.Close()
+		fakeKraken.UseEndpoints()
+
+		accountID = fixtures.AccountID
+		apiKey = DemoAPIKey
+		config.Accounts = nil // the fake API serves a single account
+		daemon = true
+		webUI = true
+	}
+
 	// Update config with final values for validation
 	config.AccountID = accountID
 	config.APIKey = apiKey
@@ -100,7 +126,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if accountID == "" || apiKey == "" {
+	if len(config.Accounts) == 0 && (accountID == "" || apiKey == "") {
 		fmt.Fprintf(os.Stderr, "Usage: %s -account=<account_id> -key=<api_key>\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Or set environment variables: OCTOPUS_ACCOUNT_ID and OCTOPUS_API_KEY\n")
 		fmt.Fprintf(os.Stderr, "Or use a configuration file with -config=<path>\n")
@@ -108,29 +134,42 @@ func main() {
 		os.Exit(1)
 	}
 
+	accounts := config.AccountConfigs()
+
 	// Initialize logger for main application
 	logger := NewLogger(debug).WithComponent("main")
 
 	logger.Info("Starting Octopus Energy Saving Session Monitor",
 		"version", GetVersion(),
 	)
+	if demo {
+		logger.Info("Demo mode - using fake Octopus API", "url", fakeKraken.URL, "account_id", accountID)
+	}
 	logger.Debug("Configuration",
-		"account_id_prefix", accountID[:min(5, len(accountID))],
+		"account_id_prefix", accounts[0].AccountID[:min(5, len(accounts[0].AccountID))],
+		"accounts", len(accounts),
 		"daemon_mode", daemon,
 		"web_ui", webUI,
 		"min_points", minPoints,
 	)
 
-	// Initialize API client
-	client := NewOctopusClient(accountID, apiKey, debug)
+	// Initialize an API client per account
+	clients := make([]*OctopusClient, len(accounts))
+	for i, account := range accounts {
+		clients[i] = NewOctopusClient(account.AccountID, account.APIKey, debug)
+		if config.GasCalorificValue > 0 {
+			clients[i].SetGasCalorificValue(config.GasCalorificValue)
+		}
+	}
 	
-	// Handle compatibility testing flag
+	// Handle compatibility testing flag (tests the first account)
 	if runTest {
+		client := clients[0]
 		fmt.Println("🔍 Running OctoJoin Compatibility Test...")
 		fmt.Println("===========================================")
 		
 		// Initialize state for caching
-		monitor := NewSavingSessionMonitor(client, accountID)
+		monitor := NewSavingSessionMonitor(client, accounts[0].AccountID)
 		testPassed := true
 		
 		// Test 1: Basic API connectivity and account info
@@ -155,6 +194,7 @@ func main() {
 			fmt.Printf("✅ Saving Sessions API accessible")
 			fmt.Printf("   Current OctoPoints: %d", sessions.Data.OctoPoints.Account.CurrentPointsInWallet)
 			fmt.Printf("   Joined sessions: %d", len(sessions.Data.SavingSessions.Account.JoinedEvents))
+			fmt.Printf("   Unjoined sessions available: %d", len(sessions.UnjoinedEvents()))
 			fmt.Printf("   Campaign enrolled: %t", sessions.Data.SavingSessions.Account.HasJoinedCampaign)
 			
 			if !sessions.Data.SavingSessions.Account.HasJoinedCampaign {
@@ -211,6 +251,13 @@ func main() {
 			if len(devices) == 0 {
 				fmt.Printf("⚠️  Warning: No smart meter devices found - usage graphs will not work")
 			}
+
+			if gasDevices, err := client.getGasMeterDevicesWithCache(monitor.state); err == nil {
+				fmt.Printf("✅ Found %d GSME (gas smart meter) devices:", len(gasDevices))
+				for i, device := range gasDevices {
+					fmt.Printf("   %d. %s", i+1, device)
+				}
+			}
 		}
 		
 		// Test 6: Usage measurements (if smart meter available)
@@ -247,6 +294,21 @@ func main() {
 			fmt.Printf("   Gas spins: %d", spins.GasSpins)
 		}
 		
+		// Test 8: Tariffs (informational, the daemon works without them)
+		fmt.Println("\n8️⃣  Testing tariff discovery...")
+		tariffs, err := client.getTariffs(time.Now())
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Failed to read tariffs - /api/tariff and rate metrics will be empty: %v\n", err)
+		} else {
+			fmt.Printf("✅ Found %d current tariffs:\n", len(tariffs))
+			for i, tariff := range tariffs {
+				fmt.Printf("   %d. %s %s on %s\n", i+1, strings.ToLower(tariff.Fuel), tariff.TariffCode, tariff.MeterPoint)
+				for _, rate := range tariff.CurrentUnitRates(time.Now()) {
+					fmt.Printf("      Unit rate: %.2fp/kWh\n", rate.ValueIncVAT)
+				}
+			}
+		}
+		
 		// Final results
 		fmt.Println("\n===========================================")
 		if testPassed {
@@ -261,32 +323,111 @@ func main() {
 		return
 	}
 	
-	// Check for updates in background (non-blocking)
-	go PrintUpdateNotification()
-
-	// Initialize monitor
-	monitor := NewSavingSessionMonitor(client, accountID)
-	monitor.SetMinPointsThreshold(minPoints)
+	// Check for updates in background (non-blocking); demo mode stays offline.
+	// The check shares the first account's transport and API metrics.
+	if !demo {
+		go PrintUpdateNotification(clients[0].client)
+	}
 
-	// Configure smart intervals (command line flag takes precedence over config)
+	// Initialize a monitor per account, each with its own join policy and notifications
 	disableSmartIntervals := noSmartIntervals || config.NoSmartIntervals
-	monitor.SetSmartIntervals(!disableSmartIntervals)
-	
-	// Set custom check interval if specified in config
+	monitors := make([]*SavingSessionMonitor, len(accounts))
+	for i, account := range accounts {
+		monitor := NewSavingSessionMonitor(clients[i], account.AccountID)
+		monitor.SetName(account.Name)
+		monitor.SetMinPointsThreshold(minPoints)
+
+		// Configure the join policy (min_points is kept as an implicit rule)
+		joinPolicy, err := NewJoinPolicy(account.JoinPolicy, minPoints)
+		if err != nil {
+			log.Fatal(err)
+		}
+		monitor.SetJoinPolicy(joinPolicy)
+		if account.JoinPolicy != nil {
+			logger.Info("Join policy loaded", "account_id", account.AccountID, "rules", len(account.JoinPolicy.Rules))
+		}
+
+		// Configure notification backends
+		notifier, err := NewNotificationManager(account.Notifications, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		monitor.SetNotifier(notifier)
+		if notifier.Enabled() {
+			logger.Info("Notifications enabled", "account_id", account.AccountID, "backends", len(account.Notifications.Backends))
+		}
+
+		// Configure Agile price window alerts
+		if config.Agile != nil && config.Agile.Enabled {
+			monitor.SetAgileAlerts(config.Agile)
+		}
+
+		// Configure automatic OctoPoints redemption
+		if config.Redemption != nil && config.Redemption.Enabled {
+			monitor.SetRedemptionPolicy(config.Redemption)
+		}
+
+		// Configure smart intervals (command line flag takes precedence over config)
+		monitor.SetSmartIntervals(!disableSmartIntervals)
+
+		// Set custom check interval if specified in config
+		if config.CheckInterval > 0 && config.CheckInterval != 10 {
+			monitor.SetCheckInterval(time.Duration(config.CheckInterval) * time.Minute)
+		} else if demo {
+			// Check often so scripted announcements appear while watching
+			monitor.SetSmartIntervals(false)
+			monitor.SetCheckInterval(DemoCheckInterval)
+		}
+
+		// Enable MQTT / Home Assistant integration in daemon mode; topics are
+		// already per account, but a fixed client ID must be made unique
+		if config.MQTT != nil && config.MQTT.Enabled && daemon {
+			mqttConfig := *config.MQTT
+			if mqttConfig.ClientID != "" && len(accounts) > 1 {
+				mqttConfig.ClientID += "-" + strings.ToLower(account.AccountID)
+			}
+			monitor.EnableMQTT(&mqttConfig)
+		}
+
+		monitors[i] = monitor
+	}
+
+	if config.Agile != nil && config.Agile.Enabled {
+		logger.Info("Agile price alerts enabled", "alert_on", monitors[0].agile.AlertOn)
+	}
+	if config.Redemption != nil && config.Redemption.Enabled {
+		logger.Info("OctoPoints redemption enabled",
+			"min_balance", monitors[0].redemption.MinBalance,
+			"multiple", monitors[0].redemption.Multiple,
+			"reserve", monitors[0].redemption.Reserve,
+			"dry_run", monitors[0].redemption.DryRun,
+		)
+	}
 	if config.CheckInterval > 0 && config.CheckInterval != 10 {
-		monitor.SetCheckInterval(time.Duration(config.CheckInterval) * time.Minute)
 		logger.Info("Using custom check interval", "interval_minutes", config.CheckInterval)
 	}
 
-	// Enable web UI if requested and in daemon mode
+	// Enable web UI if requested and in daemon mode; one server shows every account
+	var webServer *WebServer
 	if webUI && daemon {
-		monitor.SetDaemonMode(true) // Use structured logging for daemon mode
-		monitor.EnableWebUI(webPort)
+		webServer = NewAccountsWebServer(monitors, webPort)
+		for _, monitor := range monitors {
+			monitor.SetDaemonMode(true) // Use structured logging for daemon mode
+			monitor.SetWebServer(webServer)
+		}
 		logger.Info("Web UI enabled", "url", fmt.Sprintf("http://localhost:%d", webPort))
 	} else if webUI && !daemon {
 		logger.Warn("Web UI can only be enabled in daemon mode")
 	}
 
+	if config.MQTT != nil && config.MQTT.Enabled {
+		if daemon {
+			logger.Info("MQTT enabled", "broker", config.MQTT.Broker)
+		} else {
+			logger.Warn("MQTT can only be enabled in daemon mode")
+		}
+	}
+
 	if minPoints > 0 {
 		logger.Info("Minimum points threshold set", "min_points", minPoints)
 	} else {
@@ -304,18 +445,27 @@ func main() {
 	if daemon {
 		logger.Info("Running in daemon mode - continuous monitoring")
 
-		// Start monitor in goroutine
-		go func() {
-			if err := monitor.StartWithContext(ctx); err != nil && err != context.Canceled {
-				logger.Error("Monitor error", "error", err.Error())
-			}
-		}()
+		// Start the web server and each account's monitor in goroutines
+		if webServer != nil {
+			go func() {
+				if err := webServer.StartWithContext(ctx); err != nil && err != context.Canceled {
+					logger.Error("Web server error", "error", err.Error())
+				}
+			}()
+		}
+		for _, monitor := range monitors {
+			go func() {
+				if err := monitor.StartWithContext(ctx); err != nil && err != context.Canceled {
+					logger.Error("Monitor error", "account_id", monitor.accountID, "error", err.Error())
+				}
+			}()
+		}
 
 		// Wait for shutdown signal
 		sig := <-sigCh
 		logger.Info("Received signal, initiating graceful shutdown", "signal", sig.String())
 
-		// Cancel context to stop monitor
+		// Cancel context to stop the monitors and web server
 		cancel()
 
 		// Give monitor time to finish current operations
@@ -323,6 +473,8 @@ func main() {
 		logger.Info("Shutdown complete")
 	} else {
 		logger.Info("Running in one-shot mode")
-		monitor.CheckOnce()
+		for _, monitor := range monitors {
+			monitor.CheckOnce()
+		}
 	}
 }
\ No newline at end of file
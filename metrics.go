@@ -17,21 +17,100 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// MetricsSnapshot is the account view recorded by the monitor at the end of
+// each cycle. /metrics renders the latest snapshot so scrapes never call the
+// Octopus API themselves.
+type MetricsSnapshot struct {
+	CollectedAt     time.Time
+	AccountInfo     *AccountInfo
+	SavingSessions  *SavingSessionsResponse
+	Campaigns       map[string]bool
+	WheelSpins      *WheelOfFortuneSpins
+	FreeElectricity *FreeElectricitySessionsResponse
+	Properties      []Property         // only collected with the web UI
+	Export          []UsageMeasurement // readings received for the last 24 hours, only collected with the web UI
+	Tariffs         []Tariff           // only collected with the web UI
+	Dispatches      []Dispatch         // only on Intelligent Octopus tariffs
+	State           *AppState // Copy of the state for bookkeeping and cache ages
+}
+
+// recordMetricsSnapshot captures the account view for /metrics. The getters
+// normally answer from the caches the cycle has just refreshed; anything that
+// fails is left out of the snapshot rather than reported as zero.
+func (m *SavingSessionMonitor) recordMetricsSnapshot() {
+	snapshot := &MetricsSnapshot{
+		CollectedAt: time.Now(),
+		State:       m.state.Snapshot(),
+	}
+
+	if accountInfo, err := m.client.getAccountInfoWithCache(m.state); err == nil {
+		snapshot.AccountInfo = accountInfo
+	}
+	if sessions, err := m.client.GetSavingSessionsWithCache(m.state); err == nil {
+		snapshot.SavingSessions = sessions
+	}
+	if campaigns, err := m.client.getCampaignStatusWithCache(m.state); err == nil {
+		snapshot.Campaigns = campaigns
+	}
+	if spins, err := m.client.getWheelOfFortuneSpinsWithCache(m.state); err == nil {
+		snapshot.WheelSpins = spins
+	}
+	if freeElectricity, err := m.client.GetFreeElectricitySessionsWithCache(m.state); err == nil {
+		snapshot.FreeElectricity = freeElectricity
+	}
+	if dispatches, err := m.intelligentDispatches(); err == nil {
+		snapshot.Dispatches = dispatches
+	}
+	if m.webServer != nil {
+		if properties, err := m.client.getPropertiesWithCache(m.state); err == nil {
+			snapshot.Properties = properties
+		}
+		if export, err := m.client.getExportMeasurementsWithCache(m.state, 1); err == nil {
+			snapshot.Export = export
+		}
+		if tariffs, err := m.client.getTariffsWithCache(m.state); err == nil {
+			snapshot.Tariffs = tariffs
+		}
+	}
+
+	m.metricsSnapshot.Store(snapshot)
+}
+
+// MetricsSnapshot returns the latest snapshot, or nil before the first cycle
+func (m *SavingSessionMonitor) MetricsSnapshot() *MetricsSnapshot {
+	return m.metricsSnapshot.Load()
+}
+
 // MetricsCollector collects and exposes metrics in Prometheus format
 type MetricsCollector struct {
-	client  *OctopusClient
-	monitor *SavingSessionMonitor
+	client   *OctopusClient
+	monitor  *SavingSessionMonitor
+	monitors []*SavingSessionMonitor
+	account  string // added as an account label to every sample when set
 }
 
 // NewMetricsCollector creates a new metrics collector
 func NewMetricsCollector(client *OctopusClient, monitor *SavingSessionMonitor) *MetricsCollector {
 	return &MetricsCollector{
-		client:  client,
-		monitor: monitor,
+		client:   client,
+		monitor:  monitor,
+		monitors: []*SavingSessionMonitor{monitor},
+	}
+}
+
+// NewAccountsMetricsCollector creates a metrics collector covering every
+// account's monitor, labelling each account's samples with its ID
+func NewAccountsMetricsCollector(monitors []*SavingSessionMonitor) *MetricsCollector {
+	return &MetricsCollector{
+		client:   monitors[0].client,
+		monitor:  monitors[0],
+		monitors: monitors,
 	}
 }
 
@@ -43,7 +122,8 @@ func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, metrics)
 }
 
-// collectMetrics gathers all application metrics
+// collectMetrics renders the latest snapshot and API counters of every
+// account. It only reads in-memory data.
 func (m *MetricsCollector) collectMetrics() string {
 	var metrics strings.Builder
 	
@@ -56,70 +136,132 @@ func (m *MetricsCollector) collectMetrics() string {
 	
 	m.writeMetricHeader(&metrics, "octojoin_up", "gauge", "Whether the application is up and running")
 	m.writeMetric(&metrics, "octojoin_up", nil, 1)
-	
-	m.writeMetricHeader(&metrics, "octojoin_last_check_timestamp", "gauge", "Unix timestamp of last successful check")
-	m.writeMetric(&metrics, "octojoin_last_check_timestamp", nil, float64(time.Now().Unix()))
-	
-	// Get account balance (with caching)
-	accountInfo, err := m.client.getAccountInfoWithCache(m.monitor.state)
-	if err == nil && accountInfo != nil {
-		m.writeMetricHeader(&metrics, "octojoin_account_balance_pounds", "gauge", "Account balance in pounds")
-		m.writeMetric(&metrics, "octojoin_account_balance_pounds", nil, accountInfo.Balance)
+
+	accounts := make([]string, 0, len(m.monitors))
+	for _, monitor := range m.monitors {
+		account := &MetricsCollector{client: monitor.client, monitor: monitor, account: monitor.accountID}
+		accounts = append(accounts, account.collectAccountMetrics())
+	}
+	metrics.WriteString(mergeMetricFamilies(accounts))
+
+	return metrics.String()
+}
+
+// collectAccountMetrics renders one account's snapshot and API counters
+func (m *MetricsCollector) collectAccountMetrics() string {
+	var metrics strings.Builder
+
+	snapshot := m.monitor.MetricsSnapshot()
+
+	m.writeMetricHeader(&metrics, "octojoin_last_check_timestamp", "gauge", "Unix timestamp of the last completed check (0 if none yet)")
+	lastCheck := 0.0
+	if snapshot != nil {
+		lastCheck = float64(snapshot.CollectedAt.Unix())
+	}
+	m.writeMetric(&metrics, "octojoin_last_check_timestamp", nil, lastCheck)
+
+	if snapshot != nil {
+		m.writeMetricHeader(&metrics, "octojoin_snapshot_age_seconds", "gauge", "Seconds since the monitor last refreshed the metrics snapshot")
+		m.writeMetric(&metrics, "octojoin_snapshot_age_seconds", nil, time.Since(snapshot.CollectedAt).Seconds())
+		m.writeSnapshotMetrics(&metrics, snapshot)
 	}
 
-	// Get current session data
-	sessions, err := m.client.GetSavingSessionsWithCache(m.monitor.state)
-	if err == nil && sessions != nil {
+	m.writeAPIMetrics(&metrics, m.client.metrics.Snapshot())
+
+	return metrics.String()
+}
+
+// mergeMetricFamilies combines per-account outputs so each metric family is
+// described once, with every account's samples grouped under its header.
+// Families keep the order they were first seen in.
+func mergeMetricFamilies(outputs []string) string {
+	var order []string
+	headers := make(map[string]string)
+	samples := make(map[string][]string)
+
+	for _, output := range outputs {
+		family := ""
+		for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+			if name, ok := strings.CutPrefix(line, "# HELP "); ok {
+				family, _, _ = strings.Cut(name, " ")
+				if _, seen := headers[family]; !seen {
+					order = append(order, family)
+					headers[family] = line + "\n"
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "# TYPE ") {
+				if !strings.Contains(headers[family], "# TYPE ") {
+					headers[family] += line + "\n"
+				}
+				continue
+			}
+			samples[family] = append(samples[family], line)
+		}
+	}
+
+	var merged strings.Builder
+	for _, family := range order {
+		merged.WriteString(headers[family])
+		for _, line := range samples[family] {
+			merged.WriteString(line + "\n")
+		}
+	}
+	return merged.String()
+}
+
+// writeSnapshotMetrics renders the account and state metrics from a snapshot
+func (m *MetricsCollector) writeSnapshotMetrics(metrics *strings.Builder, snapshot *MetricsSnapshot) {
+	if snapshot.AccountInfo != nil {
+		m.writeMetricHeader(metrics, "octojoin_account_balance_pounds", "gauge", "Account balance in pounds")
+		m.writeMetric(metrics, "octojoin_account_balance_pounds", nil, snapshot.AccountInfo.Balance)
+	}
+
+	if sessions := snapshot.SavingSessions; sessions != nil {
 		// OctoPoints metrics
-		m.writeMetricHeader(&metrics, "octojoin_octopoints_total", "gauge", "Total OctoPoints in wallet")
-		m.writeMetric(&metrics, "octojoin_octopoints_total", nil, float64(sessions.Data.OctoPoints.Account.CurrentPointsInWallet))
+		m.writeMetricHeader(metrics, "octojoin_octopoints_total", "gauge", "Total OctoPoints in wallet")
+		m.writeMetric(metrics, "octojoin_octopoints_total", nil, float64(sessions.Data.OctoPoints.Account.CurrentPointsInWallet))
 		
 		// Saving sessions metrics
-		m.writeMetricHeader(&metrics, "octojoin_saving_sessions_total", "gauge", "Total number of joined saving sessions")
-		m.writeMetric(&metrics, "octojoin_saving_sessions_total", nil, float64(len(sessions.Data.SavingSessions.Account.JoinedEvents)))
+		m.writeMetricHeader(metrics, "octojoin_saving_sessions_total", "gauge", "Total number of joined saving sessions")
+		m.writeMetric(metrics, "octojoin_saving_sessions_total", nil, float64(len(sessions.Data.SavingSessions.Account.JoinedEvents)))
 		
 		// Campaign enrollment status
-		m.writeMetricHeader(&metrics, "octojoin_campaign_enrolled", "gauge", "Whether enrolled in saving sessions campaign (1=yes, 0=no)")
+		m.writeMetricHeader(metrics, "octojoin_campaign_enrolled", "gauge", "Whether enrolled in saving sessions campaign (1=yes, 0=no)")
 		enrolled := 0
 		if sessions.Data.SavingSessions.Account.HasJoinedCampaign {
 			enrolled = 1
 		}
-		m.writeMetric(&metrics, "octojoin_campaign_enrolled", nil, float64(enrolled))
+		m.writeMetric(metrics, "octojoin_campaign_enrolled", nil, float64(enrolled))
 	}
 	
-	// Get campaign status
-	campaigns, err := m.client.getCampaignStatusWithCache(m.monitor.state)
-	if err == nil {
-		m.writeMetricHeader(&metrics, "octojoin_campaign_status", "gauge", "Campaign enrollment status by type")
-		for campaign, enrolled := range campaigns {
+	if snapshot.Campaigns != nil {
+		m.writeMetricHeader(metrics, "octojoin_campaign_status", "gauge", "Campaign enrollment status by type")
+		for campaign, enrolled := range snapshot.Campaigns {
 			value := 0
 			if enrolled {
 				value = 1
 			}
-			m.writeMetric(&metrics, "octojoin_campaign_status", map[string]string{
+			m.writeMetric(metrics, "octojoin_campaign_status", map[string]string{
 				"campaign": campaign,
 			}, float64(value))
 		}
 	}
 	
-	// Wheel of Fortune spins (with caching)
-	spins, err := m.client.getWheelOfFortuneSpinsWithCache(m.monitor.state)
-	if err == nil && spins != nil {
-		m.writeMetricHeader(&metrics, "octojoin_wheel_spins_total", "gauge", "Available Wheel of Fortune spins by fuel type")
-		m.writeMetric(&metrics, "octojoin_wheel_spins_total", map[string]string{
+	if spins := snapshot.WheelSpins; spins != nil {
+		m.writeMetricHeader(metrics, "octojoin_wheel_spins_total", "gauge", "Available Wheel of Fortune spins by fuel type")
+		m.writeMetric(metrics, "octojoin_wheel_spins_total", map[string]string{
 			"fuel_type": "electricity",
 		}, float64(spins.ElectricitySpins))
-		m.writeMetric(&metrics, "octojoin_wheel_spins_total", map[string]string{
+		m.writeMetric(metrics, "octojoin_wheel_spins_total", map[string]string{
 			"fuel_type": "gas",
 		}, float64(spins.GasSpins))
 		
-		m.writeMetricHeader(&metrics, "octojoin_wheel_spins_combined", "gauge", "Total combined Wheel of Fortune spins")
-		m.writeMetric(&metrics, "octojoin_wheel_spins_combined", nil, float64(spins.ElectricitySpins+spins.GasSpins))
+		m.writeMetricHeader(metrics, "octojoin_wheel_spins_combined", "gauge", "Total combined Wheel of Fortune spins")
+		m.writeMetric(metrics, "octojoin_wheel_spins_combined", nil, float64(spins.ElectricitySpins+spins.GasSpins))
 	}
 	
-	// Free electricity sessions
-	freeElectricity, err := m.client.GetFreeElectricitySessions()
-	if err == nil && freeElectricity != nil {
+	if freeElectricity := snapshot.FreeElectricity; freeElectricity != nil {
 		now := time.Now()
 		upcomingSessions := 0
 		for _, session := range freeElectricity.Data {
@@ -128,131 +270,299 @@ func (m *MetricsCollector) collectMetrics() string {
 			}
 		}
 		
-		m.writeMetricHeader(&metrics, "octojoin_free_electricity_sessions_total", "gauge", "Total number of free electricity sessions")
-		m.writeMetric(&metrics, "octojoin_free_electricity_sessions_total", nil, float64(len(freeElectricity.Data)))
+		m.writeMetricHeader(metrics, "octojoin_free_electricity_sessions_total", "gauge", "Total number of free electricity sessions")
+		m.writeMetric(metrics, "octojoin_free_electricity_sessions_total", nil, float64(len(freeElectricity.Data)))
 		
-		m.writeMetricHeader(&metrics, "octojoin_free_electricity_sessions_upcoming", "gauge", "Number of upcoming free electricity sessions")
-		m.writeMetric(&metrics, "octojoin_free_electricity_sessions_upcoming", nil, float64(upcomingSessions))
+		m.writeMetricHeader(metrics, "octojoin_free_electricity_sessions_upcoming", "gauge", "Number of upcoming free electricity sessions")
+		m.writeMetric(metrics, "octojoin_free_electricity_sessions_upcoming", nil, float64(upcomingSessions))
 	}
 	
-	// State metrics
-	if m.monitor.state != nil {
-		m.writeMetricHeader(&metrics, "octojoin_known_sessions_total", "gauge", "Total number of known sessions in state")
-		m.writeMetric(&metrics, "octojoin_known_sessions_total", nil, float64(len(m.monitor.state.KnownSessions)))
+	if len(snapshot.Properties) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_property_smart_meters", "gauge", "Smart meters on each property by fuel")
+		for _, property := range snapshot.Properties {
+			m.writeMetric(metrics, "octojoin_property_smart_meters", map[string]string{"property": property.ID, "fuel": "electricity"}, float64(len(property.ElectricityDevices)))
+			m.writeMetric(metrics, "octojoin_property_smart_meters", map[string]string{"property": property.ID, "fuel": "gas"}, float64(len(property.GasDevices)))
+		}
+		
+		m.writeMetricHeader(metrics, "octojoin_property_free_electricity_eligible", "gauge", "Whether a property can take part in free electricity sessions (1=yes, 0=no)")
+		for _, property := range snapshot.Properties {
+			eligible := 0.0
+			if property.FreeElectricityEligible {
+				eligible = 1
+			}
+			m.writeMetric(metrics, "octojoin_property_free_electricity_eligible", map[string]string{"property": property.ID}, eligible)
+		}
+	}
+	
+	if export := exportByProperty(snapshot.Properties, snapshot.Export); len(export) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_export_kwh", "gauge", "Electricity exported to the grid in the smart meter readings received for the last 24 hours, which lag by up to a day")
+		for _, id := range sortedKeys(export) {
+			m.writeMetric(metrics, "octojoin_export_kwh", map[string]string{"property": id}, export[id].kWh)
+		}
+		
+		m.writeMetricHeader(metrics, "octojoin_export_earnings_pounds", "gauge", "Estimated export earnings in the smart meter readings received for the last 24 hours, which lag by up to a day")
+		for _, id := range sortedKeys(export) {
+			m.writeMetric(metrics, "octojoin_export_earnings_pounds", map[string]string{"property": id}, export[id].earnings/100)
+		}
+	}
+	
+	if len(snapshot.Tariffs) > 0 {
+		now := time.Now()
+		m.writeMetricHeader(metrics, "octojoin_unit_rate_pence", "gauge", "Current unit rate including VAT in pence per kWh")
+		for _, tariff := range snapshot.Tariffs {
+			for _, rate := range tariff.CurrentUnitRates(now) {
+				register := rate.Register
+				if register == "" {
+					register = "standard"
+				}
+				m.writeMetric(metrics, "octojoin_unit_rate_pence", map[string]string{
+					"property": tariff.PropertyID,
+					"fuel":     strings.ToLower(tariff.Fuel),
+					"tariff":   tariff.TariffCode,
+					"register": register,
+				}, rate.ValueIncVAT)
+			}
+		}
 		
-		m.writeMetricHeader(&metrics, "octojoin_last_updated_timestamp_seconds", "gauge", "Unix timestamp of last state update")
-		// Format timestamp to avoid scientific notation
-		timestamp := float64(m.monitor.state.LastUpdated.Unix())
-		m.writeMetric(&metrics, "octojoin_last_updated_timestamp_seconds", nil, timestamp)
+		m.writeMetricHeader(metrics, "octojoin_standing_charge_pence", "gauge", "Current standing charge including VAT in pence per day")
+		for _, tariff := range snapshot.Tariffs {
+			if tariff.StandingCharge != nil {
+				m.writeMetric(metrics, "octojoin_standing_charge_pence", map[string]string{
+					"property": tariff.PropertyID,
+					"fuel":     strings.ToLower(tariff.Fuel),
+					"tariff": tariff.TariffCode,
+				}, tariff.StandingCharge.ValueIncVAT)
+			}
+		}
+	}
+	
+	if len(snapshot.Dispatches) > 0 {
+		counts := map[string]int{DispatchPlanned: 0, DispatchCompleted: 0}
+		extra := map[string]int{DispatchPlanned: 0, DispatchCompleted: 0}
+		durations := map[string]float64{DispatchPlanned: 0, DispatchCompleted: 0}
+		energy := map[string]float64{DispatchPlanned: 0, DispatchCompleted: 0}
+		for _, dispatch := range snapshot.Dispatches {
+			counts[dispatch.Status]++
+			durations[dispatch.Status] += dispatch.EndAt.Sub(dispatch.StartAt).Seconds()
+			energy[dispatch.Status] += dispatch.DeltaKWh
+			if dispatch.Extra {
+				extra[dispatch.Status]++
+			}
+		}
+		statuses := []string{DispatchPlanned, DispatchCompleted}
 		
-		// Cache metrics
-		if m.monitor.state.CachedSavingSessions != nil {
-			m.writeMetricHeader(&metrics, "octojoin_cache_age_seconds", "gauge", "Age of cached data in seconds")
-			cacheAge := time.Since(m.monitor.state.CachedSavingSessions.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "saving_sessions",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_dispatches", "gauge", "Intelligent Octopus smart-charge dispatches planned or recently completed")
+		for _, status := range statuses {
+			m.writeMetric(metrics, "octojoin_dispatches", map[string]string{"status": status}, float64(counts[status]))
 		}
 		
-		if m.monitor.state.CachedCampaignStatus != nil {
-			cacheAge := time.Since(m.monitor.state.CachedCampaignStatus.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "campaign_status",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_dispatches_extra", "gauge", "Dispatches outside the standard overnight off-peak window")
+		for _, status := range statuses {
+			m.writeMetric(metrics, "octojoin_dispatches_extra", map[string]string{"status": status}, float64(extra[status]))
 		}
 		
-		if m.monitor.state.CachedFreeElectricity != nil {
-			cacheAge := time.Since(m.monitor.state.CachedFreeElectricity.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "free_electricity",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_dispatch_duration_seconds", "gauge", "Total duration of planned or recently completed dispatches")
+		for _, status := range statuses {
+			m.writeMetric(metrics, "octojoin_dispatch_duration_seconds", map[string]string{"status": status}, durations[status])
 		}
 		
-		if m.monitor.state.CachedOctoPoints != nil {
-			cacheAge := time.Since(m.monitor.state.CachedOctoPoints.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "octo_points",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_dispatch_energy_kwh", "gauge", "Energy scheduled or drawn during planned or recently completed dispatches")
+		for _, status := range statuses {
+			m.writeMetric(metrics, "octojoin_dispatch_energy_kwh", map[string]string{"status": status}, energy[status])
+		}
+	}
+	
+	// State metrics
+	state := snapshot.State
+	if len(state.PointsLedger) > 0 {
+		// The stored ledger only grows, so its totals behave as counters
+		reasons := summarisePointsLedger(state.PointsLedger)
+		
+		m.writeMetricHeader(metrics, "octojoin_octopoints_ledger_entries_total", "counter", "OctoPoints ledger entries by reason")
+		for _, summary := range reasons {
+			m.writeMetric(metrics, "octojoin_octopoints_ledger_entries_total", map[string]string{"reason": summary.Reason}, float64(summary.Entries))
 		}
 		
-		if m.monitor.state.CachedWheelOfFortuneSpins != nil {
-			cacheAge := time.Since(m.monitor.state.CachedWheelOfFortuneSpins.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "wheel_of_fortune_spins",
-			}, cacheAge)
+		// Earned and spent are kept apart so neither goes down when a reason
+		// has entries both ways
+		m.writeMetricHeader(metrics, "octojoin_octopoints_ledger_earned_points_total", "counter", "OctoPoints added to the wallet by reason")
+		for _, summary := range reasons {
+			m.writeMetric(metrics, "octojoin_octopoints_ledger_earned_points_total", map[string]string{"reason": summary.Reason}, float64(summary.Earned))
 		}
 		
-		if m.monitor.state.CachedAccountInfo != nil {
-			cacheAge := time.Since(m.monitor.state.CachedAccountInfo.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "account_info",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_octopoints_ledger_spent_points_total", "counter", "OctoPoints taken from the wallet by reason")
+		for _, summary := range reasons {
+			m.writeMetric(metrics, "octojoin_octopoints_ledger_spent_points_total", map[string]string{"reason": summary.Reason}, float64(summary.Spent))
 		}
+	}
+	
+	if len(state.WheelSpinTotals) > 0 {
+		// Lifetime totals, unlike the capped spin history, so they can be counters
+		fuels := []string{"ELECTRICITY", "GAS"}
 		
-		if m.monitor.state.CachedMeterDevices != nil {
-			cacheAge := time.Since(m.monitor.state.CachedMeterDevices.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "meter_devices",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_wheel_spun_total", "counter", "Wheel of Fortune spins taken by fuel type")
+		for _, fuel := range fuels {
+			m.writeMetric(metrics, "octojoin_wheel_spun_total", map[string]string{"fuel_type": strings.ToLower(fuel)}, float64(state.WheelSpinTotals[fuel].Spins))
 		}
 		
-		if m.monitor.state.CachedUsageMeasurements != nil {
-			cacheAge := time.Since(m.monitor.state.CachedUsageMeasurements.Timestamp).Seconds()
-			m.writeMetric(&metrics, "octojoin_cache_age_seconds", map[string]string{
-				"cache_type": "usage_measurements",
-			}, cacheAge)
+		m.writeMetricHeader(metrics, "octojoin_wheel_prize_points_total", "counter", "OctoPoints won on the Wheel of Fortune by fuel type")
+		for _, fuel := range fuels {
+			m.writeMetric(metrics, "octojoin_wheel_prize_points_total", map[string]string{"fuel_type": strings.ToLower(fuel)}, float64(state.WheelSpinTotals[fuel].Points))
 		}
 	}
-
-	// API performance metrics
-	m.writeMetricHeader(&metrics, "octojoin_api_requests_total", "counter", "Total number of API requests")
-	m.writeMetric(&metrics, "octojoin_api_requests_total", nil, float64(m.client.metrics.TotalRequests))
-
-	// Rate limiting metrics
-	m.writeMetricHeader(&metrics, "octojoin_rate_limit_sleeps_total", "counter", "Number of times rate limiting was triggered")
-	m.writeMetric(&metrics, "octojoin_rate_limit_sleeps_total", nil, float64(m.client.metrics.RateLimitSleeps))
-
-	m.writeMetricHeader(&metrics, "octojoin_rate_limit_sleep_seconds_total", "counter", "Total time spent sleeping due to rate limits")
-	m.writeMetric(&metrics, "octojoin_rate_limit_sleep_seconds_total", nil, m.client.metrics.TotalSleepSeconds)
-
-	// API call duration metrics (summary statistics per endpoint)
-	for endpoint, durations := range m.client.metrics.RequestDurations {
-		if len(durations) == 0 {
-			continue
+	
+	if check := state.LastWheelSpinCheck; check != nil {
+		verified := 0.0
+		if check.Verified {
+			verified = 1
+		}
+		m.writeMetricHeader(metrics, "octojoin_wheel_balance_verified", "gauge", "Whether the OctoPoints balance matched the prizes from the last spins (1=yes, 0=no)")
+		m.writeMetric(metrics, "octojoin_wheel_balance_verified", nil, verified)
+	}
+	
+	if len(state.Redemptions) > 0 {
+		// Only final results are counters: a pending record is later replaced
+		// by its outcome, so pending is a gauge of its own
+		results := []string{"redeemed", "dry_run", "failed"}
+		counts := make(map[string]int)
+		points := make(map[string]int)
+		for _, record := range state.Redemptions {
+			counts[record.Result()]++
+			points[record.Result()] += record.Points
+		}
+		
+		m.writeMetricHeader(metrics, "octojoin_octopoints_redemptions_total", "counter", "Automatic OctoPoints redemptions by result")
+		for _, result := range results {
+			m.writeMetric(metrics, "octojoin_octopoints_redemptions_total", map[string]string{"result": result}, float64(counts[result]))
 		}
+		
+		m.writeMetricHeader(metrics, "octojoin_octopoints_redeemed_points_total", "counter", "OctoPoints in automatic redemptions by result")
+		for _, result := range results {
+			m.writeMetric(metrics, "octojoin_octopoints_redeemed_points_total", map[string]string{"result": result}, float64(points[result]))
+		}
+		
+		m.writeMetricHeader(metrics, "octojoin_octopoints_redemption_pending", "gauge", "Automatic OctoPoints redemptions interrupted before their outcome was known")
+		m.writeMetric(metrics, "octojoin_octopoints_redemption_pending", nil, float64(counts["pending"]))
+	}
+	
+	m.writeMetricHeader(metrics, "octojoin_known_sessions_total", "gauge", "Total number of known sessions in state")
+	m.writeMetric(metrics, "octojoin_known_sessions_total", nil, float64(len(state.KnownSessions)))
+	
+	m.writeMetricHeader(metrics, "octojoin_last_updated_timestamp_seconds", "gauge", "Unix timestamp of last state update")
+	// Format timestamp to avoid scientific notation
+	m.writeMetric(metrics, "octojoin_last_updated_timestamp_seconds", nil, float64(state.LastUpdated.Unix()))
 
-		// Calculate summary statistics
-		var sum, min, max float64
-		min = durations[0]
-		for _, d := range durations {
-			sum += d
-			if d < min {
-				min = d
-			}
-			if d > max {
-				max = d
-			}
+	// Cache metrics, aged to now so stale caches are visible between cycles
+	cacheTimes := map[string]time.Time{}
+	if state.CachedSavingSessions != nil {
+		cacheTimes["saving_sessions"] = state.CachedSavingSessions.Timestamp
+	}
+	if state.CachedCampaignStatus != nil {
+		cacheTimes["campaign_status"] = state.CachedCampaignStatus.Timestamp
+	}
+	if state.CachedFreeElectricity != nil {
+		cacheTimes["free_electricity"] = state.CachedFreeElectricity.Timestamp
+	}
+	if state.CachedOctoPoints != nil {
+		cacheTimes["octo_points"] = state.CachedOctoPoints.Timestamp
+	}
+	if state.CachedDispatches != nil {
+		cacheTimes["dispatches"] = state.CachedDispatches.Timestamp
+	}
+	if state.CachedWheelOfFortuneSpins != nil {
+		cacheTimes["wheel_of_fortune_spins"] = state.CachedWheelOfFortuneSpins.Timestamp
+	}
+	if state.CachedAccountInfo != nil {
+		cacheTimes["account_info"] = state.CachedAccountInfo.Timestamp
+	}
+	if state.CachedMeterDevices != nil {
+		cacheTimes["meter_devices"] = state.CachedMeterDevices.Timestamp
+	}
+	if state.CachedUsageMeasurements != nil {
+		cacheTimes["usage_measurements"] = state.CachedUsageMeasurements.Timestamp
+	}
+	if state.CachedGasUsage != nil {
+		cacheTimes["gas_usage_measurements"] = state.CachedGasUsage.Timestamp
+	}
+	if state.CachedExportUsage != nil {
+		cacheTimes["export_measurements"] = state.CachedExportUsage.Timestamp
+	}
+	if state.CachedTariffs != nil {
+		cacheTimes["tariffs"] = state.CachedTariffs.Timestamp
+	}
+	if len(cacheTimes) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_cache_age_seconds", "gauge", "Age of cached data in seconds")
+		for _, cacheType := range sortedKeys(cacheTimes) {
+			m.writeMetric(metrics, "octojoin_cache_age_seconds", map[string]string{
+				"cache_type": cacheType,
+			}, time.Since(cacheTimes[cacheType]).Seconds())
 		}
-		avg := sum / float64(len(durations))
+	}
+}
 
-		m.writeMetricHeader(&metrics, "octojoin_api_request_duration_seconds", "gauge", "API request duration statistics by endpoint")
+// writeAPIMetrics renders the API request counters and duration histograms
+func (m *MetricsCollector) writeAPIMetrics(metrics *strings.Builder, apiMetrics *APIMetrics) {
+	m.writeMetricHeader(metrics, "octojoin_api_requests_total", "counter", "Total number of API requests")
+	m.writeMetric(metrics, "octojoin_api_requests_total", nil, float64(apiMetrics.TotalRequests))
 
-		labels := map[string]string{"endpoint": endpoint, "stat": "min"}
-		m.writeMetric(&metrics, "octojoin_api_request_duration_seconds", labels, min)
+	if len(apiMetrics.Responses) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_api_responses_total", "counter", "API responses by endpoint and HTTP status code")
+		for _, endpoint := range sortedKeys(apiMetrics.Responses) {
+			for code, count := range apiMetrics.Responses[endpoint] {
+				m.writeMetric(metrics, "octojoin_api_responses_total", map[string]string{
+					"endpoint": endpoint,
+					"code":     strconv.Itoa(code),
+				}, float64(count))
+			}
+		}
+	}
 
-		labels = map[string]string{"endpoint": endpoint, "stat": "max"}
-		m.writeMetric(&metrics, "octojoin_api_request_duration_seconds", labels, max)
+	if len(apiMetrics.Errors) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_api_errors_total", "counter", "Failed API calls by error class")
+		for _, class := range sortedKeys(apiMetrics.Errors) {
+			m.writeMetric(metrics, "octojoin_api_errors_total", map[string]string{"class": class}, float64(apiMetrics.Errors[class]))
+		}
+	}
+	
+	// Rate limiting metrics
+	m.writeMetricHeader(metrics, "octojoin_rate_limit_sleeps_total", "counter", "Number of times rate limiting was triggered")
+	m.writeMetric(metrics, "octojoin_rate_limit_sleeps_total", nil, float64(apiMetrics.RateLimitSleeps))
 
-		labels = map[string]string{"endpoint": endpoint, "stat": "avg"}
-		m.writeMetric(&metrics, "octojoin_api_request_duration_seconds", labels, avg)
+	m.writeMetricHeader(metrics, "octojoin_rate_limit_sleep_seconds_total", "counter", "Total time spent sleeping due to rate limits")
+	m.writeMetric(metrics, "octojoin_rate_limit_sleep_seconds_total", nil, apiMetrics.TotalSleepSeconds)
 
-		labels = map[string]string{"endpoint": endpoint, "stat": "count"}
-		m.writeMetric(&metrics, "octojoin_api_request_duration_seconds", labels, float64(len(durations)))
+	// API call duration histograms per endpoint
+	if len(apiMetrics.Durations) > 0 {
+		m.writeMetricHeader(metrics, "octojoin_api_request_duration_seconds", "histogram", "API request duration by endpoint")
+		for _, endpoint := range sortedKeys(apiMetrics.Durations) {
+			histogram := apiMetrics.Durations[endpoint]
+			var cumulative uint64
+			for i, bound := range apiDurationBuckets {
+				cumulative += histogram.BucketCounts[i]
+				m.writeMetric(metrics, "octojoin_api_request_duration_seconds_bucket", map[string]string{
+					"endpoint": endpoint,
+					"le":       strconv.FormatFloat(bound, 'g', -1, 64),
+				}, float64(cumulative))
+			}
+			m.writeMetric(metrics, "octojoin_api_request_duration_seconds_bucket", map[string]string{
+				"endpoint": endpoint,
+				"le":       "+Inf",
+			}, float64(histogram.Count))
+			m.writeMetric(metrics, "octojoin_api_request_duration_seconds_sum", map[string]string{"endpoint": endpoint}, histogram.Sum)
+			m.writeMetric(metrics, "octojoin_api_request_duration_seconds_count", map[string]string{"endpoint": endpoint}, float64(histogram.Count))
+		}
 	}
-
-	return metrics.String()
 }
 
+// sortedKeys returns map keys in order so metric output is stable between scrapes
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
 // writeMetricHeader writes metric description and type
 func (m *MetricsCollector) writeMetricHeader(sb *strings.Builder, name, metricType, description string) {
 	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, description))
@@ -267,13 +577,21 @@ func (m *MetricsCollector) writeMetric(sb *strings.Builder, name string, labels
 		format = "%.0f"
 	}
 	
+	if m.account != "" {
+		accountLabels := map[string]string{"account": m.account}
+		for key, val := range labels {
+			accountLabels[key] = val
+		}
+		labels = accountLabels
+	}
+
 	if len(labels) > 0 {
 		var labelPairs []string
-		for key, val := range labels {
-			labelPairs = append(labelPairs, fmt.Sprintf(`%s="%s"`, key, val))
+		for _, key := range sortedKeys(labels) {
+			labelPairs = append(labelPairs, fmt.Sprintf(`%s="%s"`, key, labels[key]))
 		}
 		sb.WriteString(fmt.Sprintf("%s{%s} "+format+"\n", name, strings.Join(labelPairs, ","), value))
 	} else {
 		sb.WriteString(fmt.Sprintf("%s "+format+"\n", name, value))
 	}
-}
\ No newline at end of file
+}
@@ -18,6 +18,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -158,8 +159,8 @@ func TestAPIMetrics(t *testing.T) {
 		t.Errorf("Expected at least 3 total requests, got %d", client.metrics.TotalRequests)
 	}
 
-	if len(client.metrics.RequestDurations) == 0 {
-		t.Error("Expected request durations to be tracked, but map is empty")
+	if histogram := client.metrics.Snapshot().Durations["/test-endpoint"]; histogram == nil || histogram.Count != 3 {
+		t.Errorf("Expected 3 request durations to be tracked, got %+v", histogram)
 	}
 
 	// Rate limit sleeps should be tracked (we have minInterval enforced)
@@ -194,7 +195,7 @@ func TestAPIMetrics(t *testing.T) {
 	lines := strings.Split(metricsOutput, "\n")
 	foundRequestTotal := false
 	for _, line := range lines {
-		if strings.HasPrefix(line, "octojoin_api_requests_total ") {
+		if strings.HasPrefix(line, `octojoin_api_requests_total{account="A-TEST"} `) {
 			foundRequestTotal = true
 			// Should have a numeric value (metrics collection may make additional requests)
 			if !strings.Contains(line, " ") {
@@ -206,4 +207,184 @@ func TestAPIMetrics(t *testing.T) {
 	if !foundRequestTotal {
 		t.Error("octojoin_api_requests_total metric value not found in output")
 	}
-}
\ No newline at end of file
+}
+// countingTransport counts requests made through a client
+type countingTransport struct {
+	next  http.RoundTripper
+	count int32
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&ct.count, 1)
+	return ct.next.RoundTrip(req)
+}
+
+func TestMetricsScrapeUsesSnapshotOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	client := newTestKrakenClient(t)
+	counter := &countingTransport{next: newTestKrakenTransport(t)}
+	client.SetTransport(counter)
+	monitor := NewSavingSessionMonitor(client, "A-TEST1234")
+	collector := NewMetricsCollector(client, monitor)
+
+	// Before the first cycle only process metrics are available
+	output := collector.collectMetrics()
+	if !strings.Contains(output, `octojoin_last_check_timestamp{account="A-TEST1234"} 0`) {
+		t.Errorf("Expected zero last check timestamp before first cycle, got:\n%s", output)
+	}
+	if strings.Contains(output, "octojoin_octopoints_total") {
+		t.Error("Expected no account metrics before first cycle")
+	}
+	if got := atomic.LoadInt32(&counter.count); got != 0 {
+		t.Errorf("Expected scrape before first cycle to make no requests, got %d", got)
+	}
+
+	monitor.checkForNewSessions()
+	if monitor.MetricsSnapshot() == nil {
+		t.Fatal("Expected monitor cycle to record a metrics snapshot")
+	}
+
+	// Scrapes render the snapshot without touching the API
+	before := atomic.LoadInt32(&counter.count)
+	for i := 0; i < 5; i++ {
+		output = collector.collectMetrics()
+	}
+	if got := atomic.LoadInt32(&counter.count); got != before {
+		t.Errorf("Expected scrapes to make no requests, got %d", got-before)
+	}
+
+	expectedMetrics := []string{
+		"octojoin_snapshot_age_seconds",
+		"octojoin_octopoints_total",
+		"octojoin_free_electricity_sessions_total",
+		`octojoin_known_sessions_total{account="A-TEST1234"} 1`,
+		`octojoin_api_request_duration_seconds_bucket{`,
+		`le="+Inf"`,
+		"# TYPE octojoin_api_request_duration_seconds histogram",
+		"octojoin_api_responses_total{",
+	}
+	for _, metric := range expectedMetrics {
+		if !strings.Contains(output, metric) {
+			t.Errorf("Expected %q in metrics output", metric)
+		}
+	}
+}
+
+func TestMetricsExport(t *testing.T) {
+	client := NewOctopusClient("test-account", "test-key", false)
+	monitor := NewSavingSessionMonitor(client, "test-account")
+	collector := NewMetricsCollector(client, monitor)
+
+	monitor.metricsSnapshot.Store(&MetricsSnapshot{State: &AppState{}})
+	if output := collector.collectMetrics(); strings.Contains(output, "octojoin_export_kwh") {
+		t.Error("Expected no export metrics without export readings")
+	}
+
+	monitor.metricsSnapshot.Store(&MetricsSnapshot{
+		State: &AppState{},
+		Properties: []Property{
+			{ID: "1000001", ElectricityDevices: []string{"00-11"}},
+			{ID: "1000002", ElectricityDevices: []string{"00-22"}},
+		},
+		Export: []UsageMeasurement{{DeviceID: "00-11", Value: "0.250"}, {DeviceID: "00-11", Value: "0.500"}, {DeviceID: "00-22", Value: "0.100"}},
+	})
+	output := collector.collectMetrics()
+	for _, metric := range []string{
+		`octojoin_export_kwh{account="test-account",property="1000001"} 0.75`,
+		`octojoin_export_kwh{account="test-account",property="1000002"} 0.1`,
+		`octojoin_export_earnings_pounds{account="test-account",property="1000001"} 0`,
+	} {
+		if !strings.Contains(output, metric) {
+			t.Errorf("Expected %q in metrics output, got:\n%s", metric, output)
+		}
+	}
+}
+
+func TestDurationHistogramBuckets(t *testing.T) {
+	metrics := NewAPIMetrics()
+	for _, seconds := range []float64{0.01, 0.2, 0.2, 3, 120} {
+		metrics.recordResponse("/accounts/:account/", 200, seconds)
+	}
+
+	histogram := metrics.Snapshot().Durations["/accounts/:account/"]
+	if histogram.Count != 5 {
+		t.Errorf("Expected 5 observations, got %d", histogram.Count)
+	}
+	if histogram.Sum != 123.41 {
+		t.Errorf("Expected sum 123.41, got %g", histogram.Sum)
+	}
+
+	// 0.01 -> 0.05, 0.2 -> 0.25, 3 -> 5, 120 is only counted in +Inf
+	expected := map[float64]uint64{0.05: 1, 0.25: 2, 5: 1}
+	for i, bound := range apiDurationBuckets {
+		if histogram.BucketCounts[i] != expected[bound] {
+			t.Errorf("Expected %d observations in bucket %g, got %d", expected[bound], bound, histogram.BucketCounts[i])
+		}
+	}
+}
+
+func TestMetricsEndpointLabel(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		expected string
+	}{
+		{"/accounts/A-1234ABCD/", "/accounts/:account/"},
+		{"/accounts/A-1234ABCD/saving-sessions/4242/join", "/accounts/:account/saving-sessions/:id/join"},
+		{"/test-endpoint", "/test-endpoint"},
+		{"/products/AGILE-24-10-01/electricity-tariffs/E-1R-AGILE-24-10-01-C/standing-charges/?page_size=1500&period_from=2025-01-15T10%3A00%3A00Z", "/products/:product/electricity-tariffs/:tariff/standing-charges/"},
+		{"/products/VAR-22-11-01/gas-tariffs/G-1R-VAR-22-11-01-C/standard-unit-rates/", "/products/:product/gas-tariffs/:tariff/standard-unit-rates/"},
+	}
+
+	for _, tc := range testCases {
+		if got := metricsEndpointLabel(tc.endpoint, "A-1234ABCD"); got != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, got)
+		}
+	}
+
+	// Each tariff refresh asks for a new period, which must not add a series
+	client := NewOctopusClient("A-1234ABCD", "sk_live_test", false)
+	req := httptest.NewRequest("GET", client.BaseURL+"/products/AGILE-24-10-01/electricity-tariffs/E-1R-AGILE-24-10-01-C/standing-charges/?period_from=2025-01-15T10%3A00%3A00Z", nil)
+	if got := client.endpointLabel(req); got != "/products/:product/electricity-tariffs/:tariff/standing-charges/" {
+		t.Errorf("Expected the query string left out of the label, got %s", got)
+	}
+	req = httptest.NewRequest("GET", getEndpoint("graphql")+"?query=x", nil)
+	if got := client.endpointLabel(req); got != "graphql" {
+		t.Errorf("Expected the graphql endpoint key, got %s", got)
+	}
+}
+
+func TestMetricsMultipleAccounts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var monitors []*SavingSessionMonitor
+	for i, accountID := range []string{"A-HOME0001", "A-FLAT0002"} {
+		monitor := NewSavingSessionMonitor(NewOctopusClient(accountID, "sk_live_test", false), accountID)
+		monitor.metricsSnapshot.Store(&MetricsSnapshot{
+			State: &AppState{WheelSpinTotals: map[string]WheelSpinTotal{"ELECTRICITY": {Spins: i + 1, Points: 20}}},
+		})
+		monitors = append(monitors, monitor)
+	}
+	output := NewAccountsMetricsCollector(monitors).collectMetrics()
+
+	for _, expected := range []string{
+		"octojoin_up 1",
+		`octojoin_wheel_spun_total{account="A-HOME0001",fuel_type="electricity"} 1`,
+		`octojoin_wheel_spun_total{account="A-FLAT0002",fuel_type="electricity"} 2`,
+		`octojoin_api_requests_total{account="A-FLAT0002"} 0`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in metrics output", expected)
+		}
+	}
+
+	// Each family is described once, with every account's samples under it
+	if count := strings.Count(output, "# TYPE octojoin_wheel_spun_total "); count != 1 {
+		t.Errorf("Expected one TYPE line for octojoin_wheel_spun_total, got %d", count)
+	}
+	family := output[strings.Index(output, "# TYPE octojoin_wheel_spun_total "):]
+	family = family[:strings.Index(family, "# HELP")]
+	if !strings.Contains(family, `account="A-HOME0001"`) || !strings.Contains(family, `account="A-FLAT0002"`) {
+		t.Errorf("Expected both accounts grouped under one header, got:\n%s", family)
+	}
+}
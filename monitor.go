@@ -17,6 +17,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,15 +35,43 @@ type SavingSessionMonitor struct {
 	client               *OctopusClient
 	state                *AppState
 	accountID            string
+	name                 string // shown in the dashboard account switcher
 	checkInterval        time.Duration
 	stopCh               chan struct{}
 	minPointsThreshold   int
+	joinPolicy           *JoinPolicy
+	notifier             *NotificationManager
 	webServer            *WebServer
 	useSmartIntervals    bool
+	intervalMu           sync.Mutex // guards the two fields below, read by the scheduler while a check may still be running
 	consecutiveEmptyChecks int
 	lastNewSessionTime   time.Time
 	logger               *Logger
 	daemonMode           bool // true if running with web UI
+	mqtt                 *MQTTPublisher
+	metricsSnapshot      atomic.Pointer[MetricsSnapshot]
+	liveDemand           atomic.Pointer[LiveDemand]
+	lastTelemetryPoll    time.Time
+	telemetryUnavailable int // event ID of a session with no Home Mini readings
+	commands             chan MonitorCommand
+	agile                *AgileConfig // nil unless Agile price alerts are enabled
+	redemption           *RedemptionConfig // nil unless automatic redemption is enabled
+	scheduler            *Scheduler
+	wallet               sync.Mutex // serialises spins and redemptions, so spin verification only sees its own prizes
+}
+
+// Monitor command kinds, used to trigger actions from outside the monitor loop
+const (
+	CommandCheck       = "check"
+	CommandSpinWheels  = "spin_wheels"
+	CommandJoinSession = "join_session"
+)
+
+// MonitorCommand asks the monitor loop to perform an action. EventID is only
+// used by CommandJoinSession, where 0 means the next unjoined session.
+type MonitorCommand struct {
+	Kind    string
+	EventID int
 }
 
 func NewSavingSessionMonitor(client *OctopusClient, accountID string) *SavingSessionMonitor {
@@ -54,6 +84,9 @@ func NewSavingSessionMonitor(client *OctopusClient, accountID string) *SavingSes
 			AlertStates:                  make(map[string]*FreeElectricityAlertState),
 			KnownSessions:                make(map[int]bool),
 			KnownFreeElectricitySessions: make(map[string]bool),
+			JoinDecisions:                make(map[int]*JoinDecision),
+			SessionHistory:               make(map[int]*SessionRecord),
+			KnownDispatches:              make(map[string]time.Time),
 		}
 	}
 
@@ -63,7 +96,7 @@ func NewSavingSessionMonitor(client *OctopusClient, accountID string) *SavingSes
 	// Set state on client for JWT token caching
 	client.SetState(state)
 	
-	return &SavingSessionMonitor{
+	m := &SavingSessionMonitor{
 		client:             client,
 		state:              state,
 		accountID:          accountID,
@@ -73,13 +106,37 @@ func NewSavingSessionMonitor(client *OctopusClient, accountID string) *SavingSes
 		useSmartIntervals:  true,
 		logger:             logger,
 		daemonMode:         false, // default to standalone mode
+		commands:           make(chan MonitorCommand, MonitorCommandQueueSize),
 	}
+	m.scheduler = NewScheduler(logger.WithComponent("scheduler"), m.tasks()...)
+	return m
 }
 
 func (m *SavingSessionMonitor) SetMinPointsThreshold(threshold int) {
 	m.minPointsThreshold = threshold
 }
 
+// SetJoinPolicy sets the policy used to decide which saving sessions to join
+func (m *SavingSessionMonitor) SetJoinPolicy(policy *JoinPolicy) {
+	m.joinPolicy = policy
+}
+
+// SetNotifier sets the notification manager used for session alerts
+func (m *SavingSessionMonitor) SetNotifier(notifier *NotificationManager) {
+	m.notifier = notifier
+}
+
+// notify sends an alert to the configured notification backends, if any
+func (m *SavingSessionMonitor) notify(eventType, title, message string, data map[string]interface{}) {
+	m.notifier.Notify(NotificationEvent{
+		Type:      eventType,
+		Title:     title,
+		Message:   message,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
 func (m *SavingSessionMonitor) SetCheckInterval(interval time.Duration) {
 	m.checkInterval = interval
 }
@@ -110,8 +167,12 @@ func (m *SavingSessionMonitor) getSmartInterval() time.Duration {
 	hour := now.Hour()
 	weekday := now.Weekday()
 
+	m.intervalMu.Lock()
+	consecutiveEmptyChecks, lastNewSessionTime := m.consecutiveEmptyChecks, m.lastNewSessionTime
+	m.intervalMu.Unlock()
+
 	// Recently found new sessions - check more frequently for a batch
-	if !m.lastNewSessionTime.IsZero() && time.Since(m.lastNewSessionTime) < IntervalAfterNewSession {
+	if !lastNewSessionTime.IsZero() && time.Since(lastNewSessionTime) < IntervalAfterNewSession {
 		return IntervalPeakAnnouncement
 	}
 
@@ -126,9 +187,9 @@ func (m *SavingSessionMonitor) getSmartInterval() time.Duration {
 	}
 
 	// Event-driven backoff based on consecutive empty checks
-	if m.consecutiveEmptyChecks > 0 {
+	if consecutiveEmptyChecks > 0 {
 		// Gradually increase intervals after consecutive empty checks (up to off-peak max)
-		backoffMinutes := int(IntervalEventDrivenBase.Minutes()) + (int(IntervalEventDrivenIncrement.Minutes()) * m.consecutiveEmptyChecks)
+		backoffMinutes := int(IntervalEventDrivenBase.Minutes()) + (int(IntervalEventDrivenIncrement.Minutes()) * consecutiveEmptyChecks)
 		maxMinutes := int(IntervalOffPeak.Minutes())
 		if backoffMinutes > maxMinutes {
 			backoffMinutes = maxMinutes
@@ -140,8 +201,122 @@ func (m *SavingSessionMonitor) getSmartInterval() time.Duration {
 	return IntervalOffPeak
 }
 
-func (m *SavingSessionMonitor) EnableWebUI(port int) {
-	m.webServer = NewWebServer(m, port)
+// SetName sets the name the dashboard shows for this account
+func (m *SavingSessionMonitor) SetName(name string) {
+	m.name = name
+}
+
+// Name returns the account's display name, defaulting to its ID
+func (m *SavingSessionMonitor) Name() string {
+	if m.name == "" {
+		return m.accountID
+	}
+	return m.name
+}
+
+// SetWebServer attaches the web server showing this account. The server may
+// be shared with other accounts' monitors, so the caller starts and stops it.
+func (m *SavingSessionMonitor) SetWebServer(ws *WebServer) {
+	m.webServer = ws
+}
+
+// EnableMQTT publishes account state to an MQTT broker and listens for commands
+func (m *SavingSessionMonitor) EnableMQTT(cfg *MQTTConfig) {
+	m.mqtt = NewMQTTPublisher(cfg, m)
+}
+
+// SubmitCommand queues a command for the monitor loop without blocking
+func (m *SavingSessionMonitor) SubmitCommand(cmd MonitorCommand) error {
+	select {
+	case m.commands <- cmd:
+		return nil
+	default:
+		return fmt.Errorf("command queue full, dropping %s command", cmd.Kind)
+	}
+}
+
+// runCommand performs a queued command and publishes the resulting state
+func (m *SavingSessionMonitor) runCommand(cmd MonitorCommand) {
+	m.logger.Info("Running requested command", "command", cmd.Kind, "event_id", cmd.EventID)
+
+	switch cmd.Kind {
+	case CommandCheck:
+		m.checkForNewSessions()
+		return // checkForNewSessions already publishes state
+	case CommandSpinWheels:
+		// Bypass the spins cache so newly granted spins are picked up
+		m.state.Update(func(s *AppState) { s.CachedWheelOfFortuneSpins = nil })
+		m.scheduler.RunTask(TaskWheelSpins)
+	case CommandJoinSession:
+		// Joining shares the known sessions with saving session discovery
+		m.scheduler.RunExclusive(TaskSavingSessions, func() {
+			if err := m.joinRequestedSession(cmd.EventID); err != nil {
+				m.logger.Error("Requested join failed", "event_id", cmd.EventID, "error", err.Error())
+			}
+		})
+	default:
+		m.logger.Warn("Ignoring unknown command", "command", cmd.Kind)
+		return
+	}
+
+	m.recordCycle()
+}
+
+// joinRequestedSession joins a session on request, bypassing the join policy.
+// An eventID of 0 joins the next upcoming unjoined session.
+func (m *SavingSessionMonitor) joinRequestedSession(eventID int) error {
+	response, err := m.client.GetSavingSessionsWithCache(m.state)
+	if err != nil {
+		return fmt.Errorf("failed to fetch saving sessions: %w", err)
+	}
+
+	var target *SavingSession
+	for _, event := range response.UnjoinedEvents() {
+		if !event.StartAt.After(time.Now()) {
+			continue
+		}
+		if (eventID == 0 && (target == nil || event.StartAt.Before(target.StartAt))) || event.ID == eventID {
+			session := event.ToSavingSession()
+			target = &session
+		}
+	}
+	if target == nil {
+		return &SessionError{SessionID: fmt.Sprint(eventID), Operation: "join", Err: fmt.Errorf("no matching upcoming unjoined session")}
+	}
+
+	if err := m.joinSession(target.EventID); err != nil {
+		return err
+	}
+
+	decision := JoinDecision{
+		EventID:   target.EventID,
+		Join:      true,
+		Rule:      "manual",
+		Reason:    "join requested by command",
+		DecidedAt: time.Now(),
+	}
+	m.state.Update(func(s *AppState) {
+		s.JoinDecisions[target.EventID] = &decision
+		s.KnownSessions[target.EventID] = true
+		delete(s.JoinRetries, target.EventID)
+		s.CachedSavingSessions = nil
+	})
+	m.recordSessionJoined(*target, &decision)
+
+	m.logger.Info("Successfully joined session", "event_id", target.EventID, "rule", decision.Rule)
+	m.notify(EventSavingSessionJoined, "Joined saving session",
+		fmt.Sprintf("Joined the saving session on %s at %s (%s: %s)",
+			target.StartAt.Format("Monday, Jan 2"), target.StartAt.Format("15:04"), decision.Rule, decision.Reason),
+		savingSessionNotificationData(*target, &decision))
+	return nil
+}
+
+// publishState pushes the current account view to MQTT, if enabled
+func (m *SavingSessionMonitor) publishState() {
+	if m.mqtt == nil {
+		return
+	}
+	m.mqtt.PublishState(m.buildSessionData())
 }
 
 func (m *SavingSessionMonitor) Start() {
@@ -156,94 +331,197 @@ func (m *SavingSessionMonitor) StartWithContext(ctx context.Context) error {
 		m.logger.Info("Smart interval adjustment enabled")
 	}
 
-	// Start web server if enabled
-	if m.webServer != nil {
-		go func() {
-			if err := m.webServer.StartWithContext(ctx); err != nil && err != context.Canceled {
-				m.logger.Error("Web server error", "error", err.Error())
-			}
-		}()
+	// Start MQTT publisher if enabled
+	if m.mqtt != nil {
+		go m.mqtt.Run(ctx)
 	}
 
-	// Initial check
+	// Initial check, then each task runs on its own schedule
 	m.checkForNewSessions()
 
-	// Dynamic interval monitoring
-	for {
-		interval := m.getSmartInterval()
-		timer := time.NewTimer(interval)
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	defer stopScheduler()
+	go m.scheduler.Run(schedulerCtx)
 
-		if m.useSmartIntervals {
-			m.logger.Debug("Next check scheduled", "interval", m.formatDuration(interval))
+	for {
+		// Live demand has its own timer while a joined session is running
+		var telemetry <-chan time.Time
+		var telemetryTimer *time.Timer
+		if next := m.nextTelemetryPoll(time.Now()); !next.IsZero() {
+			telemetryTimer = time.NewTimer(time.Until(next))
+			telemetry = telemetryTimer.C
 		}
 
 		select {
-		case <-timer.C:
-			m.checkForNewSessions()
+		case <-m.scheduler.Completed():
+			// State is saved and published from here so tasks never wait on it
+			m.recordCycle()
+		case <-telemetry:
+			m.pollTelemetry()
+		case cmd := <-m.commands:
+			m.runCommand(cmd)
 		case <-m.stopCh:
-			timer.Stop()
 			m.logger.Info("Stopping saving session monitoring")
 			return nil
 		case <-ctx.Done():
-			timer.Stop()
 			m.logger.Info("Stopping saving session monitoring (context canceled)")
-			// Stop web server gracefully
-			if m.webServer != nil {
-				m.webServer.Stop()
-			}
 			return ctx.Err()
 		}
 
-		timer.Stop()
+		if telemetryTimer != nil {
+			telemetryTimer.Stop()
+		}
 	}
 }
 
+// TaskStatuses reports the schedule and last outcome of every monitor task
+func (m *SavingSessionMonitor) TaskStatuses() []TaskStatus {
+	return m.scheduler.Status()
+}
+
 func (m *SavingSessionMonitor) Stop() {
 	close(m.stopCh)
 }
 
+// Monitor task names, as shown at /api/tasks
+const (
+	TaskSavingSessions  = "saving_sessions"
+	TaskWheelSpins      = "wheel_spins"
+	TaskFreeElectricity = "free_electricity"
+	TaskAgilePrices     = "agile_prices"
+	TaskDispatches      = "dispatches"
+	TaskUsageSync       = "usage_sync"
+	TaskAccountInfo     = "account_info"
+	TaskPointsLedger    = "points_ledger"
+)
+
+// tasks splits the monitor's work into independently scheduled tasks, in
+// the order a full check runs them. Points are redeemed last, once spins
+// have landed.
+func (m *SavingSessionMonitor) tasks() []Task {
+	return []Task{
+		{Name: TaskSavingSessions, Run: m.discoverSavingSessions, Interval: m.getSmartInterval, Jitter: 30 * time.Second, Timeout: TaskDefaultTimeout},
+		{Name: TaskWheelSpins, Run: m.runWheelSpins, Interval: fixedInterval(CacheDurationWheelSpins), Jitter: 10 * time.Minute, Timeout: TaskDefaultTimeout},
+		{Name: TaskFreeElectricity, Run: m.checkFreeElectricitySessions, Interval: fixedInterval(CacheDurationFreeElectricity), Jitter: 30 * time.Second, Timeout: TaskDefaultTimeout},
+		{Name: TaskAgilePrices, Run: m.checkAgilePrices, Interval: fixedInterval(TaskIntervalAgilePrices), Jitter: time.Minute, Timeout: TaskDefaultTimeout},
+		{Name: TaskDispatches, Run: m.checkDispatches, Interval: fixedInterval(CacheDurationDispatches), Jitter: 30 * time.Second, Timeout: TaskDefaultTimeout},
+		{Name: TaskUsageSync, Run: m.syncUsage, Interval: fixedInterval(CacheDurationUsageMeasurements), Jitter: 2 * time.Minute, Timeout: TaskUsageSyncTimeout},
+		{Name: TaskAccountInfo, Run: m.refreshAccountInfo, Interval: fixedInterval(CacheDurationAccountInfo), Jitter: 5 * time.Minute, Timeout: TaskDefaultTimeout},
+		{Name: TaskPointsLedger, Run: m.syncPointsLedger, Interval: fixedInterval(CacheDurationOctoPoints), Jitter: 5 * time.Minute, Timeout: TaskDefaultTimeout},
+	}
+}
+
+// checkForNewSessions runs every task once, whatever its schedule, then
+// saves and publishes the result
 func (m *SavingSessionMonitor) checkForNewSessions() {
 	m.logger.Info("Checking for new sessions")
+	m.scheduler.RunAll()
+	m.recordCycle()
+}
 
-	foundNewSessions := false
-
-	// Check saving sessions
-	if m.checkSavingSessions() {
-		foundNewSessions = true
+// recordCycle saves state and refreshes the metrics snapshot and MQTT state
+// after tasks have run
+func (m *SavingSessionMonitor) recordCycle() {
+	if err := m.state.Save(m.accountID); err != nil {
+		m.logger.Warn("Failed to save state", "error", err.Error())
 	}
 
-	// Check free electricity sessions
-	if m.checkFreeElectricitySessions() {
-		foundNewSessions = true
+	m.recordMetricsSnapshot()
+	m.publishState()
+}
+
+// discoverSavingSessions checks for saving sessions and adjusts the smart
+// interval, checking more often after new sessions turn up
+func (m *SavingSessionMonitor) discoverSavingSessions() error {
+	foundNewSessions, err := m.checkSavingSessions()
+	if err != nil {
+		return err
 	}
 
 	// Update event-driven tracking
+	m.intervalMu.Lock()
 	if foundNewSessions {
 		m.lastNewSessionTime = time.Now()
 		m.consecutiveEmptyChecks = 0
-		if m.useSmartIntervals {
-			m.logger.Info("New sessions found - will check more frequently for potential batches")
-		}
 	} else {
 		m.consecutiveEmptyChecks++
-		if m.useSmartIntervals && m.consecutiveEmptyChecks > 1 {
+	}
+	emptyChecks := m.consecutiveEmptyChecks
+	m.intervalMu.Unlock()
+
+	if m.useSmartIntervals {
+		if foundNewSessions {
+			m.logger.Info("New sessions found - will check more frequently for potential batches")
+		} else if emptyChecks > 1 {
 			m.logger.Info("No new sessions found - extending next interval",
-				"consecutive_empty_checks", m.consecutiveEmptyChecks,
+				"consecutive_empty_checks", emptyChecks,
 			)
 		}
 	}
+	return nil
+}
 
-	// Save state after checks
-	if err := m.state.Save(m.accountID); err != nil {
-		m.logger.Warn("Failed to save state", "error", err.Error())
+// runWheelSpins spins any available wheels
+func (m *SavingSessionMonitor) runWheelSpins() error {
+	m.wallet.Lock()
+	defer m.wallet.Unlock()
+	_, err := m.spinWheels()
+	return err
+}
+
+// syncUsage keeps the dashboard's default usage range fresh and compares
+// finished sessions with their baseline once readings arrive
+func (m *SavingSessionMonitor) syncUsage() error {
+	if m.webServer != nil {
+		properties, err := m.client.getPropertiesWithCache(m.state)
+		if err != nil {
+			return fmt.Errorf("failed to get meter devices: %w", err)
+		}
+		electricity, gas := meterDevices(properties)
+		if len(electricity) > 0 {
+			if _, err := m.client.getUsageMeasurementsWithCache(m.state, WebDefaultUsageDays); err != nil {
+				return fmt.Errorf("failed to sync electricity usage: %w", err)
+			}
+		}
+		if len(gas) > 0 {
+			if _, err := m.client.getGasUsageMeasurementsWithCache(m.state, WebDefaultUsageDays); err != nil {
+				return fmt.Errorf("failed to sync gas usage: %w", err)
+			}
+		}
+	}
+
+	m.updateSessionPerformance()
+	return nil
+}
+
+// refreshAccountInfo refreshes the account balance and campaign enrolment
+func (m *SavingSessionMonitor) refreshAccountInfo() error {
+	if _, err := m.client.getAccountInfoWithCache(m.state); err != nil {
+		return fmt.Errorf("failed to fetch account info: %w", err)
 	}
+	if _, err := m.client.getCampaignStatusWithCache(m.state); err != nil {
+		return fmt.Errorf("failed to fetch campaign status: %w", err)
+	}
+	return nil
 }
 
-func (m *SavingSessionMonitor) checkSavingSessions() bool {
+// syncPointsLedger refreshes the OctoPoints balance, which stores new ledger
+// entries, then redeems points if the policy calls for it
+func (m *SavingSessionMonitor) syncPointsLedger() error {
+	m.wallet.Lock()
+	defer m.wallet.Unlock()
+	if _, err := m.client.getOctoPointsGraphQLWithCache(m.state); err != nil {
+		return fmt.Errorf("failed to fetch OctoPoints: %w", err)
+	}
+	return m.checkRedemption()
+}
+
+// checkSavingSessions announces new saving sessions and joins those the
+// policy allows. It reports whether any session was new.
+func (m *SavingSessionMonitor) checkSavingSessions() (bool, error) {
 	response, err := m.client.GetSavingSessionsWithCache(m.state)
 	if err != nil {
-		m.logger.Error("Error fetching saving sessions", "error", err.Error())
-		return false
+		return false, fmt.Errorf("failed to fetch saving sessions: %w", err)
 	}
 
 	foundNewSessions := false
@@ -252,135 +530,301 @@ func (m *SavingSessionMonitor) checkSavingSessions() bool {
 		"points", response.Data.OctoPoints.Account.CurrentPointsInWallet,
 	)
 
-	// Get and display Wheel of Fortune spins (with caching)
-	spins, err := m.client.getWheelOfFortuneSpinsWithCache(m.state)
-	if err != nil {
-		m.logger.Warn("Could not get Wheel of Fortune spins", "error", err.Error())
-	} else {
-		totalSpins := spins.ElectricitySpins + spins.GasSpins
-		if totalSpins > 0 {
-			m.logger.Info("Wheel of Fortune spins available",
-				"total", totalSpins,
-				"electricity", spins.ElectricitySpins,
-				"gas", spins.GasSpins,
-			)
-
-			// Auto-spin all available wheels
-			m.logger.Info("Auto-spinning all available wheels")
-			results, err := m.client.spinAllAvailableWheels(spins)
-			if err != nil {
-				m.logger.Error("Error during auto-spinning", "error", err.Error())
-			} else if len(results) > 0 {
-				totalPoints := 0
-				electricityPoints := 0
-				gasPoints := 0
-
-				for _, result := range results {
-					totalPoints += result.Prize
-					if result.FuelType == "ELECTRICITY" {
-						electricityPoints += result.Prize
-					} else {
-						gasPoints += result.Prize
-					}
-				}
-
-				m.logger.Info("Auto-spin complete",
-					"total_points", totalPoints,
-					"electricity_points", electricityPoints,
-					"gas_points", gasPoints,
-				)
-				
-				// Clear the cached spins so we check for new ones on next run
-				if m.state != nil {
-					m.state.CachedWheelOfFortuneSpins = nil
-				}
+	// Sessions that have already been joined only need announcing
+	for _, session := range response.Data.SavingSessions.Account.JoinedEvents {
+		m.recordJoinedSession(session)
+		if !m.state.IsSessionKnown(session.EventID) {
+			foundNewSessions = true
+			if session.StartAt.After(time.Now()) {
+				m.announceSavingSession(session)
+				m.logger.Info("Session already joined", "event_id", session.EventID)
 			} else {
-				m.logger.Warn("No wheels were successfully spun")
+				m.logger.Debug("Saving session already started/ended",
+					"event_id", session.EventID,
+				)
 			}
-		} else {
-			m.logger.Debug("No Wheel of Fortune spins available")
+			m.state.MarkSessionKnown(session.EventID)
 		}
 	}
 
-	for _, session := range response.Data.SavingSessions.Account.JoinedEvents {
-		if !m.state.KnownSessions[session.EventID] {
-			foundNewSessions = true
-			now := time.Now()
-			duration := session.EndAt.Sub(session.StartAt)
-
-			if session.StartAt.After(now) {
-				// Upcoming session
-				timeUntil := session.StartAt.Sub(now)
-
-				// Use user-friendly output in standalone mode, structured logging in daemon mode
-				if m.daemonMode {
-					m.logger.Info("SAVING SESSION FOUND",
-						"event_id", session.EventID,
-						"date", session.StartAt.Format("Monday, Jan 2"),
-						"time", session.StartAt.Format("15:04"),
-						"duration", m.formatDuration(duration),
-						"reward_points", session.OctoPoints,
-						"starts_in", m.formatTimeUntil(timeUntil),
-					)
-				} else {
-					m.logger.UserMessage("🎉 SAVING SESSION FOUND")
-					m.logger.UserMessage("   Date: %s at %s", session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"))
-					m.logger.UserMessage("   Duration: %s", m.formatDuration(duration))
-					m.logger.UserMessage("   Reward: %d OctoPoints", session.OctoPoints)
-					m.logger.UserMessage("   Starts in %s", m.formatTimeUntil(timeUntil))
-				}
-
-				if m.shouldJoinSession(session) {
-					if m.daemonMode {
-						m.logger.Info("Attempting to join session",
-							"event_id", session.EventID,
-							"points", session.OctoPoints,
-							"threshold", m.minPointsThreshold,
-						)
-					} else {
-						m.logger.UserMessage("   Joining session (meets threshold of %d points)", m.minPointsThreshold)
-					}
-					if err := m.joinSession(session.EventID); err != nil {
-						m.logger.Error("Failed to join session",
-							"event_id", session.EventID,
-							"error", err.Error(),
-						)
-					} else {
-						m.logger.Info("Successfully joined session", "event_id", session.EventID)
-					}
-				} else {
-					m.logger.Info("Skipped session - insufficient points",
-						"event_id", session.EventID,
-						"points", session.OctoPoints,
-						"threshold", m.minPointsThreshold,
-					)
-				}
+	// Announced sessions we have not joined yet go through the join decision
+	joinedAny := false
+	for _, event := range response.UnjoinedEvents() {
+		if m.state.IsSessionKnown(event.ID) {
+			continue
+		}
+
+		session := event.ToSavingSession()
+		if !session.StartAt.After(time.Now()) {
+			m.logger.Debug("Saving session already started/ended",
+				"event_id", session.EventID,
+			)
+			m.recordSessionHistory(session, nil)
+			m.state.MarkSessionKnown(session.EventID)
+			continue
+		}
+
+		foundNewSessions = true
+		m.announceSavingSession(session)
+
+		decision := m.shouldJoinSession(session)
+		if decision.Join {
+			if m.daemonMode {
+				m.logger.Info("Attempting to join session",
+					"event_id", session.EventID,
+					"points_per_kwh", session.PointsPerKWh,
+					"rule", decision.Rule,
+					"reason", decision.Reason,
+				)
 			} else {
-				m.logger.Debug("Saving session already started/ended",
+				m.logger.UserMessage("   Joining session (%s: %s)", decision.Rule, decision.Reason)
+			}
+			if err := m.joinSession(session.EventID); err != nil {
+				m.logger.Error("Failed to join session",
 					"event_id", session.EventID,
+					"error", err.Error(),
 				)
+				m.recordSessionHistory(session, func(r *SessionRecord) {
+					r.Decision = &decision
+					r.JoinError = err.Error()
+				})
+				data := savingSessionNotificationData(session, &decision)
+				data["error"] = err.Error()
+				m.notify(EventSavingSessionJoinFailed, "Failed to join saving session",
+					fmt.Sprintf("Could not join the saving session on %s at %s: %v",
+						session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"), err),
+					data)
+				// The session stays announced; the join is retried with backoff
+				m.state.RecordJoinFailure(session.EventID, err, time.Now())
+			} else {
+				m.sessionJoined(session, &decision)
+				joinedAny = true
 			}
-
-			m.state.KnownSessions[session.EventID] = true
+		} else {
+			m.recordSessionHistory(session, func(r *SessionRecord) {
+				r.Decision = &decision
+			})
+			m.logger.Info("Skipped session - denied by join policy",
+				"event_id", session.EventID,
+				"points_per_kwh", session.PointsPerKWh,
+				"rule", decision.Rule,
+				"reason", decision.Reason,
+			)
+			m.notify(EventSavingSessionSkipped, "Skipped saving session",
+				fmt.Sprintf("Skipped the saving session on %s at %s (%s: %s)",
+					session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"), decision.Rule, decision.Reason),
+				savingSessionNotificationData(session, &decision))
 		}
+
+		m.state.MarkSessionKnown(session.EventID)
 	}
 
-	if len(response.Data.SavingSessions.Account.JoinedEvents) == 0 {
+	if m.retryFailedJoins(response) {
+		joinedAny = true
+	}
+
+	// Joined sessions must show up as joined on the next fetch
+	if joinedAny {
+		m.state.Update(func(s *AppState) { s.CachedSavingSessions = nil })
+	}
+
+	if len(response.Data.SavingSessions.Account.JoinedEvents) == 0 && len(response.AvailableEvents) == 0 {
 		m.logger.Debug("No saving sessions found")
 	}
 	
-	return foundNewSessions
+	return foundNewSessions, nil
+}
+
+// sessionJoined logs, records and notifies a session octojoin has just joined
+func (m *SavingSessionMonitor) sessionJoined(session SavingSession, decision *JoinDecision) {
+	m.logger.Info("Successfully joined session", "event_id", session.EventID)
+	m.recordSessionJoined(session, decision)
+	m.notify(EventSavingSessionJoined, "Joined saving session",
+		fmt.Sprintf("Joined the saving session on %s at %s (%s: %s)",
+			session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"), decision.Rule, decision.Reason),
+		savingSessionNotificationData(session, decision))
+}
+
+// retryFailedJoins tries failed joins again once their backoff has passed,
+// until the session starts, is joined some other way or the attempts run
+// out. Only the first failure is notified. It reports whether any join
+// succeeded.
+func (m *SavingSessionMonitor) retryFailedJoins(response *SavingSessionsResponse) bool {
+	var retries map[int]JoinRetry
+	var decisions map[int]*JoinDecision
+	m.state.View(func(s *AppState) {
+		retries = make(map[int]JoinRetry, len(s.JoinRetries))
+		for eventID, retry := range s.JoinRetries {
+			retries[eventID] = *retry
+		}
+		decisions = make(map[int]*JoinDecision, len(s.JoinDecisions))
+		for eventID, decision := range s.JoinDecisions {
+			decisions[eventID] = decision
+		}
+	})
+	if len(retries) == 0 {
+		return false
+	}
+
+	upcoming := make(map[int]SavingSession)
+	for _, event := range response.UnjoinedEvents() {
+		session := event.ToSavingSession()
+		upcoming[session.EventID] = session
+	}
+
+	joined := false
+	now := time.Now()
+	for eventID, retry := range retries {
+		session, ok := upcoming[eventID]
+		decision := decisions[eventID]
+		if !ok || !session.StartAt.After(now) || decision == nil || !decision.Join {
+			// Joined some other way, withdrawn or already started
+			m.state.ClearJoinRetry(eventID)
+			continue
+		}
+		if now.Before(retry.NextAttempt) {
+			continue
+		}
+
+		if err := m.joinSession(eventID); err != nil {
+			m.recordSessionHistory(session, func(r *SessionRecord) { r.JoinError = err.Error() })
+			if retry.Attempts+1 >= JoinMaxAttempts {
+				m.logger.Error("Giving up joining session", "event_id", eventID, "attempts", retry.Attempts+1, "error", err.Error())
+				m.state.ClearJoinRetry(eventID)
+				continue
+			}
+			m.logger.Warn("Retried join failed", "event_id", eventID, "attempts", retry.Attempts+1, "error", err.Error())
+			m.state.RecordJoinFailure(eventID, err, now)
+			continue
+		}
+
+		m.state.ClearJoinRetry(eventID)
+		m.sessionJoined(session, decision)
+		joined = true
+	}
+	return joined
+}
+
+// spinWheels spins every available Wheel of Fortune and returns the results
+func (m *SavingSessionMonitor) spinWheels() ([]WheelSpinResult, error) {
+	spins, err := m.client.getWheelOfFortuneSpinsWithCache(m.state)
+	if err != nil {
+		return nil, fmt.Errorf("could not get Wheel of Fortune spins: %w", err)
+	}
+
+	totalSpins := spins.ElectricitySpins + spins.GasSpins
+	if totalSpins == 0 {
+		m.logger.Debug("No Wheel of Fortune spins available")
+		return nil, nil
+	}
+
+	m.logger.Info("Wheel of Fortune spins available",
+		"total", totalSpins,
+		"electricity", spins.ElectricitySpins,
+		"gas", spins.GasSpins,
+	)
+
+	// The balance before spinning lets the prizes be checked afterwards
+	before, beforeErr := m.freshOctoPoints()
+
+	// Auto-spin all available wheels
+	m.logger.Info("Auto-spinning all available wheels")
+	results, spinErr := m.client.spinAllAvailableWheels(spins)
+	if spinErr != nil {
+		// Spins taken before the error still count
+		spinErr = fmt.Errorf("error during auto-spinning: %w", spinErr)
+	}
+	if len(results) == 0 {
+		m.logger.Warn("No wheels were successfully spun")
+		return nil, spinErr
+	}
+	m.state.RecordWheelSpins(results, time.Now())
+
+	totalPoints := 0
+	electricityPoints := 0
+	gasPoints := 0
+
+	for _, result := range results {
+		totalPoints += result.Prize
+		if result.FuelType == "ELECTRICITY" {
+			electricityPoints += result.Prize
+		} else {
+			gasPoints += result.Prize
+		}
+	}
+
+	m.logger.Info("Auto-spin complete",
+		"total_points", totalPoints,
+		"electricity_points", electricityPoints,
+		"gas_points", gasPoints,
+	)
+
+	// Clear the cached spins so we check for new ones on next run
+	if m.state != nil {
+		m.state.Update(func(s *AppState) { s.CachedWheelOfFortuneSpins = nil })
+	}
+
+	if beforeErr != nil {
+		m.logger.Warn("Could not read OctoPoints before spinning, prizes not verified", "error", beforeErr.Error())
+	} else {
+		m.verifyWheelSpins(before, results)
+	}
+
+	return results, spinErr
+}
+
+// announceSavingSession reports a newly discovered upcoming saving session
+func (m *SavingSessionMonitor) announceSavingSession(session SavingSession) {
+	duration := session.EndAt.Sub(session.StartAt)
+	timeUntil := time.Until(session.StartAt)
+
+	// Use user-friendly output in standalone mode, structured logging in daemon mode
+	if m.daemonMode {
+		m.logger.Info("SAVING SESSION FOUND",
+			"event_id", session.EventID,
+			"date", session.StartAt.Format("Monday, Jan 2"),
+			"time", session.StartAt.Format("15:04"),
+			"duration", m.formatDuration(duration),
+			"points_per_kwh", session.PointsPerKWh,
+			"starts_in", m.formatTimeUntil(timeUntil),
+		)
+	} else {
+		m.logger.UserMessage("🎉 SAVING SESSION FOUND")
+		m.logger.UserMessage("   Date: %s at %s", session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"))
+		m.logger.UserMessage("   Duration: %s", m.formatDuration(duration))
+		m.logger.UserMessage("   Reward: %d OctoPoints per kWh", session.PointsPerKWh)
+		m.logger.UserMessage("   Starts in %s", m.formatTimeUntil(timeUntil))
+	}
+
+	m.notify(EventSavingSessionFound, "Saving session found",
+		fmt.Sprintf("%s at %s for %s, %d OctoPoints per kWh, starts in %s",
+			session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"),
+			m.formatDuration(duration), session.PointsPerKWh, m.formatTimeUntil(timeUntil)),
+		savingSessionNotificationData(session, nil))
+}
+
+// savingSessionNotificationData builds the structured payload for saving session events
+func savingSessionNotificationData(session SavingSession, decision *JoinDecision) map[string]interface{} {
+	data := map[string]interface{}{
+		"event_id":       session.EventID,
+		"start_at":       session.StartAt,
+		"end_at":         session.EndAt,
+		"points_per_kwh": session.PointsPerKWh,
+	}
+	if decision != nil {
+		data["rule"] = decision.Rule
+		data["reason"] = decision.Reason
+	}
+	return data
 }
 
-func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
+func (m *SavingSessionMonitor) checkFreeElectricitySessions() error {
 	response, err := m.client.GetFreeElectricitySessionsWithCache(m.state)
 	if err != nil {
-		m.logger.Error("Error fetching free electricity sessions", "error", err.Error())
-		return false
+		return fmt.Errorf("failed to fetch free electricity sessions: %w", err)
 	}
 
 	currentSessionsFound := 0
-	foundNewSessions := false
 	for _, session := range response.Data {
 		now := time.Now()
 		
@@ -389,13 +833,8 @@ func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
 			continue
 		}
 		
-		// Check if this is a new session
-		if !m.state.KnownFreeElectricitySessions[session.Code] {
-			foundNewSessions = true
-		}
-		
 		// Track that we've seen this session
-		m.state.KnownFreeElectricitySessions[session.Code] = true
+		m.state.Update(func(s *AppState) { s.KnownFreeElectricitySessions[session.Code] = true })
 		currentSessionsFound++
 		
 		// Check if we should alert
@@ -413,6 +852,12 @@ func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
 		
 		// Display the appropriate alert
 		duration := session.EndAt.Sub(session.StartAt)
+		alertData := map[string]interface{}{
+			"code":       session.Code,
+			"start_at":   session.StartAt,
+			"end_at":     session.EndAt,
+			"alert_type": alertType,
+		}
 		
 		if session.StartAt.Before(now) && session.EndAt.After(now) {
 			// Currently active
@@ -428,6 +873,10 @@ func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
 				m.logger.UserMessage("   Time remaining: %s", m.formatTimeUntil(timeLeft))
 				m.logger.UserMessage("   Ends at %s", session.EndAt.Format("15:04"))
 			}
+			m.notify(EventFreeElectricityAlert, "Free electricity session active now",
+				fmt.Sprintf("Your electricity is currently free for another %s (ends at %s)",
+					m.formatTimeUntil(timeLeft), session.EndAt.Format("15:04")),
+				alertData)
 		} else {
 			// Upcoming session
 			startsIn := ""
@@ -451,6 +900,11 @@ func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
 				m.logger.UserMessage("   Starts in %s", startsIn)
 				m.logger.UserMessage("   No action needed - automatically free!")
 			}
+			m.notify(EventFreeElectricityAlert, "Free electricity session - "+alertType,
+				fmt.Sprintf("%s at %s for %s, starts %s",
+					session.StartAt.Format("Monday, Jan 2"), session.StartAt.Format("15:04"),
+					m.formatDuration(duration), startsIn),
+				alertData)
 		}
 	}
 
@@ -458,7 +912,7 @@ func (m *SavingSessionMonitor) checkFreeElectricitySessions() bool {
 		m.logger.Debug("No current or upcoming free electricity sessions found")
 	}
 	
-	return foundNewSessions
+	return nil
 }
 
 func (m *SavingSessionMonitor) CheckOnce() {
@@ -534,8 +988,72 @@ func (m *SavingSessionMonitor) displayCampaignStatus() {
 	}
 }
 
-func (m *SavingSessionMonitor) shouldJoinSession(session SavingSession) bool {
-	return session.OctoPoints >= m.minPointsThreshold
+// shouldJoinSession evaluates the join policy for a session and records the decision
+func (m *SavingSessionMonitor) shouldJoinSession(session SavingSession) JoinDecision {
+	policy := m.joinPolicy
+	if policy == nil {
+		// No policy configured - fall back to the plain points threshold
+		policy, _ = NewJoinPolicy(nil, m.minPointsThreshold)
+	}
+
+	decision := policy.Evaluate(session, time.Now())
+	if m.state != nil {
+		m.state.Update(func(s *AppState) {
+			if s.JoinDecisions == nil {
+				s.JoinDecisions = make(map[int]*JoinDecision)
+			}
+			s.JoinDecisions[session.EventID] = &decision
+		})
+	}
+	return decision
+}
+
+// recordSessionHistory updates the history ledger entry for a session, keeping
+// its times and per-kWh reward current before fn adds anything else
+func (m *SavingSessionMonitor) recordSessionHistory(session SavingSession, fn func(r *SessionRecord)) {
+	m.state.RecordSession(session.EventID, func(r *SessionRecord) {
+		r.StartAt = session.StartAt
+		r.EndAt = session.EndAt
+		if session.PointsPerKWh > 0 {
+			r.PointsPerKWh = session.PointsPerKWh
+		}
+		if fn != nil {
+			fn(r)
+		}
+	})
+}
+
+// recordSessionJoined notes in the ledger that octojoin joined a session
+func (m *SavingSessionMonitor) recordSessionJoined(session SavingSession, decision *JoinDecision) {
+	joinedAt := time.Now()
+	m.recordSessionHistory(session, func(r *SessionRecord) {
+		r.Decision = decision
+		r.Joined = true
+		r.JoinedAt = &joinedAt
+		r.JoinError = ""
+	})
+}
+
+// recordJoinedSession keeps the ledger in step with a session the account has
+// joined, picking up the points awarded once Octopus settles it
+func (m *SavingSessionMonitor) recordJoinedSession(session SavingSession) {
+	settled := session.RewardGivenInOctoPoints > 0
+	upToDate := false
+	m.state.View(func(s *AppState) {
+		record := s.SessionHistory[session.EventID]
+		upToDate = record != nil && record.Joined && (!settled || record.AwardedPoints != nil)
+	})
+	if upToDate {
+		return // nothing new
+	}
+	m.recordSessionHistory(session, func(r *SessionRecord) {
+		r.Joined = true
+		r.JoinError = ""
+		if settled {
+			awarded := session.RewardGivenInOctoPoints
+			r.AwardedPoints = &awarded
+		}
+	})
 }
 
 func (m *SavingSessionMonitor) joinSession(eventID int) error {
@@ -592,26 +1110,43 @@ func (m *SavingSessionMonitor) formatDaysUntil(d time.Duration) string {
 }
 
 func (m *SavingSessionMonitor) shouldAlert(session FreeElectricitySession, timeUntil time.Duration) (bool, string) {
-	code := session.Code
+	return m.shouldAlertWindow(session.Code, session.StartAt, session.EndAt, timeUntil)
+}
+
+// shouldAlertWindow applies the multi-stage reminders to any timed window
+// identified by a unique code
+func (m *SavingSessionMonitor) shouldAlertWindow(code string, startAt, endAt time.Time, timeUntil time.Duration) (bool, string) {
+	var shouldAlert bool
+	var alertType string
+	// Alert flags are updated in place, so hold the write lock throughout
+	m.state.Update(func(s *AppState) {
+		shouldAlert, alertType = nextWindowAlert(s.AlertStates, code, startAt, endAt, timeUntil)
+	})
+	return shouldAlert, alertType
+}
+
+// nextWindowAlert decides which reminder, if any, is due for a window and
+// records it in alerts
+func nextWindowAlert(alerts map[string]*FreeElectricityAlertState, code string, startAt, endAt time.Time, timeUntil time.Duration) (bool, string) {
 	now := time.Now()
 	
 	// Initialize alert state if not exists
-	if _, exists := m.state.AlertStates[code]; !exists {
-		m.state.AlertStates[code] = &FreeElectricityAlertState{
+	if _, exists := alerts[code]; !exists {
+		alerts[code] = &FreeElectricityAlertState{
 			Code: code,
 		}
 	}
 	
-	alert := m.state.AlertStates[code]
+	alert := alerts[code]
 	
 	// Check if session has ended - cleanup alert state
-	if session.EndAt.Before(now) {
-		delete(m.state.AlertStates, code)
+	if endAt.Before(now) {
+		delete(alerts, code)
 		return false, ""
 	}
 	
 	// Currently active - only alert once
-	if session.StartAt.Before(now) && session.EndAt.After(now) {
+	if startAt.Before(now) && endAt.After(now) {
 		if !alert.FinalAlert {
 			alert.FinalAlert = true
 			return true, "ACTIVE NOW"
@@ -0,0 +1,369 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttPacketConnect    = 1
+	mqttPacketConnack    = 2
+	mqttPacketPublish    = 3
+	mqttPacketPuback     = 4
+	mqttPacketSubscribe  = 8
+	mqttPacketSuback     = 9
+	mqttPacketPingreq    = 12
+	mqttPacketPingresp   = 13
+	mqttPacketDisconnect = 14
+)
+
+// mqttOptions describes how to connect to a broker
+type mqttOptions struct {
+	Broker    string // tcp://host:1883, ssl://host:8883 or host:port
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+
+	// Last will, published by the broker if the connection drops
+	WillTopic   string
+	WillPayload []byte
+	WillRetain  bool
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client supporting QoS 0 publish and
+// subscribe, which is all the Home Assistant integration needs
+type mqttClient struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writeMu   sync.Mutex
+	keepAlive time.Duration
+	nextID    uint16
+	onMessage func(topic string, payload []byte)
+}
+
+// dialMQTT connects to the broker and completes the CONNECT handshake
+func dialMQTT(ctx context.Context, opts mqttOptions) (*mqttClient, error) {
+	network, addr, useTLS, err := parseMQTTBroker(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: MQTTConnectTimeout}
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, network, addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", addr, err)
+	}
+
+	client := &mqttClient{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: opts.KeepAlive,
+	}
+
+	conn.SetDeadline(time.Now().Add(MQTTConnectTimeout))
+	if err := client.writePacket(mqttPacketConnect<<4, encodeMQTTConnect(opts)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send MQTT CONNECT: %w", err)
+	}
+
+	packetType, body, err := client.readPacket()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read MQTT CONNACK: %w", err)
+	}
+	if packetType>>4 != mqttPacketConnack || len(body) < 2 {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected MQTT packet %d while connecting", packetType>>4)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, &AuthError{Code: fmt.Sprintf("MQTT-%d", body[1]), Message: mqttConnackReason(body[1])}
+	}
+	conn.SetDeadline(time.Time{})
+
+	return client, nil
+}
+
+// parseMQTTBroker converts a broker URL into a dial network and address
+func parseMQTTBroker(broker string) (string, string, bool, error) {
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", "", false, fmt.Errorf("invalid MQTT broker %q", broker)
+	}
+
+	useTLS := false
+	defaultPort := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		defaultPort = "8883"
+	default:
+		return "", "", false, fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return "tcp", addr, useTLS, nil
+}
+
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("connection refused (code %d)", code)
+	}
+}
+
+// encodeMQTTConnect builds the variable header and payload of a CONNECT packet
+func encodeMQTTConnect(opts mqttOptions) []byte {
+	var flags byte = 0x02 // clean session
+	if opts.WillTopic != "" {
+		flags |= 0x04
+		if opts.WillRetain {
+			flags |= 0x20
+		}
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	var buf []byte
+	buf = appendMQTTString(buf, "MQTT")
+	buf = append(buf, 4, flags) // protocol level 4 = MQTT 3.1.1
+	buf = binary.BigEndian.AppendUint16(buf, uint16(opts.KeepAlive/time.Second))
+	buf = appendMQTTString(buf, opts.ClientID)
+	if opts.WillTopic != "" {
+		buf = appendMQTTString(buf, opts.WillTopic)
+		buf = appendMQTTBytes(buf, opts.WillPayload)
+	}
+	if opts.Username != "" {
+		buf = appendMQTTString(buf, opts.Username)
+		if opts.Password != "" {
+			buf = appendMQTTString(buf, opts.Password)
+		}
+	}
+	return buf
+}
+
+func appendMQTTString(buf []byte, s string) []byte {
+	return appendMQTTBytes(buf, []byte(s))
+}
+
+func appendMQTTBytes(buf []byte, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b)))
+	return append(buf, b...)
+}
+
+// writePacket writes a complete control packet with its remaining length
+func (c *mqttClient) writePacket(header byte, body []byte) error {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// readPacket reads one control packet, returning its first header byte and body
+func (c *mqttClient) readPacket() (byte, []byte, error) {
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := 0
+	multiplier := 1
+	for i := 0; ; i++ {
+		if i >= 4 {
+			return 0, nil, errors.New("malformed MQTT remaining length")
+		}
+		digit, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// Publish sends a QoS 0 message
+func (c *mqttClient) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = mqttPacketPublish << 4
+	if retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+	return c.writePacket(header, body)
+}
+
+// Subscribe requests QoS 0 delivery for the given topic filters
+func (c *mqttClient) Subscribe(topics ...string) error {
+	c.writeMu.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	c.writeMu.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, topic := range topics {
+		body = appendMQTTString(body, topic)
+		body = append(body, 0) // requested QoS 0
+	}
+	return c.writePacket(mqttPacketSubscribe<<4|0x02, body)
+}
+
+// Run reads incoming packets and sends keep-alive pings until the connection
+// fails or the context is cancelled
+func (c *mqttClient) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.readLoop()
+	}()
+
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.Close()
+			return ctx.Err()
+		case err := <-errCh:
+			c.conn.Close()
+			return err
+		case <-ticker.C:
+			if err := c.writePacket(mqttPacketPingreq<<4, nil); err != nil {
+				c.conn.Close()
+				return fmt.Errorf("failed to send MQTT ping: %w", err)
+			}
+		}
+	}
+}
+
+func (c *mqttClient) readLoop() error {
+	for {
+		// The broker must answer pings within the keep-alive window
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		header, body, err := c.readPacket()
+		if err != nil {
+			return fmt.Errorf("MQTT connection lost: %w", err)
+		}
+
+		switch header >> 4 {
+		case mqttPacketPublish:
+			c.handlePublish(header, body)
+		case mqttPacketSuback:
+			if len(body) >= 3 && body[2] == 0x80 {
+				return errors.New("MQTT broker rejected subscription")
+			}
+		case mqttPacketPingresp:
+			// Keep-alive acknowledged
+		}
+	}
+}
+
+func (c *mqttClient) handlePublish(header byte, body []byte) {
+	if len(body) < 2 {
+		return
+	}
+	topicLen := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+topicLen {
+		return
+	}
+	topic := string(body[2 : 2+topicLen])
+	payload := body[2+topicLen:]
+
+	// QoS 1 messages carry a packet identifier that must be acknowledged
+	if qos := (header >> 1) & 0x03; qos > 0 && len(payload) >= 2 {
+		id := payload[:2]
+		payload = payload[2:]
+		if qos == 1 {
+			c.writePacket(mqttPacketPuback<<4, id)
+		}
+	}
+
+	if c.onMessage != nil {
+		c.onMessage(topic, payload)
+	}
+}
+
+// Close sends DISCONNECT and closes the connection
+func (c *mqttClient) Close() error {
+	c.writePacket(mqttPacketDisconnect<<4, nil)
+	return c.conn.Close()
+}
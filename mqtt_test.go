@@ -0,0 +1,257 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMQTTBroker accepts a single client, records its publishes and lets the
+// test push messages back to it
+type fakeMQTTBroker struct {
+	listener net.Listener
+	mu       sync.Mutex
+	connect  []byte
+	retained map[string][]byte
+	subs     []string
+	conn     *mqttClient
+	ready    chan struct{}
+}
+
+func newFakeMQTTBroker(t *testing.T) *fakeMQTTBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	broker := &fakeMQTTBroker{
+		listener: listener,
+		retained: make(map[string][]byte),
+		ready:    make(chan struct{}),
+	}
+	go broker.serve()
+	t.Cleanup(func() { listener.Close() })
+	return broker
+}
+
+func (b *fakeMQTTBroker) serve() {
+	conn, err := b.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The client's framing helpers work equally well on the broker side
+	peer := &mqttClient{conn: conn, reader: bufio.NewReader(conn)}
+	b.mu.Lock()
+	b.conn = peer
+	b.mu.Unlock()
+
+	for {
+		header, body, err := peer.readPacket()
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case mqttPacketConnect:
+			b.mu.Lock()
+			b.connect = body
+			b.mu.Unlock()
+			peer.writePacket(mqttPacketConnack<<4, []byte{0, 0})
+		case mqttPacketPublish:
+			topicLen := int(binary.BigEndian.Uint16(body))
+			b.mu.Lock()
+			b.retained[string(body[2:2+topicLen])] = body[2+topicLen:]
+			b.mu.Unlock()
+		case mqttPacketSubscribe:
+			topicLen := int(binary.BigEndian.Uint16(body[2:]))
+			b.mu.Lock()
+			b.subs = append(b.subs, string(body[4:4+topicLen]))
+			b.mu.Unlock()
+			peer.writePacket(mqttPacketSuback<<4, []byte{body[0], body[1], 0})
+			close(b.ready)
+		case mqttPacketPingreq:
+			peer.writePacket(mqttPacketPingresp<<4, nil)
+		case mqttPacketDisconnect:
+			return
+		}
+	}
+}
+
+func (b *fakeMQTTBroker) get(topic string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	payload, ok := b.retained[topic]
+	return payload, ok
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMQTTPublisherDiscoveryStateAndCommands(t *testing.T) {
+	broker := newFakeMQTTBroker(t)
+
+	client := NewOctopusClient("A-TEST1234", "sk_live_test", false)
+	monitor := NewSavingSessionMonitor(client, "A-TEST1234")
+	monitor.EnableMQTT(&MQTTConfig{
+		Enabled:  true,
+		Broker:   broker.listener.Addr().String(),
+		Username: "octojoin",
+		Password: "secret",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.mqtt.Run(ctx)
+
+	select {
+	case <-broker.ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for subscription")
+	}
+
+	broker.mu.Lock()
+	connect, subs := broker.connect, broker.subs
+	broker.mu.Unlock()
+	if !strings.Contains(string(connect), "octojoin/A-TEST1234/status") || !strings.Contains(string(connect), "offline") {
+		t.Errorf("Expected last will on status topic in CONNECT, got %q", connect)
+	}
+	if len(subs) != 1 || subs[0] != "octojoin/A-TEST1234/command/+" {
+		t.Errorf("Expected command subscription, got %v", subs)
+	}
+
+	waitFor(t, "availability", func() bool {
+		payload, ok := broker.get("octojoin/A-TEST1234/status")
+		return ok && string(payload) == "online"
+	})
+
+	payload, ok := broker.get("homeassistant/sensor/octojoin_a_test1234/octopoints/config")
+	if !ok {
+		t.Fatal("Expected OctoPoints discovery config to be published")
+	}
+	var discovery map[string]interface{}
+	if err := json.Unmarshal(payload, &discovery); err != nil {
+		t.Fatalf("Expected JSON discovery config, got %v", err)
+	}
+	if discovery["state_topic"] != "octojoin/A-TEST1234/state" || discovery["unique_id"] != "octojoin_a_test1234_octopoints" {
+		t.Errorf("Unexpected discovery config: %v", discovery)
+	}
+	if _, ok := broker.get("homeassistant/button/octojoin_a_test1234/join_next_session/config"); !ok {
+		t.Error("Expected join button discovery config to be published")
+	}
+
+	// State is published as retained JSON in the dashboard shape
+	monitor.mqtt.PublishState(SessionData{CurrentPoints: 1234})
+	waitFor(t, "state", func() bool {
+		_, ok := broker.get("octojoin/A-TEST1234/state")
+		return ok
+	})
+	payload, _ = broker.get("octojoin/A-TEST1234/state")
+	var state SessionData
+	if err := json.Unmarshal(payload, &state); err != nil || state.CurrentPoints != 1234 {
+		t.Errorf("Expected state with 1234 points, got %s (%v)", payload, err)
+	}
+
+	// Command topics are queued for the monitor loop
+	testCases := []struct {
+		topic    string
+		payload  string
+		expected MonitorCommand
+	}{
+		{"octojoin/A-TEST1234/command/check", "", MonitorCommand{Kind: CommandCheck}},
+		{"octojoin/A-TEST1234/command/spin", "PRESS", MonitorCommand{Kind: CommandSpinWheels}},
+		{"octojoin/A-TEST1234/command/join", "next", MonitorCommand{Kind: CommandJoinSession}},
+		{"octojoin/A-TEST1234/command/join", "4242", MonitorCommand{Kind: CommandJoinSession, EventID: 4242}},
+	}
+
+	for _, tc := range testCases {
+		body := appendMQTTString(nil, tc.topic)
+		body = append(body, tc.payload...)
+		broker.conn.writePacket(mqttPacketPublish<<4, body)
+
+		select {
+		case cmd := <-monitor.commands:
+			if cmd != tc.expected {
+				t.Errorf("Expected command %+v for %s, got %+v", tc.expected, tc.topic, cmd)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for command from %s", tc.topic)
+		}
+	}
+}
+
+func TestParseMQTTBroker(t *testing.T) {
+	testCases := []struct {
+		broker  string
+		addr    string
+		useTLS  bool
+		wantErr bool
+	}{
+		{"localhost", "localhost:1883", false, false},
+		{"192.168.1.10:1884", "192.168.1.10:1884", false, false},
+		{"tcp://mqtt.local", "mqtt.local:1883", false, false},
+		{"ssl://mqtt.example.com", "mqtt.example.com:8883", true, false},
+		{"tls://mqtt.example.com:9883", "mqtt.example.com:9883", true, false},
+		{"ws://mqtt.example.com", "", false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.broker, func(t *testing.T) {
+			_, addr, useTLS, err := parseMQTTBroker(tc.broker)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tc.wantErr, err)
+			}
+			if addr != tc.addr || useTLS != tc.useTLS {
+				t.Errorf("Expected %s (tls=%v), got %s (tls=%v)", tc.addr, tc.useTLS, addr, useTLS)
+			}
+		})
+	}
+}
+
+func TestMQTTRemainingLengthRoundTrip(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	writer := &mqttClient{conn: client}
+	reader := &mqttClient{conn: server, reader: bufio.NewReader(server)}
+
+	// 200 bytes needs a two byte remaining length
+	body := []byte(strings.Repeat("x", 200))
+	go writer.writePacket(mqttPacketPublish<<4, body)
+
+	header, got, err := reader.readPacket()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if header>>4 != mqttPacketPublish || len(got) != len(body) {
+		t.Errorf("Expected publish with %d bytes, got type %d with %d bytes", len(body), header>>4, len(got))
+	}
+}
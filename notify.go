@@ -0,0 +1,522 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification event types
+const (
+	EventSavingSessionFound      = "saving_session_found"
+	EventSavingSessionJoined     = "saving_session_joined"
+	EventSavingSessionJoinFailed = "saving_session_join_failed"
+	EventSavingSessionSkipped    = "saving_session_skipped"
+	EventFreeElectricityAlert    = "free_electricity_alert"
+	EventAgilePriceAlert         = "agile_price_alert"
+	EventIntelligentDispatch     = "intelligent_dispatch"
+	EventPointsRedeemed          = "points_redeemed"
+	EventPointsRedemptionFailed  = "points_redemption_failed"
+)
+
+// knownNotificationEvents lists the event types backends may filter on
+var knownNotificationEvents = map[string]bool{
+	EventSavingSessionFound:      true,
+	EventSavingSessionJoined:     true,
+	EventSavingSessionJoinFailed: true,
+	EventSavingSessionSkipped:    true,
+	EventFreeElectricityAlert:    true,
+	EventAgilePriceAlert:         true,
+	EventIntelligentDispatch:     true,
+	EventPointsRedeemed:          true,
+	EventPointsRedemptionFailed:  true,
+}
+
+// NotificationEvent is a single alert sent to every interested backend
+type NotificationEvent struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Notifier delivers notification events to an external service
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event NotificationEvent) error
+}
+
+// NotificationsConfig is the notifications block of the configuration file
+type NotificationsConfig struct {
+	Backends []NotificationBackendConfig `yaml:"backends"`
+}
+
+// NotificationBackendConfig configures one notification backend. Only the
+// fields relevant to Type are used.
+type NotificationBackendConfig struct {
+	Name              string   `yaml:"name"`
+	Type              string   `yaml:"type"`   // webhook, ntfy, gotify or smtp
+	Events            []string `yaml:"events"` // Event types to send (empty = all)
+	Retries           int      `yaml:"retries"`
+	RetryDelaySeconds int      `yaml:"retry_delay_seconds"`
+
+	// Webhook, ntfy and gotify
+	URL      string `yaml:"url"`
+	Secret   string `yaml:"secret"`   // Webhook HMAC signing secret
+	Topic    string `yaml:"topic"`    // ntfy topic
+	Token    string `yaml:"token"`    // ntfy access token or gotify app token
+	Priority int    `yaml:"priority"` // ntfy (1-5) or gotify (0-10) priority
+
+	// SMTP
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// validate returns a list of problems with the backend configuration
+func (b *NotificationBackendConfig) validate() []string {
+	var problems []string
+	label := b.label()
+
+	switch strings.ToLower(b.Type) {
+	case "webhook", "gotify":
+		if b.URL == "" {
+			problems = append(problems, fmt.Sprintf("%s: url is required", label))
+		}
+	case "ntfy":
+		if b.Topic == "" {
+			problems = append(problems, fmt.Sprintf("%s: topic is required", label))
+		}
+	case "smtp":
+		if b.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s: host is required", label))
+		}
+		if b.From == "" || len(b.To) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: from and to are required", label))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unknown type %q (expected webhook, ntfy, gotify or smtp)", label, b.Type))
+	}
+
+	for _, event := range b.Events {
+		if !knownNotificationEvents[event] {
+			problems = append(problems, fmt.Sprintf("%s: unknown event %q", label, event))
+		}
+	}
+	if b.Retries < 0 {
+		problems = append(problems, fmt.Sprintf("%s: retries cannot be negative", label))
+	}
+
+	return problems
+}
+
+func (b *NotificationBackendConfig) label() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.Type
+}
+
+// NotificationManager fans events out to the configured backends, applying
+// per-backend event filters and retries
+type NotificationManager struct {
+	backends []*notificationBackend
+	logger   *Logger
+}
+
+type notificationBackend struct {
+	notifier   Notifier
+	events     map[string]bool
+	retries    int
+	retryDelay time.Duration
+}
+
+// wants reports whether the backend is subscribed to the event type
+func (b *notificationBackend) wants(eventType string) bool {
+	return len(b.events) == 0 || b.events[eventType]
+}
+
+// NewNotificationManager builds the backends described by the configuration
+func NewNotificationManager(cfg *NotificationsConfig, logger *Logger) (*NotificationManager, error) {
+	manager := &NotificationManager{
+		logger: logger.WithComponent("notifications"),
+	}
+	if cfg == nil {
+		return manager, nil
+	}
+
+	for i := range cfg.Backends {
+		backendCfg := cfg.Backends[i]
+		if problems := backendCfg.validate(); len(problems) > 0 {
+			return nil, fmt.Errorf("invalid notification backend: %s", strings.Join(problems, "; "))
+		}
+
+		notifier := newNotifier(backendCfg)
+		manager.AddNotifier(notifier, backendCfg.Events, backendCfg.Retries, time.Duration(backendCfg.RetryDelaySeconds)*time.Second)
+	}
+
+	return manager, nil
+}
+
+func newNotifier(cfg NotificationBackendConfig) Notifier {
+	client := &http.Client{Timeout: NotificationTimeout}
+	switch strings.ToLower(cfg.Type) {
+	case "webhook":
+		return &WebhookNotifier{name: cfg.label(), url: cfg.URL, secret: cfg.Secret, client: client}
+	case "ntfy":
+		server := cfg.URL
+		if server == "" {
+			server = NotificationDefaultNtfyServer
+		}
+		return &NtfyNotifier{name: cfg.label(), server: server, topic: cfg.Topic, token: cfg.Token, priority: cfg.Priority, client: client}
+	case "gotify":
+		return &GotifyNotifier{name: cfg.label(), server: cfg.URL, token: cfg.Token, priority: cfg.Priority, client: client}
+	default:
+		port := cfg.Port
+		if port == 0 {
+			port = 587
+		}
+		return &SMTPNotifier{name: cfg.label(), host: cfg.Host, port: port, username: cfg.Username, password: cfg.Password, from: cfg.From, to: cfg.To}
+	}
+}
+
+// AddNotifier registers a backend. An empty events list subscribes to everything.
+func (n *NotificationManager) AddNotifier(notifier Notifier, events []string, retries int, retryDelay time.Duration) {
+	backend := &notificationBackend{
+		notifier:   notifier,
+		retries:    retries,
+		retryDelay: retryDelay,
+	}
+	if backend.retryDelay <= 0 {
+		backend.retryDelay = NotificationDefaultRetryDelay
+	}
+	if len(events) > 0 {
+		backend.events = make(map[string]bool)
+		for _, event := range events {
+			backend.events[event] = true
+		}
+	}
+	n.backends = append(n.backends, backend)
+}
+
+// Enabled reports whether any backend is configured
+func (n *NotificationManager) Enabled() bool {
+	return n != nil && len(n.backends) > 0
+}
+
+// Notify delivers an event in the background so slow backends never block the monitor
+func (n *NotificationManager) Notify(event NotificationEvent) {
+	if !n.Enabled() {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), NotificationDispatchTimeout)
+		defer cancel()
+		_ = n.Dispatch(ctx, event)
+	}()
+}
+
+// Dispatch delivers an event to every subscribed backend and returns the first failure
+func (n *NotificationManager) Dispatch(ctx context.Context, event NotificationEvent) error {
+	if !n.Enabled() {
+		return nil
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	var firstErr error
+	for _, backend := range n.backends {
+		if !backend.wants(event.Type) {
+			continue
+		}
+		if err := n.deliver(ctx, backend, event); err != nil {
+			n.logger.Warn("Notification delivery failed",
+				"backend", backend.notifier.Name(),
+				"event", event.Type,
+				"error", err.Error(),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// deliver sends to a single backend, retrying with exponential backoff
+func (n *NotificationManager) deliver(ctx context.Context, backend *notificationBackend, event NotificationEvent) error {
+	var err error
+	for attempt := 0; attempt <= backend.retries; attempt++ {
+		if attempt > 0 {
+			delay := backend.retryDelay * time.Duration(1<<(attempt-1))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err = backend.notifier.Notify(ctx, event); err == nil {
+			n.logger.Debug("Notification delivered",
+				"backend", backend.notifier.Name(),
+				"event", event.Type,
+				"attempt", attempt+1,
+			)
+			return nil
+		}
+	}
+	return err
+}
+
+// WebhookNotifier posts the event as JSON, signed with HMAC-SHA256 when a secret is set
+type WebhookNotifier struct {
+	name   string
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (w *WebhookNotifier) Name() string { return w.name }
+
+func (w *WebhookNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", GetUserAgent())
+	req.Header.Set("X-Octojoin-Event", event.Type)
+	if w.secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhookPayload(w.secret, body))
+	}
+
+	return doNotificationRequest(w.client, req)
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of the payload
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NtfyNotifier publishes to an ntfy topic
+type NtfyNotifier struct {
+	name     string
+	server   string
+	topic    string
+	token    string
+	priority int
+	client   *http.Client
+}
+
+func (n *NtfyNotifier) Name() string { return n.name }
+
+func (n *NtfyNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	url := strings.TrimRight(n.server, "/") + "/" + n.topic
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(event.Message))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("User-Agent", GetUserAgent())
+	req.Header.Set("Title", event.Title)
+	req.Header.Set("Tags", event.Type)
+	if n.priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(n.priority))
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return doNotificationRequest(n.client, req)
+}
+
+// GotifyNotifier sends a message to a Gotify server
+type GotifyNotifier struct {
+	name     string
+	server   string
+	token    string
+	priority int
+	client   *http.Client
+}
+
+func (g *GotifyNotifier) Name() string { return g.name }
+
+func (g *GotifyNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    event.Title,
+		"message":  event.Message,
+		"priority": g.priority,
+		"extras": map[string]interface{}{
+			"octojoin::event": event.Type,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal gotify payload: %w", err)
+	}
+
+	url := strings.TrimRight(g.server, "/") + "/message"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create gotify request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", GetUserAgent())
+	req.Header.Set("X-Gotify-Key", g.token)
+
+	return doNotificationRequest(g.client, req)
+}
+
+// doNotificationRequest executes a request and treats any non-2xx response as a failure
+func doNotificationRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return NewAPIError(resp.StatusCode, req.URL.Host, "notification rejected", nil)
+	}
+	return nil
+}
+
+// SMTPNotifier emails the event using plain SMTP (with STARTTLS when offered)
+type SMTPNotifier struct {
+	name     string
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (s *SMTPNotifier) Name() string { return s.name }
+
+func (s *SMTPNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + s.from + "\r\n")
+	msg.WriteString("To: " + strings.Join(s.to, ", ") + "\r\n")
+	msg.WriteString("Subject: [octojoin] " + event.Title + "\r\n")
+	msg.WriteString("Date: " + event.Timestamp.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("X-Octojoin-Event: " + event.Type + "\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(event.Message + "\r\n")
+
+	// The deadline covers the whole conversation, so a server that stops
+	// responding can't hold the connection open
+	deadline := time.Now().Add(NotificationTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set deadline for %s: %w", addr, err)
+	}
+	// Cancelling ctx closes the connection, unblocking any read or write
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := s.send(conn, auth, []byte(msg.String())); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send email via %s: %w", addr, err)
+	}
+	return nil
+}
+
+// send runs the SMTP conversation on conn, as smtp.SendMail does
+func (s *SMTPNotifier) send(conn net.Conn, auth smtp.Auth, msg []byte) error {
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("server does not support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
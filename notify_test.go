@@ -0,0 +1,342 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testNotificationEvent(eventType string) NotificationEvent {
+	return NotificationEvent{
+		Type:      eventType,
+		Title:     "Saving session found",
+		Message:   "Monday, Jan 20 at 17:30 for 1h, 1600 OctoPoints",
+		Timestamp: time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC),
+		Data:      map[string]interface{}{"event_id": 42},
+	}
+}
+
+func TestWebhookNotifierSignsPayload(t *testing.T) {
+	var gotSignature, gotEvent string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get("X-Octojoin-Event")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	manager, err := NewNotificationManager(&NotificationsConfig{
+		Backends: []NotificationBackendConfig{
+			{Type: "webhook", URL: server.URL, Secret: "s3cret"},
+		},
+	}, NewLogger(false))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := manager.Dispatch(context.Background(), testNotificationEvent(EventSavingSessionFound)); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+
+	if gotEvent != EventSavingSessionFound {
+		t.Errorf("Expected event header %s, got %s", EventSavingSessionFound, gotEvent)
+	}
+
+	expected := "sha256=" + signWebhookPayload("s3cret", gotBody)
+	if gotSignature != expected {
+		t.Errorf("Expected signature %s, got %s", expected, gotSignature)
+	}
+
+	var payload NotificationEvent
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("Expected JSON payload, got %v", err)
+	}
+	if payload.Type != EventSavingSessionFound || payload.Title == "" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestNtfyAndGotifyNotifiers(t *testing.T) {
+	var ntfyTitle, ntfyAuth, ntfyBody, gotifyKey string
+	var gotifyPayload map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/octojoin-alerts":
+			ntfyTitle = r.Header.Get("Title")
+			ntfyAuth = r.Header.Get("Authorization")
+			body, _ := io.ReadAll(r.Body)
+			ntfyBody = string(body)
+		case "/message":
+			gotifyKey = r.Header.Get("X-Gotify-Key")
+			json.NewDecoder(r.Body).Decode(&gotifyPayload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager, err := NewNotificationManager(&NotificationsConfig{
+		Backends: []NotificationBackendConfig{
+			{Type: "ntfy", URL: server.URL, Topic: "octojoin-alerts", Token: "tk_test"},
+			{Type: "gotify", URL: server.URL, Token: "gotify-app-token", Priority: 5},
+		},
+	}, NewLogger(false))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	event := testNotificationEvent(EventFreeElectricityAlert)
+	if err := manager.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+
+	if ntfyTitle != event.Title || ntfyBody != event.Message {
+		t.Errorf("Unexpected ntfy message: title=%q body=%q", ntfyTitle, ntfyBody)
+	}
+	if ntfyAuth != "Bearer tk_test" {
+		t.Errorf("Expected ntfy bearer token, got %q", ntfyAuth)
+	}
+	if gotifyKey != "gotify-app-token" {
+		t.Errorf("Expected gotify token header, got %q", gotifyKey)
+	}
+	if gotifyPayload["title"] != event.Title || gotifyPayload["priority"] != float64(5) {
+		t.Errorf("Unexpected gotify payload: %v", gotifyPayload)
+	}
+}
+
+func TestNotificationEventFiltering(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	manager, err := NewNotificationManager(&NotificationsConfig{
+		Backends: []NotificationBackendConfig{
+			{Type: "webhook", URL: server.URL, Events: []string{EventSavingSessionJoined}},
+		},
+	}, NewLogger(false))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	manager.Dispatch(context.Background(), testNotificationEvent(EventSavingSessionFound))
+	manager.Dispatch(context.Background(), testNotificationEvent(EventSavingSessionJoined))
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 delivery for subscribed event, got %d", got)
+	}
+}
+
+func TestNotificationRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	manager, _ := NewNotificationManager(nil, NewLogger(false))
+	manager.AddNotifier(&WebhookNotifier{name: "flaky", url: server.URL, client: http.DefaultClient}, nil, 2, 10*time.Millisecond)
+
+	if err := manager.Dispatch(context.Background(), testNotificationEvent(EventSavingSessionJoined)); err != nil {
+		t.Fatalf("Expected delivery to succeed after retries, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+
+	// A backend that never recovers reports the last error
+	atomic.StoreInt32(&calls, -100)
+	manager.backends[0].retries = 1
+	if err := manager.Dispatch(context.Background(), testNotificationEvent(EventSavingSessionJoined)); err == nil {
+		t.Error("Expected delivery to fail once retries are exhausted")
+	}
+}
+
+// fakeSMTPServer is a minimal SMTP stand-in that records the DATA of each message
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	write("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			write("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			write("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			write("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			write("221 bye")
+			return
+		default:
+			write("250 ok")
+		}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, portStr, _ := net.SplitHostPort(server.listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	manager, err := NewNotificationManager(&NotificationsConfig{
+		Backends: []NotificationBackendConfig{
+			{Type: "smtp", Host: host, Port: port, From: "octojoin@example.com", To: []string{"me@example.com"}},
+		},
+	}, NewLogger(false))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	event := testNotificationEvent(EventSavingSessionJoined)
+	if err := manager.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("Expected email delivery to succeed, got %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.messages) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(server.messages))
+	}
+	if !strings.Contains(server.messages[0], "Subject: [octojoin] "+event.Title) {
+		t.Errorf("Expected subject in email, got %q", server.messages[0])
+	}
+	if !strings.Contains(server.messages[0], event.Message) {
+		t.Errorf("Expected message body in email, got %q", server.messages[0])
+	}
+}
+
+func TestSMTPNotifierStalledServer(t *testing.T) {
+	// Accepts connections but never sends its greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	notifier := &SMTPNotifier{name: "smtp", host: host, port: port, from: "octojoin@example.com", to: []string{"me@example.com"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := notifier.Notify(ctx, testNotificationEvent(EventSavingSessionJoined)); err == nil {
+		t.Error("Expected the stalled delivery to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the deadline to end the delivery, took %v", elapsed)
+	}
+}
+
+func TestNotificationBackendValidation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		backend NotificationBackendConfig
+		valid   bool
+	}{
+		{name: "Valid webhook", backend: NotificationBackendConfig{Type: "webhook", URL: "https://example.com/hook"}, valid: true},
+		{name: "Webhook without URL", backend: NotificationBackendConfig{Type: "webhook"}, valid: false},
+		{name: "Ntfy without topic", backend: NotificationBackendConfig{Type: "ntfy"}, valid: false},
+		{name: "SMTP without recipients", backend: NotificationBackendConfig{Type: "smtp", Host: "mail.example.com", From: "a@example.com"}, valid: false},
+		{name: "Unknown type", backend: NotificationBackendConfig{Type: "pager"}, valid: false},
+		{name: "Unknown event", backend: NotificationBackendConfig{Type: "ntfy", Topic: "t", Events: []string{"everything"}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := tc.backend.validate()
+			if (len(problems) == 0) != tc.valid {
+				t.Errorf("Expected valid=%v, got problems %v", tc.valid, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// SessionPerformance compares usage during a joined saving session with a
+// locally calculated baseline. Octopus settles against its own baseline, so
+// the points are an estimate.
+type SessionPerformance struct {
+	BaselineKWh      float64   `json:"baseline_kwh"`
+	ActualKWh        float64   `json:"actual_kwh"`
+	ReductionKWh     float64   `json:"reduction_kwh"` // negative if more was used than usual
+	ReductionPercent float64   `json:"reduction_percent"`
+	EstimatedPoints  int       `json:"estimated_points"`
+	BaselineDays     []string  `json:"baseline_days"` // dates the baseline was averaged over
+	CalculatedAt     time.Time `json:"calculated_at"`
+}
+
+// halfHourlyUsage sums measurements from every meter into kWh per half-hour
+func halfHourlyUsage(measurements []UsageMeasurement) map[time.Time]float64 {
+	usage := make(map[time.Time]float64)
+	for _, m := range measurements {
+		usage[m.StartAt.UTC()] += m.GetValueAsFloat64()
+	}
+	return usage
+}
+
+// isWeekend reports whether t falls on a Saturday or Sunday
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// calculateSessionPerformance works out the baseline for a session the way
+// Octopus describes it: the average of the same half-hours over the previous
+// 10 weekdays (or 4 weekend days for a weekend session), skipping days with
+// another saving session or missing readings. Points are earned per kWh saved
+// in each half-hour, so half-hours above baseline don't cancel out the others.
+func calculateSessionPerformance(startAt, endAt time.Time, pointsPerKWh int, usage map[time.Time]float64, excludedDays map[string]bool, now time.Time) (*SessionPerformance, error) {
+	ukLocation, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		ukLocation = time.UTC
+	}
+
+	var slots []time.Time
+	for slot := startAt.UTC(); slot.Before(endAt); slot = slot.Add(30 * time.Minute) {
+		slots = append(slots, slot)
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("session has no half-hours")
+	}
+
+	// shift moves a session half-hour to the same UK clock time daysBack earlier
+	shift := func(slot time.Time, daysBack int) time.Time {
+		local := slot.In(ukLocation)
+		return time.Date(local.Year(), local.Month(), local.Day()-daysBack, local.Hour(), local.Minute(), 0, 0, ukLocation).UTC()
+	}
+	complete := func(daysBack int) bool {
+		for _, slot := range slots {
+			if _, ok := usage[shift(slot, daysBack)]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !complete(0) {
+		return nil, fmt.Errorf("smart meter readings for the session are not available yet")
+	}
+
+	sessionDay := startAt.In(ukLocation)
+	wanted := BaselineWeekdays
+	if isWeekend(sessionDay) {
+		wanted = BaselineWeekendDays
+	}
+	var days []int
+	for daysBack := 1; daysBack <= BaselineLookbackDays && len(days) < wanted; daysBack++ {
+		day := sessionDay.AddDate(0, 0, -daysBack)
+		if isWeekend(day) != isWeekend(sessionDay) || excludedDays[day.Format("2006-01-02")] || !complete(daysBack) {
+			continue
+		}
+		days = append(days, daysBack)
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no comparable days with readings in the last %d days", BaselineLookbackDays)
+	}
+
+	performance := &SessionPerformance{BaselineDays: []string{}, CalculatedAt: now}
+	for _, daysBack := range days {
+		performance.BaselineDays = append(performance.BaselineDays, sessionDay.AddDate(0, 0, -daysBack).Format("2006-01-02"))
+	}
+	savedKWh := 0.0
+	for _, slot := range slots {
+		baseline := 0.0
+		for _, daysBack := range days {
+			baseline += usage[shift(slot, daysBack)]
+		}
+		baseline /= float64(len(days))
+		actual := usage[slot]
+
+		performance.BaselineKWh += baseline
+		performance.ActualKWh += actual
+		savedKWh += math.Max(0, baseline-actual)
+	}
+	performance.ReductionKWh = performance.BaselineKWh - performance.ActualKWh
+	if performance.BaselineKWh > 0 {
+		performance.ReductionPercent = performance.ReductionKWh / performance.BaselineKWh * 100
+	}
+	performance.EstimatedPoints = int(math.Floor(savedKWh * float64(pointsPerKWh)))
+	return performance, nil
+}
+
+// updateSessionPerformance reports on joined sessions that have finished,
+// once their smart meter readings arrive. Readings can lag by a day or more,
+// so sessions are retried each check until PerformanceReportMaxAge.
+func (m *SavingSessionMonitor) updateSessionPerformance() {
+	now := time.Now()
+	ukLocation, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		ukLocation = time.UTC
+	}
+
+	// Days with any saving session are left out of every baseline
+	var pending []*SessionRecord
+	excludedDays := make(map[string]bool)
+	m.state.View(func(s *AppState) {
+		for _, record := range s.SessionHistory {
+			excludedDays[record.StartAt.In(ukLocation).Format("2006-01-02")] = true
+			if record.Joined && record.Performance == nil && !record.EndAt.After(now) && now.Sub(record.EndAt) < PerformanceReportMaxAge {
+				pending = append(pending, record)
+			}
+		}
+	})
+	if len(pending) == 0 {
+		return
+	}
+
+	oldest := now
+	for _, record := range pending {
+		if record.StartAt.Before(oldest) {
+			oldest = record.StartAt
+		}
+	}
+	days := min(int(now.Sub(oldest).Hours()/24)+BaselineLookbackDays+1, WebMaxUsageDays)
+	measurements, err := m.client.getUsageMeasurementsWithCache(m.state, days)
+	if err != nil {
+		m.logger.Debug("No usage for saving session performance", "error", err.Error())
+		return
+	}
+	usage := halfHourlyUsage(measurements)
+
+	for _, record := range pending {
+		performance, err := calculateSessionPerformance(record.StartAt, record.EndAt, record.PointsPerKWh, usage, excludedDays, now)
+		if err != nil {
+			m.logger.Debug("Saving session performance not ready", "event_id", record.EventID, "reason", err.Error())
+			continue
+		}
+
+		m.state.RecordSession(record.EventID, func(r *SessionRecord) { r.Performance = performance })
+		m.logger.Info("Saving session performance",
+			"event_id", record.EventID,
+			"baseline_kwh", fmt.Sprintf("%.3f", performance.BaselineKWh),
+			"actual_kwh", fmt.Sprintf("%.3f", performance.ActualKWh),
+			"reduction_percent", fmt.Sprintf("%.1f", performance.ReductionPercent),
+			"estimated_points", performance.EstimatedPoints,
+		)
+	}
+}
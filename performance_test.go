@@ -0,0 +1,184 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeHalfHourlyUsage returns 30 days of readings up to the end of 2025-01-19:
+// 1 kWh per half-hour on weekdays and 5 kWh at weekends
+func fakeHalfHourlyUsage() map[time.Time]float64 {
+	usage := make(map[time.Time]float64)
+	end := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	for slot := end.AddDate(0, 0, -30); slot.Before(end); slot = slot.Add(30 * time.Minute) {
+		usage[slot] = 1
+		if isWeekend(slot) {
+			usage[slot] = 5
+		}
+	}
+	return usage
+}
+
+func TestCalculateSessionPerformance(t *testing.T) {
+	usage := fakeHalfHourlyUsage()
+	start := time.Date(2025, 1, 15, 17, 0, 0, 0, time.UTC) // a Wednesday, UK time is UTC in January
+	usage[start] = 0.4
+	usage[start.Add(30*time.Minute)] = 1.2
+	// The day before had another session and must not count
+	usage[start.AddDate(0, 0, -1)] = 9
+	excluded := map[string]bool{"2025-01-14": true}
+
+	performance, err := calculateSessionPerformance(start, start.Add(time.Hour), 100, usage, excluded, start.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("Expected a performance report, got %v", err)
+	}
+	if len(performance.BaselineDays) != BaselineWeekdays {
+		t.Errorf("Expected %d baseline days, got %v", BaselineWeekdays, performance.BaselineDays)
+	}
+	for _, day := range performance.BaselineDays {
+		date, _ := time.Parse("2006-01-02", day)
+		if day == "2025-01-14" || isWeekend(date) {
+			t.Errorf("Expected only weekdays without sessions in the baseline, got %s", day)
+		}
+	}
+	if math.Abs(performance.BaselineKWh-2) > 1e-9 || math.Abs(performance.ActualKWh-1.6) > 1e-9 {
+		t.Errorf("Expected 2 kWh baseline and 1.6 kWh actual, got %+v", performance)
+	}
+	if math.Abs(performance.ReductionPercent-20) > 1e-9 {
+		t.Errorf("Expected a 20%% reduction, got %.2f", performance.ReductionPercent)
+	}
+	// Only the first half-hour was below baseline: 0.6 kWh at 100 points per kWh
+	if performance.EstimatedPoints != 60 {
+		t.Errorf("Expected 60 estimated points, got %d", performance.EstimatedPoints)
+	}
+
+	// Weekend sessions are compared with weekend days
+	saturday := time.Date(2025, 1, 18, 17, 0, 0, 0, time.UTC)
+	weekend, err := calculateSessionPerformance(saturday, saturday.Add(time.Hour), 100, usage, nil, saturday.Add(48*time.Hour))
+	if err != nil || len(weekend.BaselineDays) != BaselineWeekendDays || weekend.BaselineKWh != 10 || weekend.ReductionKWh != 0 {
+		t.Errorf("Expected a weekend baseline of 10 kWh from %d days, got %+v (%v)", BaselineWeekendDays, weekend, err)
+	}
+
+	// Sessions without readings yet are retried later
+	delete(usage, start.Add(30*time.Minute))
+	if _, err := calculateSessionPerformance(start, start.Add(time.Hour), 100, usage, nil, start.Add(time.Hour)); err == nil {
+		t.Error("Expected an error while session readings are missing")
+	}
+}
+
+// cacheFlatUsage stores 30 days of 1 kWh half-hours up to now, with the
+// given half-hours overridden, as if they had just been fetched
+func cacheFlatUsage(state *AppState, overrides map[time.Time]float64) {
+	now := time.Now()
+	var measurements []UsageMeasurement
+	for slot := now.Truncate(30*time.Minute).AddDate(0, 0, -30); slot.Before(now); slot = slot.Add(30 * time.Minute) {
+		value, ok := overrides[slot]
+		if !ok {
+			value = 1
+		}
+		if value < 0 {
+			continue // missing reading
+		}
+		measurements = append(measurements, UsageMeasurement{
+			Value:   strconv.FormatFloat(value, 'f', -1, 64),
+			Unit:    "kWh",
+			StartAt: slot,
+			EndAt:   slot.Add(30 * time.Minute),
+		})
+	}
+	state.Update(func(s *AppState) {
+		s.CachedUsageMeasurements = &CachedUsageMeasurements{Data: measurements, Timestamp: now, Days: WebMaxUsageDays}
+	})
+}
+
+func TestUpdateSessionPerformance(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	start := time.Now().Truncate(30 * time.Minute).Add(-30 * time.Hour)
+	// The household used 40% of its usual load during the session
+	cacheFlatUsage(monitor.state, map[time.Time]float64{start: 0.4, start.Add(30 * time.Minute): 0.4})
+
+	monitor.state.RecordSession(1, func(r *SessionRecord) {
+		r.StartAt, r.EndAt, r.PointsPerKWh, r.Joined = start, start.Add(time.Hour), 100, true
+	})
+	monitor.state.RecordSession(2, func(r *SessionRecord) {
+		r.StartAt, r.EndAt, r.PointsPerKWh = start.Add(-time.Hour), start, 100
+	})
+	old := start.Add(-PerformanceReportMaxAge - 24*time.Hour)
+	monitor.state.RecordSession(3, func(r *SessionRecord) {
+		r.StartAt, r.EndAt, r.PointsPerKWh, r.Joined = old, old.Add(time.Hour), 100, true
+	})
+
+	monitor.updateSessionPerformance()
+
+	history := monitor.state.Snapshot().SessionHistory
+	performance := history[1].Performance
+	if performance == nil {
+		t.Fatal("Expected a performance report for the joined session")
+	}
+	if math.Abs(performance.ReductionPercent-60) > 1e-9 || performance.EstimatedPoints != 120 {
+		t.Errorf("Expected a 60%% reduction worth 120 points, got %+v", performance)
+	}
+	if history[2].Performance != nil {
+		t.Errorf("Expected no report for a session that was not joined, got %+v", history[2].Performance)
+	}
+	if history[3].Performance != nil {
+		t.Errorf("Expected no report once readings are too old to wait for, got %+v", history[3].Performance)
+	}
+}
+
+func TestUpdateSessionPerformanceWaitsForReadings(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	start := time.Now().Truncate(30 * time.Minute).Add(-30 * time.Hour)
+	monitor.state.RecordSession(1, func(r *SessionRecord) {
+		r.StartAt, r.EndAt, r.PointsPerKWh, r.Joined = start, start.Add(time.Hour), 100, true
+	})
+
+	cacheFlatUsage(monitor.state, map[time.Time]float64{start: 0.4, start.Add(30 * time.Minute): -1})
+	monitor.updateSessionPerformance()
+	if performance := monitor.state.Snapshot().SessionHistory[1].Performance; performance != nil {
+		t.Fatalf("Expected no report while readings are missing, got %+v", performance)
+	}
+
+	// The next check picks up the late reading
+	cacheFlatUsage(monitor.state, map[time.Time]float64{start: 0.4, start.Add(30 * time.Minute): 0.4})
+	monitor.updateSessionPerformance()
+	if monitor.state.Snapshot().SessionHistory[1].Performance == nil {
+		t.Error("Expected a report once the readings arrive")
+	}
+}
+
+func TestPerformanceInSessionData(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	start := time.Now().Truncate(24 * time.Hour).Add(-24*time.Hour + 17*time.Hour)
+	for day := 0; day <= WebRecentPerformanceSessions; day++ {
+		sessionStart := start.AddDate(0, 0, -day)
+		monitor.state.RecordSession(day+1, func(r *SessionRecord) {
+			r.StartAt, r.EndAt, r.Joined = sessionStart, sessionStart.Add(time.Hour), true
+			r.Performance = &SessionPerformance{ReductionPercent: 50}
+		})
+	}
+	monitor.state.RecordSession(99, func(r *SessionRecord) {
+		r.StartAt, r.EndAt, r.Joined = start.Add(time.Hour), start.Add(2*time.Hour), true
+	})
+
+	data := monitor.buildSessionData()
+	if len(data.RecentPerformance) != WebRecentPerformanceSessions || data.RecentPerformance[0].EventID != 1 {
+		t.Errorf("Expected the %d most recent results on the dashboard, got %+v", WebRecentPerformanceSessions, data.RecentPerformance)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OctoPoints ledger reasons
+const (
+	PointsReasonSavingSession = "saving_session"
+	PointsReasonWheelSpin     = "wheel_spin"
+	PointsReasonReferral      = "referral"
+	PointsReasonRedemption    = "redemption"
+	PointsReasonOther         = "other"
+)
+
+// pointsReasons lists the reasons in display order
+var pointsReasons = []string{
+	PointsReasonSavingSession,
+	PointsReasonWheelSpin,
+	PointsReasonReferral,
+	PointsReasonRedemption,
+	PointsReasonOther,
+}
+
+// PointsLedgerEntry is one movement of OctoPoints in or out of the wallet
+type PointsLedgerEntry struct {
+	ID         string    `json:"id"`
+	PostedAt   time.Time `json:"posted_at"`
+	Reason     string    `json:"reason"`
+	ReasonCode string    `json:"reason_code"` // as reported by Kraken
+	Delta      int       `json:"delta"`       // negative when points are spent
+	Balance    int       `json:"balance"`     // wallet balance after the entry
+}
+
+// krakenLedgerEntry is an entry as returned by loyaltyPointLedgers. Numbers
+// arrive as strings.
+type krakenLedgerEntry struct {
+	ID                    string `json:"id"`
+	LedgerType            string `json:"ledgerType"`
+	Value                 string `json:"value"`
+	BalanceBroughtForward string `json:"balanceBroughtForward"`
+	BalanceCarriedForward string `json:"balanceCarriedForward"`
+	ReasonCode            string `json:"reasonCode"`
+	PostedAt              string `json:"postedAt"`
+}
+
+// classifyPointsReason maps Kraken's ledger and reason codes onto the reasons
+// octojoin reports. Anything spent counts as a redemption.
+func classifyPointsReason(ledgerType, reasonCode string, delta int) string {
+	code := strings.ToUpper(ledgerType + " " + reasonCode)
+	switch {
+	case delta < 0 || strings.Contains(code, "REDEEM") || strings.Contains(code, "REDEMPTION"):
+		return PointsReasonRedemption
+	case strings.Contains(code, "SAVING"):
+		return PointsReasonSavingSession
+	case strings.Contains(code, "WHEEL") || strings.Contains(code, "SPIN"):
+		return PointsReasonWheelSpin
+	case strings.Contains(code, "REFER"):
+		return PointsReasonReferral
+	default:
+		return PointsReasonOther
+	}
+}
+
+// toLedgerEntry converts a Kraken ledger entry. The delta comes from the
+// balances either side of the entry when they are present, since value is
+// unsigned for some ledger types.
+func (e krakenLedgerEntry) toLedgerEntry() (PointsLedgerEntry, error) {
+	balance, err := strconv.Atoi(e.BalanceCarriedForward)
+	if err != nil {
+		return PointsLedgerEntry{}, fmt.Errorf("failed to convert points to integer: %w", err)
+	}
+	postedAt, err := time.Parse(time.RFC3339, e.PostedAt)
+	if err != nil {
+		return PointsLedgerEntry{}, fmt.Errorf("unrecognised ledger time %q", e.PostedAt)
+	}
+
+	delta, _ := strconv.Atoi(e.Value)
+	if brought, err := strconv.Atoi(e.BalanceBroughtForward); err == nil {
+		delta = balance - brought
+	}
+
+	entry := PointsLedgerEntry{
+		ID:         e.ID,
+		PostedAt:   postedAt,
+		Reason:     classifyPointsReason(e.LedgerType, e.ReasonCode, delta),
+		ReasonCode: e.ReasonCode,
+		Delta:      delta,
+		Balance:    balance,
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("%d-%s-%d", postedAt.Unix(), e.ReasonCode, delta)
+	}
+	return entry, nil
+}
+
+// MergePointsLedger adds ledger entries that aren't stored yet, keeping the
+// ledger oldest first. Kraken only returns recent entries, so the stored
+// ledger grows into the full history. It returns the number of new entries.
+func (s *AppState) MergePointsLedger(entries []PointsLedgerEntry) int {
+	added := 0
+	s.Update(func(s *AppState) {
+		known := make(map[string]bool, len(s.PointsLedger))
+		for _, entry := range s.PointsLedger {
+			known[entry.ID] = true
+		}
+		ledger := append([]PointsLedgerEntry{}, s.PointsLedger...)
+		for _, entry := range entries {
+			if known[entry.ID] {
+				continue
+			}
+			known[entry.ID] = true
+			ledger = append(ledger, entry)
+			added++
+		}
+		if added == 0 {
+			return
+		}
+		sort.SliceStable(ledger, func(i, j int) bool { return ledger[i].PostedAt.Before(ledger[j].PostedAt) })
+		s.PointsLedger = ledger
+	})
+	return added
+}
+
+// PointsReasonSummary totals the ledger for one reason
+type PointsReasonSummary struct {
+	Reason  string `json:"reason"`
+	Entries int    `json:"entries"`
+	Points  int    `json:"points"` // net movement, negative for redemptions
+	Earned  int    `json:"earned"` // sum of the positive entries
+	Spent   int    `json:"spent"`  // sum of the negative entries, as a positive number
+}
+
+// PointsHistory is the /api/points/history response
+type PointsHistory struct {
+	Balance     int                   `json:"balance"`
+	Entries     []PointsLedgerEntry   `json:"entries"` // newest first
+	Reasons     []PointsReasonSummary `json:"reasons"`
+	Redemptions []RedemptionRecord    `json:"redemptions"` // audit trail, newest first
+}
+
+// summarisePointsLedger totals the ledger per reason, in display order. Every
+// reason is listed so counters don't disappear before their first entry.
+func summarisePointsLedger(ledger []PointsLedgerEntry) []PointsReasonSummary {
+	totals := make(map[string]*PointsReasonSummary)
+	summaries := make([]PointsReasonSummary, len(pointsReasons))
+	for i, reason := range pointsReasons {
+		summaries[i].Reason = reason
+		totals[reason] = &summaries[i]
+	}
+	for _, entry := range ledger {
+		summary, ok := totals[entry.Reason]
+		if !ok {
+			summary = totals[PointsReasonOther]
+		}
+		summary.Entries++
+		summary.Points += entry.Delta
+		if entry.Delta > 0 {
+			summary.Earned += entry.Delta
+		} else {
+			summary.Spent -= entry.Delta
+		}
+	}
+	return summaries
+}
+
+// buildPointsHistory lists a stored ledger and the redemption audit trail
+// newest first, with the ledger's totals
+func buildPointsHistory(ledger []PointsLedgerEntry, redemptions []RedemptionRecord) PointsHistory {
+	history := PointsHistory{
+		Entries:     make([]PointsLedgerEntry, 0, len(ledger)),
+		Reasons:     summarisePointsLedger(ledger),
+		Redemptions: make([]RedemptionRecord, 0, len(redemptions)),
+	}
+	for i := len(ledger) - 1; i >= 0; i-- {
+		history.Entries = append(history.Entries, ledger[i])
+	}
+	for i := len(redemptions) - 1; i >= 0; i-- {
+		history.Redemptions = append(history.Redemptions, redemptions[i])
+	}
+	if len(ledger) > 0 {
+		history.Balance = ledger[len(ledger)-1].Balance
+	}
+	return history
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyPointsReason(t *testing.T) {
+	tests := []struct {
+		ledgerType, reasonCode string
+		delta                  int
+		reason                 string
+	}{
+		{"POINTS_EARNED", "SAVING_SESSION_REWARD", 1620, PointsReasonSavingSession},
+		{"POINTS_EARNED", "WHEEL_OF_FORTUNE_PRIZE", 20, PointsReasonWheelSpin},
+		{"POINTS_EARNED", "REFERRAL_REWARD", 400, PointsReasonReferral},
+		{"POINTS_SPENT", "ACCOUNT_CREDIT", -500, PointsReasonRedemption},
+		{"", "POINTS_REDEMPTION", 0, PointsReasonRedemption},
+		{"POINTS_EARNED", "BIRTHDAY_BONUS", 50, PointsReasonOther},
+	}
+
+	for _, test := range tests {
+		if got := classifyPointsReason(test.ledgerType, test.reasonCode, test.delta); got != test.reason {
+			t.Errorf("%s/%s: expected %s, got %s", test.ledgerType, test.reasonCode, test.reason, got)
+		}
+	}
+}
+
+func TestKrakenLedgerEntryConversion(t *testing.T) {
+	entry, err := krakenLedgerEntry{
+		ID:                    "123",
+		LedgerType:            "POINTS_SPENT",
+		Value:                 "500",
+		BalanceBroughtForward: "2350",
+		BalanceCarriedForward: "1850",
+		ReasonCode:            "POINTS_REDEMPTION",
+		PostedAt:              "2025-01-15T10:00:00Z",
+	}.toLedgerEntry()
+	if err != nil {
+		t.Fatalf("Expected ledger entry to convert, got %v", err)
+	}
+	if entry.Delta != -500 || entry.Balance != 1850 || entry.Reason != PointsReasonRedemption {
+		t.Errorf("Expected a 500 point redemption leaving 1850, got %+v", entry)
+	}
+
+	if _, err := (krakenLedgerEntry{BalanceCarriedForward: "10", PostedAt: "yesterday"}).toLedgerEntry(); err == nil {
+		t.Error("Expected an error for an unparseable time")
+	}
+}
+
+func TestMergePointsLedger(t *testing.T) {
+	state := &AppState{}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := PointsLedgerEntry{ID: "1", PostedAt: start, Reason: PointsReasonReferral, Delta: 400, Balance: 400}
+	second := PointsLedgerEntry{ID: "2", PostedAt: start.Add(time.Hour), Reason: PointsReasonWheelSpin, Delta: 20, Balance: 420}
+	third := PointsLedgerEntry{ID: "3", PostedAt: start.Add(2 * time.Hour), Reason: PointsReasonRedemption, Delta: -100, Balance: 320}
+
+	if added := state.MergePointsLedger([]PointsLedgerEntry{second, first}); added != 2 {
+		t.Fatalf("Expected 2 new entries, got %d", added)
+	}
+	snapshot := state.Snapshot()
+
+	// Kraken only returns recent entries; older ones stay stored
+	if added := state.MergePointsLedger([]PointsLedgerEntry{third, second}); added != 1 {
+		t.Fatalf("Expected only the new entry to be added, got %d", added)
+	}
+	if len(snapshot.PointsLedger) != 2 {
+		t.Errorf("Expected earlier snapshots to be unchanged, got %+v", snapshot.PointsLedger)
+	}
+
+	history := buildPointsHistory(state.Snapshot().PointsLedger, nil)
+	if history.Balance != 320 || len(history.Entries) != 3 || history.Entries[0].ID != "3" {
+		t.Errorf("Expected 3 entries newest first with a 320 balance, got %+v", history)
+	}
+	totals := make(map[string]PointsReasonSummary)
+	for _, summary := range history.Reasons {
+		totals[summary.Reason] = summary
+	}
+	if len(history.Reasons) != len(pointsReasons) || totals[PointsReasonRedemption].Points != -100 || totals[PointsReasonSavingSession].Entries != 0 {
+		t.Errorf("Expected totals for every reason, got %+v", history.Reasons)
+	}
+}
+
+func TestSummarisePointsLedgerEarnedAndSpent(t *testing.T) {
+	ledger := []PointsLedgerEntry{
+		{ID: "1", Reason: PointsReasonOther, Delta: 100},
+		{ID: "2", Reason: PointsReasonOther, Delta: -40},
+		{ID: "3", Reason: PointsReasonRedemption, Delta: -800},
+	}
+
+	totals := make(map[string]PointsReasonSummary)
+	for _, summary := range summarisePointsLedger(ledger) {
+		totals[summary.Reason] = summary
+	}
+	// A reason with entries both ways nets out, but earned and spent only grow
+	if other := totals[PointsReasonOther]; other.Points != 60 || other.Earned != 100 || other.Spent != 40 {
+		t.Errorf("Expected 100 earned and 40 spent for other, got %+v", other)
+	}
+	if redemption := totals[PointsReasonRedemption]; redemption.Earned != 0 || redemption.Spent != 800 {
+		t.Errorf("Expected 800 spent on redemptions, got %+v", redemption)
+	}
+}
+
+func TestGetOctoPointsStoresLedger(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fake, client := startFakeKraken(t, fixtures)
+	state := &AppState{}
+
+	points, err := client.getOctoPointsGraphQLWithCache(state)
+	if err != nil || points != fixtures.Points {
+		t.Fatalf("Expected %d points, got %d (%v)", fixtures.Points, points, err)
+	}
+	ledger := state.Snapshot().PointsLedger
+	if len(ledger) != len(fixtures.PointsLedger) || ledger[len(ledger)-1].Balance != fixtures.Points {
+		t.Fatalf("Expected the fixture ledger ending at %d points, got %+v", fixtures.Points, ledger)
+	}
+	if ledger[0].Reason != PointsReasonReferral || ledger[len(ledger)-1].Reason != PointsReasonRedemption {
+		t.Errorf("Expected the ledger oldest first with reasons classified, got %+v", ledger)
+	}
+
+	// The next fetch adds only the new entry
+	fake.mu.Lock()
+	fake.addLedgerEntry(time.Now(), "BIRTHDAY_BONUS", 50, fixtures.Points+50)
+	fake.mu.Unlock()
+	state.Update(func(s *AppState) { s.CachedOctoPoints = nil })
+	if _, err := client.getOctoPointsGraphQLWithCache(state); err != nil {
+		t.Fatalf("Expected points on the second fetch, got %v", err)
+	}
+	ledger = state.Snapshot().PointsLedger
+	if len(ledger) != len(fixtures.PointsLedger)+1 || ledger[len(ledger)-1].Reason != PointsReasonOther {
+		t.Errorf("Expected the new entry at the end of the ledger, got %+v", ledger)
+	}
+}
+
+// testPointsLedger is a referral, two wheel spins, a saving session reward
+// and a redemption, ending at 1870 points
+func testPointsLedger() []PointsLedgerEntry {
+	start := time.Now().AddDate(0, -2, 0)
+	return []PointsLedgerEntry{
+		{ID: "1", PostedAt: start, Reason: PointsReasonReferral, Delta: 400, Balance: 400},
+		{ID: "2", PostedAt: start.AddDate(0, 0, 15), Reason: PointsReasonWheelSpin, Delta: 50, Balance: 450},
+		{ID: "3", PostedAt: start.AddDate(0, 0, 30), Reason: PointsReasonSavingSession, Delta: 1900, Balance: 2350},
+		{ID: "4", PostedAt: start.AddDate(0, 0, 50), Reason: PointsReasonRedemption, Delta: -500, Balance: 1850},
+		{ID: "5", PostedAt: start.AddDate(0, 0, 55), Reason: PointsReasonWheelSpin, Delta: 20, Balance: 1870},
+	}
+}
+
+func TestPointsHistoryAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	api := newMonitorAPI(t, monitor)
+
+	// Before the ledger is fetched the cached balance is shown
+	monitor.state.Update(func(s *AppState) { s.CachedOctoPoints = &CachedOctoPoints{Data: 900, Timestamp: time.Now()} })
+	var history PointsHistory
+	if status := api.get("/api/points/history", &history); status != 200 || history.Balance != 900 || len(history.Entries) != 0 {
+		t.Errorf("Expected the cached balance with no entries, got %d %+v", status, history)
+	}
+
+	monitor.state.MergePointsLedger(testPointsLedger())
+	history = PointsHistory{}
+	api.get("/api/points/history", &history)
+	if history.Balance != 1870 || len(history.Entries) != 5 || history.Entries[0].Reason != PointsReasonWheelSpin || history.Entries[0].Delta != 20 {
+		t.Errorf("Expected the 20 point spin at the top of the history, got %+v", history)
+	}
+}
+
+func TestPointsLedgerMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	monitor.state.MergePointsLedger(testPointsLedger())
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_octopoints_ledger_entries_total{account="A-DEMO0001",reason="wheel_spin"} 2`,
+		`octojoin_octopoints_ledger_earned_points_total{account="A-DEMO0001",reason="wheel_spin"} 70`,
+		`octojoin_octopoints_ledger_spent_points_total{account="A-DEMO0001",reason="redemption"} 500`,
+		`octojoin_octopoints_ledger_earned_points_total{account="A-DEMO0001",reason="saving_session"} 1900`,
+	)
+}
@@ -0,0 +1,333 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Join policy actions
+const (
+	PolicyActionAllow = "allow"
+	PolicyActionDeny  = "deny"
+)
+
+// JoinPolicyConfig is the join_policy block of the configuration file.
+// Rules are evaluated in order and the first matching rule decides.
+type JoinPolicyConfig struct {
+	Default string           `yaml:"default"` // Action when no rule matches (default: allow)
+	Rules   []JoinPolicyRule `yaml:"rules"`
+}
+
+// JoinPolicyRule matches a saving session when every condition that is set matches
+type JoinPolicyRule struct {
+	Name   string `yaml:"name"`
+	Action string `yaml:"action"`
+
+	// Weekdays the session starts on, e.g. ["sat", "sunday"] (UK time)
+	Weekdays []string `yaml:"weekdays"`
+
+	// StartHours matches sessions starting at or after From and before To (UK time)
+	StartHours *HourRange `yaml:"start_hours"`
+
+	MinDurationMinutes int `yaml:"min_duration_minutes"`
+	MaxDurationMinutes int `yaml:"max_duration_minutes"`
+
+	// Points are the reward per kWh saved, as Octopus announces it
+	MinPoints int `yaml:"min_points"`
+	MaxPoints int `yaml:"max_points"`
+
+	// The per-kWh reward divided by the session length in hours
+	MinPointsPerHour float64 `yaml:"min_points_per_hour"`
+	MaxPointsPerHour float64 `yaml:"max_points_per_hour"`
+
+	// Lead time is how long before the session starts the decision is made
+	MinLeadTimeMinutes int `yaml:"min_lead_time_minutes"`
+	MaxLeadTimeMinutes int `yaml:"max_lead_time_minutes"`
+
+	// Dates the session starts on, as "2006-01-02" or "2006-01-02..2006-01-05" (UK time)
+	Dates []string `yaml:"dates"`
+}
+
+// HourRange is a half-open range of hours of the day
+type HourRange struct {
+	From int `yaml:"from"`
+	To   int `yaml:"to"`
+}
+
+// JoinDecision records whether a session should be joined and why
+type JoinDecision struct {
+	EventID   int       `json:"event_id"`
+	Join      bool      `json:"join"`
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// JoinPolicy evaluates saving sessions against a validated rule set
+type JoinPolicy struct {
+	defaultAction string
+	rules         []compiledRule
+	minPoints     int
+	location      *time.Location
+}
+
+type compiledRule struct {
+	JoinPolicyRule
+	weekdays map[time.Weekday]bool
+	dates    []dateRange
+}
+
+type dateRange struct {
+	from time.Time
+	to   time.Time
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// NewJoinPolicy builds a policy from configuration. minPoints is kept as an
+// implicit deny rule evaluated after the configured rules, so existing
+// min_points settings keep working alongside a join_policy block.
+func NewJoinPolicy(cfg *JoinPolicyConfig, minPoints int) (*JoinPolicy, error) {
+	ukLocation, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		ukLocation = time.UTC
+	}
+
+	policy := &JoinPolicy{
+		defaultAction: PolicyActionAllow,
+		minPoints:     minPoints,
+		location:      ukLocation,
+	}
+
+	if cfg == nil {
+		return policy, nil
+	}
+
+	if problems := cfg.validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid join policy: %s", strings.Join(problems, "; "))
+	}
+
+	if cfg.Default != "" {
+		policy.defaultAction = strings.ToLower(cfg.Default)
+	}
+
+	for _, rule := range cfg.Rules {
+		compiled := compiledRule{JoinPolicyRule: rule}
+		if len(rule.Weekdays) > 0 {
+			compiled.weekdays = make(map[time.Weekday]bool)
+			for _, name := range rule.Weekdays {
+				compiled.weekdays[weekdayNames[strings.ToLower(name)]] = true
+			}
+		}
+		for _, spec := range rule.Dates {
+			dr, _ := parseDateRange(spec, ukLocation)
+			compiled.dates = append(compiled.dates, dr)
+		}
+		compiled.Action = strings.ToLower(rule.Action)
+		policy.rules = append(policy.rules, compiled)
+	}
+
+	return policy, nil
+}
+
+// validate returns a list of problems with the policy configuration
+func (p *JoinPolicyConfig) validate() []string {
+	var problems []string
+
+	if p.Default != "" && !isPolicyAction(p.Default) {
+		problems = append(problems, fmt.Sprintf("default must be 'allow' or 'deny', got: %s", p.Default))
+	}
+
+	for i, rule := range p.Rules {
+		label := rule.Name
+		if label == "" {
+			label = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if !isPolicyAction(rule.Action) {
+			problems = append(problems, fmt.Sprintf("%s: action must be 'allow' or 'deny', got: %q", label, rule.Action))
+		}
+		for _, name := range rule.Weekdays {
+			if _, ok := weekdayNames[strings.ToLower(name)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown weekday %q", label, name))
+			}
+		}
+		if rule.StartHours != nil {
+			if rule.StartHours.From < 0 || rule.StartHours.From > 23 || rule.StartHours.To < 1 || rule.StartHours.To > 24 || rule.StartHours.From >= rule.StartHours.To {
+				problems = append(problems, fmt.Sprintf("%s: start_hours must satisfy 0 <= from < to <= 24", label))
+			}
+		}
+		if rule.MaxDurationMinutes > 0 && rule.MinDurationMinutes > rule.MaxDurationMinutes {
+			problems = append(problems, fmt.Sprintf("%s: min_duration_minutes exceeds max_duration_minutes", label))
+		}
+		if rule.MaxPoints > 0 && rule.MinPoints > rule.MaxPoints {
+			problems = append(problems, fmt.Sprintf("%s: min_points exceeds max_points", label))
+		}
+		if rule.MaxPointsPerHour > 0 && rule.MinPointsPerHour > rule.MaxPointsPerHour {
+			problems = append(problems, fmt.Sprintf("%s: min_points_per_hour exceeds max_points_per_hour", label))
+		}
+		if rule.MaxLeadTimeMinutes > 0 && rule.MinLeadTimeMinutes > rule.MaxLeadTimeMinutes {
+			problems = append(problems, fmt.Sprintf("%s: min_lead_time_minutes exceeds max_lead_time_minutes", label))
+		}
+		for _, spec := range rule.Dates {
+			if _, err := parseDateRange(spec, time.UTC); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", label, err))
+			}
+		}
+	}
+
+	return problems
+}
+
+func isPolicyAction(action string) bool {
+	action = strings.ToLower(action)
+	return action == PolicyActionAllow || action == PolicyActionDeny
+}
+
+// parseDateRange parses "2006-01-02" or "2006-01-02..2006-01-05" into an inclusive range of days
+func parseDateRange(spec string, loc *time.Location) (dateRange, error) {
+	parts := strings.SplitN(spec, "..", 2)
+	from, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(parts[0]), loc)
+	if err != nil {
+		return dateRange{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", spec)
+	}
+	to := from
+	if len(parts) == 2 {
+		to, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(parts[1]), loc)
+		if err != nil || to.Before(from) {
+			return dateRange{}, fmt.Errorf("invalid date range %q (expected YYYY-MM-DD..YYYY-MM-DD)", spec)
+		}
+	}
+	return dateRange{from: from, to: to.AddDate(0, 0, 1)}, nil
+}
+
+// Evaluate decides whether a session should be joined. The returned decision
+// names the rule that matched so callers can explain the outcome.
+func (p *JoinPolicy) Evaluate(session SavingSession, now time.Time) JoinDecision {
+	decision := JoinDecision{
+		EventID:   session.EventID,
+		DecidedAt: now,
+	}
+
+	for i, rule := range p.rules {
+		if matched, why := rule.matches(session, now, p.location); matched {
+			decision.Join = rule.Action == PolicyActionAllow
+			decision.Rule = rule.Name
+			if decision.Rule == "" {
+				decision.Rule = fmt.Sprintf("rule %d", i+1)
+			}
+			decision.Reason = why
+			return decision
+		}
+	}
+
+	if p.minPoints > 0 && session.PointsPerKWh < p.minPoints {
+		decision.Join = false
+		decision.Rule = "min_points"
+		decision.Reason = fmt.Sprintf("%d points per kWh is below the minimum of %d", session.PointsPerKWh, p.minPoints)
+		return decision
+	}
+
+	decision.Join = p.defaultAction == PolicyActionAllow
+	decision.Rule = "default"
+	decision.Reason = fmt.Sprintf("no rule matched, default action is %s", p.defaultAction)
+	return decision
+}
+
+// matches reports whether every configured condition holds, with a short description of why
+func (r compiledRule) matches(session SavingSession, now time.Time, loc *time.Location) (bool, string) {
+	start := session.StartAt.In(loc)
+	duration := session.EndAt.Sub(session.StartAt)
+	leadTime := session.StartAt.Sub(now)
+	var reasons []string
+
+	if r.weekdays != nil {
+		if !r.weekdays[start.Weekday()] {
+			return false, ""
+		}
+		reasons = append(reasons, "starts on "+start.Weekday().String())
+	}
+
+	if r.StartHours != nil {
+		if start.Hour() < r.StartHours.From || start.Hour() >= r.StartHours.To {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("starts between %02d:00 and %02d:00", r.StartHours.From, r.StartHours.To))
+	}
+
+	if r.MinDurationMinutes > 0 || r.MaxDurationMinutes > 0 {
+		minutes := int(duration.Minutes())
+		if minutes < r.MinDurationMinutes || (r.MaxDurationMinutes > 0 && minutes > r.MaxDurationMinutes) {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("lasts %d minutes", minutes))
+	}
+
+	if r.MinPoints > 0 || r.MaxPoints > 0 {
+		if session.PointsPerKWh < r.MinPoints || (r.MaxPoints > 0 && session.PointsPerKWh > r.MaxPoints) {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("offers %d points per kWh", session.PointsPerKWh))
+	}
+
+	if r.MinPointsPerHour > 0 || r.MaxPointsPerHour > 0 {
+		if duration <= 0 {
+			return false, ""
+		}
+		perHour := float64(session.PointsPerKWh) / duration.Hours()
+		if perHour < r.MinPointsPerHour || (r.MaxPointsPerHour > 0 && perHour > r.MaxPointsPerHour) {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("offers %.0f points per hour", perHour))
+	}
+
+	if r.MinLeadTimeMinutes > 0 || r.MaxLeadTimeMinutes > 0 {
+		minutes := int(leadTime.Minutes())
+		if minutes < r.MinLeadTimeMinutes || (r.MaxLeadTimeMinutes > 0 && minutes > r.MaxLeadTimeMinutes) {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("starts in %d minutes", minutes))
+	}
+
+	if len(r.dates) > 0 {
+		inRange := false
+		for _, dr := range r.dates {
+			if !start.Before(dr.from) && start.Before(dr.to) {
+				inRange = true
+				break
+			}
+		}
+		if !inRange {
+			return false, ""
+		}
+		reasons = append(reasons, "falls on a listed date ("+start.Format("2006-01-02")+")")
+	}
+
+	if len(reasons) == 0 {
+		return true, "rule has no conditions"
+	}
+	return true, strings.Join(reasons, ", ")
+}
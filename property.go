@@ -0,0 +1,227 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+)
+
+// Property is one supply address on the account with its smart meters. An
+// account can have several, each with its own usage and tariffs.
+type Property struct {
+	ID                 string   `json:"id"`
+	Address            string   `json:"address"`
+	ElectricityDevices []string `json:"electricity_devices"` // ESME device IDs
+	GasDevices         []string `json:"gas_devices"`         // GSME device IDs
+	// Free electricity sessions are settled from half-hourly smart meter
+	// readings, so only properties with an electricity smart meter qualify
+	FreeElectricityEligible bool `json:"free_electricity_eligible"`
+}
+
+// hasDevice reports whether a smart meter belongs to the property
+func (p *Property) hasDevice(deviceID string) bool {
+	for _, devices := range [][]string{p.ElectricityDevices, p.GasDevices} {
+		for _, device := range devices {
+			if device == deviceID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getProperties retrieves the account's properties with their ESME
+// (electricity) and GSME (gas) smart meters
+func (c *OctopusClient) getProperties() ([]Property, error) {
+	query := `query getEligibility($accountNumber: String!) {
+		account(accountNumber: $accountNumber) {
+			properties {
+				id
+				address
+				smartDeviceNetworks {
+					smartDevices {
+						deviceId
+						type
+						__typename
+					}
+					__typename
+				}
+				__typename
+			}
+			__typename
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"accountNumber": c.AccountID,
+	}
+
+	resp, err := c.makeGraphQLRequest(query, variables, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute meter eligibility request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result MeterEligibilityResponse
+	if err := c.decodeGraphQL(resp, "getEligibility", &result.Data); err != nil {
+		return nil, fmt.Errorf("failed to get meter devices: %w", err)
+	}
+
+	properties := make([]Property, 0, len(result.Data.Account.Properties))
+	for _, p := range result.Data.Account.Properties {
+		property := Property{ID: p.ID, Address: p.Address, ElectricityDevices: []string{}, GasDevices: []string{}}
+		for _, network := range p.SmartDeviceNetworks {
+			for _, device := range network.SmartDevices {
+				switch device.Type {
+				case "ESME":
+					property.ElectricityDevices = append(property.ElectricityDevices, device.DeviceID)
+					c.debugLog("Found ESME device: %s (property %s)", device.DeviceID, p.ID)
+				case "GSME":
+					property.GasDevices = append(property.GasDevices, device.DeviceID)
+					c.debugLog("Found GSME device: %s (property %s)", device.DeviceID, p.ID)
+				}
+			}
+		}
+		property.FreeElectricityEligible = len(property.ElectricityDevices) > 0
+		properties = append(properties, property)
+	}
+
+	c.debugLog("Found %d properties", len(properties))
+	return properties, nil
+}
+
+// meterDevices flattens the properties' smart meters into one list per fuel.
+// Gas is never nil, so callers can tell a lookup from no lookup.
+func meterDevices(properties []Property) ([]string, []string) {
+	var electricity []string
+	gas := []string{}
+	for _, property := range properties {
+		electricity = append(electricity, property.ElectricityDevices...)
+		gas = append(gas, property.GasDevices...)
+	}
+	return electricity, gas
+}
+
+// getPropertiesWithCache retrieves the account's properties with caching
+func (c *OctopusClient) getPropertiesWithCache(state *AppState) ([]Property, error) {
+	devices, err := c.getMeterDevicesWithCache(state)
+	if err != nil {
+		return nil, err
+	}
+	return devices.Properties, nil
+}
+
+// findProperty returns the property with the given ID, or nil
+func findProperty(properties []Property, id string) *Property {
+	for i := range properties {
+		if properties[i].ID == id {
+			return &properties[i]
+		}
+	}
+	return nil
+}
+
+// devicePropertyIDs maps each smart meter to the property it belongs to
+func devicePropertyIDs(properties []Property) map[string]string {
+	ids := make(map[string]string)
+	for _, property := range properties {
+		for _, devices := range [][]string{property.ElectricityDevices, property.GasDevices} {
+			for _, device := range devices {
+				ids[device] = property.ID
+			}
+		}
+	}
+	return ids
+}
+
+// propertyMeasurements keeps the measurements read from a property's meters
+func propertyMeasurements(measurements []UsageMeasurement, property *Property) []UsageMeasurement {
+	var kept []UsageMeasurement
+	for _, m := range measurements {
+		if property.hasDevice(m.DeviceID) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// propertyTariffs keeps the tariffs on a property's meter points
+func propertyTariffs(tariffs []Tariff, propertyID string) []Tariff {
+	kept := []Tariff{}
+	for _, tariff := range tariffs {
+		if tariff.PropertyID == propertyID {
+			kept = append(kept, tariff)
+		}
+	}
+	return kept
+}
+
+// exportDevices returns the smart meters to read export from. An export MPAN
+// has no smart meter of its own: the property's ESME records it as the
+// GENERATION reading direction. So export is read from the ESMEs of properties
+// with an export agreement, and import-only properties are never asked.
+func exportDevices(properties []Property, tariffs []Tariff) []string {
+	exporting := make(map[string]bool)
+	for _, tariff := range tariffs {
+		if tariff.Fuel == FuelExport {
+			exporting[tariff.PropertyID] = true
+		}
+	}
+	var devices []string
+	for _, property := range properties {
+		if exporting[property.ID] {
+			devices = append(devices, property.ElectricityDevices...)
+		}
+	}
+	return devices
+}
+
+// getExportDevicesWithCache returns the ESMEs that read an export MPAN, from
+// the cached properties and tariffs
+func (c *OctopusClient) getExportDevicesWithCache(state *AppState) ([]string, error) {
+	properties, err := c.getPropertiesWithCache(state)
+	if err != nil {
+		return nil, err
+	}
+	tariffs, err := c.getTariffsWithCache(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export agreements: %w", err)
+	}
+	return exportDevices(properties, tariffs), nil
+}
+
+// exportTotals is the electricity a property exported and what it earned
+type exportTotals struct {
+	kWh, earnings float64 // earnings in pence
+}
+
+// exportByProperty totals export readings and earnings for each property.
+// Readings from meters no longer on the account are left out.
+func exportByProperty(properties []Property, export []UsageMeasurement) map[string]*exportTotals {
+	owners := devicePropertyIDs(properties)
+	totals := make(map[string]*exportTotals)
+	for _, reading := range export {
+		id, ok := owners[reading.DeviceID]
+		if !ok {
+			continue
+		}
+		if totals[id] == nil {
+			totals[id] = &exportTotals{}
+		}
+		totals[id].kWh += reading.GetValueAsFloat64()
+		totals[id].earnings += reading.GetCostAsFloat64()
+	}
+	return totals
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// twoPropertyFixtures splits the demo meters between a home with both fuels
+// and a garage with only a gas meter
+func twoPropertyFixtures() KrakenFixtures {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.Properties = []FixtureProperty{
+		{ID: "1000001", Address: "1 Demo Street", DeviceIDs: []string{"00-11-22-33-44-55-66-77"}, GasDeviceIDs: []string{"00-AA-BB-CC-DD-EE-FF-00"}},
+		{ID: "1000002", Address: "2 Demo Street", GasDeviceIDs: []string{"00-AA-BB-CC-DD-EE-FF-01"}},
+	}
+	return fixtures
+}
+
+func TestGetProperties(t *testing.T) {
+	_, client := startFakeKraken(t, twoPropertyFixtures())
+
+	properties, err := client.getProperties()
+	if err != nil {
+		t.Fatalf("Expected properties, got %v", err)
+	}
+	if len(properties) != 2 {
+		t.Fatalf("Expected 2 properties, got %+v", properties)
+	}
+	if home := properties[0]; home.Address != "1 Demo Street" || len(home.ElectricityDevices) != 1 || len(home.GasDevices) != 1 || !home.FreeElectricityEligible {
+		t.Errorf("Expected the home with both meters to be eligible, got %+v", home)
+	}
+	if garage := properties[1]; len(garage.ElectricityDevices) != 0 || len(garage.GasDevices) != 1 || garage.FreeElectricityEligible {
+		t.Errorf("Expected the gas-only garage to be ineligible, got %+v", garage)
+	}
+
+	// The flattened device lists still cover every property
+	electricity, gas, err := client.getMeterDevices()
+	if err != nil || len(electricity) != 1 || len(gas) != 2 {
+		t.Errorf("Expected 1 ESME and 2 GSME devices, got %v and %v (%v)", electricity, gas, err)
+	}
+}
+
+func TestGetTariffsPerProperty(t *testing.T) {
+	_, client := startFakeKraken(t, twoPropertyFixtures())
+
+	tariffs, err := client.getTariffs(time.Now())
+	if err != nil {
+		t.Fatalf("Expected tariffs, got %v", err)
+	}
+	if home := propertyTariffs(tariffs, "1000001"); len(home) != 3 {
+		t.Errorf("Expected import, export and gas tariffs at the home, got %+v", home)
+	}
+	if garage := propertyTariffs(tariffs, "1000002"); len(garage) != 1 || garage[0].Fuel != FuelGas {
+		t.Errorf("Expected one gas tariff at the garage, got %+v", garage)
+	}
+}
+
+// cacheTwoProperties stores the meters, tariffs and a day of gas readings
+// for twoPropertyFixtures as if they had just been fetched
+func cacheTwoProperties(state *AppState) {
+	now := time.Now()
+	var properties []Property
+	for _, fixture := range twoPropertyFixtures().Properties {
+		properties = append(properties, Property{
+			ID:                      fixture.ID,
+			Address:                 fixture.Address,
+			ElectricityDevices:      append([]string{}, fixture.DeviceIDs...),
+			GasDevices:              append([]string{}, fixture.GasDeviceIDs...),
+			FreeElectricityEligible: len(fixture.DeviceIDs) > 0,
+		})
+	}
+	electricity, gas := meterDevices(properties)
+
+	agreement := func(property, fuel, code string) Agreement {
+		return Agreement{PropertyID: property, Fuel: fuel, TariffCode: code, ValidFrom: now.AddDate(0, -1, 0)}
+	}
+	rates := []TariffRate{{ValueIncVAT: 18.5, ValidFrom: now.Add(-10 * time.Minute)}}
+	tariffs := []Tariff{
+		{Agreement: agreement("1000001", FuelElectricity, "E-1R-AGILE-24-10-01-C"), UnitRates: rates},
+		{Agreement: agreement("1000001", FuelExport, "E-1R-OUTGOING-FIX-12M-19-05-13-C"), UnitRates: rates},
+		{Agreement: agreement("1000001", FuelGas, "G-1R-VAR-22-11-01-C"), UnitRates: rates},
+		{Agreement: agreement("1000002", FuelGas, "G-1R-VAR-22-11-01-C"), UnitRates: rates},
+	}
+
+	var readings []UsageMeasurement
+	slot := now.Truncate(30 * time.Minute).Add(-time.Hour)
+	for _, device := range gas {
+		readings = append(readings, UsageMeasurement{DeviceID: device, Value: "0.5", Unit: "kWh", StartAt: slot, EndAt: slot.Add(30 * time.Minute)})
+	}
+
+	state.Update(func(s *AppState) {
+		s.CachedMeterDevices = &CachedMeterDevices{Data: electricity, Gas: gas, Properties: properties, Timestamp: now}
+		s.CachedTariffs = &CachedTariffs{Data: tariffs, Timestamp: now}
+		s.CachedGasUsage = &CachedUsageMeasurements{Data: readings, Timestamp: now, Days: WebMaxUsageDays}
+	})
+}
+
+func TestPropertiesAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTwoProperties(monitor.state)
+
+	var properties []struct {
+		Property
+		Tariffs []Tariff `json:"tariffs"`
+	}
+	if status := newMonitorAPI(t, monitor).get("/api/properties", &properties); status != http.StatusOK {
+		t.Fatalf("Expected properties, got status %d", status)
+	}
+	if len(properties) != 2 || len(properties[0].Tariffs) != 3 || len(properties[1].Tariffs) != 1 {
+		t.Errorf("Expected each property with its own tariffs, got %+v", properties)
+	}
+}
+
+func TestUsageAPIByProperty(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTwoProperties(monitor.state)
+	api := newMonitorAPI(t, monitor)
+
+	series := func(path string) []string {
+		var usage struct {
+			Series []struct {
+				DeviceID string `json:"device_id"`
+			} `json:"series"`
+		}
+		if status := api.get(path, &usage); status != http.StatusOK {
+			t.Fatalf("%s: expected usage, got status %d", path, status)
+		}
+		var devices []string
+		for _, s := range usage.Series {
+			devices = append(devices, s.DeviceID)
+		}
+		return devices
+	}
+	if devices := series("/api/usage?days=1&fuel=gas"); len(devices) != 2 {
+		t.Errorf("Expected both gas meters across the account, got %v", devices)
+	}
+	if devices := series("/api/usage?days=1&fuel=gas&property=1000002"); len(devices) != 1 || devices[0] != "00-AA-BB-CC-DD-EE-FF-01" {
+		t.Errorf("Expected only the garage's gas meter, got %v", devices)
+	}
+	if status := api.get("/api/usage?days=1&property=9999999", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown property, got %d", status)
+	}
+}
+
+func TestTariffAPIByProperty(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTwoProperties(monitor.state)
+
+	var tariffs struct {
+		Tariffs []Tariff `json:"tariffs"`
+	}
+	if status := newMonitorAPI(t, monitor).get("/api/tariff?property=1000002", &tariffs); status != http.StatusOK || len(tariffs.Tariffs) != 1 {
+		t.Errorf("Expected the garage's gas tariff, got %d %+v", status, tariffs)
+	}
+}
+
+func TestPropertyMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTwoProperties(monitor.state)
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_property_free_electricity_eligible{account="A-DEMO0001",property="1000001"} 1`,
+		`octojoin_property_free_electricity_eligible{account="A-DEMO0001",property="1000002"} 0`,
+		`octojoin_property_smart_meters{account="A-DEMO0001",fuel="gas",property="1000002"} 1`,
+		`property="1000001",register="standard",tariff="E-1R-AGILE-24-10-01-C"}`,
+	)
+}
+
+func TestExportDevices(t *testing.T) {
+	properties := []Property{
+		{ID: "1000001", ElectricityDevices: []string{"00-11-22-33-44-55-66-77"}},
+		{ID: "1000002", ElectricityDevices: []string{"00-11-22-33-44-55-66-78"}},
+	}
+	tariffs := []Tariff{
+		{Agreement: Agreement{PropertyID: "1000001", Fuel: FuelElectricity}},
+		{Agreement: Agreement{PropertyID: "1000002", Fuel: FuelElectricity}},
+		{Agreement: Agreement{PropertyID: "1000001", Fuel: FuelExport}},
+	}
+
+	// Only the property with an export MPAN is read for generation
+	if devices := exportDevices(properties, tariffs); len(devices) != 1 || devices[0] != "00-11-22-33-44-55-66-77" {
+		t.Errorf("Expected export from the first property's meter, got %v", devices)
+	}
+	if devices := exportDevices(properties, tariffs[:2]); len(devices) != 0 {
+		t.Errorf("Expected no export without an export MPAN, got %v", devices)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedemptionConfig is the opt-in policy for turning OctoPoints into account
+// credit: once the wallet holds min_balance, redeem everything above reserve
+// in whole multiples
+type RedemptionConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	MinBalance int  `yaml:"min_balance"` // default reserve + multiple
+	Multiple   int  `yaml:"multiple"`    // default 800 (£1 of credit)
+	Reserve    int  `yaml:"reserve"`     // points always left in the wallet
+	DryRun     bool `yaml:"dry_run"`     // log, record and notify without redeeming
+}
+
+// applyDefaults fills settings that were left out
+func (c *RedemptionConfig) applyDefaults() {
+	if c.Multiple == 0 {
+		c.Multiple = OctoPointsPerPound
+	}
+	if c.MinBalance == 0 {
+		c.MinBalance = c.Reserve + c.Multiple
+	}
+}
+
+func (c *RedemptionConfig) validate() []string {
+	var problems []string
+	if !c.Enabled {
+		return problems
+	}
+	if c.MinBalance < 0 {
+		problems = append(problems, fmt.Sprintf("min_balance cannot be negative, got: %d", c.MinBalance))
+	}
+	if c.Multiple < 0 {
+		problems = append(problems, fmt.Sprintf("multiple cannot be negative, got: %d", c.Multiple))
+	}
+	if c.Reserve < 0 {
+		problems = append(problems, fmt.Sprintf("reserve cannot be negative, got: %d", c.Reserve))
+	}
+	return problems
+}
+
+// amount returns the points to redeem from balance, or 0 if the policy
+// doesn't call for a redemption
+func (c *RedemptionConfig) amount(balance int) int {
+	if balance < c.MinBalance || balance <= c.Reserve {
+		return 0
+	}
+	return (balance - c.Reserve) / c.Multiple * c.Multiple
+}
+
+// RedemptionRecord is one entry in the redemption audit trail
+type RedemptionRecord struct {
+	At            time.Time `json:"at"`
+	Points        int       `json:"points"`
+	CreditPounds  float64   `json:"credit_pounds"`
+	BalanceBefore int       `json:"balance_before"`
+	LedgerState   string    `json:"ledger_state"` // balance and latest ledger entry the decision was based on
+	DryRun        bool      `json:"dry_run"`
+	Pending       bool      `json:"pending,omitempty"` // saved before the mutation is sent, cleared with its outcome
+	Error         string    `json:"error,omitempty"`
+}
+
+// Result summarises the record for display and metrics. A record still
+// pending was interrupted before its outcome was known.
+func (r RedemptionRecord) Result() string {
+	switch {
+	case r.DryRun:
+		return "dry_run"
+	case r.Pending:
+		return "pending"
+	case r.Error != "":
+		return "failed"
+	default:
+		return "redeemed"
+	}
+}
+
+// pointsLedgerState identifies the wallet as of the latest ledger entry, so a
+// redemption decision is only ever acted on once per state
+func pointsLedgerState(balance int, ledger []PointsLedgerEntry) string {
+	if len(ledger) == 0 {
+		return fmt.Sprintf("balance:%d", balance)
+	}
+	return fmt.Sprintf("balance:%d/entry:%s", balance, ledger[len(ledger)-1].ID)
+}
+
+// RecordRedemption appends to the audit trail. The slice is replaced rather
+// than appended to in place, so snapshots already handed out never change.
+func (s *AppState) RecordRedemption(record RedemptionRecord) {
+	s.Update(func(s *AppState) {
+		redemptions := make([]RedemptionRecord, 0, len(s.Redemptions)+1)
+		redemptions = append(redemptions, s.Redemptions...)
+		s.Redemptions = append(redemptions, record)
+	})
+}
+
+// FinishRedemption replaces the pending record made at the same time with its outcome
+func (s *AppState) FinishRedemption(record RedemptionRecord) {
+	s.Update(func(s *AppState) {
+		redemptions := make([]RedemptionRecord, len(s.Redemptions))
+		copy(redemptions, s.Redemptions)
+		for i := len(redemptions) - 1; i >= 0; i-- {
+			if redemptions[i].Pending && redemptions[i].At.Equal(record.At) {
+				redemptions[i] = record
+				break
+			}
+		}
+		s.Redemptions = redemptions
+	})
+}
+
+// redemptionBlocked explains why no redemption may run for ledgerState: it
+// has been acted on already (failures and interrupted attempts included, since
+// a lost response may still have redeemed), or the last redemption hasn't
+// reached the ledger yet
+func redemptionBlocked(redemptions []RedemptionRecord, ledger []PointsLedgerEntry, balance int, ledgerState string, dryRun bool) string {
+	for _, record := range redemptions {
+		if record.LedgerState == ledgerState && record.DryRun == dryRun {
+			return "already handled for this ledger state"
+		}
+	}
+
+	for i := len(redemptions) - 1; i >= 0; i-- {
+		last := redemptions[i]
+		if last.DryRun || last.Pending || last.Error != "" {
+			continue
+		}
+		if balance <= last.BalanceBefore-last.Points {
+			return ""
+		}
+		for _, entry := range ledger {
+			if entry.Reason == PointsReasonRedemption && !entry.PostedAt.Before(last.At.Add(-RedemptionClockSkew)) {
+				return ""
+			}
+		}
+		return "waiting for the last redemption to reach the ledger"
+	}
+	return ""
+}
+
+// redeemOctoPoints converts points into account credit and returns the points
+// Kraken reports as redeemed. The mutation is sent at most once.
+func (c *OctopusClient) redeemOctoPoints(points int) (int, error) {
+	c.debugLog("Redeeming %d OctoPoints...", points)
+
+	query := `mutation redeemLoyaltyPointsForAccountCredit($input: RedeemLoyaltyPointsInput!) {
+		redeemLoyaltyPointsForAccountCredit(input: $input) {
+			pointsRedeemed
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{
+			"accountNumber": c.AccountID,
+			"points":        points,
+		},
+	}
+
+	ctx := withoutRetries(context.Background())
+	resp, err := c.makeGraphQLRequestWithContext(ctx, getEndpoint("graphql"), query, variables, true, "redeemLoyaltyPointsForAccountCredit")
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute redemption request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			RedeemLoyaltyPointsForAccountCredit struct {
+				PointsRedeemed int `json:"pointsRedeemed"`
+			} `json:"redeemLoyaltyPointsForAccountCredit"`
+		} `json:"data"`
+	}
+	if err := c.decodeGraphQL(resp, "redeemLoyaltyPointsForAccountCredit", &result.Data); err != nil {
+		return 0, fmt.Errorf("failed to redeem points: %w", err)
+	}
+
+	redeemed := result.Data.RedeemLoyaltyPointsForAccountCredit.PointsRedeemed
+	c.debugLog("Redeemed %d OctoPoints", redeemed)
+	return redeemed, nil
+}
+
+// SetRedemptionPolicy enables automatic OctoPoints redemption
+func (m *SavingSessionMonitor) SetRedemptionPolicy(cfg *RedemptionConfig) {
+	redemption := *cfg
+	redemption.applyDefaults()
+	m.redemption = &redemption
+}
+
+// checkRedemption redeems OctoPoints when the policy calls for it. Each
+// ledger state is acted on at most once, and every attempt is audited and
+// notified, so only a failure to read the balance is returned.
+func (m *SavingSessionMonitor) checkRedemption() error {
+	if m.redemption == nil {
+		return nil
+	}
+
+	balance, err := m.client.getOctoPointsGraphQLWithCache(m.state)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OctoPoints for redemption: %w", err)
+	}
+	points := m.redemption.amount(balance)
+	if points == 0 {
+		return nil
+	}
+
+	var ledger []PointsLedgerEntry
+	var redemptions []RedemptionRecord
+	m.state.View(func(s *AppState) {
+		ledger, redemptions = s.PointsLedger, s.Redemptions
+	})
+	ledgerState := pointsLedgerState(balance, ledger)
+	if reason := redemptionBlocked(redemptions, ledger, balance, ledgerState, m.redemption.DryRun); reason != "" {
+		m.logger.Debug("Skipping OctoPoints redemption", "points", points, "reason", reason)
+		return nil
+	}
+
+	record := RedemptionRecord{
+		At:            time.Now(),
+		Points:        points,
+		CreditPounds:  float64(points) / OctoPointsPerPound,
+		BalanceBefore: balance,
+		LedgerState:   ledgerState,
+		DryRun:        m.redemption.DryRun,
+	}
+	if record.DryRun {
+		m.state.RecordRedemption(record)
+	} else {
+		// The attempt reaches disk before the mutation is sent, so a restart
+		// in between can never redeem the same ledger state again
+		pending := record
+		pending.Pending = true
+		m.state.RecordRedemption(pending)
+		if err := m.state.Save(m.accountID); err != nil {
+			record.Error = fmt.Sprintf("not sent, state could not be saved: %v", err)
+		} else {
+			redeemed, err := m.client.redeemOctoPoints(points)
+			if err != nil {
+				record.Error = err.Error()
+			} else {
+				record.Points = redeemed
+				record.CreditPounds = float64(redeemed) / OctoPointsPerPound
+			}
+			// Points and the account balance have changed, or might have
+			m.state.Update(func(s *AppState) {
+				s.CachedOctoPoints = nil
+				s.CachedAccountInfo = nil
+			})
+		}
+		m.state.FinishRedemption(record)
+		if err := m.state.Save(m.accountID); err != nil {
+			m.logger.Warn("Failed to save state", "error", err.Error())
+		}
+	}
+
+	data := map[string]interface{}{
+		"points":         record.Points,
+		"credit_pounds":  record.CreditPounds,
+		"balance_before": record.BalanceBefore,
+		"dry_run":        record.DryRun,
+	}
+	switch record.Result() {
+	case "failed":
+		m.logger.Error("OctoPoints redemption failed", "points", points, "error", record.Error)
+		m.notify(EventPointsRedemptionFailed, "OctoPoints redemption failed",
+			fmt.Sprintf("Could not redeem %d OctoPoints: %s", points, record.Error), data)
+	case "dry_run":
+		m.logger.Info("OctoPoints redemption (dry run)", "points", points, "credit_pounds", fmt.Sprintf("%.2f", record.CreditPounds), "balance", balance)
+		m.notify(EventPointsRedeemed, "OctoPoints redemption (dry run)",
+			fmt.Sprintf("Would redeem %d of %d OctoPoints for £%.2f account credit", points, balance, record.CreditPounds), data)
+	default:
+		m.logger.Info("Redeemed OctoPoints", "points", record.Points, "credit_pounds", fmt.Sprintf("%.2f", record.CreditPounds), "balance", balance)
+		m.notify(EventPointsRedeemed, "OctoPoints redeemed",
+			fmt.Sprintf("Redeemed %d OctoPoints for £%.2f account credit", record.Points, record.CreditPounds), data)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// channelNotifier hands events to a test as they are delivered
+type channelNotifier chan NotificationEvent
+
+func (c channelNotifier) Name() string { return "test" }
+
+func (c channelNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	c <- event
+	return nil
+}
+
+func TestRedemptionAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RedemptionConfig
+		balance int
+		points  int
+	}{
+		{"defaults redeem whole pounds", RedemptionConfig{}, 1850, 1600},
+		{"below the default minimum", RedemptionConfig{}, 799, 0},
+		{"reserve is kept back", RedemptionConfig{Reserve: 500}, 1850, 800},
+		{"minimum balance not reached", RedemptionConfig{MinBalance: 2000, Multiple: 100}, 1850, 0},
+		{"smaller multiples", RedemptionConfig{MinBalance: 1000, Multiple: 100, Reserve: 250}, 1850, 1600},
+		{"reserve above balance", RedemptionConfig{MinBalance: 100, Multiple: 100, Reserve: 2000}, 1850, 0},
+	}
+
+	for _, test := range tests {
+		config := test.config
+		config.applyDefaults()
+		if got := config.amount(test.balance); got != test.points {
+			t.Errorf("%s: expected %d points, got %d", test.name, test.points, got)
+		}
+	}
+}
+
+func TestRedemptionConfigValidate(t *testing.T) {
+	if problems := (&RedemptionConfig{Reserve: -1}).validate(); len(problems) != 0 {
+		t.Errorf("Expected a disabled policy not to be checked, got %v", problems)
+	}
+	if problems := (&RedemptionConfig{Enabled: true, Reserve: 200, DryRun: true}).validate(); len(problems) != 0 {
+		t.Errorf("Expected a valid policy, got %v", problems)
+	}
+	if problems := (&RedemptionConfig{Enabled: true, MinBalance: -1, Multiple: -800, Reserve: -1}).validate(); len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", problems)
+	}
+}
+
+func TestRedemptionBlocked(t *testing.T) {
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	ledger := []PointsLedgerEntry{{ID: "1", PostedAt: at.Add(-time.Hour), Reason: PointsReasonSavingSession, Delta: 1850, Balance: 1850}}
+	state := pointsLedgerState(1850, ledger)
+	redeemed := []RedemptionRecord{{At: at, Points: 1600, BalanceBefore: 1850, LedgerState: state}}
+
+	if reason := redemptionBlocked(nil, ledger, 1850, state, false); reason != "" {
+		t.Errorf("Expected the first redemption to go ahead, got %q", reason)
+	}
+	if reason := redemptionBlocked(redeemed, ledger, 1850, state, false); reason == "" {
+		t.Error("Expected the same ledger state not to be redeemed twice")
+	}
+	if reason := redemptionBlocked([]RedemptionRecord{{LedgerState: state, DryRun: true}}, ledger, 1850, state, false); reason != "" {
+		t.Errorf("Expected a dry run not to block a real redemption, got %q", reason)
+	}
+	failed := []RedemptionRecord{{At: at, Points: 1600, BalanceBefore: 1850, LedgerState: state, Error: "timeout"}}
+	if reason := redemptionBlocked(failed, ledger, 1850, state, false); reason == "" {
+		t.Error("Expected a failed attempt to block its ledger state, in case it went through")
+	}
+
+	// A new entry changes the state, but the redemption hasn't been posted yet
+	spun := append(ledger, PointsLedgerEntry{ID: "2", PostedAt: at.Add(time.Minute), Reason: PointsReasonWheelSpin, Delta: 20, Balance: 1870})
+	if reason := redemptionBlocked(redeemed, spun, 1870, pointsLedgerState(1870, spun), false); reason == "" {
+		t.Error("Expected to wait for the last redemption to reach the ledger")
+	}
+	posted := append(spun, PointsLedgerEntry{ID: "3", PostedAt: at.Add(2 * time.Minute), Reason: PointsReasonRedemption, Delta: -1600, Balance: 270})
+	if reason := redemptionBlocked(redeemed, posted, 270, pointsLedgerState(270, posted), false); reason != "" {
+		t.Errorf("Expected redemption to resume once posted, got %q", reason)
+	}
+}
+
+func TestRedemptionPendingBlocksRestart(t *testing.T) {
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	ledger := []PointsLedgerEntry{{ID: "1", PostedAt: at.Add(-time.Hour), Reason: PointsReasonSavingSession, Delta: 1850, Balance: 1850}}
+	ledgerState := pointsLedgerState(1850, ledger)
+	pending := RedemptionRecord{At: at, Points: 1600, BalanceBefore: 1850, LedgerState: ledgerState, Pending: true}
+
+	// An attempt interrupted before its outcome was known is never repeated
+	if reason := redemptionBlocked([]RedemptionRecord{pending}, ledger, 1850, ledgerState, false); reason == "" {
+		t.Error("Expected a pending redemption to block its ledger state")
+	}
+	if pending.Result() != "pending" {
+		t.Errorf("Expected a pending result, got %s", pending.Result())
+	}
+
+	state := &AppState{}
+	state.RecordRedemption(RedemptionRecord{At: at.Add(-time.Hour), Points: 800})
+	state.RecordRedemption(pending)
+	finished := pending
+	finished.Pending = false
+	finished.Points = 800
+	state.FinishRedemption(finished)
+	if redemptions := state.Snapshot().Redemptions; len(redemptions) != 2 || redemptions[1].Pending || redemptions[1].Points != 800 {
+		t.Errorf("Expected the pending record to be replaced by its outcome, got %+v", redemptions)
+	}
+}
+
+func TestRedemptionSavedBeforeMutation(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	monitor.SetRedemptionPolicy(&RedemptionConfig{Enabled: true, Reserve: 200})
+
+	// Look at the state file as the mutation goes past
+	target, _ := url.Parse(octopusEndpoints["graphql"])
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: target.Scheme, Host: target.Host})
+	var onDisk []RedemptionRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(body, []byte("redeemLoyaltyPointsForAccountCredit")) {
+			if saved, err := LoadState(fixtures.AccountID); err == nil {
+				onDisk = saved.Redemptions
+			}
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	octopusEndpoints["graphql"] = server.URL + target.Path
+
+	if err := monitor.checkRedemption(); err != nil {
+		t.Fatalf("Expected the redemption to run, got %v", err)
+	}
+	if len(onDisk) != 1 || !onDisk[0].Pending || onDisk[0].Points != 1600 {
+		t.Errorf("Expected a pending record on disk when the mutation was sent, got %+v", onDisk)
+	}
+
+	saved, err := LoadState(fixtures.AccountID)
+	if err != nil || len(saved.Redemptions) != 1 || saved.Redemptions[0].Result() != "redeemed" {
+		t.Errorf("Expected the outcome saved without waiting for the cycle, got %+v (%v)", saved, err)
+	}
+}
+
+// redemptionMonitor returns a monitor redeeming from the fake Kraken, and the
+// notifications it sends
+func redemptionMonitor(t *testing.T, config RedemptionConfig) (*FakeKraken, *SavingSessionMonitor, channelNotifier) {
+	t.Helper()
+	fixtures := DefaultKrakenFixtures()
+	fake, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	events := make(channelNotifier, 10)
+	notifier, _ := NewNotificationManager(nil, NewLogger(false))
+	notifier.AddNotifier(events, nil, 0, 0)
+	monitor.SetNotifier(notifier)
+	monitor.SetRedemptionPolicy(&config)
+	return fake, monitor, events
+}
+
+// nextEvent waits for the next notification
+func nextEvent(t *testing.T, events channelNotifier) NotificationEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a notification")
+		return NotificationEvent{}
+	}
+}
+
+func TestCheckRedemptionDryRun(t *testing.T) {
+	fake, monitor, events := redemptionMonitor(t, RedemptionConfig{Enabled: true, Reserve: 200, DryRun: true})
+
+	// A dry run is audited and notified once per ledger state, and redeems nothing
+	monitor.checkRedemption()
+	monitor.checkRedemption()
+	if event := nextEvent(t, events); event.Type != EventPointsRedeemed || !strings.Contains(event.Title, "dry run") {
+		t.Errorf("Expected a dry run notification, got %+v", event)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected a single notification, got another %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fake.mu.Lock()
+	points, redeemed := fake.points, fake.redeemed
+	fake.mu.Unlock()
+	redemptions := monitor.state.Snapshot().Redemptions
+	if len(redemptions) != 1 || redemptions[0].Result() != "dry_run" || redeemed != 0 || points != DefaultKrakenFixtures().Points {
+		t.Errorf("Expected one dry run and no points taken, got %+v with %d points left", redemptions, points)
+	}
+}
+
+func TestCheckRedemptionRedeemsOnce(t *testing.T) {
+	fake, monitor, events := redemptionMonitor(t, RedemptionConfig{Enabled: true, Reserve: 200})
+
+	monitor.checkRedemption()
+	if event := nextEvent(t, events); event.Type != EventPointsRedeemed || event.Data["points"] != 1600 {
+		t.Errorf("Expected a notification for 1600 points, got %+v", event)
+	}
+	monitor.checkRedemption()
+
+	fake.mu.Lock()
+	points, redeemed := fake.points, fake.redeemed
+	fake.mu.Unlock()
+	if points != 250 || redeemed != 1600 {
+		t.Errorf("Expected 1600 points redeemed once leaving 250, got %d redeemed and %d left", redeemed, points)
+	}
+	snapshot := monitor.state.Snapshot()
+	if len(snapshot.Redemptions) != 1 || snapshot.Redemptions[0].Result() != "redeemed" || snapshot.Redemptions[0].CreditPounds != 2 {
+		t.Errorf("Expected the redemption in the audit trail, got %+v", snapshot.Redemptions)
+	}
+	// The posted redemption is picked up from the ledger straight away
+	if last := snapshot.PointsLedger[len(snapshot.PointsLedger)-1]; last.Reason != PointsReasonRedemption || last.Balance != 250 {
+		t.Errorf("Expected the redemption at the end of the ledger, got %+v", last)
+	}
+}
+
+// testRedemptions are a dry run followed by a 1600 point redemption
+func testRedemptions() []RedemptionRecord {
+	at := time.Now().Add(-time.Hour)
+	return []RedemptionRecord{
+		{At: at, Points: 1600, BalanceBefore: 1850, DryRun: true},
+		{At: at.Add(time.Minute), Points: 1600, BalanceBefore: 1850, CreditPounds: 2},
+	}
+}
+
+func TestRedemptionsInPointsHistoryAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	for _, redemption := range testRedemptions() {
+		monitor.state.RecordRedemption(redemption)
+	}
+
+	var history PointsHistory
+	newMonitorAPI(t, monitor).get("/api/points/history", &history)
+	if len(history.Redemptions) != 2 || history.Redemptions[0].DryRun || !history.Redemptions[1].DryRun {
+		t.Errorf("Expected redemptions newest first, got %+v", history.Redemptions)
+	}
+}
+
+func TestRedemptionMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	for _, redemption := range testRedemptions() {
+		monitor.state.RecordRedemption(redemption)
+	}
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_octopoints_redemptions_total{account="A-DEMO0001",result="redeemed"} 1`,
+		`octojoin_octopoints_redemptions_total{account="A-DEMO0001",result="dry_run"} 1`,
+		`octojoin_octopoints_redeemed_points_total{account="A-DEMO0001",result="redeemed"} 1600`,
+		`octojoin_octopoints_redemption_pending{account="A-DEMO0001"} 0`,
+	)
+}
+
+func TestRedemptionPendingMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	redemptions := testRedemptions()
+	for _, redemption := range redemptions {
+		monitor.state.RecordRedemption(redemption)
+	}
+	pending := RedemptionRecord{At: time.Now(), Points: 800, BalanceBefore: 1050, Pending: true}
+	monitor.state.RecordRedemption(pending)
+	api := newMonitorAPI(t, monitor)
+
+	// Pending is a gauge, so the counters never see a record leave it
+	api.expectMetrics(`octojoin_octopoints_redemption_pending{account="A-DEMO0001"} 1`)
+	output := NewMetricsCollector(monitor.client, monitor).collectMetrics()
+	if strings.Contains(output, `result="pending"`) {
+		t.Error("Expected no pending series in the redemption counters")
+	}
+
+	pending.Pending, pending.CreditPounds = false, 1
+	monitor.state.FinishRedemption(pending)
+	api.expectMetrics(
+		`octojoin_octopoints_redemption_pending{account="A-DEMO0001"} 0`,
+		`octojoin_octopoints_redemptions_total{account="A-DEMO0001",result="redeemed"} 2`,
+		`octojoin_octopoints_redeemed_points_total{account="A-DEMO0001",result="redeemed"} 2400`,
+	)
+}
@@ -0,0 +1,285 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task is one piece of monitor work the scheduler runs on its own cadence.
+// A run that overruns Timeout is reported as failed and abandoned rather than
+// cancelled, as the Octopus client has no context support; the task is not
+// started again until the abandoned run returns.
+type Task struct {
+	Name     string
+	Run      func() error
+	Interval func() time.Duration // asked after every run, so a task can speed up or back off
+	Jitter   time.Duration        // up to this much is added at random to each wait
+	Timeout  time.Duration        // 0 for no limit
+}
+
+// fixedInterval is an interval strategy that always waits d
+func fixedInterval(d time.Duration) func() time.Duration {
+	return func() time.Duration { return d }
+}
+
+// TaskStatus is a task's schedule and last outcome, as served at /api/tasks
+type TaskStatus struct {
+	Name                string     `json:"name"`
+	Running             bool       `json:"running"`
+	IntervalSeconds     float64    `json:"interval_seconds"` // wait chosen after the last run, before jitter
+	TimeoutSeconds      float64    `json:"timeout_seconds"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastDurationSeconds float64    `json:"last_duration_seconds"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"` // kept after a later success, see LastErrorAt
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+	Runs                int        `json:"runs"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+type scheduledTask struct {
+	Task
+	running sync.Mutex // held for as long as Run is executing
+
+	mu     sync.Mutex
+	status TaskStatus
+}
+
+// Scheduler runs each task on its own goroutine, so a slow or failing task
+// never holds up the others
+type Scheduler struct {
+	logger    *Logger
+	tasks     []*scheduledTask
+	completed chan struct{} // holds at most one pending signal, so runs coalesce rather than drop
+}
+
+// NewScheduler creates a scheduler for the given tasks. RunAll runs them in
+// the order given.
+func NewScheduler(logger *Logger, tasks ...Task) *Scheduler {
+	s := &Scheduler{
+		logger:    logger,
+		completed: make(chan struct{}, 1),
+	}
+	for _, task := range tasks {
+		s.tasks = append(s.tasks, &scheduledTask{
+			Task:   task,
+			status: TaskStatus{Name: task.Name, TimeoutSeconds: task.Timeout.Seconds()},
+		})
+	}
+	return s
+}
+
+// Completed receives after scheduled runs finish. Runs that finish while a
+// signal is still pending share it, so each receive covers every run since
+// the last one and tasks never wait on the listener.
+func (s *Scheduler) Completed() <-chan struct{} {
+	return s.completed
+}
+
+// Run schedules every task until ctx is done. Tasks that have not run yet,
+// through RunAll or RunTask, start straight away.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range s.tasks {
+		wg.Add(1)
+		go func(t *scheduledTask) {
+			defer wg.Done()
+			s.loop(ctx, t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, t *scheduledTask) {
+	t.mu.Lock()
+	ran := t.status.LastRun != nil
+	t.mu.Unlock()
+	if !ran {
+		s.run(t)
+		s.notifyCompleted()
+	}
+
+	for {
+		wait := t.nextWait()
+		s.logger.Debug("Next task run scheduled", "task", t.Name, "in", wait.Round(time.Second).String())
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			s.run(t)
+			s.notifyCompleted()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Scheduler) notifyCompleted() {
+	select {
+	case s.completed <- struct{}{}:
+	default:
+		// A signal is already pending and will cover this run
+	}
+}
+
+// RunAll runs every task once, in order, waiting for each to finish or time
+// out. A failing task is logged and the rest still run.
+func (s *Scheduler) RunAll() {
+	for _, t := range s.tasks {
+		s.run(t)
+	}
+}
+
+// RunTask runs the named task now and returns its error
+func (s *Scheduler) RunTask(name string) error {
+	for _, t := range s.tasks {
+		if t.Name == name {
+			return s.run(t)
+		}
+	}
+	return fmt.Errorf("unknown task %q", name)
+}
+
+// RunExclusive runs fn while the named task is not running, for work that
+// shares the task's state
+func (s *Scheduler) RunExclusive(name string, fn func()) {
+	for _, t := range s.tasks {
+		if t.Name == name {
+			t.running.Lock()
+			defer t.running.Unlock()
+			break
+		}
+	}
+	fn()
+}
+
+// Status returns every task's status, in the order the tasks were added
+func (s *Scheduler) Status() []TaskStatus {
+	statuses := make([]TaskStatus, len(s.tasks))
+	for i, t := range s.tasks {
+		t.mu.Lock()
+		statuses[i] = t.status
+		t.mu.Unlock()
+	}
+	return statuses
+}
+
+// run runs a task once, recovering from panics and giving up waiting after
+// its timeout. A run abandoned by its timeout signals Completed when it
+// finally returns, so its changes are still saved. A task whose previous run
+// is still going is skipped.
+func (s *Scheduler) run(t *scheduledTask) error {
+	if !t.running.TryLock() {
+		s.logger.Warn("Skipping task, previous run still in progress", "task", t.Name)
+		return fmt.Errorf("%s: previous run still in progress", t.Name)
+	}
+
+	started := time.Now()
+	t.mu.Lock()
+	t.status.Running = true
+	t.status.LastRun = &started
+	t.mu.Unlock()
+
+	var abandoned atomic.Bool
+	done := make(chan error, 1)
+	go func() {
+		defer t.running.Unlock()
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+			t.mu.Lock()
+			t.status.Running = false
+			t.mu.Unlock()
+			if abandoned.Load() {
+				s.notifyCompleted()
+			}
+		}()
+		done <- t.Run()
+	}()
+
+	var timeout <-chan time.Time
+	if t.Timeout > 0 {
+		timer := time.NewTimer(t.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case err = <-done:
+	case <-timeout:
+		abandoned.Store(true)
+		err = fmt.Errorf("timed out after %s", t.Timeout)
+	}
+
+	finished := time.Now()
+	t.mu.Lock()
+	t.status.Runs++
+	t.status.LastDurationSeconds = finished.Sub(started).Seconds()
+	if err != nil {
+		t.status.Failures++
+		t.status.ConsecutiveFailures++
+		t.status.LastError = err.Error()
+		t.status.LastErrorAt = &finished
+	} else {
+		t.status.ConsecutiveFailures = 0
+		t.status.LastSuccess = &finished
+	}
+	failures := t.status.ConsecutiveFailures
+	t.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Task failed", "task", t.Name, "error", err.Error(), "consecutive_failures", failures)
+	}
+	return err
+}
+
+// nextWait asks the task's interval strategy how long to wait, retries
+// failures sooner with exponential backoff, and adds jitter
+func (t *scheduledTask) nextWait() time.Duration {
+	interval := t.Interval()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wait := interval
+	if failures := t.status.ConsecutiveFailures; failures > 0 {
+		retry := TaskRetryInterval
+		for i := 1; i < failures && retry < interval; i++ {
+			retry *= 2
+		}
+		if retry < interval {
+			wait = retry
+		}
+	}
+	if t.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(t.Jitter)))
+	}
+
+	next := time.Now().Add(wait)
+	t.status.IntervalSeconds = interval.Seconds()
+	t.status.NextRun = &next
+	return wait
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerIsolatesFailures(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	var ran atomic.Int32
+
+	scheduler := NewScheduler(NewLogger(false),
+		Task{Name: "failing", Run: func() error { return errors.New("api down") }, Interval: fixedInterval(time.Hour)},
+		Task{Name: "panicking", Run: func() error { panic("boom") }, Interval: fixedInterval(time.Hour)},
+		Task{Name: "hanging", Run: func() error { <-hang; return nil }, Interval: fixedInterval(time.Hour), Timeout: 20 * time.Millisecond},
+		Task{Name: "ok", Run: func() error { ran.Add(1); return nil }, Interval: fixedInterval(time.Hour)},
+	)
+	scheduler.RunAll()
+
+	if ran.Load() != 1 {
+		t.Fatalf("Expected the healthy task to run after the others failed, ran %d times", ran.Load())
+	}
+
+	statuses := scheduler.Status()
+	expectedErrors := []string{"api down", "panic: boom", "timed out after 20ms", ""}
+	for i, status := range statuses {
+		if status.LastError != expectedErrors[i] || status.Runs != 1 || status.LastRun == nil {
+			t.Errorf("%s: expected one run with error %q, got %+v", status.Name, expectedErrors[i], status)
+		}
+	}
+	if statuses[0].ConsecutiveFailures != 1 || statuses[0].LastSuccess != nil || statuses[3].LastSuccess == nil {
+		t.Errorf("Expected failures and successes to be tracked, got %+v", statuses)
+	}
+
+	// The abandoned run still holds the task, so it is not started twice
+	if !statuses[2].Running {
+		t.Error("Expected the timed out task to still be running")
+	}
+	if err := scheduler.RunTask("hanging"); err == nil || !strings.Contains(err.Error(), "still in progress") {
+		t.Errorf("Expected the overlapping run to be skipped, got %v", err)
+	}
+	if err := scheduler.RunTask("missing"); err == nil {
+		t.Error("Expected an unknown task to fail")
+	}
+}
+
+func TestSchedulerRetryBackoff(t *testing.T) {
+	task := &scheduledTask{Task: Task{Name: "flaky", Interval: fixedInterval(time.Hour)}}
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, time.Hour},
+		{1, TaskRetryInterval},
+		{3, 4 * TaskRetryInterval},
+		{20, time.Hour}, // never waits longer than the interval
+	}
+
+	for _, test := range tests {
+		task.status.ConsecutiveFailures = test.failures
+		if wait := task.nextWait(); wait != test.expected {
+			t.Errorf("%d failures: expected %s, got %s", test.failures, test.expected, wait)
+		}
+		if task.status.NextRun == nil || task.status.IntervalSeconds != time.Hour.Seconds() {
+			t.Errorf("Expected the next run and interval to be recorded, got %+v", task.status)
+		}
+	}
+
+	task.Jitter = time.Minute
+	task.status.ConsecutiveFailures = 0
+	if wait := task.nextWait(); wait < time.Hour || wait >= time.Hour+time.Minute {
+		t.Errorf("Expected up to a minute of jitter, got %s", wait)
+	}
+}
+
+func TestSchedulerRunsTasksIndependently(t *testing.T) {
+	var fast atomic.Int32
+	release := make(chan struct{})
+	scheduler := NewScheduler(NewLogger(false),
+		Task{Name: "slow", Run: func() error { <-release; return nil }, Interval: fixedInterval(time.Hour)},
+		Task{Name: "fast", Run: func() error { fast.Add(1); return nil }, Interval: fixedInterval(5 * time.Millisecond)},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fast.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fast.Load() < 3 {
+		t.Errorf("Expected the fast task to keep running while the slow one was stuck, ran %d times", fast.Load())
+	}
+	if statuses := scheduler.Status(); !statuses[0].Running || statuses[1].NextRun == nil {
+		t.Errorf("Expected the slow task running and the fast task scheduled, got %+v", statuses)
+	}
+
+	close(release)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the scheduler to stop with its context")
+	}
+}
+
+func TestFakeKrakenTasksAPI(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.ElectricitySpins = 0
+	fixtures.GasSpins = 0
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	ws := NewWebServer(monitor, 0)
+	monitor.SetWebServer(ws)
+
+	monitor.checkForNewSessions()
+
+	recorder := httptest.NewRecorder()
+	ws.server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/accounts/"+fixtures.AccountID+"/tasks", nil))
+	var statuses []TaskStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&statuses); err != nil {
+		t.Fatalf("Expected JSON task statuses, got %v", err)
+	}
+
+	var names []string
+	for _, status := range statuses {
+		names = append(names, status.Name)
+		if status.Runs != 1 || status.LastSuccess == nil || status.LastError != "" {
+			t.Errorf("%s: expected one successful run, got %+v", status.Name, status)
+		}
+	}
+	expected := "saving_sessions,wheel_spins,free_electricity,agile_prices,dispatches,usage_sync,account_info,points_ledger"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected tasks %s, got %v", expected, names)
+	}
+}
+
+func TestSchedulerCoalescesCompletions(t *testing.T) {
+	release := make(chan struct{})
+	scheduler := NewScheduler(NewLogger(false),
+		Task{Name: "slow", Run: func() error { <-release; return nil }, Interval: fixedInterval(time.Hour), Timeout: 10 * time.Millisecond},
+	)
+
+	// Several runs finishing before anyone listens leave one signal, not none
+	scheduler.notifyCompleted()
+	scheduler.notifyCompleted()
+	<-scheduler.Completed()
+	select {
+	case <-scheduler.Completed():
+		t.Fatal("Expected completions to coalesce into one signal")
+	default:
+	}
+
+	// A run abandoned by its timeout still signals once it returns
+	scheduler.RunAll()
+	close(release)
+	select {
+	case <-scheduler.Completed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the abandoned run to signal completion")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -53,20 +54,49 @@ type CachedAccountInfo struct {
 }
 
 type CachedMeterDevices struct {
-	Data      []string  `json:"data"`
+	Data      []string  `json:"data"` // electricity (ESME)
+	Gas       []string  `json:"gas"`  // nil in state saved before gas meters were discovered
+	Properties []Property `json:"properties"` // nil in state saved before properties were kept apart
 	Timestamp time.Time `json:"timestamp"`
 }
 
+type CachedTariffs struct {
+	Data      []Tariff  `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type CachedDispatches struct {
+	Data      []Dispatch `json:"data"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
 type CachedUsageMeasurements struct {
 	Data      []UsageMeasurement `json:"data"`
 	Timestamp time.Time          `json:"timestamp"`
 	Days      int                `json:"days"` // Track how many days of data this represents
 }
 
+// AppState is shared by the monitor's tasks, which run on their own
+// goroutines, the web handlers and the metrics collector. Writes always go
+// through Update and reads through View or a Snapshot, including the session
+// bookkeeping maps. Cache entries are replaced rather than
+// modified in place, so a cache pointer read under the lock stays safe to use
+// after the lock is released.
 type AppState struct {
+	mu sync.RWMutex
+
 	AlertStates                map[string]*FreeElectricityAlertState `json:"alert_states"`
 	KnownSessions             map[int]bool                          `json:"known_sessions"`
 	KnownFreeElectricitySessions map[string]bool                     `json:"known_free_electricity_sessions"`
+	JoinDecisions             map[int]*JoinDecision                 `json:"join_decisions,omitempty"`
+	JoinRetries               map[int]*JoinRetry                    `json:"join_retries,omitempty"` // failed joins still to be retried
+	SessionHistory            map[int]*SessionRecord                `json:"session_history,omitempty"`
+	KnownDispatches           map[string]time.Time                  `json:"known_dispatches,omitempty"` // extra dispatch code to end time
+	PointsLedger              []PointsLedgerEntry                   `json:"points_ledger,omitempty"`    // oldest first
+	Redemptions               []RedemptionRecord                    `json:"redemptions,omitempty"`      // audit trail, oldest first
+	WheelSpinHistory          []WheelSpinRecord                     `json:"wheel_spin_history,omitempty"` // latest StateMaxWheelSpinHistory, oldest first
+	WheelSpinTotals           map[string]WheelSpinTotal             `json:"wheel_spin_totals,omitempty"`  // by fuel type, never trimmed
+	LastWheelSpinCheck        *WheelSpinCheck                       `json:"last_wheel_spin_check,omitempty"`
 	CachedSavingSessions      *CachedSavingSessions                 `json:"cached_saving_sessions,omitempty"`
 	CachedFreeElectricity     *CachedFreeElectricitySessions        `json:"cached_free_electricity,omitempty"`
 	CachedCampaignStatus      *CachedCampaignStatus                 `json:"cached_campaign_status,omitempty"`
@@ -75,8 +105,14 @@ type AppState struct {
 	CachedAccountInfo         *CachedAccountInfo                    `json:"cached_account_info,omitempty"`
 	CachedMeterDevices        *CachedMeterDevices                   `json:"cached_meter_devices,omitempty"`
 	CachedUsageMeasurements   *CachedUsageMeasurements              `json:"cached_usage_measurements,omitempty"`
+	CachedGasUsage            *CachedUsageMeasurements              `json:"cached_gas_usage,omitempty"`
+	CachedExportUsage         *CachedUsageMeasurements              `json:"cached_export_usage,omitempty"`
+	CachedTariffs             *CachedTariffs                        `json:"cached_tariffs,omitempty"`
+	CachedDispatches          *CachedDispatches                     `json:"cached_dispatches,omitempty"`
 	JWTToken                  string                                `json:"jwt_token,omitempty"`
 	JWTTokenExpiry            time.Time                             `json:"jwt_token_expiry,omitempty"`
+	JWTRefreshToken           string                                `json:"jwt_refresh_token,omitempty"`
+	JWTRefreshTokenExpiry     time.Time                             `json:"jwt_refresh_token_expiry,omitempty"`
 	LastUpdated               time.Time                             `json:"last_updated"`
 }
 
@@ -107,6 +143,9 @@ func LoadState(accountID string) (*AppState, error) {
 			AlertStates:                  make(map[string]*FreeElectricityAlertState),
 			KnownSessions:                make(map[int]bool),
 			KnownFreeElectricitySessions: make(map[string]bool),
+			JoinDecisions:                make(map[int]*JoinDecision),
+			SessionHistory:               make(map[int]*SessionRecord),
+			KnownDispatches:              make(map[string]time.Time),
 			LastUpdated:                  time.Now(),
 		}, nil
 	}
@@ -131,6 +170,15 @@ func LoadState(accountID string) (*AppState, error) {
 	if state.KnownFreeElectricitySessions == nil {
 		state.KnownFreeElectricitySessions = make(map[string]bool)
 	}
+	if state.JoinDecisions == nil {
+		state.JoinDecisions = make(map[int]*JoinDecision)
+	}
+	if state.SessionHistory == nil {
+		state.SessionHistory = make(map[int]*SessionRecord)
+	}
+	if state.KnownDispatches == nil {
+		state.KnownDispatches = make(map[string]time.Time)
+	}
 	
 	return &state, nil
 }
@@ -141,9 +189,10 @@ func (s *AppState) Save(accountID string) error {
 		return err
 	}
 	
+	s.mu.Lock()
 	s.LastUpdated = time.Now()
-	
 	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
@@ -155,11 +204,153 @@ func (s *AppState) Save(accountID string) error {
 	return nil
 }
 
+// View runs fn with the state locked for reading
+func (s *AppState) View(fn func(s *AppState)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s)
+}
+
+// Update runs fn with the state locked for writing
+func (s *AppState) Update(fn func(s *AppState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+// Snapshot returns a copy of the state that can be read without locking.
+// Maps and alert states are copied; cache entries are shared since they are
+// never modified once stored.
+func (s *AppState) Snapshot() *AppState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := &AppState{
+		AlertStates:                  make(map[string]*FreeElectricityAlertState, len(s.AlertStates)),
+		KnownSessions:                make(map[int]bool, len(s.KnownSessions)),
+		KnownFreeElectricitySessions: make(map[string]bool, len(s.KnownFreeElectricitySessions)),
+		JoinDecisions:                make(map[int]*JoinDecision, len(s.JoinDecisions)),
+		JoinRetries:                  make(map[int]*JoinRetry, len(s.JoinRetries)),
+		SessionHistory:               make(map[int]*SessionRecord, len(s.SessionHistory)),
+		KnownDispatches:              make(map[string]time.Time, len(s.KnownDispatches)),
+		PointsLedger:                 s.PointsLedger, // replaced rather than appended to, like the caches
+		Redemptions:                  s.Redemptions,
+		WheelSpinHistory:             s.WheelSpinHistory,
+		WheelSpinTotals:              s.WheelSpinTotals, // replaced as a whole on every batch
+		LastWheelSpinCheck:           s.LastWheelSpinCheck,
+		CachedSavingSessions:         s.CachedSavingSessions,
+		CachedFreeElectricity:        s.CachedFreeElectricity,
+		CachedCampaignStatus:         s.CachedCampaignStatus,
+		CachedOctoPoints:             s.CachedOctoPoints,
+		CachedWheelOfFortuneSpins:    s.CachedWheelOfFortuneSpins,
+		CachedAccountInfo:            s.CachedAccountInfo,
+		CachedMeterDevices:           s.CachedMeterDevices,
+		CachedUsageMeasurements:      s.CachedUsageMeasurements,
+		CachedGasUsage:               s.CachedGasUsage,
+		CachedExportUsage:            s.CachedExportUsage,
+		CachedTariffs:                s.CachedTariffs,
+		CachedDispatches:             s.CachedDispatches,
+		JWTToken:                     s.JWTToken,
+		JWTTokenExpiry:               s.JWTTokenExpiry,
+		JWTRefreshToken:              s.JWTRefreshToken,
+		JWTRefreshTokenExpiry:        s.JWTRefreshTokenExpiry,
+		LastUpdated:                  s.LastUpdated,
+	}
+	for code, alert := range s.AlertStates {
+		copied := *alert
+		snapshot.AlertStates[code] = &copied
+	}
+	for id, known := range s.KnownSessions {
+		snapshot.KnownSessions[id] = known
+	}
+	for code, known := range s.KnownFreeElectricitySessions {
+		snapshot.KnownFreeElectricitySessions[code] = known
+	}
+	for id, decision := range s.JoinDecisions {
+		snapshot.JoinDecisions[id] = decision
+	}
+	for id, retry := range s.JoinRetries {
+		snapshot.JoinRetries[id] = retry // replaced rather than modified
+	}
+	for id, record := range s.SessionHistory {
+		snapshot.SessionHistory[id] = record
+	}
+	for code, endAt := range s.KnownDispatches {
+		snapshot.KnownDispatches[code] = endAt
+	}
+	return snapshot
+}
+
+// IsSessionKnown reports whether a saving session has already been handled
+func (s *AppState) IsSessionKnown(eventID int) bool {
+	var known bool
+	s.View(func(s *AppState) {
+		known = s.KnownSessions[eventID]
+	})
+	return known
+}
+
+// MarkSessionKnown records that a saving session has been handled
+func (s *AppState) MarkSessionKnown(eventID int) {
+	s.Update(func(s *AppState) {
+		s.KnownSessions[eventID] = true
+	})
+}
+
+// JoinRetry tracks a saving session whose join failed and will be tried again
+type JoinRetry struct {
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error"`
+}
+
+// RecordJoinFailure counts a failed join and schedules the next attempt,
+// doubling the wait after each failure
+func (s *AppState) RecordJoinFailure(eventID int, err error, now time.Time) {
+	s.Update(func(s *AppState) {
+		if s.JoinRetries == nil {
+			s.JoinRetries = make(map[int]*JoinRetry)
+		}
+		attempts := 1
+		if existing := s.JoinRetries[eventID]; existing != nil {
+			attempts = existing.Attempts + 1
+		}
+		s.JoinRetries[eventID] = &JoinRetry{
+			Attempts:    attempts,
+			NextAttempt: now.Add(JoinRetryDelay << (attempts - 1)),
+			LastError:   err.Error(),
+		}
+	})
+}
+
+// ClearJoinRetry stops retrying a session's join
+func (s *AppState) ClearJoinRetry(eventID int) {
+	s.Update(func(s *AppState) {
+		delete(s.JoinRetries, eventID)
+	})
+}
+
+// loadCached reads a cache entry under the state's read lock. It returns nil
+// when state is nil or nothing is cached.
+func loadCached[T any](state *AppState, field func(s *AppState) *T) *T {
+	if state == nil {
+		return nil
+	}
+	var cached *T
+	state.View(func(s *AppState) {
+		cached = field(s)
+	})
+	return cached
+}
+
 func (s *AppState) IsCacheValid(cacheTime time.Time, maxAge time.Duration) bool {
 	return time.Since(cacheTime) < maxAge
 }
 
 func (s *AppState) CleanupExpiredSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Clean up alert states for sessions that have ended
 	for code := range s.AlertStates {
 		// Clean up very old alert states
@@ -167,4 +358,32 @@ func (s *AppState) CleanupExpiredSessions() {
 			delete(s.AlertStates, code)
 		}
 	}
+
+	// Forget join decisions once they are old enough that the session has passed
+	for eventID, decision := range s.JoinDecisions {
+		if time.Since(decision.DecidedAt) > StateCleanupAge {
+			delete(s.JoinDecisions, eventID)
+		}
+	}
+
+	// Retries are dropped once the session has started, but may outlive a restart
+	for eventID, retry := range s.JoinRetries {
+		if time.Since(retry.NextAttempt) > StateCleanupAge {
+			delete(s.JoinRetries, eventID)
+		}
+	}
+
+	// Extra dispatches only need remembering until they have passed
+	for code, endAt := range s.KnownDispatches {
+		if time.Since(endAt) > StateCleanupAge {
+			delete(s.KnownDispatches, code)
+		}
+	}
+
+	// Session history is kept for several seasons
+	for eventID, record := range s.SessionHistory {
+		if time.Since(record.EndAt) > SessionHistoryRetention {
+			delete(s.SessionHistory, eventID)
+		}
+	}
 }
\ No newline at end of file
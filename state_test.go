@@ -15,6 +15,7 @@
 package main
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -154,4 +155,68 @@ func TestCachedSavingSessionsStruct(t *testing.T) {
 	if cached.Timestamp != now {
 		t.Errorf("Expected timestamp %v, got %v", now, cached.Timestamp)
 	}
-}
\ No newline at end of file
+}
+func TestAppStateSnapshotIsIndependent(t *testing.T) {
+	state := &AppState{
+		AlertStates:                  map[string]*FreeElectricityAlertState{"FREE_1": {Code: "FREE_1"}},
+		KnownSessions:                map[int]bool{1: true},
+		KnownFreeElectricitySessions: make(map[string]bool),
+		JoinDecisions:                make(map[int]*JoinDecision),
+		CachedOctoPoints:             &CachedOctoPoints{Data: 100, Timestamp: time.Now()},
+	}
+
+	snapshot := state.Snapshot()
+
+	state.Update(func(s *AppState) {
+		s.KnownSessions[2] = true
+		s.AlertStates["FREE_1"].InitialAlert = true
+		s.CachedOctoPoints = nil
+	})
+
+	if len(snapshot.KnownSessions) != 1 {
+		t.Errorf("Expected snapshot to keep 1 known session, got %d", len(snapshot.KnownSessions))
+	}
+	if snapshot.AlertStates["FREE_1"].InitialAlert {
+		t.Error("Expected snapshot alert state to be unaffected by later updates")
+	}
+	if snapshot.CachedOctoPoints == nil || snapshot.CachedOctoPoints.Data != 100 {
+		t.Error("Expected snapshot to keep the cache entry it was taken with")
+	}
+}
+
+func TestAppStateConcurrentAccess(t *testing.T) {
+	state := &AppState{
+		AlertStates:                  make(map[string]*FreeElectricityAlertState),
+		KnownSessions:                make(map[int]bool),
+		KnownFreeElectricitySessions: make(map[string]bool),
+		JoinDecisions:                make(map[int]*JoinDecision),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				state.MarkSessionKnown(i*100 + j)
+				state.Update(func(s *AppState) {
+					s.CachedOctoPoints = &CachedOctoPoints{Data: j, Timestamp: time.Now()}
+				})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if cached := loadCached(state, func(s *AppState) *CachedOctoPoints { return s.CachedOctoPoints }); cached != nil {
+					_ = cached.Data
+				}
+				_ = len(state.Snapshot().KnownSessions)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(state.KnownSessions) != 400 {
+		t.Errorf("Expected 400 known sessions, got %d", len(state.KnownSessions))
+	}
+}
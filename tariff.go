@@ -0,0 +1,296 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// restAgreement is a tariff agreement in the REST account payload
+type restAgreement struct {
+	TariffCode string     `json:"tariff_code"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to"`
+}
+
+type restMeter struct {
+	SerialNumber string `json:"serial_number"`
+}
+
+// AccountAgreementsResponse is the part of GET /accounts/{id}/ describing the
+// meter points on each property and their tariffs
+type AccountAgreementsResponse struct {
+	Number     string `json:"number"`
+	Properties []struct {
+		ID                     int `json:"id"`
+		ElectricityMeterPoints []struct {
+			MPAN       string          `json:"mpan"`
+			IsExport   bool            `json:"is_export"`
+			Meters     []restMeter     `json:"meters"`
+			Agreements []restAgreement `json:"agreements"`
+		} `json:"electricity_meter_points"`
+		GasMeterPoints []struct {
+			MPRN       string          `json:"mprn"`
+			Meters     []restMeter     `json:"meters"`
+			Agreements []restAgreement `json:"agreements"`
+		} `json:"gas_meter_points"`
+	} `json:"properties"`
+}
+
+// Agreement is one tariff agreement on a meter point
+type Agreement struct {
+	PropertyID   string     `json:"property_id"`
+	Fuel         string     `json:"fuel"`        // FuelElectricity, FuelExport or FuelGas
+	MeterPoint   string     `json:"meter_point"` // MPAN, or MPRN for gas
+	MeterSerials []string   `json:"meter_serials"`
+	TariffCode   string     `json:"tariff_code"`
+	ProductCode  string     `json:"product_code"`
+	ValidFrom    time.Time  `json:"valid_from"`
+	ValidTo      *time.Time `json:"valid_to,omitempty"`
+}
+
+// ActiveAt reports whether the agreement is in force at t
+func (a *Agreement) ActiveAt(t time.Time) bool {
+	return !a.ValidFrom.After(t) && (a.ValidTo == nil || a.ValidTo.After(t))
+}
+
+// TariffRate is a unit rate or standing charge from the products API, in pence
+type TariffRate struct {
+	ValueExcVAT float64    `json:"value_exc_vat"`
+	ValueIncVAT float64    `json:"value_inc_vat"`
+	ValidFrom   time.Time  `json:"valid_from"`
+	ValidTo     *time.Time `json:"valid_to"`
+	Register    string     `json:"register,omitempty"` // "day" or "night" on Economy 7 tariffs
+}
+
+// ActiveAt reports whether the rate applies at t
+func (r *TariffRate) ActiveAt(t time.Time) bool {
+	return !r.ValidFrom.After(t) && (r.ValidTo == nil || r.ValidTo.After(t))
+}
+
+// Tariff is the agreement currently in force on a meter point with its rates
+// for today and, once published, tomorrow
+type Tariff struct {
+	Agreement
+	UnitRates      []TariffRate `json:"unit_rates"`
+	StandingCharge *TariffRate  `json:"standing_charge,omitempty"`
+}
+
+// CurrentUnitRates returns the unit rates that apply at t, one per register
+func (t *Tariff) CurrentUnitRates(at time.Time) []TariffRate {
+	var current []TariffRate
+	for _, rate := range t.UnitRates {
+		if rate.ActiveAt(at) {
+			current = append(current, rate)
+		}
+	}
+	return current
+}
+
+// tariffProductCode derives the product from a tariff code, which wraps it in
+// fuel, register and region: E-1R-AGILE-24-10-01-C is AGILE-24-10-01
+func tariffProductCode(tariffCode string) string {
+	parts := strings.Split(tariffCode, "-")
+	if len(parts) < 4 {
+		return ""
+	}
+	return strings.Join(parts[2:len(parts)-1], "-")
+}
+
+// getAgreements lists every tariff agreement on the account from the REST
+// account payload, tagged with its property. Export MPANs are reported with
+// FuelExport.
+func (c *OctopusClient) getAgreements() ([]Agreement, error) {
+	endpoint := fmt.Sprintf("/accounts/%s/", c.AccountID)
+
+	resp, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	var result AccountAgreementsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode account response: %w", err)
+	}
+
+	serials := func(meters []restMeter) []string {
+		list := make([]string, 0, len(meters))
+		for _, meter := range meters {
+			list = append(list, meter.SerialNumber)
+		}
+		return list
+	}
+	var agreements []Agreement
+	add := func(propertyID, fuel, meterPoint string, meters []restMeter, restAgreements []restAgreement) {
+		for _, a := range restAgreements {
+			agreements = append(agreements, Agreement{
+				PropertyID:   propertyID,
+				Fuel:         fuel,
+				MeterPoint:   meterPoint,
+				MeterSerials: serials(meters),
+				TariffCode:   a.TariffCode,
+				ProductCode:  tariffProductCode(a.TariffCode),
+				ValidFrom:    a.ValidFrom,
+				ValidTo:      a.ValidTo,
+			})
+		}
+	}
+	for _, property := range result.Properties {
+		propertyID := strconv.Itoa(property.ID) // the GraphQL API uses the same ID as a string
+		for _, point := range property.ElectricityMeterPoints {
+			fuel := FuelElectricity
+			if point.IsExport {
+				fuel = FuelExport
+			}
+			add(propertyID, fuel, point.MPAN, point.Meters, point.Agreements)
+		}
+		for _, point := range property.GasMeterPoints {
+			add(propertyID, FuelGas, point.MPRN, point.Meters, point.Agreements)
+		}
+	}
+
+	c.debugLog("Found %d tariff agreements", len(agreements))
+	return agreements, nil
+}
+
+// getTariffCharges reads one kind of charge (standard-unit-rates,
+// standing-charges, ...) for a tariff from the public products API
+func (c *OctopusClient) getTariffCharges(fuel, productCode, tariffCode, charge string, from, to time.Time) ([]TariffRate, error) {
+	kind := "electricity-tariffs"
+	if fuel == FuelGas {
+		kind = "gas-tariffs"
+	}
+	query := url.Values{}
+	query.Set("period_from", from.UTC().Format(time.RFC3339))
+	query.Set("period_to", to.UTC().Format(time.RFC3339))
+	query.Set("page_size", fmt.Sprint(TariffRatesPageSize))
+	endpoint := fmt.Sprintf("/products/%s/%s/%s/%s/?%s", productCode, kind, tariffCode, charge, query.Encode())
+
+	resp, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("products API request failed with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []TariffRate `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", charge, err)
+	}
+	return result.Results, nil
+}
+
+// getTariffs returns the agreements in force at now with unit rates from the
+// start of today (UK time) to the end of tomorrow and the current standing charge
+func (c *OctopusClient) getTariffs(now time.Time) ([]Tariff, error) {
+	agreements, err := c.getAgreements()
+	if err != nil {
+		return nil, err
+	}
+
+	ukLocation, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		ukLocation = time.UTC
+	}
+	local := now.In(ukLocation)
+	from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, ukLocation)
+	to := from.AddDate(0, 0, 2)
+
+	tariffs := []Tariff{}
+	for _, agreement := range agreements {
+		if !agreement.ActiveAt(now) {
+			continue
+		}
+		if agreement.ProductCode == "" {
+			c.logger.Warn("Unrecognised tariff code", "tariff_code", agreement.TariffCode)
+			continue
+		}
+
+		tariff := Tariff{Agreement: agreement, UnitRates: []TariffRate{}}
+		registers := [][2]string{{"standard-unit-rates", ""}}
+		if strings.HasPrefix(agreement.TariffCode, "E-2R-") {
+			registers = [][2]string{{"day-unit-rates", "day"}, {"night-unit-rates", "night"}}
+		}
+		for _, r := range registers {
+			charge, register := r[0], r[1]
+			rates, err := c.getTariffCharges(agreement.Fuel, agreement.ProductCode, agreement.TariffCode, charge, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("tariff %s: %w", agreement.TariffCode, err)
+			}
+			for _, rate := range rates {
+				rate.Register = register
+				tariff.UnitRates = append(tariff.UnitRates, rate)
+			}
+		}
+
+		charges, err := c.getTariffCharges(agreement.Fuel, agreement.ProductCode, agreement.TariffCode, "standing-charges", now, now.Add(time.Minute))
+		if err != nil {
+			return nil, fmt.Errorf("tariff %s: %w", agreement.TariffCode, err)
+		}
+		for _, charge := range charges {
+			if charge.ActiveAt(now) {
+				standing := charge
+				tariff.StandingCharge = &standing
+				break
+			}
+		}
+
+		tariffs = append(tariffs, tariff)
+	}
+
+	return tariffs, nil
+}
+
+// getTariffsWithCache returns the current tariffs, refreshed hourly so Agile
+// picks up tomorrow's rates soon after they are published
+func (c *OctopusClient) getTariffsWithCache(state *AppState) ([]Tariff, error) {
+	if cached := loadCached(state, func(s *AppState) *CachedTariffs { return s.CachedTariffs }); cached != nil {
+		if state.IsCacheValid(cached.Timestamp, CacheDurationTariffs) {
+			return cached.Data, nil
+		}
+	}
+
+	tariffs, err := c.getTariffs(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil {
+		state.Update(func(s *AppState) {
+			s.CachedTariffs = &CachedTariffs{
+				Data:      tariffs,
+				Timestamp: time.Now(),
+			}
+		})
+	}
+
+	return tariffs, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTariffProductCode(t *testing.T) {
+	tests := []struct {
+		tariffCode string
+		expected   string
+	}{
+		{"E-1R-AGILE-24-10-01-C", "AGILE-24-10-01"},
+		{"E-2R-VAR-22-11-01-A", "VAR-22-11-01"},
+		{"G-1R-VAR-22-11-01-C", "VAR-22-11-01"},
+		{"E-1R-OUTGOING-FIX-12M-19-05-13-C", "OUTGOING-FIX-12M-19-05-13"},
+		{"E-1R-C", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := tariffProductCode(test.tariffCode); got != test.expected {
+			t.Errorf("Expected product %q for %q, got %q", test.expected, test.tariffCode, got)
+		}
+	}
+}
+
+func TestTariffCurrentUnitRates(t *testing.T) {
+	now := time.Date(2025, 1, 15, 17, 10, 0, 0, time.UTC)
+	slot := func(start time.Time, pence float64, register string) TariffRate {
+		end := start.Add(30 * time.Minute)
+		return TariffRate{ValueIncVAT: pence, ValidFrom: start, ValidTo: &end, Register: register}
+	}
+	tariff := Tariff{UnitRates: []TariffRate{
+		slot(now.Add(-40*time.Minute), 20, ""),
+		slot(now.Add(-10*time.Minute), 35, ""),
+		slot(now.Add(20*time.Minute), 38, ""),
+		{ValueIncVAT: 12, ValidFrom: now.AddDate(0, -1, 0)}, // open-ended
+	}}
+
+	current := tariff.CurrentUnitRates(now)
+	if len(current) != 2 || current[0].ValueIncVAT != 35 || current[1].ValueIncVAT != 12 {
+		t.Errorf("Expected the 35p slot and the open-ended rate, got %+v", current)
+	}
+	if got := tariff.CurrentUnitRates(now.Add(-time.Hour)); len(got) != 1 {
+		t.Errorf("Expected only the open-ended rate an hour earlier, got %+v", got)
+	}
+}
+
+func TestGetTariffsFromAgreements(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	_, client := startFakeKraken(t, fixtures)
+	state := &AppState{}
+	client.SetState(state)
+
+	agreements, err := client.getAgreements()
+	if err != nil || len(agreements) != 4 {
+		t.Fatalf("Expected 4 agreements (old and current import, export, gas), got %d (%v)", len(agreements), err)
+	}
+
+	tariffs, err := client.getTariffsWithCache(state)
+	if err != nil {
+		t.Fatalf("Expected tariffs, got %v", err)
+	}
+	byFuel := make(map[string]Tariff)
+	for _, tariff := range tariffs {
+		byFuel[tariff.Fuel] = tariff
+	}
+	if len(tariffs) != 3 || len(byFuel) != 3 {
+		t.Fatalf("Expected current electricity, export and gas tariffs, got %+v", tariffs)
+	}
+
+	electricity := byFuel[FuelElectricity]
+	if electricity.TariffCode != fixtures.TariffCode || electricity.ProductCode != "AGILE-24-10-01" || electricity.MeterPoint == "" || len(electricity.MeterSerials) != 1 {
+		t.Errorf("Expected the Agile agreement with its MPAN and meter serial, got %+v", electricity.Agreement)
+	}
+	// Two days of half-hours, give or take a clock change
+	if n := len(electricity.UnitRates); n < 92 || n > 100 {
+		t.Errorf("Expected half-hourly Agile rates for today and tomorrow, got %d", n)
+	}
+	if current := electricity.CurrentUnitRates(time.Now()); len(current) != 1 || current[0].ValueIncVAT <= 0 {
+		t.Errorf("Expected one current Agile rate, got %+v", current)
+	}
+	if electricity.StandingCharge == nil || electricity.StandingCharge.ValueIncVAT != fixtures.StandingPence {
+		t.Errorf("Expected a %.2fp standing charge, got %+v", fixtures.StandingPence, electricity.StandingCharge)
+	}
+	if gas := byFuel[FuelGas]; len(gas.UnitRates) != 1 || gas.UnitRates[0].ValueIncVAT != fixtures.GasUnitRatePence {
+		t.Errorf("Expected a single gas unit rate, got %+v", gas.UnitRates)
+	}
+	if state.Snapshot().CachedTariffs == nil {
+		t.Error("Expected tariffs to be cached")
+	}
+}
+
+// cacheTestTariffs stores an electricity tariff with a current rate and
+// standing charge, and a gas tariff, as if they had just been fetched
+func cacheTestTariffs(state *AppState) {
+	now := time.Now()
+	state.Update(func(s *AppState) {
+		s.CachedTariffs = &CachedTariffs{Timestamp: now, Data: []Tariff{
+			{
+				Agreement:      Agreement{PropertyID: "1000001", Fuel: FuelElectricity, TariffCode: "E-1R-AGILE-24-10-01-C", ValidFrom: now.AddDate(0, -1, 0)},
+				UnitRates:      []TariffRate{{ValueIncVAT: 18.5, ValidFrom: now.Add(-10 * time.Minute)}, {ValueIncVAT: 30, ValidFrom: now.Add(20 * time.Minute)}},
+				StandingCharge: &TariffRate{ValueIncVAT: 53.35, ValidFrom: now.AddDate(0, -1, 0)},
+			},
+			{
+				Agreement: Agreement{PropertyID: "1000001", Fuel: FuelGas, TariffCode: "G-1R-VAR-22-11-01-C", ValidFrom: now.AddDate(0, -1, 0)},
+				UnitRates: []TariffRate{{ValueIncVAT: 6.2, ValidFrom: now.AddDate(0, -1, 0)}},
+			},
+		}}
+	})
+}
+
+func TestTariffAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTestTariffs(monitor.state)
+
+	var response struct {
+		Tariffs []Tariff `json:"tariffs"`
+		Current []struct {
+			TariffCode string       `json:"tariff_code"`
+			UnitRates  []TariffRate `json:"unit_rates"`
+		} `json:"current"`
+	}
+	if status := newMonitorAPI(t, monitor).get("/api/tariff", &response); status != http.StatusOK {
+		t.Fatalf("Expected tariffs, got status %d", status)
+	}
+	if len(response.Tariffs) != 2 || len(response.Tariffs[0].UnitRates) != 2 {
+		t.Errorf("Expected both tariffs with all their rates, got %+v", response.Tariffs)
+	}
+	// Only the rate in force now is current, not the one starting later
+	if len(response.Current) != 2 || len(response.Current[0].UnitRates) != 1 || response.Current[0].UnitRates[0].ValueIncVAT != 18.5 {
+		t.Errorf("Expected the 18.5p rate to be current, got %+v", response.Current)
+	}
+}
+
+func TestTariffMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	cacheTestTariffs(monitor.state)
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_unit_rate_pence{account="A-DEMO0001",fuel="electricity",property="1000001",register="standard",tariff="E-1R-AGILE-24-10-01-C"} 18.5`,
+		`octojoin_unit_rate_pence{account="A-DEMO0001",fuel="gas",property="1000001",register="standard",tariff="G-1R-VAR-22-11-01-C"} 6.2`,
+		`octojoin_standing_charge_pence{account="A-DEMO0001",fuel="electricity",property="1000001",tariff="E-1R-AGILE-24-10-01-C"} 53.35`,
+		`cache_type="tariffs"`,
+	)
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+)
+
+// LiveDemand is the latest Home Mini reading taken during a joined saving session
+type LiveDemand struct {
+	EventID        int       `json:"event_id"`
+	DeviceID       string    `json:"device_id"`
+	DemandWatts    float64   `json:"demand_watts"`
+	ReadAt         time.Time `json:"read_at"`
+	SessionStartAt time.Time `json:"session_start_at"`
+	SessionEndAt   time.Time `json:"session_end_at"`
+}
+
+// LiveDemand returns the latest reading while its session is still running,
+// or nil when there is none. Safe to call from any goroutine.
+func (m *SavingSessionMonitor) LiveDemand() *LiveDemand {
+	live := m.liveDemand.Load()
+	if live == nil || !live.SessionEndAt.After(time.Now()) {
+		return nil
+	}
+	return live
+}
+
+// nextJoinedSession returns the joined saving session running at now, or the
+// next one to start. It reads the cached sessions so it never calls the API.
+func (m *SavingSessionMonitor) nextJoinedSession(now time.Time) *SavingSession {
+	cached := loadCached(m.state, func(s *AppState) *CachedSavingSessions { return s.CachedSavingSessions })
+	if cached == nil || cached.Data == nil {
+		return nil
+	}
+
+	var next *SavingSession
+	for _, session := range cached.Data.Data.SavingSessions.Account.JoinedEvents {
+		if !session.EndAt.After(now) {
+			continue
+		}
+		if next == nil || session.StartAt.Before(next.StartAt) {
+			s := session
+			next = &s
+		}
+	}
+	return next
+}
+
+// nextTelemetryPoll returns when live demand should next be read, or the zero
+// time when no joined session is due. Nothing shows live demand without the
+// web UI, so it is only polled when that is enabled.
+func (m *SavingSessionMonitor) nextTelemetryPoll(now time.Time) time.Time {
+	if m.webServer == nil {
+		return time.Time{}
+	}
+	session := m.nextJoinedSession(now)
+	if session == nil || session.EventID == m.telemetryUnavailable {
+		return time.Time{}
+	}
+	if session.StartAt.After(now) {
+		return session.StartAt
+	}
+	return m.lastTelemetryPoll.Add(TelemetryPollInterval)
+}
+
+// pollTelemetry reads the Home Mini for the running session. Accounts without
+// one return no readings, after which the session is no longer polled.
+func (m *SavingSessionMonitor) pollTelemetry() {
+	now := time.Now()
+	m.lastTelemetryPoll = now
+
+	session := m.nextJoinedSession(now)
+	if session == nil || session.StartAt.After(now) {
+		return
+	}
+
+	// The Home Mini pairs with the main electricity meter
+	devices, err := m.client.getSmartMeterDevicesWithCache(m.state)
+	if err != nil || len(devices) == 0 {
+		m.logger.Warn("No electricity meter for live demand", "event_id", session.EventID, "error", err)
+		m.telemetryUnavailable = session.EventID
+		return
+	}
+
+	readings, err := m.client.getSmartMeterTelemetry(devices[0], now.Add(-TelemetryWindow), now)
+	if err != nil {
+		m.logger.Warn("Failed to read Home Mini telemetry", "event_id", session.EventID, "error", err.Error())
+		return
+	}
+	if len(readings) == 0 {
+		m.logger.Info("No Home Mini telemetry, live demand unavailable for this session", "event_id", session.EventID)
+		m.telemetryUnavailable = session.EventID
+		return
+	}
+
+	latest := readings[0]
+	for _, reading := range readings[1:] {
+		if reading.ReadAt.After(latest.ReadAt) {
+			latest = reading
+		}
+	}
+	m.liveDemand.Store(&LiveDemand{
+		EventID:        session.EventID,
+		DeviceID:       devices[0],
+		DemandWatts:    latest.GetDemandAsFloat64(),
+		ReadAt:         latest.ReadAt,
+		SessionStartAt: session.StartAt,
+		SessionEndAt:   session.EndAt,
+	})
+	m.logger.Debug("Live demand", "event_id", session.EventID, "watts", latest.GetDemandAsFloat64())
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTelemetryPoll(t *testing.T) {
+	now := time.Date(2025, 1, 15, 17, 30, 0, 0, time.UTC)
+	running := SavingSession{EventID: 1, StartAt: now.Add(-10 * time.Minute), EndAt: now.Add(50 * time.Minute)}
+	upcoming := SavingSession{EventID: 2, StartAt: now.Add(2 * time.Hour), EndAt: now.Add(3 * time.Hour)}
+	ended := SavingSession{EventID: 3, StartAt: now.Add(-2 * time.Hour), EndAt: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name        string
+		sessions    []SavingSession
+		lastPoll    time.Time
+		unavailable int
+		noWebUI     bool
+		expected    time.Time
+	}{
+		{"no joined sessions", nil, time.Time{}, 0, false, time.Time{}},
+		{"ended session", []SavingSession{ended}, time.Time{}, 0, false, time.Time{}},
+		{"upcoming session", []SavingSession{upcoming}, time.Time{}, 0, false, upcoming.StartAt},
+		{"running session never polled", []SavingSession{upcoming, running}, time.Time{}, 0, false, time.Time{}.Add(TelemetryPollInterval)},
+		{"running session polled", []SavingSession{running}, now.Add(-3 * time.Second), 0, false, now.Add(7 * time.Second)},
+		{"no Home Mini", []SavingSession{running}, now.Add(-3 * time.Second), 1, false, time.Time{}},
+		{"without web UI", []SavingSession{running}, time.Time{}, 0, true, time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := &SavingSessionsResponse{}
+			response.Data.SavingSessions.Account.JoinedEvents = test.sessions
+			monitor := &SavingSessionMonitor{
+				state:                &AppState{CachedSavingSessions: &CachedSavingSessions{Data: response, Timestamp: now}},
+				lastTelemetryPoll:    test.lastPoll,
+				telemetryUnavailable: test.unavailable,
+			}
+			if !test.noWebUI {
+				monitor.webServer = &WebServer{}
+			}
+
+			if got := monitor.nextTelemetryPoll(now); !got.Equal(test.expected) {
+				t.Errorf("Expected next poll at %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+// runningSessionFixtures has one joined saving session that started ten
+// minutes ago
+func runningSessionFixtures() KrakenFixtures {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.SavingSessions = []FixtureSavingSession{
+		{ID: 9100, StartsIn: -10 * time.Minute, Duration: time.Hour, Reward: 1800, Joined: true},
+	}
+	return fixtures
+}
+
+func TestPollTelemetryRecordsLiveDemand(t *testing.T) {
+	fixtures := runningSessionFixtures()
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	monitor.SetWebServer(NewWebServer(monitor, 0)) // live demand is only polled for the web UI
+	if _, err := client.GetSavingSessionsWithCache(monitor.state); err != nil {
+		t.Fatalf("Expected saving sessions, got %v", err)
+	}
+	if monitor.nextTelemetryPoll(time.Now()).After(time.Now()) {
+		t.Fatal("Expected live demand to be due for the running session")
+	}
+
+	monitor.pollTelemetry()
+	live := monitor.LiveDemand()
+	if live == nil || live.EventID != 9100 || live.DemandWatts <= 0 || time.Since(live.ReadAt) > TelemetryWindow {
+		t.Fatalf("Expected a recent reading for event 9100, got %+v", live)
+	}
+	if next := monitor.nextTelemetryPoll(time.Now()); time.Until(next) <= 0 || time.Until(next) > TelemetryPollInterval {
+		t.Errorf("Expected the next poll within %v, got %v", TelemetryPollInterval, time.Until(next))
+	}
+}
+
+func TestPollTelemetryWithoutHomeMini(t *testing.T) {
+	fixtures := runningSessionFixtures()
+	fixtures.HomeMini = false
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+	monitor.SetWebServer(NewWebServer(monitor, 0)) // live demand is only polled for the web UI
+	if _, err := client.GetSavingSessionsWithCache(monitor.state); err != nil {
+		t.Fatalf("Expected saving sessions, got %v", err)
+	}
+
+	monitor.pollTelemetry()
+	if monitor.LiveDemand() != nil {
+		t.Error("Expected no live demand without a Home Mini")
+	}
+	if !monitor.nextTelemetryPoll(time.Now()).IsZero() {
+		t.Error("Expected polling to stop for the session once the Home Mini returned nothing")
+	}
+}
+
+func TestLiveDemandAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	api := newMonitorAPI(t, monitor)
+
+	var response struct {
+		LiveDemand *LiveDemand `json:"live_demand"`
+	}
+	if api.get("/api/live", &response); response.LiveDemand != nil {
+		t.Errorf("Expected no live demand before a reading, got %+v", response.LiveDemand)
+	}
+
+	now := time.Now()
+	monitor.liveDemand.Store(&LiveDemand{EventID: 9100, DemandWatts: 640, ReadAt: now, SessionStartAt: now.Add(-10 * time.Minute), SessionEndAt: now.Add(50 * time.Minute)})
+	if api.get("/api/live", &response); response.LiveDemand == nil || response.LiveDemand.EventID != 9100 || response.LiveDemand.DemandWatts != 640 {
+		t.Errorf("Expected the API to serve the latest reading, got %+v", response.LiveDemand)
+	}
+
+	// Readings are dropped once their session ends
+	monitor.liveDemand.Store(&LiveDemand{EventID: 9100, DemandWatts: 640, ReadAt: now.Add(-time.Hour), SessionEndAt: now.Add(-time.Minute)})
+	response.LiveDemand = nil
+	if api.get("/api/live", &response); response.LiveDemand != nil {
+		t.Errorf("Expected no live demand after the session ended, got %+v", response.LiveDemand)
+	}
+}
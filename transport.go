@@ -0,0 +1,382 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TransportMiddleware wraps a RoundTripper with additional behaviour
+type TransportMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ChainTransport wraps base in the given middleware. The first middleware is
+// the outermost, so it sees each request first and each response last.
+func ChainTransport(base http.RoundTripper, middleware ...TransportMiddleware) http.RoundTripper {
+	transport := base
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+	return transport
+}
+
+// TimeoutMiddleware bounds each attempt, including reading the response body,
+// so retries further out in the chain each get a full timeout
+func TimeoutMiddleware(timeout time.Duration) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// sensitiveBodyFields matches JSON fields whose values must never be logged
+var sensitiveBodyFields = regexp.MustCompile(`("(?i:apikey|api_key|token|refreshToken|password|secret)"\s*:\s*)"[^"]*"`)
+
+// redactBody masks credentials in a request or response body and truncates it
+func redactBody(body []byte) string {
+	redacted := sensitiveBodyFields.ReplaceAllString(string(body), `$1"***"`)
+	if len(redacted) > 500 {
+		redacted = redacted[:500] + "... (truncated)"
+	}
+	return redacted
+}
+
+// redactHeaders masks credentials in request headers
+func redactHeaders(headers http.Header) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization", "X-Gotify-Key", "Cookie":
+			// Show only the start and end of tokens
+			val := values[0]
+			if len(val) > 12 {
+				masked[key] = val[:6] + "..." + val[len(val)-4:]
+			} else {
+				masked[key] = "***"
+			}
+		default:
+			masked[key] = values[0]
+		}
+	}
+	return masked
+}
+
+// LoggingMiddleware logs every attempt at debug level with credentials redacted
+func LoggingMiddleware(logger *Logger, enabled bool) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if !enabled {
+			return next
+		}
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			logger.Debug("→ HTTP Request",
+				"method", req.Method,
+				"url", req.URL.String(),
+				"headers", redactHeaders(req.Header),
+			)
+			if req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					bodyBytes, _ := io.ReadAll(body)
+					body.Close()
+					if len(bodyBytes) > 0 {
+						logger.Debug("  Request Body", "body", redactBody(bodyBytes))
+					}
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			duration := time.Since(start)
+			if err != nil {
+				logger.Debug("← HTTP Error", "url", req.URL.String(), "duration_ms", duration.Milliseconds(), "error", err.Error())
+				return nil, err
+			}
+
+			logger.Debug("← HTTP Response",
+				"status", resp.StatusCode,
+				"status_text", resp.Status,
+				"duration_ms", duration.Milliseconds(),
+				"content_type", resp.Header.Get("Content-Type"),
+			)
+
+			// Read the body for the preview, then restore it for the caller
+			bodyBytes, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+			if readErr != nil {
+				return nil, readErr
+			}
+			if len(bodyBytes) > 0 {
+				logger.Debug("  Response Body", "body", redactBody(bodyBytes))
+			}
+			return resp, nil
+		})
+	}
+}
+
+// RateLimitMiddleware calls wait before every attempt
+func RateLimitMiddleware(wait func()) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			wait()
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// MetricsMiddleware records every attempt and its outcome under the label
+// returned for the request
+func MetricsMiddleware(metrics *APIMetrics, label func(req *http.Request) string) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			metrics.recordRequest()
+			if err == nil {
+				metrics.recordResponse(label(req), resp.StatusCode, time.Since(start).Seconds())
+			}
+			return resp, err
+		})
+	}
+}
+
+// noRetryKey marks a request context that must not be retried
+type noRetryKey struct{}
+
+// withoutRetries returns a context whose requests are sent at most once, for
+// mutations where a lost response doesn't mean the request failed
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// RetryMiddleware retries transport errors and retryable status codes with
+// exponential backoff, honouring Retry-After. Request bodies are replayed via
+// GetBody, so requests built with http.NewRequest from a byte slice retry safely.
+// Requests with a withoutRetries context are passed straight through.
+func RetryMiddleware(maxRetries int, logger *Logger) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if noRetry, _ := req.Context().Value(noRetryKey{}).(bool); noRetry {
+				return next.RoundTrip(req)
+			}
+			for attempt := 0; ; attempt++ {
+				if attempt > 0 && req.Body != nil {
+					if req.GetBody == nil {
+						return nil, errors.New("request body cannot be replayed for retry")
+					}
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					req = req.Clone(req.Context())
+					req.Body = body
+				}
+
+				resp, err := next.RoundTrip(req)
+
+				var circuitErr *CircuitOpenError
+				retryable := (err != nil && !errors.As(err, &circuitErr) && req.Context().Err() == nil) ||
+					(err == nil && isRetryableStatus(resp.StatusCode))
+				if !retryable || attempt >= maxRetries {
+					return resp, err
+				}
+
+				backoff := calculateBackoff(attempt)
+				if err != nil {
+					logger.Warn("Request failed, retrying",
+						"method", req.Method,
+						"url", req.URL.String(),
+						"attempt", attempt+1,
+						"max_attempts", maxRetries+1,
+						"backoff_ms", backoff.Milliseconds(),
+						"error", err.Error(),
+					)
+				} else {
+					backoff = retryAfter(resp, backoff)
+					logger.Warn("Retrying due to status code",
+						"url", req.URL.String(),
+						"status_code", resp.StatusCode,
+						"attempt", attempt+1,
+						"max_attempts", maxRetries+1,
+						"backoff_ms", backoff.Milliseconds(),
+					)
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(backoff):
+				}
+			}
+		})
+	}
+}
+
+// calculateBackoff returns an exponential backoff with up to 10% jitter
+func calculateBackoff(attempt int) time.Duration {
+	base := float64(time.Second)
+	backoff := base * math.Pow(2, float64(attempt))
+	jitter := rand.Float64() * 0.1 * backoff
+	return time.Duration(backoff + jitter)
+}
+
+// retryAfter returns the delay requested by a Retry-After header (seconds or
+// an HTTP date), capped at HTTPMaxRetryAfter, or fallback if there is none
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return fallback
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if when, err := http.ParseTime(header); err == nil {
+		delay = time.Until(when)
+	} else {
+		return fallback
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > HTTPMaxRetryAfter {
+		delay = HTTPMaxRetryAfter
+	}
+	return delay
+}
+
+// CircuitBreaker stops calling a host after repeated failures and lets a
+// single trial request through once the cooldown has passed
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuitState
+}
+
+type circuitState struct {
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+}
+
+// NewCircuitBreaker opens a host's circuit after threshold consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuitState),
+	}
+}
+
+// allow reports whether a request to host may proceed
+func (b *CircuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.hosts[host]
+	if !ok || state.failures < b.threshold {
+		return nil
+	}
+	if time.Now().Before(state.openUntil) || state.trial {
+		return &CircuitOpenError{Host: host, Until: state.openUntil}
+	}
+	// Cooldown over - half-open, let one request test the host
+	state.trial = true
+	return nil
+}
+
+// record updates the host's circuit with the outcome of a request
+func (b *CircuitBreaker) record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.hosts[host]
+	if !ok {
+		state = &circuitState{}
+		b.hosts[host] = state
+	}
+	state.trial = false
+
+	if !failed {
+		state.failures = 0
+		return
+	}
+	state.failures++
+	if state.failures >= b.threshold {
+		state.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// CircuitBreakerMiddleware fails fast while a host's circuit is open. Server
+// errors and transport failures count against the host; any other response
+// closes the circuit.
+func CircuitBreakerMiddleware(breaker *CircuitBreaker) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+			if err := breaker.allow(host); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(req)
+			// Requests we cancelled ourselves say nothing about the host
+			failed := (err != nil && req.Context().Err() == nil) || (err == nil && resp.StatusCode >= http.StatusInternalServerError)
+			breaker.record(host, failed)
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChainTransportOrder(t *testing.T) {
+	var order []string
+	record := func(name string) TransportMiddleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "base")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	transport := ChainTransport(base, record("outer"), record("inner"))
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := strings.Join(order, ","); got != "outer,inner,base" {
+		t.Errorf("Expected outer,inner,base, got %s", got)
+	}
+}
+
+func TestRetryMiddlewareHonoursRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: ChainTransport(http.DefaultTransport, RetryMiddleware(2, NewLogger(false)))}
+	start := time.Now()
+	resp, err := client.Post(server.URL, "application/json", bytes.NewReader([]byte(`{"query":"x"}`)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 after retry, got %d", resp.StatusCode)
+	}
+	// Retry-After: 0 replaces the one second exponential backoff
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("Expected Retry-After to be honoured, retry took %v", elapsed)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != `{"query":"x"}` {
+		t.Errorf("Expected the request body to be replayed, got %q", bodies)
+	}
+}
+
+func TestRetryMiddlewareWithoutRetries(t *testing.T) {
+	calls := 0
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"0"}}, Body: http.NoBody}, nil
+	})
+	transport := ChainTransport(base, RetryMiddleware(2, NewLogger(false)))
+
+	req, _ := http.NewRequestWithContext(withoutRetries(context.Background()), "POST", "http://kraken.test/v1/graphql/", strings.NewReader(`{"query":"mutation"}`))
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the 503 to be returned as is, got %v (%v)", resp, err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestMutationsAreNotRetried(t *testing.T) {
+	_, client := startFakeKraken(t, DefaultKrakenFixtures())
+
+	// Each mutation gets its own host, so the circuit breaker opened by one
+	// doesn't hide retries of the other
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	failing := func() *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls[r.URL.Path]++
+			mu.Unlock()
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	client.BaseURL = failing().URL
+	octopusEndpoints["backend-graphql"] = failing().URL + "/graphql/"
+
+	if err := client.JoinSavingSession(9002); err == nil {
+		t.Error("Expected the join to fail")
+	}
+	if _, err := client.spinWheelOfFortune("ELECTRICITY"); err == nil {
+		t.Error("Expected the spin to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["/accounts/A-DEMO0001/saving-sessions/9002/join"] != 1 || calls["/graphql/"] != 1 {
+		t.Errorf("Expected each mutation to be sent once, got %v", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	fallback := 3 * time.Second
+	testCases := []struct {
+		name     string
+		header   string
+		expected time.Duration
+	}{
+		{"missing", "", fallback},
+		{"seconds", "5", 5 * time.Second},
+		{"capped", "86400", HTTPMaxRetryAfter},
+		{"past date", "Mon, 02 Jan 2006 15:04:05 GMT", 0},
+		{"invalid", "soon", fallback},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+			if got := retryAfter(resp, fallback); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	calls := 0
+	status := http.StatusInternalServerError
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+
+	breaker := NewCircuitBreaker(2, 50*time.Millisecond)
+	transport := ChainTransport(base, CircuitBreakerMiddleware(breaker))
+	do := func() error {
+		req, _ := http.NewRequest("GET", "http://kraken.test/v1/", nil)
+		_, err := transport.RoundTrip(req)
+		return err
+	}
+
+	// Two server errors open the circuit
+	do()
+	do()
+	var circuitErr *CircuitOpenError
+	if err := do(); !errors.As(err, &circuitErr) {
+		t.Fatalf("Expected CircuitOpenError once the threshold is reached, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the open circuit to skip the host, got %d calls", calls)
+	}
+	if circuitErr.Host != "kraken.test" {
+		t.Errorf("Expected host kraken.test, got %s", circuitErr.Host)
+	}
+
+	// After the cooldown a successful trial closes it again
+	time.Sleep(60 * time.Millisecond)
+	status = http.StatusOK
+	if err := do(); err != nil {
+		t.Fatalf("Expected half-open trial to be allowed, got %v", err)
+	}
+	if err := do(); err != nil {
+		t.Errorf("Expected circuit to close after a successful trial, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 calls to the host, got %d", calls)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"api key", `{"input":{"APIKey":"sk_live_secret"}}`, `{"input":{"APIKey":"***"}}`},
+		{"tokens", `{"token":"eyJ.abc","refreshToken": "r1"}`, `{"token":"***","refreshToken": "***"}`},
+		{"password", `{"password":"hunter2","user":"me"}`, `{"password":"***","user":"me"}`},
+		{"plain", `{"query":"{ viewer { id } }"}`, `{"query":"{ viewer { id } }"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := redactBody([]byte(tc.body)); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+
+	long := redactBody(bytes.Repeat([]byte("x"), 600))
+	if !strings.HasSuffix(long, "... (truncated)") || len(long) != 500+len("... (truncated)") {
+		t.Errorf("Expected long bodies to be truncated, got %d bytes", len(long))
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "JWT eyJhbGciOiJIUzI1NiJ9.payload.signature")
+	headers.Set("Cookie", "short")
+	headers.Set("Content-Type", "application/json")
+
+	masked := redactHeaders(headers)
+	if masked["Authorization"] != "JWT ey...ture" {
+		t.Errorf("Expected masked Authorization header, got %s", masked["Authorization"])
+	}
+	if masked["Cookie"] != "***" {
+		t.Errorf("Expected short Cookie header to be fully masked, got %s", masked["Cookie"])
+	}
+	if masked["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type to be left alone, got %s", masked["Content-Type"])
+	}
+}
+
+func TestClientRoutesAllCallsThroughTransport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	client := newTestKrakenClient(t)
+
+	// Token refresh, GraphQL queries and the free electricity feed all go
+	// through the same chain and show up in the API metrics
+	resp, err := client.makeGraphQLRequest(`query { viewer { id } }`, nil, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if _, err := client.GetFreeElectricitySessions(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	snapshot := client.metrics.Snapshot()
+	if count := snapshot.Responses["graphql"][http.StatusOK]; count != 2 {
+		t.Errorf("Expected token and query requests under graphql, got %d", count)
+	}
+	if _, ok := snapshot.Responses["free-electricity"]; !ok {
+		t.Errorf("Expected free electricity feed in metrics, got %v", snapshot.Responses)
+	}
+	if snapshot.TotalRequests != 3 {
+		t.Errorf("Expected 3 requests, got %d", snapshot.TotalRequests)
+	}
+}
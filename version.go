@@ -20,7 +20,6 @@ import (
 	"net/http"
 	"runtime/debug"
 	"strings"
-	"time"
 
 	"golang.org/x/mod/semver"
 )
@@ -66,8 +65,11 @@ type GitHubRelease struct {
 	HTMLURL string `json:"html_url"`
 }
 
-// CheckForUpdates checks if a newer version is available on GitHub
-func CheckForUpdates() (string, string, bool) {
+// CheckForUpdates checks if a newer version is available on GitHub. The
+// request goes through client's transport, so like every other upstream call
+// it is retried, rate limited, counted in the API metrics and stopped by the
+// circuit breaker.
+func CheckForUpdates(client *http.Client) (string, string, bool) {
 	currentVersion := GetVersion()
 
 	// Skip update check for dev builds, commit hashes, or non-tagged versions
@@ -81,11 +83,7 @@ func CheckForUpdates() (string, string, bool) {
 		return "", "", false
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", "https://api.github.com/repos/matthewgall/octojoin/releases/latest", nil)
+	req, err := http.NewRequest("GET", getEndpoint("github-releases"), nil)
 	if err != nil {
 		return "", "", false
 	}
@@ -118,8 +116,8 @@ func CheckForUpdates() (string, string, bool) {
 }
 
 // PrintUpdateNotification prints an update notification if available
-func PrintUpdateNotification() {
-	newVersion, url, available := CheckForUpdates()
+func PrintUpdateNotification(client *http.Client) {
+	newVersion, url, available := CheckForUpdates(client)
 	if available {
 		fmt.Println()
 		fmt.Println("╔════════════════════════════════════════════════════════════════╗")
@@ -15,6 +15,8 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"golang.org/x/mod/semver"
@@ -87,6 +89,27 @@ func TestVersionComparison(t *testing.T) {
 	}
 }
 
+func TestCheckForUpdatesUsesClientTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v1.1.0","html_url":"https://example.com/v1.1.0"}`))
+	}))
+	defer server.Close()
+
+	savedVersion, savedEndpoint := version, octopusEndpoints["github-releases"]
+	defer func() { version, octopusEndpoints["github-releases"] = savedVersion, savedEndpoint }()
+	version, octopusEndpoints["github-releases"] = "v1.0.0", server.URL
+
+	client := NewOctopusClient("A-1234ABCD", "sk_live_test", false)
+	client.minInterval = 0
+	newVersion, url, available := CheckForUpdates(client.client)
+	if !available || newVersion != "v1.1.0" || url != "https://example.com/v1.1.0" {
+		t.Errorf("Expected v1.1.0 to be available, got %q %q %v", newVersion, url, available)
+	}
+	if responses := client.metrics.Snapshot().Responses["github-releases"]; responses[http.StatusOK] != 1 {
+		t.Errorf("Expected the check in the API metrics, got %v", responses)
+	}
+}
+
 func TestGetVersion(t *testing.T) {
 	v := GetVersion()
 	if v == "" {
@@ -20,7 +20,8 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -37,23 +38,49 @@ type SessionData struct {
 	AccountBalance      float64                  `json:"account_balance"`
 	WheelOfFortuneSpins *WheelOfFortuneSpins     `json:"wheel_of_fortune_spins"`
 	SavingSessions      []SavingSession          `json:"saving_sessions"`
+	AvailableSavingSessions []SavingSessionEvent `json:"available_saving_sessions"`
+	JoinDecisions       map[int]*JoinDecision    `json:"join_decisions"`
 	FreeElectricitySessions []FreeElectricitySession `json:"free_electricity_sessions"`
+	NextSavingSession   *SavingSession           `json:"next_saving_session"`
+	NextFreeElectricitySession *FreeElectricitySession `json:"next_free_electricity_session"`
+	RecentPerformance   []*SessionRecord         `json:"recent_performance"`
+	Dispatches          []Dispatch               `json:"dispatches"`
+	AgileWindows        []AgileWindow            `json:"agile_windows"`
+	NextAgileWindow     *AgileWindow             `json:"next_agile_window"`
 	CampaignStatus      CampaignStatus           `json:"campaign_status"`
 	LastUpdated         time.Time                `json:"last_updated"`
 }
 
 type WebServer struct {
-	monitor *SavingSessionMonitor
-	server  *http.Server
-	logger  *Logger
+	monitor  *SavingSessionMonitor // the default account, served by the unscoped /api routes
+	monitors []*SavingSessionMonitor
+	server   *http.Server
+	logger   *Logger
+}
+
+// accountHandler serves an API route for one account
+type accountHandler func(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor)
+
+// AccountSummary is an entry in the dashboard's account switcher
+type AccountSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 func NewWebServer(monitor *SavingSessionMonitor, port int) *WebServer {
+	return NewAccountsWebServer([]*SavingSessionMonitor{monitor}, port)
+}
+
+// NewAccountsWebServer serves the dashboard and API for several accounts.
+// Each API route is available as /api/accounts/{id}/<route>, and the
+// unscoped /api/<route> serves the first account.
+func NewAccountsWebServer(monitors []*SavingSessionMonitor, port int) *WebServer {
 	mux := http.NewServeMux()
-	logger := NewLogger(monitor.client.debug).WithComponent("web_server")
+	logger := NewLogger(monitors[0].client.debug).WithComponent("web_server")
 
 	ws := &WebServer{
-		monitor: monitor,
+		monitor:  monitors[0],
+		monitors: monitors,
 		server: &http.Server{
 			Addr:    fmt.Sprintf(":%d", port),
 			Handler: mux,
@@ -62,17 +89,67 @@ func NewWebServer(monitor *SavingSessionMonitor, port int) *WebServer {
 	}
 	
 	mux.HandleFunc("/", ws.handleDashboard)
-	mux.HandleFunc("/api/sessions", ws.handleSessionsAPI)
-	mux.HandleFunc("/api/usage", ws.handleUsageAPI)
-	mux.HandleFunc("/api/usage/refresh", ws.handleUsageRefreshAPI)
+	mux.HandleFunc("/api/accounts", ws.handleAccountsAPI)
+	for _, route := range []struct {
+		path    string
+		handler accountHandler
+	}{
+		{"sessions", ws.handleSessionsAPI},
+		{"usage", ws.handleUsageAPI},
+		{"usage/refresh", ws.handleUsageRefreshAPI},
+		{"history/sessions", ws.handleSessionHistoryAPI},
+		{"points/history", ws.handlePointsHistoryAPI},
+		{"wheel/history", ws.handleWheelHistoryAPI},
+		{"live", ws.handleLiveDemandAPI},
+		{"tariff", ws.handleTariffAPI},
+		{"properties", ws.handlePropertiesAPI},
+		{"tasks", ws.handleTasksAPI},
+	} {
+		mux.HandleFunc("/api/"+route.path, ws.forAccount(route.handler))
+		mux.HandleFunc("/api/accounts/{id}/"+route.path, ws.forAccount(route.handler))
+	}
+	mux.HandleFunc("/history", ws.handleHistory)
 	
 	// Add Prometheus metrics endpoint
-	metricsCollector := NewMetricsCollector(monitor.client, monitor)
+	metricsCollector := NewAccountsMetricsCollector(monitors)
 	mux.Handle("/metrics", metricsCollector)
 	
 	return ws
 }
 
+// forAccount runs an account handler for the account named in the path, or
+// the default account on the unscoped routes
+func (ws *WebServer) forAccount(handler accountHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		monitor := ws.monitor
+		if id := r.PathValue("id"); id != "" {
+			monitor = nil
+			for _, candidate := range ws.monitors {
+				if strings.EqualFold(candidate.accountID, id) {
+					monitor = candidate
+				}
+			}
+			if monitor == nil {
+				http.Error(w, "Unknown account", http.StatusNotFound)
+				return
+			}
+		}
+		handler(w, r, monitor)
+	}
+}
+
+// handleAccountsAPI lists the accounts served, default account first
+func (ws *WebServer) handleAccountsAPI(w http.ResponseWriter, r *http.Request) {
+	accounts := make([]AccountSummary, 0, len(ws.monitors))
+	for _, monitor := range ws.monitors {
+		accounts = append(accounts, AccountSummary{ID: monitor.accountID, Name: monitor.Name()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(accounts)
+}
+
 func (ws *WebServer) Start() error {
 	// Legacy method for backward compatibility
 	return ws.StartWithContext(context.Background())
@@ -116,23 +193,26 @@ func getCacheAge(cached *CachedUsageMeasurements) int {
 	return int(time.Since(cached.Timestamp).Seconds())
 }
 
-func (ws *WebServer) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
+// buildSessionData assembles the dashboard view of the account. It is shared by
+// the web API and the MQTT publisher so both expose the same shape.
+func (m *SavingSessionMonitor) buildSessionData() SessionData {
 	// Get current session data
-	sessions, err := ws.monitor.client.GetSavingSessionsWithCache(ws.monitor.state)
+	sessions, err := m.client.GetSavingSessionsWithCache(m.state)
 	if err != nil {
-		ws.logger.Warn("Failed to get saving sessions", "error", err)
+		m.logger.Warn("Failed to get saving sessions", "error", err)
 		sessions = nil // Will use default values
 	}
 
-	freeElectricity, err := ws.monitor.client.GetFreeElectricitySessionsWithCache(ws.monitor.state)
+	freeElectricity, err := m.client.GetFreeElectricitySessionsWithCache(m.state)
 	if err != nil {
-		ws.logger.Warn("Failed to get free electricity sessions", "error", err)
+		m.logger.Warn("Failed to get free electricity sessions", "error", err)
 		freeElectricity = &FreeElectricitySessionsResponse{} // Empty response
 	}
 	
 	// Filter upcoming sessions
 	now := time.Now()
 	var upcomingSavingSessions []SavingSession
+	var availableSavingSessions []SavingSessionEvent
 	var upcomingFreeElectricitySessions []FreeElectricitySession
 	
 	// Filter saving sessions
@@ -143,6 +223,15 @@ func (ws *WebServer) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+
+	// Filter announced sessions that have not been joined yet
+	if sessions != nil {
+		for _, event := range sessions.UnjoinedEvents() {
+			if event.EndAt.After(now) {
+				availableSavingSessions = append(availableSavingSessions, event)
+			}
+		}
+	}
 	
 	// Filter free electricity sessions  
 	if freeElectricity != nil {
@@ -161,24 +250,24 @@ func (ws *WebServer) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 
 	// Get account balance (with caching)
 	accountBalance := 0.0
-	accountInfo, err := ws.monitor.client.getAccountInfoWithCache(ws.monitor.state)
+	accountInfo, err := m.client.getAccountInfoWithCache(m.state)
 	if err != nil {
-		ws.logger.Warn("Could not get account balance", "error", err)
+		m.logger.Warn("Could not get account balance", "error", err)
 	} else {
 		accountBalance = accountInfo.Balance
 	}
 
 	// Get Wheel of Fortune spins (with caching)
-	wheelSpins, err := ws.monitor.client.getWheelOfFortuneSpinsWithCache(ws.monitor.state)
+	wheelSpins, err := m.client.getWheelOfFortuneSpinsWithCache(m.state)
 	if err != nil {
-		ws.logger.Warn("Could not get Wheel of Fortune spins", "error", err)
+		m.logger.Warn("Could not get Wheel of Fortune spins", "error", err)
 		wheelSpins = &WheelOfFortuneSpins{ElectricitySpins: 0, GasSpins: 0}
 	}
 
 	// Get campaign status (with caching)
-	campaigns, err := ws.monitor.client.getCampaignStatusWithCache(ws.monitor.state)
+	campaigns, err := m.client.getCampaignStatusWithCache(m.state)
 	if err != nil {
-		ws.logger.Warn("Could not get campaign status", "error", err)
+		m.logger.Warn("Could not get campaign status", "error", err)
 		campaigns = map[string]bool{
 			"octoplus": false,
 			"octoplus-saving-sessions": false,
@@ -198,26 +287,203 @@ func (ws *WebServer) handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
 	if upcomingSavingSessions == nil {
 		upcomingSavingSessions = []SavingSession{}
 	}
+	if availableSavingSessions == nil {
+		availableSavingSessions = []SavingSessionEvent{}
+	}
+
+	// Explain the join decision for every session shown
+	joinDecisions := make(map[int]*JoinDecision)
+	if m.state != nil {
+		m.state.View(func(s *AppState) {
+			for _, session := range upcomingSavingSessions {
+				if decision, ok := s.JoinDecisions[session.EventID]; ok {
+					joinDecisions[session.EventID] = decision
+				}
+			}
+			for _, event := range availableSavingSessions {
+				if decision, ok := s.JoinDecisions[event.ID]; ok {
+					joinDecisions[event.ID] = decision
+				}
+			}
+		})
+	}
 	if upcomingFreeElectricitySessions == nil {
 		upcomingFreeElectricitySessions = []FreeElectricitySession{}
 	}
+
+	// Latest joined sessions that have a performance report
+	recentPerformance := []*SessionRecord{}
+	if m.state != nil {
+		for _, record := range buildSessionHistory(m.state.Snapshot().SessionHistory, "", now).Sessions {
+			if record.Performance != nil && len(recentPerformance) < WebRecentPerformanceSessions {
+				recentPerformance = append(recentPerformance, record)
+			}
+		}
+	}
+
+	// Smart-charge dispatches from the last day onwards, empty unless the
+	// account is on an Intelligent Octopus tariff
+	allDispatches, err := m.intelligentDispatches()
+	if err != nil {
+		m.logger.Warn("Could not get Intelligent Octopus dispatches", "error", err)
+	}
+	dispatches := []Dispatch{}
+	for _, dispatch := range allDispatches {
+		if dispatch.EndAt.After(now.Add(-24 * time.Hour)) {
+			dispatches = append(dispatches, dispatch)
+		}
+	}
+
+	// Agile price windows, empty unless Agile alerts are enabled
+	agileWindows, err := m.agileWindows(now)
+	if err != nil {
+		m.logger.Warn("Could not get Agile prices", "error", err)
+	}
+	if agileWindows == nil {
+		agileWindows = []AgileWindow{}
+	}
 	
 	data := SessionData{
 		CurrentPoints:               currentPoints,
 		AccountBalance:              accountBalance,
 		WheelOfFortuneSpins:        wheelSpins,
 		SavingSessions:             upcomingSavingSessions,
+		AvailableSavingSessions:    availableSavingSessions,
+		JoinDecisions:              joinDecisions,
 		FreeElectricitySessions:    upcomingFreeElectricitySessions,
+		RecentPerformance:          recentPerformance,
+		Dispatches:                 dispatches,
+		AgileWindows:               agileWindows,
 		CampaignStatus:             campaignStatus,
 		LastUpdated:                time.Now(),
 	}
-	
+
+	// Pick out the next sessions so consumers don't have to sort
+	for _, session := range upcomingSavingSessions {
+		if data.NextSavingSession == nil || session.StartAt.Before(data.NextSavingSession.StartAt) {
+			next := session
+			data.NextSavingSession = &next
+		}
+	}
+	for _, event := range availableSavingSessions {
+		if data.NextSavingSession == nil || event.StartAt.Before(data.NextSavingSession.StartAt) {
+			next := event.ToSavingSession()
+			data.NextSavingSession = &next
+		}
+	}
+	for _, session := range upcomingFreeElectricitySessions {
+		if data.NextFreeElectricitySession == nil || session.StartAt.Before(data.NextFreeElectricitySession.StartAt) {
+			next := session
+			data.NextFreeElectricitySession = &next
+		}
+	}
+	if len(agileWindows) > 0 {
+		next := agileWindows[0] // already in time order
+		data.NextAgileWindow = &next
+	}
+
+	return data
+}
+
+func (ws *WebServer) handleSessionsAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	data := monitor.buildSessionData()
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(data)
 }
 
-func (ws *WebServer) handleUsageAPI(w http.ResponseWriter, r *http.Request) {
+// handleLiveDemandAPI returns the latest Home Mini reading while a joined
+// saving session is running, otherwise live_demand is null
+func (ws *WebServer) handleLiveDemandAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	response := map[string]interface{}{
+		"live_demand":   monitor.LiveDemand(),
+		"poll_interval": int(TelemetryPollInterval.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleTariffAPI returns the tariffs in force on each meter point with their
+// rates for today and tomorrow, and the unit rates that apply right now
+func (ws *WebServer) handleTariffAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	property, ok := ws.requestProperty(w, r, monitor)
+	if !ok {
+		return
+	}
+
+	tariffs, err := monitor.client.getTariffsWithCache(monitor.state)
+	if err != nil {
+		ws.logger.Error("Error getting tariffs", "error", err)
+		http.Error(w, "Failed to get tariff data", http.StatusInternalServerError)
+		return
+	}
+	if property != nil {
+		tariffs = propertyTariffs(tariffs, property.ID)
+	}
+
+	now := time.Now()
+	current := make([]map[string]interface{}, 0, len(tariffs))
+	for _, tariff := range tariffs {
+		current = append(current, map[string]interface{}{
+			"property_id":     tariff.PropertyID,
+			"fuel":            tariff.Fuel,
+			"meter_point":     tariff.MeterPoint,
+			"tariff_code":     tariff.TariffCode,
+			"unit_rates":      tariff.CurrentUnitRates(now),
+			"standing_charge": tariff.StandingCharge,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tariffs": tariffs,
+		"current": current,
+	})
+}
+
+func (ws *WebServer) handleSessionHistoryAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	// Optional ?season=2025/26 limits the session list, totals cover every season
+	season := r.URL.Query().Get("season")
+	history := buildSessionHistory(monitor.state.Snapshot().SessionHistory, season, time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (ws *WebServer) handlePointsHistoryAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	snapshot := monitor.state.Snapshot()
+	history := buildPointsHistory(snapshot.PointsLedger, snapshot.Redemptions)
+	if len(snapshot.PointsLedger) == 0 && snapshot.CachedOctoPoints != nil {
+		history.Balance = snapshot.CachedOctoPoints.Data
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (ws *WebServer) handleWheelHistoryAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	snapshot := monitor.state.Snapshot()
+	history := buildWheelHistory(snapshot.WheelSpinHistory, snapshot.WheelSpinTotals, snapshot.LastWheelSpinCheck)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(history)
+}
+
+// handleTasksAPI reports each monitor task's schedule and last outcome
+func (ws *WebServer) handleTasksAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(monitor.TaskStatuses())
+}
+
+func (ws *WebServer) handleUsageAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
 	// Parse query parameters
 	daysParam := r.URL.Query().Get("days")
 	days := WebDefaultUsageDays // default
@@ -229,52 +495,213 @@ func (ws *WebServer) handleUsageAPI(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
+	fuel := usageFuel(r)
+	
+	property, ok := ws.requestProperty(w, r, monitor)
+	if !ok {
+		return
+	}
+	
 	// Get usage measurements with caching
-	measurements, err := ws.monitor.client.getUsageMeasurementsWithCache(ws.monitor.state, days)
+	measurements, err := monitor.client.getFuelUsageMeasurementsWithCache(monitor.state, fuel, days)
 	if err != nil {
-		ws.logger.Error("Error getting usage measurements", "error", err)
+		ws.logger.Error("Error getting usage measurements", "fuel", fuel, "error", err)
 		http.Error(w, "Failed to get usage data", http.StatusInternalServerError)
 		return
 	}
+	if property != nil {
+		measurements = propertyMeasurements(measurements, property)
+	}
 	
-	// Transform measurements for Chart.js
-	var chartData []map[string]interface{}
+	cachedUsage := loadCached(monitor.state, func(s *AppState) *CachedUsageMeasurements { return *usageCache(s, fuel) })
+	response := map[string]interface{}{
+		"success":      true,
+		"fuel":         strings.ToLower(fuel),
+		"days":         days,
+		"measurements": len(measurements),
+		"series":       usageSeries(measurements),
+		"cache_age":    getCacheAge(cachedUsage),
+	}
+	if property != nil {
+		response["property"] = property.ID
+	}
+	if fuel == FuelElectricity {
+		ws.addExportUsage(monitor, property, response, measurements, days)
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// usageSeries groups measurements into one Chart.js series per meter, in the
+// order the meters were returned
+func usageSeries(measurements []UsageMeasurement) []map[string]interface{} {
+	series := []map[string]interface{}{}
+	index := make(map[string]int)
 	for _, m := range measurements {
-		costEstimate := 0.0
-		if len(m.MetaData.Statistics) > 0 {
-			if val, err := strconv.ParseFloat(m.MetaData.Statistics[0].CostInclTax.EstimatedAmount, 64); err == nil {
-				costEstimate = val
-			}
+		i, ok := index[m.DeviceID]
+		if !ok {
+			i = len(series)
+			index[m.DeviceID] = i
+			series = append(series, map[string]interface{}{
+				"device_id": m.DeviceID,
+				"data":      []map[string]interface{}{},
+			})
 		}
-		
-		chartData = append(chartData, map[string]interface{}{
+		series[i]["data"] = append(series[i]["data"].([]map[string]interface{}), map[string]interface{}{
 			"timestamp": m.StartAt.Unix() * 1000, // JavaScript timestamp
 			"datetime":  m.StartAt.Format("2006-01-02T15:04:05Z07:00"),
 			"value":     m.GetValueAsFloat64(),
 			"unit":      m.Unit,
-			"cost":      costEstimate,
+			"cost":      m.GetCostAsFloat64(),
 			"duration":  m.Duration,
 		})
 	}
-	
-	response := map[string]interface{}{
-		"success":      true,
-		"days":         days,
-		"measurements": len(measurements),
-		"data":         chartData,
-		"cache_age":    getCacheAge(ws.monitor.state.CachedUsageMeasurements),
+	return series
+}
+
+// addExportUsage adds export series, net import and export totals to an
+// electricity usage response when the account, or the selected property,
+// exports to the grid
+func (ws *WebServer) addExportUsage(monitor *SavingSessionMonitor, property *Property, response map[string]interface{}, imports []UsageMeasurement, days int) {
+	exports, err := monitor.client.getExportMeasurementsWithCache(monitor.state, days)
+	if err != nil {
+		ws.logger.Debug("No export measurements", "error", err)
+		return
 	}
-	
+	if property != nil {
+		exports = propertyMeasurements(exports, property)
+	}
+	if len(exports) == 0 {
+		return
+	}
+
+	var exported, earnings float64
+	for _, m := range exports {
+		exported += m.GetValueAsFloat64()
+		earnings += m.GetCostAsFloat64()
+	}
+	response["export"] = usageSeries(exports)
+	response["net"] = netUsage(imports, exports)
+	response["export_kwh"] = exported
+	response["export_earnings"] = earnings / 100 // pence to pounds
+}
+
+// netUsage totals import and export across all meters for each interval.
+// Positive net is drawn from the grid, negative net is exported.
+func netUsage(imports, exports []UsageMeasurement) []map[string]interface{} {
+	type interval struct{ imported, exported float64 }
+	intervals := make(map[int64]*interval)
+	get := func(m UsageMeasurement) *interval {
+		key := m.StartAt.Unix()
+		if intervals[key] == nil {
+			intervals[key] = &interval{}
+		}
+		return intervals[key]
+	}
+	for _, m := range imports {
+		get(m).imported += m.GetValueAsFloat64()
+	}
+	for _, m := range exports {
+		get(m).exported += m.GetValueAsFloat64()
+	}
+
+	keys := make([]int64, 0, len(intervals))
+	for key := range intervals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	net := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		i := intervals[key]
+		net = append(net, map[string]interface{}{
+			"timestamp": key * 1000, // JavaScript timestamp
+			"import":    i.imported,
+			"export":    i.exported,
+			"net":       i.imported - i.exported,
+		})
+	}
+	return net
+}
+
+// requestProperty reads the property selected with ?property=, or nil for
+// every property. An unknown property is answered with a 404.
+func (ws *WebServer) requestProperty(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) (*Property, bool) {
+	id := r.URL.Query().Get("property")
+	if id == "" {
+		return nil, true
+	}
+
+	properties, err := monitor.client.getPropertiesWithCache(monitor.state)
+	if err != nil {
+		ws.logger.Error("Error getting properties", "error", err)
+		http.Error(w, "Failed to get properties", http.StatusInternalServerError)
+		return nil, false
+	}
+	property := findProperty(properties, id)
+	if property == nil {
+		http.Error(w, "Unknown property", http.StatusNotFound)
+		return nil, false
+	}
+	return property, true
+}
+
+// handlePropertiesAPI lists the account's properties with their smart meters,
+// free electricity eligibility and current tariffs
+func (ws *WebServer) handlePropertiesAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	properties, err := monitor.client.getPropertiesWithCache(monitor.state)
+	if err != nil {
+		ws.logger.Error("Error getting properties", "error", err)
+		http.Error(w, "Failed to get properties", http.StatusInternalServerError)
+		return
+	}
+
+	// Tariffs are optional here, the properties are still useful without them
+	tariffs, err := monitor.client.getTariffsWithCache(monitor.state)
+	if err != nil {
+		ws.logger.Warn("Error getting tariffs", "error", err)
+	}
+
+	type propertyView struct {
+		Property
+		Tariffs []Tariff `json:"tariffs"`
+	}
+	response := make([]propertyView, 0, len(properties))
+	for _, property := range properties {
+		response = append(response, propertyView{Property: property, Tariffs: propertyTariffs(tariffs, property.ID)})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(response)
 }
 
-func (ws *WebServer) handleUsageRefreshAPI(w http.ResponseWriter, r *http.Request) {
+// usageFuel reads the fuel requested with ?fuel=, defaulting to electricity
+func usageFuel(r *http.Request) string {
+	if strings.EqualFold(r.URL.Query().Get("fuel"), "gas") {
+		return FuelGas
+	}
+	return FuelElectricity
+}
+
+func (ws *WebServer) handleUsageRefreshAPI(w http.ResponseWriter, r *http.Request, monitor *SavingSessionMonitor) {
+	fuel := usageFuel(r)
+	property, ok := ws.requestProperty(w, r, monitor)
+	if !ok {
+		return
+	}
+
 	// Force cache invalidation by clearing cached usage measurements
-	if ws.monitor.state != nil {
-		ws.monitor.state.CachedUsageMeasurements = nil
-		ws.logger.Debug("Cleared usage measurements cache")
+	if monitor.state != nil {
+		monitor.state.Update(func(s *AppState) {
+			*usageCache(s, fuel) = nil
+			if fuel == FuelElectricity {
+				s.CachedExportUsage = nil
+			}
+		})
+		ws.logger.Debug("Cleared usage measurements cache", "fuel", fuel)
 	}
 
 	// Parse query parameters
@@ -288,58 +715,32 @@ func (ws *WebServer) handleUsageRefreshAPI(w http.ResponseWriter, r *http.Reques
 		}
 	}
 	
-	// Get fresh usage measurements (bypassing cache)
-	measurements, err := ws.monitor.client.getUsageMeasurements([]string{}, days)
+	// Get fresh usage measurements, now the cache is empty
+	measurements, err := monitor.client.getFuelUsageMeasurementsWithCache(monitor.state, fuel, days)
 	if err != nil {
-		// Get device IDs first
-		devices, err := ws.monitor.client.getSmartMeterDevicesWithCache(ws.monitor.state)
-		if err != nil {
-			ws.logger.Error("Error getting meter devices", "error", err)
-			http.Error(w, "Failed to get meter devices", http.StatusInternalServerError)
-			return
-		}
-
-		if len(devices) == 0 {
-			http.Error(w, "No ESME devices found", http.StatusInternalServerError)
-			return
-		}
-
-		measurements, err = ws.monitor.client.getUsageMeasurements(devices, days)
-		if err != nil {
-			ws.logger.Error("Error getting fresh usage measurements", "error", err)
-			http.Error(w, "Failed to get fresh usage data", http.StatusInternalServerError)
-			return
-		}
+		ws.logger.Error("Error getting fresh usage measurements", "fuel", fuel, "error", err)
+		http.Error(w, "Failed to get fresh usage data", http.StatusInternalServerError)
+		return
 	}
-	
-	// Transform measurements for Chart.js
-	var chartData []map[string]interface{}
-	for _, m := range measurements {
-		costEstimate := 0.0
-		if len(m.MetaData.Statistics) > 0 {
-			if val, err := strconv.ParseFloat(m.MetaData.Statistics[0].CostInclTax.EstimatedAmount, 64); err == nil {
-				costEstimate = val
-			}
-		}
-		
-		chartData = append(chartData, map[string]interface{}{
-			"timestamp": m.StartAt.Unix() * 1000, // JavaScript timestamp
-			"datetime":  m.StartAt.Format("2006-01-02T15:04:05Z07:00"),
-			"value":     m.GetValueAsFloat64(),
-			"unit":      m.Unit,
-			"cost":      costEstimate,
-			"duration":  m.Duration,
-		})
+	if property != nil {
+		measurements = propertyMeasurements(measurements, property)
 	}
 	
 	response := map[string]interface{}{
 		"success":      true,
+		"fuel":         strings.ToLower(fuel),
 		"days":         days,
 		"measurements": len(measurements),
-		"data":         chartData,
+		"series":       usageSeries(measurements),
 		"cache_age":    0, // Fresh data
 		"refreshed":    true,
 	}
+	if property != nil {
+		response["property"] = property.ID
+	}
+	if fuel == FuelElectricity {
+		ws.addExportUsage(monitor, property, response, measurements, days)
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -383,6 +784,34 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             margin-bottom: 10px;
         }
         
+        .account-switcher {
+            margin-bottom: 10px;
+        }
+        
+        .property-switcher {
+            text-align: center;
+            margin-bottom: 15px;
+        }
+        
+        .property-details {
+            margin-top: 8px;
+            font-size: 0.9rem;
+            opacity: 0.8;
+        }
+        
+        .account-switcher select, .property-switcher select {
+            background: rgba(255, 255, 255, 0.2);
+            color: white;
+            border: 1px solid rgba(255, 255, 255, 0.3);
+            border-radius: 5px;
+            padding: 6px 12px;
+            font-size: 1rem;
+        }
+        
+        .account-switcher option, .property-switcher option {
+            color: #333;
+        }
+        
         .status {
             background: rgba(255, 255, 255, 0.1);
             backdrop-filter: blur(10px);
@@ -458,6 +887,75 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             margin-top: 5px;
         }
         
+        .live-demand-label {
+            margin-top: 8px;
+            font-size: 0.9rem;
+        }
+        
+        .live-demand-gauge {
+            height: 10px;
+            margin-top: 4px;
+            border-radius: 5px;
+            background: rgba(255, 255, 255, 0.15);
+            overflow: hidden;
+        }
+        
+        .live-demand-fill {
+            height: 100%;
+            border-radius: 5px;
+            transition: width 0.5s ease;
+        }
+        
+        .live-demand-time {
+            font-size: 0.75rem;
+            opacity: 0.7;
+            margin-top: 3px;
+        }
+        
+        .session-badge {
+            display: inline-block;
+            font-size: 0.75rem;
+            font-weight: bold;
+            padding: 2px 8px;
+            border-radius: 10px;
+            margin-left: 8px;
+            vertical-align: middle;
+        }
+        
+        .badge-joined {
+            background: rgba(74, 222, 128, 0.3);
+            color: #4ade80;
+        }
+        
+        .badge-available {
+            background: rgba(255, 215, 0, 0.2);
+            color: #ffd700;
+        }
+        
+        .agile-plunge, .agile-cheap {
+            border-left: 4px solid #4caf50;
+        }
+        
+        .performance-title {
+            font-size: 1rem;
+            margin: 15px 0 10px;
+            opacity: 0.85;
+        }
+        
+        .dispatch-extra {
+            border-left: 4px solid #ffd700;
+        }
+        
+        .agile-peak {
+            border-left: 4px solid #f44336;
+        }
+        
+        .session-decision {
+            font-size: 0.8rem;
+            opacity: 0.75;
+            margin-top: 4px;
+        }
+        
         .no-sessions {
             text-align: center;
             opacity: 0.7;
@@ -554,6 +1052,20 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             margin: 20px 0;
         }
         
+        .points-section, .wheel-section {
+            background: rgba(255, 255, 255, 0.1);
+            border-radius: 15px;
+            padding: 25px;
+            margin-top: 30px;
+            backdrop-filter: blur(10px);
+        }
+        
+        .points-chart-container {
+            position: relative;
+            height: 300px;
+            margin: 20px 0;
+        }
+        
         .usage-loading {
             display: flex;
             flex-direction: column;
@@ -583,7 +1095,7 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             text-align: center;
         }
         
-        .usage-controls button {
+        .usage-controls button, .usage-tabs button {
             background: rgba(255, 255, 255, 0.2);
             border: 1px solid rgba(255, 255, 255, 0.3);
             color: white;
@@ -594,11 +1106,15 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             transition: all 0.3s ease;
         }
         
-        .usage-controls button:hover {
+        .usage-controls button:hover, .usage-tabs button:hover {
             background: rgba(255, 255, 255, 0.3);
         }
         
-        .usage-controls button.active {
+        .usage-tabs {
+            text-align: center;
+        }
+        
+        .usage-controls button.active, .usage-tabs button.active {
             background: rgba(255, 255, 255, 0.4);
             border-color: rgba(255, 255, 255, 0.5);
         }
@@ -610,7 +1126,11 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
     <div class="container">
         <div class="header">
             <h1>🐙 Octopus Energy Dashboard</h1>
+            <div class="account-switcher" id="account-switcher" style="display: none;">
+                <select id="account-select" onchange="selectAccount(this.value)"></select>
+            </div>
             <div id="last-updated"></div>
+            <div><a href="/history" id="history-link" style="color: white;">📜 Session history</a></div>
         </div>
         
         <div class="status" id="status">
@@ -627,16 +1147,35 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 <div class="section">
                     <h2>💡 Saving Sessions</h2>
                     <div id="saving-sessions"></div>
+                    <div id="session-performance"></div>
                 </div>
                 
                 <div class="section">
                     <h2>🔋 Free Electricity Sessions</h2>
                     <div id="free-electricity-sessions"></div>
                 </div>
+                
+                <div class="section" id="dispatch-section" style="display: none;">
+                    <h2>🚗 Smart Charge Dispatches</h2>
+                    <div id="dispatches"></div>
+                </div>
+                
+                <div class="section" id="agile-section" style="display: none;">
+                    <h2>💷 Agile Price Windows</h2>
+                    <div id="agile-windows"></div>
+                </div>
             </div>
             
             <div class="section usage-section">
-                <h2>📊 Electricity Usage</h2>
+                <h2 id="usage-title">📊 Electricity Usage</h2>
+                <div class="property-switcher" id="property-switcher" style="display: none;">
+                    <select id="property-select" onchange="selectProperty(this.value)"></select>
+                    <div class="property-details" id="property-details"></div>
+                </div>
+                <div class="usage-tabs">
+                    <button onclick="loadUsageFuel('electricity')" id="fuel-electricity" class="active">⚡ Electricity</button>
+                    <button onclick="loadUsageFuel('gas')" id="fuel-gas">🔥 Gas</button>
+                </div>
                 <div class="usage-controls">
                     <button onclick="loadUsageData(1)" id="btn-1day">1 Day</button>
                     <button onclick="loadUsageData(3)" id="btn-3days">3 Days</button>
@@ -649,6 +1188,22 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 </div>
                 <div id="usage-stats"></div>
             </div>
+            
+            <div class="section points-section">
+                <h2>🎯 OctoPoints History</h2>
+                <div class="points-chart-container">
+                    <canvas id="pointsChart"></canvas>
+                </div>
+                <div id="points-reasons"></div>
+            </div>
+            
+            <div class="section wheel-section">
+                <h2>🎡 Wheel of Fortune</h2>
+                <div class="points-chart-container">
+                    <canvas id="wheelChart"></canvas>
+                </div>
+                <div id="wheel-stats"></div>
+            </div>
         </div>
         
         <div class="footer">
@@ -662,6 +1217,67 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
     </div>
 
     <script>
+        // The account shown, from ?account= or the last one picked; empty
+        // means the default account served by the unscoped API
+        let currentAccount = new URLSearchParams(window.location.search).get('account') || localStorage.getItem('octojoin-account') || '';
+        
+        function apiBase() {
+            return currentAccount ? '/api/accounts/' + encodeURIComponent(currentAccount) : '/api';
+        }
+        
+        function loadAccounts() {
+            return fetch('/api/accounts')
+                .then(response => response.json())
+                .then(accounts => {
+                    const known = accounts.find(account => account.id.toLowerCase() === currentAccount.toLowerCase());
+                    currentAccount = known ? known.id : '';
+                    updateHistoryLink();
+                    
+                    const select = document.getElementById('account-select');
+                    select.innerHTML = '';
+                    accounts.forEach(account => {
+                        const option = document.createElement('option');
+                        option.value = account.id;
+                        option.textContent = account.name === account.id ? account.id : account.name + ' (' + account.id + ')';
+                        select.appendChild(option);
+                    });
+                    select.value = currentAccount || (accounts.length > 0 ? accounts[0].id : '');
+                    document.getElementById('account-switcher').style.display = accounts.length > 1 ? 'block' : 'none';
+                })
+                .catch(error => console.error('Error fetching accounts:', error));
+        }
+        
+        function updateHistoryLink() {
+            document.getElementById('history-link').href = '/history' + (currentAccount ? '?account=' + encodeURIComponent(currentAccount) : '');
+        }
+        
+        function selectAccount(accountID) {
+            currentAccount = accountID;
+            localStorage.setItem('octojoin-account', accountID);
+            updateHistoryLink();
+            
+            // Forget what was rendered so every section redraws for the new account
+            window.lastSavingSessionsKey = undefined;
+            window.lastFreeSessionsKey = undefined;
+            window.lastPerformanceKey = undefined;
+            window.lastDispatchKey = undefined;
+            window.lastAgileKey = undefined;
+            window.lastPointsKey = undefined;
+            window.lastWheelKey = undefined;
+            lastLiveDemand = null;
+            currentProperty = '';
+            refreshAll();
+        }
+        
+        function refreshAll() {
+            updateDashboard();
+            loadProperties();
+            loadUsageData(currentDays);
+            loadPointsHistory();
+            loadWheelHistory();
+            updateLiveDemand();
+        }
+        
         let countdownIntervals = [];
         
         function clearCountdowns() {
@@ -718,8 +1334,39 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             });
         }
         
+        // Home Mini demand for the running joined session, polled by the monitor
+        const liveDemandGaugeMaxWatts = 3000;
+        let lastLiveDemand = null;
+        
+        function renderLiveDemand() {
+            document.querySelectorAll('.live-demand[data-event]').forEach(el => {
+                const live = lastLiveDemand;
+                if (!live || String(live.event_id) !== el.getAttribute('data-event')) {
+                    el.innerHTML = '';
+                    return;
+                }
+                const percent = Math.min(100, live.demand_watts / liveDemandGaugeMaxWatts * 100);
+                const colour = percent < 33 ? '#4ade80' : percent < 66 ? '#ffd700' : '#f87171';
+                el.innerHTML = ` + "`" + `
+                    <div class="live-demand-label">Live demand: <strong>${Math.round(live.demand_watts)} W</strong></div>
+                    <div class="live-demand-gauge"><div class="live-demand-fill" style="width: ${percent}%; background: ${colour};"></div></div>
+                    <div class="live-demand-time">Home Mini reading at ${new Date(live.read_at).toLocaleTimeString('en-GB')}</div>
+                ` + "`" + `;
+            });
+        }
+        
+        function updateLiveDemand() {
+            fetch(apiBase() + '/live')
+                .then(response => response.json())
+                .then(data => {
+                    lastLiveDemand = data.live_demand;
+                    renderLiveDemand();
+                })
+                .catch(error => console.error('Error fetching live demand:', error));
+        }
+        
         function updateDashboard() {
-            fetch('/api/sessions')
+            fetch(apiBase() + '/sessions')
                 .then(response => response.json())
                 .then(data => {
                     // Update status
@@ -797,32 +1444,61 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                     
                     campaignDiv.innerHTML = campaignHTML;
                     
-                    // Update saving sessions
+                    // Update saving sessions (joined and announced-but-unjoined)
                     const savingDiv = document.getElementById('saving-sessions');
+                    const decisions = data.join_decisions || {};
+                    const joinedSessions = (data.saving_sessions || []).map(session => ({
+                        id: session.eventId,
+                        startAt: session.startAt,
+                        endAt: session.endAt,
+                        points: session.octopoints || session.pointsPerKwh,
+                        perKwh: !session.octopoints,
+                        joined: true
+                    }));
+                    const availableSessions = (data.available_saving_sessions || []).map(event => ({
+                        id: event.id,
+                        startAt: event.startAt,
+                        endAt: event.endAt,
+                        points: event.rewardPerKwhInOctoPoints,
+                        perKwh: true,
+                        joined: false
+                    }));
+                    const allSavingSessions = joinedSessions.concat(availableSessions)
+                        .sort((a, b) => new Date(a.startAt) - new Date(b.startAt));
                     let newSavingContent = '';
-                    if (!data.saving_sessions || data.saving_sessions.length === 0) {
+                    if (allSavingSessions.length === 0) {
                         if (data.campaign_status.saving_sessions_enabled) {
                             newSavingContent = '<div class="no-sessions">No upcoming saving sessions</div>';
                         } else {
                             newSavingContent = '<div class="no-sessions">Saving sessions disabled - missing required campaigns</div>';
                         }
                     } else {
-                        newSavingContent = data.saving_sessions.map(session => {
+                        newSavingContent = allSavingSessions.map(session => {
                             const duration = Math.floor((new Date(session.endAt) - new Date(session.startAt)) / (1000 * 60));
+                            const badge = session.joined
+                                ? '<span class="session-badge badge-joined">Joined</span>'
+                                : '<span class="session-badge badge-available">Not joined</span>';
+                            const pointsLabel = session.perKwh ? 'Points/kWh' : 'Points';
+                            const decision = decisions[session.id];
+                            const decisionHTML = decision
+                                ? '<div class="session-decision">' + (decision.join ? 'Joined' : 'Skipped') + ' by ' + decision.rule + ': ' + decision.reason + '</div>'
+                                : '';
                             return ` + "`" + `
                                 <div class="session">
-                                    <div class="session-date">${formatDate(session.startAt)}</div>
+                                    <div class="session-date">${formatDate(session.startAt)}${badge}</div>
                                     <div class="session-details">
-                                        Duration: ${formatDuration(duration)} | Points: ${session.octoPoints}
+                                        Duration: ${formatDuration(duration)} | ${pointsLabel}: ${session.points}
                                     </div>
+                                    ${decisionHTML}
                                     <div class="session-countdown" data-target="${session.startAt}"></div>
+                                    ${session.joined ? '<div class="live-demand" data-event="' + session.id + '"></div>' : ''}
                                 </div>
                             ` + "`" + `;
                         }).join('');
                     }
                     
                     // Check if saving sessions data has changed
-                    const currentSessionsKey = JSON.stringify(data.saving_sessions || []);
+                    const currentSessionsKey = JSON.stringify([allSavingSessions, decisions]);
                     if (window.lastSavingSessionsKey !== currentSessionsKey) {
                         clearCountdowns();
                         savingDiv.innerHTML = newSavingContent;
@@ -831,6 +1507,7 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                         document.querySelectorAll('.session-countdown[data-target]').forEach(el => {
                             startCountdown(el, el.getAttribute('data-target'));
                         });
+                        renderLiveDemand();
                     }
                     
                     // Update free electricity sessions
@@ -870,6 +1547,81 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                         });
                     }
                     
+                    // Update how recent joined sessions went against their baseline
+                    const performance = data.recent_performance || [];
+                    const performanceKey = JSON.stringify(performance);
+                    if (window.lastPerformanceKey !== performanceKey) {
+                        window.lastPerformanceKey = performanceKey;
+                        document.getElementById('session-performance').innerHTML = performance.length === 0 ? '' :
+                            '<h3 class="performance-title">Recent results</h3>' + performance.map(record => {
+                                const p = record.performance;
+                                const colour = p.reduction_percent >= 0 ? '#4caf50' : '#f44336';
+                                return ` + "`" + `
+                                    <div class="session">
+                                        <div class="session-date">${formatDate(record.start_at)}</div>
+                                        <div class="session-details">
+                                            Used ${p.actual_kwh.toFixed(2)} kWh vs ${p.baseline_kwh.toFixed(2)} kWh baseline
+                                            (<span style="color: ${colour};">${p.reduction_percent >= 0 ? '-' : '+'}${Math.abs(p.reduction_percent).toFixed(0)}%</span>)
+                                        </div>
+                                        <div class="session-decision">
+                                            ~${p.estimated_points} points estimated${record.awarded_points != null ? ', ' + record.awarded_points + ' awarded' : ''}
+                                            | baseline from ${p.baseline_days.length} days
+                                        </div>
+                                    </div>
+                                ` + "`" + `;
+                            }).join('');
+                    }
+                    
+                    // Update Intelligent Octopus dispatches, extra off-peak slots highlighted
+                    const dispatches = data.dispatches || [];
+                    const dispatchKey = JSON.stringify(dispatches);
+                    if (window.lastDispatchKey !== dispatchKey) {
+                        window.lastDispatchKey = dispatchKey;
+                        document.getElementById('dispatch-section').style.display = dispatches.length > 0 ? 'block' : 'none';
+                        document.getElementById('dispatches').innerHTML = dispatches.map(d => {
+                            const duration = Math.floor((new Date(d.end) - new Date(d.start)) / (1000 * 60));
+                            const badge = d.status === 'completed'
+                                ? '<span class="session-badge badge-joined">Completed</span>'
+                                : (d.extra ? '<span class="session-badge badge-available">Extra off-peak</span>' : '');
+                            return ` + "`" + `
+                                <div class="session${d.extra ? ' dispatch-extra' : ''}">
+                                    <div class="session-date">${formatDate(d.start)} ${badge}</div>
+                                    <div class="session-details">
+                                        Duration: ${formatDuration(duration)} | ${d.delta_kwh.toFixed(2)} kWh | ${d.source || 'smart-charge'}
+                                    </div>
+                                    ${d.status === 'planned' ? '<div class="session-countdown" data-target="' + d.start + '"></div>' : ''}
+                                </div>
+                            ` + "`" + `;
+                        }).join('');
+                        document.querySelectorAll('#dispatches .session-countdown[data-target]').forEach(el => {
+                            startCountdown(el, el.getAttribute('data-target'));
+                        });
+                    }
+                    
+                    // Update Agile price windows, only shown when Agile alerts are enabled
+                    const agileWindows = data.agile_windows || [];
+                    const agileKey = JSON.stringify(agileWindows);
+                    if (window.lastAgileKey !== agileKey) {
+                        window.lastAgileKey = agileKey;
+                        document.getElementById('agile-section').style.display = agileWindows.length > 0 ? 'block' : 'none';
+                        const agileLabels = {plunge: '🤑 Plunge', cheap: '🟢 Cheap', peak: '🔴 Peak'};
+                        document.getElementById('agile-windows').innerHTML = agileWindows.map(w => {
+                            const duration = Math.floor((new Date(w.end) - new Date(w.start)) / (1000 * 60));
+                            return ` + "`" + `
+                                <div class="session agile-${w.kind}">
+                                    <div class="session-date">${formatDate(w.start)}</div>
+                                    <div class="session-details">
+                                        ${agileLabels[w.kind] || w.kind} | ${formatDuration(duration)} | ${w.min_pence.toFixed(2)}p-${w.max_pence.toFixed(2)}p/kWh (avg ${w.avg_pence.toFixed(2)}p)
+                                    </div>
+                                    <div class="session-countdown" data-target="${w.start}"></div>
+                                </div>
+                            ` + "`" + `;
+                        }).join('');
+                        document.querySelectorAll('#agile-windows .session-countdown[data-target]').forEach(el => {
+                            startCountdown(el, el.getAttribute('data-target'));
+                        });
+                    }
+                    
                     // Update last updated
                     document.getElementById('last-updated').textContent = 
                         'Last updated: ' + new Date(data.last_updated).toLocaleTimeString();
@@ -887,6 +1639,60 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
         // Usage chart variables
         let usageChart = null;
         let currentDays = 7;
+        let currentFuel = 'electricity';
+        
+        function fuelName() {
+            return currentFuel === 'gas' ? 'Gas' : 'Electricity';
+        }
+        
+        function loadUsageFuel(fuel) {
+            currentFuel = fuel;
+            document.querySelectorAll('.usage-tabs button').forEach(btn => btn.classList.remove('active'));
+            document.getElementById('fuel-' + fuel).classList.add('active');
+            document.getElementById('usage-title').textContent = '📊 ' + fuelName() + ' Usage';
+            loadUsageData(currentDays);
+        }
+        
+        // Property shown in the usage chart; empty shows every property
+        let currentProperty = '';
+        let properties = [];
+        
+        function loadProperties() {
+            fetch(apiBase() + '/properties')
+                .then(response => response.json())
+                .then(data => {
+                    properties = data;
+                    const select = document.getElementById('property-select');
+                    select.innerHTML = '<option value="">All properties</option>';
+                    properties.forEach(property => {
+                        const option = document.createElement('option');
+                        option.value = property.id;
+                        option.textContent = property.address || 'Property ' + property.id;
+                        select.appendChild(option);
+                    });
+                    select.value = currentProperty;
+                    document.getElementById('property-switcher').style.display = properties.length > 1 ? 'block' : 'none';
+                    renderPropertyDetails();
+                })
+                .catch(error => console.error('Error fetching properties:', error));
+        }
+        
+        function renderPropertyDetails() {
+            const property = properties.find(p => p.id === currentProperty);
+            let details = '';
+            if (property) {
+                details = property.free_electricity_eligible
+                    ? '🔋 Eligible for free electricity sessions'
+                    : '⚠️ No electricity smart meter, so not eligible for free electricity sessions';
+            }
+            document.getElementById('property-details').textContent = details;
+        }
+        
+        function selectProperty(propertyID) {
+            currentProperty = propertyID;
+            renderPropertyDetails();
+            loadUsageData(currentDays);
+        }
         
         function loadUsageData(days) {
             currentDays = days;
@@ -898,7 +1704,8 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             // Show loading spinner
             showUsageLoading();
             
-            fetch('/api/usage?days=' + days)
+            const property = currentProperty ? '&property=' + encodeURIComponent(currentProperty) : '';
+            fetch(apiBase() + '/usage?days=' + days + '&fuel=' + currentFuel + property)
                 .then(response => response.json())
                 .then(data => {
                     if (data.success) {
@@ -922,7 +1729,7 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             }
             
             // Check if data is null or empty
-            if (!data.data || data.data.length === 0) {
+            if (!data.series || data.measurements === 0) {
                 // Show "No Data" message
                 const chartContainer = document.querySelector('.chart-container');
                 chartContainer.innerHTML = '<div style="text-align: center; padding: 50px; color: rgba(255, 255, 255, 0.7); font-size: 18px;">No Data Available</div>';
@@ -934,24 +1741,53 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             chartContainer.innerHTML = '<canvas id="usageChart"></canvas>';
             const ctx = document.getElementById('usageChart').getContext('2d');
             
-            // Prepare data for Chart.js
-            const chartData = data.data.map(point => ({
-                x: new Date(point.timestamp),
-                y: point.value,
-                cost: point.cost
+            // Prepare one dataset per meter for Chart.js
+            const colours = ['75, 192, 192', '255, 159, 64', '153, 102, 255', '255, 205, 86'];
+            const datasets = data.series.map((series, i) => ({
+                label: data.series.length > 1 ? 'Meter ' + series.device_id + ' (kWh)' : fuelName() + ' Usage (kWh)',
+                data: series.data.map(point => ({
+                    x: new Date(point.timestamp),
+                    y: point.value,
+                    cost: point.cost
+                })),
+                borderColor: 'rgba(' + colours[i % colours.length] + ', 1)',
+                backgroundColor: 'rgba(' + colours[i % colours.length] + ', 0.2)',
+                fill: true,
+                tension: 0.1
             }));
             
+            // Solar export and net grid import, when the meter has an export register
+            if (data.export && data.export.length > 0) {
+                data.export.forEach(series => datasets.push({
+                    label: 'Export' + (data.export.length > 1 ? ' ' + series.device_id : '') + ' (kWh)',
+                    data: series.data.map(point => ({
+                        x: new Date(point.timestamp),
+                        y: point.value,
+                        cost: point.cost
+                    })),
+                    borderColor: 'rgba(74, 222, 128, 1)',
+                    backgroundColor: 'rgba(74, 222, 128, 0.2)',
+                    fill: true,
+                    tension: 0.1
+                }));
+                datasets.push({
+                    label: 'Net import (kWh)',
+                    data: data.net.map(point => ({
+                        x: new Date(point.timestamp),
+                        y: point.net
+                    })),
+                    borderColor: 'rgba(255, 255, 255, 0.9)',
+                    borderDash: [5, 5],
+                    fill: false,
+                    pointRadius: 0,
+                    tension: 0.1
+                });
+            }
+            
             usageChart = new Chart(ctx, {
                 type: 'line',
                 data: {
-                    datasets: [{
-                        label: 'Electricity Usage (kWh)',
-                        data: chartData,
-                        borderColor: 'rgba(75, 192, 192, 1)',
-                        backgroundColor: 'rgba(75, 192, 192, 0.2)',
-                        fill: true,
-                        tension: 0.1
-                    }]
+                    datasets: datasets
                 },
                 options: {
                     responsive: true,
@@ -989,7 +1825,7 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                             callbacks: {
                                 label: function(context) {
                                     const point = context.raw;
-                                    return 'Usage: ' + point.y.toFixed(3) + ' kWh';
+                                    return context.dataset.label.replace(' (kWh)', '') + ': ' + point.y.toFixed(3) + ' kWh';
                                 }
                             }
                         }
@@ -1000,7 +1836,7 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
         
         function updateUsageStats(data) {
             // Check if data is null or empty
-            if (!data.data || data.data.length === 0) {
+            if (!data.series || data.measurements === 0) {
                 const statsHTML = '<div style="display: flex; justify-content: space-around; margin-top: 15px;">' +
                     '<div><strong>Total Usage:</strong> No data</div>' +
                     '<div><strong>Average:</strong> No data</div>' +
@@ -1011,8 +1847,9 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 return;
             }
             
-            const totalUsage = data.data.reduce((sum, point) => sum + point.value, 0);
-            const totalCost = data.data.reduce((sum, point) => sum + point.cost, 0);
+            const points = data.series.flatMap(series => series.data);
+            const totalUsage = points.reduce((sum, point) => sum + point.value, 0);
+            const totalCost = points.reduce((sum, point) => sum + point.cost, 0);
             const avgUsage = totalUsage / data.measurements;
             
             const statsHTML = '<div style="display: flex; justify-content: space-around; margin-top: 15px;">' +
@@ -1022,7 +1859,18 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
                 '<div><strong>Period:</strong> ' + data.days + ' days</div>' +
                 '</div>';
             
-            document.getElementById('usage-stats').innerHTML = statsHTML;
+            // Octopus only sees what crosses the meter, so self-consumption
+            // (solar used in the home) cannot be shown - only import, export and net
+            let exportHTML = '';
+            if (data.export_kwh !== undefined) {
+                exportHTML = '<div style="display: flex; justify-content: space-around; margin-top: 10px;">' +
+                    '<div><strong>Exported:</strong> ' + data.export_kwh.toFixed(2) + ' kWh</div>' +
+                    '<div><strong>Export Earnings:</strong> £' + data.export_earnings.toFixed(2) + '</div>' +
+                    '<div><strong>Net Import:</strong> ' + (totalUsage - data.export_kwh).toFixed(2) + ' kWh</div>' +
+                    '</div>';
+            }
+            
+            document.getElementById('usage-stats').innerHTML = statsHTML + exportHTML;
         }
         
         function showUsageLoading() {
@@ -1053,12 +1901,185 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
             document.getElementById('usage-stats').innerHTML = '';
         }
         
-        // Initial load
-        updateDashboard();
-        loadUsageData(7); // Load 7 days of usage data by default
+        // OctoPoints balance over time, from the stored ledger
+        let pointsChart = null;
+        
+        function loadPointsHistory() {
+            fetch(apiBase() + '/points/history')
+                .then(response => response.json())
+                .then(data => {
+                    const pointsKey = data.balance + ':' + data.entries.length + ':' + data.redemptions.length;
+                    if (window.lastPointsKey === pointsKey) {
+                        return;
+                    }
+                    window.lastPointsKey = pointsKey;
+                    
+                    const reasonLabels = {saving_session: 'Saving sessions', wheel_spin: 'Wheel spins', referral: 'Referrals', redemption: 'Redemptions', other: 'Other'};
+                    document.getElementById('points-reasons').innerHTML = '<div style="display: flex; justify-content: space-around; flex-wrap: wrap; margin-top: 15px;">' +
+                        '<div><strong>Balance:</strong> ' + data.balance + '</div>' +
+                        data.reasons.filter(r => r.entries > 0).map(r =>
+                            '<div><strong>' + (reasonLabels[r.reason] || r.reason) + ':</strong> ' + (r.points > 0 ? '+' : '') + r.points + ' (' + r.entries + ')</div>'
+                        ).join('') +
+                        '</div>';
+                    
+                    // Latest entry in the redemption audit trail, if redemption is enabled
+                    const redemption = data.redemptions[0];
+                    if (redemption) {
+                        const outcome = redemption.dry_run ? 'Dry run: would redeem'
+                            : redemption.pending ? 'Interrupted redeeming'
+                            : (redemption.error ? 'Failed to redeem' : 'Redeemed');
+                        document.getElementById('points-reasons').innerHTML +=
+                            '<div style="text-align: center; margin-top: 10px; opacity: 0.85;">' +
+                            outcome + ' ' + redemption.points + ' points (£' + redemption.credit_pounds.toFixed(2) + ') on ' + formatDate(redemption.at) +
+                            '</div>';
+                    }
+                    
+                    if (pointsChart) {
+                        pointsChart.destroy();
+                        pointsChart = null;
+                    }
+                    if (data.entries.length === 0) {
+                        return;
+                    }
+                    
+                    // Entries arrive newest first
+                    const entries = data.entries.slice().reverse();
+                    const ctx = document.getElementById('pointsChart').getContext('2d');
+                    pointsChart = new Chart(ctx, {
+                        type: 'line',
+                        data: {
+                            datasets: [{
+                                label: 'OctoPoints',
+                                data: entries.map(e => ({x: new Date(e.posted_at), y: e.balance, entry: e})),
+                                borderColor: 'rgba(255, 215, 0, 0.9)',
+                                backgroundColor: 'rgba(255, 215, 0, 0.2)',
+                                fill: true,
+                                stepped: true,
+                                pointRadius: 3
+                            }]
+                        },
+                        options: {
+                            responsive: true,
+                            maintainAspectRatio: false,
+                            scales: {
+                                x: {
+                                    type: 'time',
+                                    time: { unit: 'day' },
+                                    grid: { color: 'rgba(255, 255, 255, 0.1)' },
+                                    ticks: { color: 'rgba(255, 255, 255, 0.8)' }
+                                },
+                                y: {
+                                    beginAtZero: true,
+                                    grid: { color: 'rgba(255, 255, 255, 0.1)' },
+                                    ticks: { color: 'rgba(255, 255, 255, 0.8)' }
+                                }
+                            },
+                            plugins: {
+                                legend: { display: false },
+                                tooltip: {
+                                    callbacks: {
+                                        label: function(context) {
+                                            const entry = context.raw.entry;
+                                            return (entry.delta > 0 ? '+' : '') + entry.delta + ' ' + (reasonLabels[entry.reason] || entry.reason) + ', balance ' + entry.balance;
+                                        }
+                                    }
+                                }
+                            }
+                        }
+                    });
+                })
+                .catch(error => {
+                    console.error('Error loading OctoPoints history:', error);
+                });
+        }
+        
+        // Prize distribution and expected value of the stored spins
+        let wheelChart = null;
+        
+        function loadWheelHistory() {
+            fetch(apiBase() + '/wheel/history')
+                .then(response => response.json())
+                .then(data => {
+                    const check = data.last_check;
+                    const wheelKey = data.spins.length + ':' + (data.spins.length ? data.spins[0].spun_at : '') + ':' + (check ? check.checked_at : '');
+                    if (window.lastWheelKey === wheelKey) {
+                        return;
+                    }
+                    window.lastWheelKey = wheelKey;
+                    
+                    if (wheelChart) {
+                        wheelChart.destroy();
+                        wheelChart = null;
+                    }
+                    if (data.spins.length === 0) {
+                        document.getElementById('wheel-stats').innerHTML = '<div style="text-align: center; opacity: 0.7;">No wheel spins recorded yet</div>';
+                        return;
+                    }
+                    
+                    const fuelLabels = {all: 'All spins', electricity: 'Electricity', gas: 'Gas'};
+                    document.getElementById('wheel-stats').innerHTML = '<div style="display: flex; justify-content: space-around; flex-wrap: wrap; margin-top: 15px;">' +
+                        data.stats.filter(s => s.spins > 0).map(s =>
+                            '<div><strong>' + (fuelLabels[s.fuel_type] || s.fuel_type) + ':</strong> ' + s.spins + ' spins, ' + s.points + ' points, ' + s.expected_value.toFixed(1) + ' per spin</div>'
+                        ).join('') +
+                        '</div>';
+                    if (check) {
+                        document.getElementById('wheel-stats').innerHTML +=
+                            '<div style="text-align: center; margin-top: 10px; opacity: 0.85;">' +
+                            (check.verified ? '✅ Last spins verified: balance ' + check.actual
+                                : '⚠️ Last spins expected a balance of ' + check.expected + ' but found ' + check.actual) +
+                            ' (' + formatDate(check.checked_at) + ')</div>';
+                    }
+                    
+                    // The first stats entry covers every fuel
+                    const distribution = data.stats[0].distribution;
+                    const ctx = document.getElementById('wheelChart').getContext('2d');
+                    wheelChart = new Chart(ctx, {
+                        type: 'bar',
+                        data: {
+                            labels: distribution.map(d => d.prize + ' points'),
+                            datasets: [{
+                                label: 'Spins',
+                                data: distribution.map(d => d.count),
+                                backgroundColor: 'rgba(255, 215, 0, 0.6)',
+                                borderColor: 'rgba(255, 215, 0, 0.9)',
+                                borderWidth: 1
+                            }]
+                        },
+                        options: {
+                            responsive: true,
+                            maintainAspectRatio: false,
+                            scales: {
+                                x: {
+                                    grid: { color: 'rgba(255, 255, 255, 0.1)' },
+                                    ticks: { color: 'rgba(255, 255, 255, 0.8)' }
+                                },
+                                y: {
+                                    beginAtZero: true,
+                                    grid: { color: 'rgba(255, 255, 255, 0.1)' },
+                                    ticks: { color: 'rgba(255, 255, 255, 0.8)', precision: 0 }
+                                }
+                            },
+                            plugins: {
+                                legend: { display: false }
+                            }
+                        }
+                    });
+                })
+                .catch(error => {
+                    console.error('Error loading Wheel of Fortune history:', error);
+                });
+        }
+        
+        // Initial load, once the account to show is known (7 days of usage by default)
+        loadAccounts().then(refreshAll);
+        setInterval(loadPointsHistory, 30000);
+        setInterval(loadWheelHistory, 30000);
         
         // Auto-refresh every 30 seconds
         setInterval(updateDashboard, 30000);
+        
+        // Live demand follows the Home Mini's 10-second readings
+        setInterval(updateLiveDemand, 10000);
     </script>
 </body>
 </html>`
@@ -1066,4 +2087,237 @@ func (ws *WebServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	tmpl := template.Must(template.New("dashboard").Parse(dashboardHTML))
 	w.Header().Set("Content-Type", "text/html")
 	tmpl.Execute(w, nil)
-}
\ No newline at end of file
+}
+
+func (ws *WebServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	const historyHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Saving Session History</title>
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+        
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            min-height: 100vh;
+            padding: 20px;
+        }
+        
+        .container {
+            max-width: 1200px;
+            margin: 0 auto;
+        }
+        
+        .header {
+            text-align: center;
+            margin-bottom: 40px;
+        }
+        
+        .header h1 {
+            font-size: 2.5rem;
+            margin-bottom: 10px;
+        }
+        
+        .header a {
+            color: white;
+        }
+        
+        .section {
+            background: rgba(255, 255, 255, 0.1);
+            backdrop-filter: blur(10px);
+            border-radius: 10px;
+            padding: 25px;
+            margin-bottom: 30px;
+        }
+        
+        .section h2 {
+            margin-bottom: 20px;
+            font-size: 1.5rem;
+            border-bottom: 2px solid rgba(255, 255, 255, 0.3);
+            padding-bottom: 10px;
+        }
+        
+        .seasons {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(220px, 1fr));
+            gap: 20px;
+        }
+        
+        .season {
+            background: rgba(255, 255, 255, 0.1);
+            border-radius: 8px;
+            padding: 15px;
+            cursor: pointer;
+        }
+        
+        .season.selected {
+            outline: 2px solid white;
+        }
+        
+        .season-name {
+            font-weight: bold;
+            font-size: 1.1rem;
+            margin-bottom: 8px;
+        }
+        
+        .season-points {
+            font-size: 1.5rem;
+            font-weight: bold;
+            margin-bottom: 5px;
+        }
+        
+        .season-details {
+            font-size: 0.9rem;
+            opacity: 0.9;
+        }
+        
+        table {
+            width: 100%;
+            border-collapse: collapse;
+            font-size: 0.9rem;
+        }
+        
+        th, td {
+            text-align: left;
+            padding: 8px;
+            border-bottom: 1px solid rgba(255, 255, 255, 0.2);
+        }
+        
+        .status-awarded { color: #4ade80; }
+        .status-joined, .status-awaiting_award { color: #facc15; }
+        .status-join_failed { color: #f87171; }
+        .status-skipped, .status-missed { opacity: 0.7; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>📜 Saving Session History</h1>
+            <a href="/" id="dashboard-link">← Back to dashboard</a>
+        </div>
+        
+        <div class="section">
+            <h2>🏆 Seasons</h2>
+            <div class="seasons" id="seasons">Loading...</div>
+        </div>
+        
+        <div class="section">
+            <h2 id="sessions-title">💡 Sessions</h2>
+            <table>
+                <thead>
+                    <tr>
+                        <th>Session</th>
+                        <th>Announced</th>
+                        <th>Decision</th>
+                        <th>Status</th>
+                        <th>Points/kWh</th>
+                        <th>Awarded</th>
+                    </tr>
+                </thead>
+                <tbody id="sessions"></tbody>
+            </table>
+        </div>
+    </div>
+    
+    <script>
+        const statusLabels = {
+            awarded: '✅ Awarded',
+            joined: '📅 Joined',
+            awaiting_award: '⏳ Awaiting points',
+            join_failed: '❌ Join failed',
+            skipped: '⏭️ Skipped',
+            missed: '➖ Missed'
+        };
+        let selectedSeason = '';
+        
+        // History for the account picked on the dashboard
+        const account = new URLSearchParams(window.location.search).get('account') || '';
+        const apiBase = account ? '/api/accounts/' + encodeURIComponent(account) : '/api';
+        if (account) {
+            document.getElementById('dashboard-link').href = '/?account=' + encodeURIComponent(account);
+        }
+        
+        function formatDate(value) {
+            return new Date(value).toLocaleString('en-GB', {
+                weekday: 'short', day: 'numeric', month: 'short', year: 'numeric',
+                hour: '2-digit', minute: '2-digit'
+            });
+        }
+        
+        function sessionStatus(session) {
+            const ended = new Date(session.end_at) < new Date();
+            if (session.awarded_points !== undefined) return 'awarded';
+            if (session.joined) return ended ? 'awaiting_award' : 'joined';
+            if (session.join_error) return 'join_failed';
+            if (session.decision && !session.decision.join) return 'skipped';
+            return 'missed';
+        }
+        
+        function renderSeason(summary) {
+            const div = document.createElement('div');
+            div.className = 'season' + (summary.season === selectedSeason ? ' selected' : '');
+            div.innerHTML = ` + "`" + `
+                <div class="season-name">${summary.season === 'all' ? 'All seasons' : summary.season}</div>
+                <div class="season-points">${summary.awarded_points.toLocaleString()} points</div>
+                <div class="season-details">
+                    Joined ${summary.joined} of ${summary.sessions} sessions<br>
+                    ${summary.skipped} skipped, ${summary.failed} failed, ${summary.missed} missed<br>
+                    ${summary.pending_awards} awaiting points
+                </div>
+            ` + "`" + `;
+            div.onclick = () => loadHistory(summary.season === 'all' ? '' : summary.season);
+            return div;
+        }
+        
+        async function loadHistory(season) {
+            selectedSeason = season;
+            try {
+                const response = await fetch(apiBase + '/history/sessions' + (season ? '?season=' + encodeURIComponent(season) : ''));
+                const data = await response.json();
+                
+                const seasons = document.getElementById('seasons');
+                seasons.innerHTML = '';
+                seasons.appendChild(renderSeason(data.totals));
+                data.seasons.forEach(summary => seasons.appendChild(renderSeason(summary)));
+                
+                document.getElementById('sessions-title').textContent = '💡 Sessions' + (season ? ' (' + season + ')' : '');
+                const tbody = document.getElementById('sessions');
+                tbody.innerHTML = '';
+                if (data.sessions.length === 0) {
+                    tbody.innerHTML = '<tr><td colspan="6">No sessions recorded yet</td></tr>';
+                    return;
+                }
+                data.sessions.forEach(session => {
+                    const status = sessionStatus(session);
+                    const row = document.createElement('tr');
+                    row.innerHTML = ` + "`" + `
+                        <td>${formatDate(session.start_at)}</td>
+                        <td>${formatDate(session.announced_at)}</td>
+                        <td>${session.decision ? session.decision.reason : '-'}</td>
+                        <td class="status-${status}" title="${session.join_error || ''}">${statusLabels[status]}</td>
+                        <td>${session.points_per_kwh ? session.points_per_kwh.toLocaleString() : '-'}</td>
+                        <td>${session.awarded_points !== undefined ? session.awarded_points.toLocaleString() : '-'}</td>
+                    ` + "`" + `;
+                    tbody.appendChild(row);
+                });
+            } catch (error) {
+                document.getElementById('seasons').textContent = 'Failed to load history: ' + error.message;
+            }
+        }
+        
+        loadHistory('');
+    </script>
+</body>
+</html>`
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(historyHTML))
+}
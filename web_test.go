@@ -0,0 +1,227 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redirectTransport sends every request to a test server, whatever its host
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestKrakenTransport returns a transport whose requests are answered by a
+// stub Kraken API with one upcoming, unjoined saving session
+func newTestKrakenTransport(t *testing.T) http.RoundTripper {
+	start := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	end := time.Now().Add(49 * time.Hour).UTC().Format(time.RFC3339)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "free_electricity.json"):
+			w.Write([]byte(`{"data":[]}`))
+		case strings.Contains(string(body), "obtainKrakenToken"):
+			w.Write([]byte(`{"data":{"obtainKrakenToken":{"token":"test-token","refreshExpiresIn":3600}}}`))
+		case strings.Contains(string(body), "events"):
+			w.Write([]byte(`{"data":{"savingSessions":{"events":[{"id":7,"code":"EVENT_7","startAt":"` + start + `","endAt":"` + end + `","rewardPerKwhInOctoPoints":800}]}}}`))
+		default:
+			w.Write([]byte(`{"data":{}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return redirectTransport{target: target}
+}
+
+// newTestKrakenClient returns a client backed by newTestKrakenTransport
+func newTestKrakenClient(t *testing.T) *OctopusClient {
+	client := NewOctopusClient("A-TEST1234", "sk_live_test", false)
+	client.minInterval = 0
+	client.SetTransport(newTestKrakenTransport(t))
+	return client
+}
+
+// newOfflineMonitor returns a monitor for a test to seed directly. Its client
+// gets a 404 for anything not already cached, and state files are kept out of
+// the real config directory.
+func newOfflineMonitor(t *testing.T) *SavingSessionMonitor {
+	t.Setenv("HOME", t.TempDir())
+	client := NewOctopusClient(DemoAccountID, DemoAPIKey, false)
+	client.minInterval = 0
+	client.SetTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+	}))
+	return NewSavingSessionMonitor(client, DemoAccountID)
+}
+
+// monitorAPI serves a monitor's web API and metrics to a test
+type monitorAPI struct {
+	t       *testing.T
+	monitor *SavingSessionMonitor
+	ws      *WebServer
+}
+
+func newMonitorAPI(t *testing.T, monitor *SavingSessionMonitor) *monitorAPI {
+	ws := NewWebServer(monitor, 0)
+	monitor.SetWebServer(ws)
+	return &monitorAPI{t: t, monitor: monitor, ws: ws}
+}
+
+// get requests path and decodes a 200 response into v, returning the status
+func (a *monitorAPI) get(path string, v interface{}) int {
+	a.t.Helper()
+	recorder := httptest.NewRecorder()
+	a.ws.server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	if recorder.Code == http.StatusOK && v != nil {
+		if err := json.NewDecoder(recorder.Body).Decode(v); err != nil {
+			a.t.Fatalf("%s: expected JSON, got %v", path, err)
+		}
+	}
+	return recorder.Code
+}
+
+// expectMetrics records a metrics snapshot and checks the scrape has every
+// expected line
+func (a *monitorAPI) expectMetrics(expected ...string) {
+	a.t.Helper()
+	a.monitor.recordMetricsSnapshot()
+	output := NewMetricsCollector(a.monitor.client, a.monitor).collectMetrics()
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			a.t.Errorf("Expected %q in metrics output", line)
+		}
+	}
+}
+
+func TestWebAPIConcurrentWithMonitor(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // Keep state files out of the real config directory
+
+	client := newTestKrakenClient(t)
+	monitor := NewSavingSessionMonitor(client, "A-TEST1234")
+	ws := NewWebServer(monitor, 0)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// The monitor loop checks and spins repeatedly, rewriting state and caches
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			monitor.checkForNewSessions()
+			monitor.runCommand(MonitorCommand{Kind: CommandSpinWheels})
+		}
+	}()
+
+	// Meanwhile the dashboard, usage refresh and metrics endpoints are hammered
+	paths := []string{"/api/sessions", "/api/history/sessions", "/api/usage/refresh?days=1", "/metrics"}
+	for _, path := range paths {
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					recorder := httptest.NewRecorder()
+					ws.server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+					if path == "/api/sessions" && recorder.Code != http.StatusOK {
+						t.Errorf("Expected 200 from %s, got %d", path, recorder.Code)
+					}
+				}
+			}(path)
+		}
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	// The announced session was evaluated and joined by the monitor
+	recorder := httptest.NewRecorder()
+	ws.server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/sessions", nil))
+	var data SessionData
+	if err := json.NewDecoder(recorder.Body).Decode(&data); err != nil {
+		t.Fatalf("Expected JSON session data, got %v", err)
+	}
+	if decision, ok := data.JoinDecisions[7]; !ok || !decision.Join {
+		t.Errorf("Expected a join decision for event 7, got %v", data.JoinDecisions)
+	}
+}
+
+func TestWebAccountRoutes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	home := NewSavingSessionMonitor(NewOctopusClient("A-HOME0001", "sk_live_test", false), "A-HOME0001")
+	home.SetName("Home")
+	flat := NewSavingSessionMonitor(NewOctopusClient("A-FLAT0002", "sk_live_test", false), "A-FLAT0002")
+	flat.state.RecordWheelSpins([]WheelSpinResult{{Prize: 20, FuelType: "ELECTRICITY"}}, time.Now())
+	ws := NewAccountsWebServer([]*SavingSessionMonitor{home, flat}, 0)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ws.server.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	var accounts []AccountSummary
+	if err := json.NewDecoder(get("/api/accounts").Body).Decode(&accounts); err != nil {
+		t.Fatalf("Expected JSON accounts, got %v", err)
+	}
+	if len(accounts) != 2 || accounts[0] != (AccountSummary{ID: "A-HOME0001", Name: "Home"}) || accounts[1].Name != "A-FLAT0002" {
+		t.Errorf("Expected both accounts, named or by ID, got %+v", accounts)
+	}
+
+	// Each account's routes read its own state, and the unscoped routes the first account's
+	for path, spins := range map[string]int{
+		"/api/wheel/history":                     0,
+		"/api/accounts/A-HOME0001/wheel/history": 0,
+		"/api/accounts/a-flat0002/wheel/history": 1,
+	} {
+		var history WheelHistory
+		if err := json.NewDecoder(get(path).Body).Decode(&history); err != nil {
+			t.Fatalf("%s: expected JSON wheel history, got %v", path, err)
+		}
+		if len(history.Spins) != spins {
+			t.Errorf("%s: expected %d spins, got %d", path, spins, len(history.Spins))
+		}
+	}
+
+	if recorder := get("/api/accounts/A-OTHER003/wheel/history"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown account, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// WheelSpinRecord is one Wheel of Fortune spin kept in the state file
+type WheelSpinRecord struct {
+	SpunAt   time.Time `json:"spun_at"`
+	FuelType string    `json:"fuel_type"` // "ELECTRICITY" or "GAS"
+	Prize    int       `json:"prize"`
+}
+
+// WheelSpinTotal counts every spin for a fuel. Unlike the history it is
+// never trimmed, so it can back Prometheus counters.
+type WheelSpinTotal struct {
+	Spins  int `json:"spins"`
+	Points int `json:"points"`
+}
+
+// WheelSpinCheck compares the OctoPoints balance after a batch of spins with
+// the balance before plus the prizes won
+type WheelSpinCheck struct {
+	CheckedAt     time.Time `json:"checked_at"`
+	Spins         int       `json:"spins"`
+	BalanceBefore int       `json:"balance_before"`
+	Expected      int       `json:"expected"`
+	Actual        int       `json:"actual"`
+	Verified      bool      `json:"verified"`
+}
+
+// verifyWheelSpinBalance checks a batch of spins against the balance either side
+func verifyWheelSpinBalance(before, after int, results []WheelSpinResult, now time.Time) WheelSpinCheck {
+	check := WheelSpinCheck{CheckedAt: now, Spins: len(results), BalanceBefore: before, Expected: before, Actual: after}
+	for _, result := range results {
+		check.Expected += result.Prize
+	}
+	check.Verified = check.Actual == check.Expected
+	return check
+}
+
+// RecordWheelSpins adds a batch of spins to the history, keeping the latest
+// StateMaxWheelSpinHistory, and to the per-fuel totals
+func (s *AppState) RecordWheelSpins(results []WheelSpinResult, now time.Time) {
+	s.Update(func(s *AppState) {
+		history := make([]WheelSpinRecord, 0, len(s.WheelSpinHistory)+len(results))
+		history = append(history, s.WheelSpinHistory...)
+		totals := make(map[string]WheelSpinTotal, len(s.WheelSpinTotals)+1)
+		for fuel, total := range s.WheelSpinTotals {
+			totals[fuel] = total
+		}
+
+		for _, result := range results {
+			history = append(history, WheelSpinRecord{SpunAt: now, FuelType: result.FuelType, Prize: result.Prize})
+			total := totals[result.FuelType]
+			total.Spins++
+			total.Points += result.Prize
+			totals[result.FuelType] = total
+		}
+		if len(history) > StateMaxWheelSpinHistory {
+			history = history[len(history)-StateMaxWheelSpinHistory:]
+		}
+		s.WheelSpinHistory = history
+		s.WheelSpinTotals = totals
+	})
+}
+
+// PrizeCount is how often a prize came up
+type PrizeCount struct {
+	Prize int `json:"prize"`
+	Count int `json:"count"`
+}
+
+// WheelSpinStats summarises the stored spins for one fuel, or "all"
+type WheelSpinStats struct {
+	FuelType      string       `json:"fuel_type"`
+	Spins         int          `json:"spins"`
+	Points        int          `json:"points"`
+	ExpectedValue float64      `json:"expected_value"` // average prize per spin
+	Distribution  []PrizeCount `json:"distribution"`   // smallest prize first
+}
+
+// WheelHistory is the /api/wheel/history response
+type WheelHistory struct {
+	Spins     []WheelSpinRecord         `json:"spins"` // newest first
+	Stats     []WheelSpinStats          `json:"stats"` // all, then each fuel
+	Totals    map[string]WheelSpinTotal `json:"totals"`
+	LastCheck *WheelSpinCheck           `json:"last_check,omitempty"`
+}
+
+// wheelSpinStats works out the prize distribution and expected value of spins
+func wheelSpinStats(fuelType string, spins []WheelSpinRecord) WheelSpinStats {
+	stats := WheelSpinStats{FuelType: fuelType, Distribution: []PrizeCount{}}
+	counts := make(map[int]int)
+	for _, spin := range spins {
+		stats.Spins++
+		stats.Points += spin.Prize
+		counts[spin.Prize]++
+	}
+	for prize, count := range counts {
+		stats.Distribution = append(stats.Distribution, PrizeCount{Prize: prize, Count: count})
+	}
+	sort.Slice(stats.Distribution, func(i, j int) bool { return stats.Distribution[i].Prize < stats.Distribution[j].Prize })
+	if stats.Spins > 0 {
+		stats.ExpectedValue = float64(stats.Points) / float64(stats.Spins)
+	}
+	return stats
+}
+
+// buildWheelHistory lists the stored spins newest first with their statistics
+func buildWheelHistory(spins []WheelSpinRecord, totals map[string]WheelSpinTotal, lastCheck *WheelSpinCheck) WheelHistory {
+	history := WheelHistory{
+		Spins:     make([]WheelSpinRecord, 0, len(spins)),
+		Stats:     []WheelSpinStats{wheelSpinStats("all", spins)},
+		Totals:    make(map[string]WheelSpinTotal, len(totals)),
+		LastCheck: lastCheck,
+	}
+	for i := len(spins) - 1; i >= 0; i-- {
+		history.Spins = append(history.Spins, spins[i])
+	}
+	for _, fuel := range []string{"ELECTRICITY", "GAS"} {
+		var fuelSpins []WheelSpinRecord
+		for _, spin := range spins {
+			if spin.FuelType == fuel {
+				fuelSpins = append(fuelSpins, spin)
+			}
+		}
+		history.Stats = append(history.Stats, wheelSpinStats(strings.ToLower(fuel), fuelSpins))
+	}
+	for fuel, total := range totals {
+		history.Totals[strings.ToLower(fuel)] = total
+	}
+	return history
+}
+
+// freshOctoPoints reads the balance past the cache, so it reflects any spins
+func (m *SavingSessionMonitor) freshOctoPoints() (int, error) {
+	m.state.Update(func(s *AppState) { s.CachedOctoPoints = nil })
+	return m.client.getOctoPointsGraphQLWithCache(m.state)
+}
+
+// verifyWheelSpins checks the balance after a batch of spins and records the
+// result. Octopus credits prizes straight away, so a mismatch means a prize
+// went missing or the balance moved for another reason.
+func (m *SavingSessionMonitor) verifyWheelSpins(before int, results []WheelSpinResult) {
+	after, err := m.freshOctoPoints()
+	if err != nil {
+		m.logger.Warn("Could not verify OctoPoints after spinning", "error", err.Error())
+		return
+	}
+
+	check := verifyWheelSpinBalance(before, after, results, time.Now())
+	m.state.Update(func(s *AppState) { s.LastWheelSpinCheck = &check })
+	if check.Verified {
+		m.logger.Info("OctoPoints balance matches the prizes won", "balance", check.Actual)
+		return
+	}
+	m.logger.Warn("OctoPoints balance does not match the prizes won",
+		"balance_before", check.BalanceBefore,
+		"expected", check.Expected,
+		"actual", check.Actual,
+		"difference", check.Actual-check.Expected,
+	)
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Matthew Gall <me@matthewgall.dev>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyWheelSpinBalance(t *testing.T) {
+	results := []WheelSpinResult{{Prize: 20, FuelType: "ELECTRICITY"}, {Prize: 100, FuelType: "GAS"}}
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	if check := verifyWheelSpinBalance(1850, 1970, results, now); !check.Verified || check.Expected != 1970 || check.Spins != 2 {
+		t.Errorf("Expected the prizes to verify, got %+v", check)
+	}
+	if check := verifyWheelSpinBalance(1850, 1870, results, now); check.Verified || check.Expected != 1970 || check.Actual != 1870 {
+		t.Errorf("Expected a missing prize to fail verification, got %+v", check)
+	}
+}
+
+func TestRecordWheelSpins(t *testing.T) {
+	state := &AppState{}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < StateMaxWheelSpinHistory; i++ {
+		state.RecordWheelSpins([]WheelSpinResult{{Prize: 10, FuelType: "ELECTRICITY"}}, start.Add(time.Duration(i)*time.Hour))
+	}
+	snapshot := state.Snapshot()
+	state.RecordWheelSpins([]WheelSpinResult{{Prize: 100, FuelType: "GAS"}, {Prize: 5, FuelType: "ELECTRICITY"}}, start.AddDate(1, 0, 0))
+
+	if len(snapshot.WheelSpinHistory) != StateMaxWheelSpinHistory || snapshot.WheelSpinTotals["GAS"].Spins != 0 {
+		t.Errorf("Expected earlier snapshots to be unchanged, got %d spins and %+v", len(snapshot.WheelSpinHistory), snapshot.WheelSpinTotals)
+	}
+
+	// The history is capped but the totals keep counting
+	current := state.Snapshot()
+	if history := current.WheelSpinHistory; len(history) != StateMaxWheelSpinHistory || history[len(history)-1].Prize != 5 || !history[0].SpunAt.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Expected the latest %d spins, got %d starting %v", StateMaxWheelSpinHistory, len(history), history[0].SpunAt)
+	}
+	if totals := current.WheelSpinTotals; totals["ELECTRICITY"] != (WheelSpinTotal{Spins: StateMaxWheelSpinHistory + 1, Points: 10*StateMaxWheelSpinHistory + 5}) || totals["GAS"] != (WheelSpinTotal{Spins: 1, Points: 100}) {
+		t.Errorf("Expected lifetime totals per fuel, got %+v", totals)
+	}
+}
+
+func TestBuildWheelHistory(t *testing.T) {
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	spins := []WheelSpinRecord{
+		{SpunAt: at, FuelType: "ELECTRICITY", Prize: 20},
+		{SpunAt: at, FuelType: "ELECTRICITY", Prize: 5},
+		{SpunAt: at, FuelType: "GAS", Prize: 20},
+		{SpunAt: at.Add(time.Hour), FuelType: "GAS", Prize: 100},
+	}
+
+	history := buildWheelHistory(spins, map[string]WheelSpinTotal{"GAS": {Spins: 2, Points: 120}}, nil)
+	if len(history.Spins) != 4 || history.Spins[0].Prize != 100 {
+		t.Errorf("Expected spins newest first, got %+v", history.Spins)
+	}
+	if len(history.Stats) != 3 {
+		t.Fatalf("Expected stats for all spins and each fuel, got %+v", history.Stats)
+	}
+
+	all := history.Stats[0]
+	if all.FuelType != "all" || all.Spins != 4 || all.Points != 145 || all.ExpectedValue != 36.25 {
+		t.Errorf("Expected 145 points over 4 spins, got %+v", all)
+	}
+	expected := []PrizeCount{{Prize: 5, Count: 1}, {Prize: 20, Count: 2}, {Prize: 100, Count: 1}}
+	for i, count := range expected {
+		if i >= len(all.Distribution) || all.Distribution[i] != count {
+			t.Errorf("Expected distribution %+v, got %+v", expected, all.Distribution)
+			break
+		}
+	}
+	if gas := history.Stats[2]; gas.FuelType != "gas" || gas.ExpectedValue != 60 {
+		t.Errorf("Expected gas to average 60 points, got %+v", gas)
+	}
+	if history.Totals["gas"].Points != 120 {
+		t.Errorf("Expected totals keyed by lower case fuel, got %+v", history.Totals)
+	}
+}
+
+func TestSpinWheelsRecordsAndVerifies(t *testing.T) {
+	fixtures := DefaultKrakenFixtures()
+	fixtures.ElectricitySpins = 1 // spins sleep between calls
+	fixtures.GasSpins = 0
+	_, client := startFakeKraken(t, fixtures)
+	monitor := NewSavingSessionMonitor(client, fixtures.AccountID)
+
+	results, err := monitor.spinWheels()
+	if err != nil || len(results) != 1 || results[0].Prize != 20 {
+		t.Fatalf("Expected one 20 point spin, got %+v (%v)", results, err)
+	}
+
+	snapshot := monitor.state.Snapshot()
+	if len(snapshot.WheelSpinHistory) != 1 || snapshot.WheelSpinHistory[0].FuelType != "ELECTRICITY" {
+		t.Errorf("Expected the spin in the history, got %+v", snapshot.WheelSpinHistory)
+	}
+	if check := snapshot.LastWheelSpinCheck; check == nil || !check.Verified || check.Actual != fixtures.Points+20 {
+		t.Errorf("Expected the new balance to be verified, got %+v", check)
+	}
+}
+
+// recordTestWheelSpin stores a verified 20 point electricity spin
+func recordTestWheelSpin(state *AppState) {
+	now := time.Now()
+	results := []WheelSpinResult{{Prize: 20, FuelType: "ELECTRICITY"}}
+	check := verifyWheelSpinBalance(1850, 1870, results, now)
+	state.RecordWheelSpins(results, now)
+	state.Update(func(s *AppState) { s.LastWheelSpinCheck = &check })
+}
+
+func TestWheelHistoryAPI(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	recordTestWheelSpin(monitor.state)
+
+	var history WheelHistory
+	if status := newMonitorAPI(t, monitor).get("/api/wheel/history", &history); status != 200 {
+		t.Fatalf("Expected the wheel history, got status %d", status)
+	}
+	if len(history.Spins) != 1 || history.Stats[0].ExpectedValue != 20 || history.LastCheck == nil || !history.LastCheck.Verified {
+		t.Errorf("Expected the spin and its check in the wheel history, got %+v", history)
+	}
+}
+
+func TestWheelMetrics(t *testing.T) {
+	monitor := newOfflineMonitor(t)
+	recordTestWheelSpin(monitor.state)
+
+	newMonitorAPI(t, monitor).expectMetrics(
+		`octojoin_wheel_spun_total{account="A-DEMO0001",fuel_type="electricity"} 1`,
+		`octojoin_wheel_spun_total{account="A-DEMO0001",fuel_type="gas"} 0`,
+		`octojoin_wheel_prize_points_total{account="A-DEMO0001",fuel_type="electricity"} 20`,
+		`octojoin_wheel_balance_verified{account="A-DEMO0001"} 1`,
+	)
+}